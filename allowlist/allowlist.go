@@ -0,0 +1,236 @@
+// Package allowlist provides an sqlmw.Interceptor rejecting the statements
+// that were not reviewed, for the services where any unexpected SQL is an
+// incident.
+//
+// The allowlist holds the normalized statements allowed to run, in
+// Config.Dialect, see fingerprint.NormalizeDialect, one per line:
+//
+//	# reviewed 2024-03-01
+//	select name from users where id = ?
+//	update users set name = ? where id = ?
+//
+// It is loaded with ReadFile, Read or, from an embedded file system, ReadFS.
+// The fingerprints learnt by the injection package are written with Write.
+//
+// In the Enforce mode, the statements missing from the allowlist are
+// failed with an *Error when they are run or prepared, and reported as
+// violations. So are the MySQL statements with executable comments or
+// optimizer hints, /*!...*/ and /*+...*/, whose code the fingerprint does
+// not include. In the Capture mode, they run and are written to
+// Config.Capture instead, so that the allowlist can be generated by running
+// the integration tests.
+package allowlist
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/ngrok/sqlmw"
+	"github.com/ngrok/sqlmw/fingerprint"
+	"github.com/ngrok/sqlmw/sqlinfo"
+)
+
+const defaultMaxViolations = 1000
+
+// Mode is what an Interceptor does with the statements missing from the
+// allowlist.
+type Mode int
+
+const (
+	// Enforce fails them.
+	Enforce Mode = iota
+	// Capture lets them run and writes them to Config.Capture.
+	Capture
+)
+
+// Error is the error of the statements missing from the allowlist.
+type Error struct {
+	Query       string
+	Fingerprint string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("allowlist: statement not allowed: %q", e.Fingerprint)
+}
+
+// Violation counts the statements of a fingerprint that were denied.
+type Violation struct {
+	Fingerprint string
+	// Query is the first statement denied.
+	Query string
+	Count uint64
+}
+
+// Config configures an Interceptor.
+type Config struct {
+	// Mode is what to do with the statements missing from the allowlist.
+	// Defaults to Enforce.
+	Mode Mode
+
+	// Fingerprints is the allowlist.
+	Fingerprints []string
+
+	// Dialect is used to fingerprint the statements, see
+	// fingerprint.NormalizeDialect. Defaults to sqlinfo.Generic.
+	Dialect sqlinfo.Dialect
+
+	// OnViolation, if set, is called with every statement denied in the
+	// Enforce mode.
+	OnViolation func(*Error)
+
+	// MaxViolations bounds the number of fingerprints returned by
+	// Violations. Defaults to 1000.
+	MaxViolations int
+
+	// Capture receives the fingerprints missing from the allowlist in the
+	// Capture mode, one per line and once each, as they are first seen.
+	Capture io.Writer
+
+	// OnError, if set, is called with the errors of Capture. The statements
+	// are not failed by them.
+	OnError func(error)
+}
+
+// Interceptor rejects the statements missing from an allowlist.
+type Interceptor struct {
+	sqlmw.NullInterceptor
+
+	cfg     Config
+	allowed map[string]bool
+
+	mu         sync.Mutex
+	violations map[string]*Violation
+	captured   map[string]bool
+}
+
+// New returns an Interceptor for the supplied configuration.
+func New(cfg Config) *Interceptor {
+	if cfg.MaxViolations <= 0 {
+		cfg.MaxViolations = defaultMaxViolations
+	}
+	in := &Interceptor{
+		cfg:        cfg,
+		allowed:    make(map[string]bool, len(cfg.Fingerprints)),
+		violations: make(map[string]*Violation),
+		captured:   make(map[string]bool),
+	}
+	for _, fp := range cfg.Fingerprints {
+		in.allowed[fp] = true
+	}
+	return in
+}
+
+// Violations returns the statements denied so far, by fingerprint.
+func (in *Interceptor) Violations() []Violation {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	vs := make([]Violation, 0, len(in.violations))
+	for _, v := range in.violations {
+		vs = append(vs, *v)
+	}
+	sort.Slice(vs, func(i, j int) bool { return vs[i].Fingerprint < vs[j].Fingerprint })
+	return vs
+}
+
+// Captured returns the fingerprints captured so far, sorted.
+func (in *Interceptor) Captured() []string {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	fps := make([]string, 0, len(in.captured))
+	for fp := range in.captured {
+		fps = append(fps, fp)
+	}
+	sort.Strings(fps)
+	return fps
+}
+
+// check returns the error to fail query with.
+func (in *Interceptor) check(query string) error {
+	fp := fingerprint.NormalizeDialect(in.cfg.Dialect, query)
+	if in.allowed[fp] && !in.executable(query) {
+		return nil
+	}
+
+	if in.cfg.Mode == Capture {
+		in.capture(fp)
+		return nil
+	}
+
+	err := &Error{Query: query, Fingerprint: fp}
+	in.mu.Lock()
+	v, ok := in.violations[fp]
+	if !ok && len(in.violations) < in.cfg.MaxViolations {
+		v = &Violation{Fingerprint: fp, Query: query}
+		in.violations[fp] = v
+	}
+	if v != nil {
+		v.Count++
+	}
+	in.mu.Unlock()
+	if in.cfg.OnViolation != nil {
+		in.cfg.OnViolation(err)
+	}
+	return err
+}
+
+// executable reports whether query has MySQL executable comments, which
+// its fingerprint drops.
+func (in *Interceptor) executable(query string) bool {
+	if in.cfg.Dialect != sqlinfo.MySQL {
+		return false
+	}
+	l := sqlinfo.NewLexer(in.cfg.Dialect, query)
+	for {
+		tok, ok := l.Next()
+		if !ok {
+			return false
+		}
+		if tok.Executable() {
+			return true
+		}
+	}
+}
+
+func (in *Interceptor) capture(fp string) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	if in.captured[fp] {
+		return
+	}
+	in.captured[fp] = true
+	if in.cfg.Capture == nil {
+		return
+	}
+	if _, err := io.WriteString(in.cfg.Capture, fp+"\n"); err != nil && in.cfg.OnError != nil {
+		in.cfg.OnError(err)
+	}
+}
+
+func (in *Interceptor) ConnExecContext(ctx context.Context, conn driver.ExecerContext, query string, args []driver.NamedValue) (driver.Result, error) {
+	if err := in.check(query); err != nil {
+		return nil, err
+	}
+	return conn.ExecContext(ctx, query, args)
+}
+
+func (in *Interceptor) ConnQueryContext(ctx context.Context, conn driver.QueryerContext, query string, args []driver.NamedValue) (context.Context, driver.Rows, error) {
+	if err := in.check(query); err != nil {
+		return ctx, nil, err
+	}
+	rows, err := conn.QueryContext(ctx, query, args)
+	return ctx, rows, err
+}
+
+// ConnPrepareContext checks the prepared statements, whose executions are
+// not checked again.
+func (in *Interceptor) ConnPrepareContext(ctx context.Context, conn driver.ConnPrepareContext, query string) (context.Context, driver.Stmt, error) {
+	if err := in.check(query); err != nil {
+		return ctx, nil, err
+	}
+	stmt, err := conn.PrepareContext(ctx, query)
+	return ctx, stmt, err
+}
@@ -0,0 +1,168 @@
+package allowlist
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/ngrok/sqlmw"
+	"github.com/ngrok/sqlmw/internal/fakedb"
+	"github.com/ngrok/sqlmw/sqlinfo"
+	"github.com/ngrok/sqlmw/sqlmwtest"
+)
+
+const allowlist = `# reviewed
+select name from users where id = ?
+
+update users set name = ? where id = ?
+`
+
+func openDB(t *testing.T, in *Interceptor) (*sql.DB, *fakedb.Connector) {
+	con := &fakedb.Connector{}
+	db := sql.OpenDB(sqlmw.Connector(con, in))
+	t.Cleanup(func() {
+		if err := db.Close(); err != nil {
+			t.Errorf("Failed to close db: %v", err)
+		}
+	})
+	return db, con
+}
+
+func TestEnforce(t *testing.T) {
+	dir, err := ioutil.TempDir("", "allowlist")
+	if err != nil {
+		t.Fatalf("TempDir failed: %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	path := filepath.Join(dir, "allowlist.txt")
+	if err := ioutil.WriteFile(path, []byte(allowlist), 0600); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	fps, err := ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+
+	var denied []string
+	in := New(Config{Fingerprints: fps, OnViolation: func(err *Error) { denied = append(denied, err.Query) }})
+	db, con := openDB(t, in)
+	ctx := context.Background()
+
+	if _, err := db.ExecContext(ctx, "UPDATE users SET name = $1 WHERE id = $2", "bob", 1); err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	rows, err := db.QueryContext(ctx, "SELECT name FROM users WHERE id = ?", 1)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	rows.Close()
+
+	var notAllowed *Error
+	_, err = db.ExecContext(ctx, "DELETE FROM users WHERE id = ?", 1)
+	if !errors.As(err, &notAllowed) || notAllowed.Fingerprint != "delete from users where id = ?" {
+		t.Errorf("got error %v, want *Error", err)
+	}
+	if _, err := db.QueryContext(ctx, "SELECT name FROM users WHERE id = 1 OR 1=1"); !errors.As(err, &notAllowed) {
+		t.Errorf("got error %v, want *Error", err)
+	}
+	if _, err := db.PrepareContext(ctx, "DELETE FROM users WHERE id = 7"); !errors.As(err, &notAllowed) {
+		t.Errorf("got error %v, want *Error", err)
+	}
+
+	if got := len(con.Calls()); got != 2 {
+		t.Errorf("got %d calls to the database, want 2", got)
+	}
+	if len(denied) != 3 {
+		t.Errorf("got %d violations reported, want 3", len(denied))
+	}
+	want := []Violation{
+		{Fingerprint: "delete from users where id = ?", Query: "DELETE FROM users WHERE id = ?", Count: 2},
+		{Fingerprint: "select name from users where id = ? or ? = ?", Query: "SELECT name FROM users WHERE id = 1 OR 1=1", Count: 1},
+	}
+	if got := in.Violations(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got violations %+v, want %+v", got, want)
+	}
+}
+
+func TestCapture(t *testing.T) {
+	fps, err := Read(bytes.NewBufferString(allowlist))
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	var captured bytes.Buffer
+	in := New(Config{Mode: Capture, Fingerprints: fps, Capture: &captured})
+	db, con := openDB(t, in)
+	ctx := context.Background()
+
+	for _, query := range []string{
+		"SELECT name FROM users WHERE id = ?",
+		"DELETE FROM users WHERE id = ?",
+		"INSERT INTO users (name) VALUES (?)",
+		"DELETE FROM users WHERE id = ?",
+	} {
+		if _, err := db.ExecContext(ctx, query, 1); err != nil {
+			t.Fatalf("Exec failed: %v", err)
+		}
+	}
+	if got := len(con.Calls()); got != 4 {
+		t.Errorf("got %d calls to the database, want 4", got)
+	}
+	want := "delete from users where id = ?\ninsert into users(name) values(?)\n"
+	if got := captured.String(); got != want {
+		t.Errorf("got capture %q, want %q", got, want)
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, append(fps, in.Captured()...)); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	merged, err := Read(&buf)
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if want := []string{
+		"delete from users where id = ?",
+		"insert into users(name) values(?)",
+		"select name from users where id = ?",
+		"update users set name = ? where id = ?",
+	}; !reflect.DeepEqual(merged, want) {
+		t.Errorf("got allowlist %q, want %q", merged, want)
+	}
+}
+
+func TestEnforceMySQL(t *testing.T) {
+	in := New(Config{Dialect: sqlinfo.MySQL, Fingerprints: []string{"select name from users where name = ?"}})
+	db, con := openDB(t, in)
+	ctx := context.Background()
+
+	rows, err := db.QueryContext(ctx, `SELECT name FROM users WHERE name = 'it\'s'`)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	rows.Close()
+
+	for _, query := range []string{
+		// the backslash escapes the quote, so that OR 1=1 is code
+		`SELECT name FROM users WHERE name = 'x\'' OR 1=1 -- '`,
+		"SELECT name FROM users WHERE name = 'x' /*!UNION SELECT password FROM admins*/",
+		"SELECT /*+ MAX_EXECUTION_TIME(1) */ name FROM users WHERE name = 'x'",
+	} {
+		var notAllowed *Error
+		if _, err := db.QueryContext(ctx, query); !errors.As(err, &notAllowed) {
+			t.Errorf("got error %v for %q, want *Error", err, query)
+		}
+	}
+	if got := len(con.Calls()); got != 1 {
+		t.Errorf("got %d calls to the database, want 1", got)
+	}
+}
+
+func TestConformance(t *testing.T) {
+	sqlmwtest.RunConformance(t, func() sqlmw.Interceptor { return New(Config{Mode: Capture, Capture: ioutil.Discard}) })
+}
@@ -0,0 +1,48 @@
+package allowlist
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Read reads an allowlist. Empty lines and lines starting with # are
+// ignored.
+func Read(r io.Reader) ([]string, error) {
+	var fps []string
+	s := bufio.NewScanner(r)
+	s.Buffer(nil, 1<<20)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fps = append(fps, line)
+	}
+	return fps, s.Err()
+}
+
+// ReadFile reads the allowlist at path.
+func ReadFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Read(f)
+}
+
+// Write writes fingerprints to w as an allowlist, sorted and one per line.
+func Write(w io.Writer, fingerprints []string) error {
+	sorted := append([]string(nil), fingerprints...)
+	sort.Strings(sorted)
+	bw := bufio.NewWriter(w)
+	for _, fp := range sorted {
+		if _, err := bw.WriteString(fp + "\n"); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,15 @@
+// +build go1.16
+
+package allowlist
+
+import "io/fs"
+
+// ReadFS reads the allowlist name of fsys, such as an embed.FS.
+func ReadFS(fsys fs.FS, name string) ([]string, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Read(f)
+}
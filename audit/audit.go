@@ -0,0 +1,280 @@
+// Package audit provides an sqlmw.Interceptor keeping an audit trail of the
+// writes, and of the reads of sensitive tables: who ran which statement,
+// with which arguments, when, and how many rows it affected.
+//
+// The trail is a log of JSON lines, one Record per line, appended to a
+// Sink such as a FileSink. Every line holds the SHA-256 hash of the previous
+// one, so that Verify detects a line that was modified, inserted or removed.
+//
+// The statements run within a transaction are held until it ends. They are
+// logged when it commits, followed by a COMMIT record, which holds the error
+// of a commit that failed. A transaction that rolls back is only logged as a
+// ROLLBACK record.
+//
+// Arguments often hold personal data and are only logged once redacted by
+// Config.Redact. The number of rows affected by a write is read from its
+// result as soon as it completes, so that its record is logged right away.
+package audit
+
+import (
+	"context"
+	"database/sql/driver"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ngrok/sqlmw"
+	"github.com/ngrok/sqlmw/sqlinfo"
+)
+
+// Sink stores the audit log.
+type Sink interface {
+	// Append stores lines, one or more JSON lines each ending with a
+	// newline. Append is not called concurrently.
+	Append(lines []byte) error
+}
+
+// Tailer is implemented by the sinks holding the log of a previous run,
+// whose chain an Interceptor continues.
+type Tailer interface {
+	// Tail returns the sequence number and the hash of the last record.
+	Tail() (seq uint64, hash string)
+}
+
+// Config configures an Interceptor.
+type Config struct {
+	// Sink stores the log. It is required.
+	Sink Sink
+
+	// Tables are the sensitive tables, whose reads are logged. Writes are
+	// logged whatever the tables.
+	Tables []string
+
+	// Actor returns who runs the statements of ctx. Defaults to the actor
+	// added with WithActor.
+	Actor func(ctx context.Context) string
+
+	// Redact, if set, returns the arguments of a statement as they must be
+	// logged, see redact.Redactor.Values. Arguments are not logged
+	// otherwise.
+	Redact func(query string, args []driver.NamedValue) []interface{}
+
+	// OnError, if set, is called with the errors of the Sink. The statements
+	// are not failed by them.
+	OnError func(error)
+
+	// Dialect is used to describe statements that are not described by an
+	// sqlinfo.Interceptor. Defaults to sqlinfo.Generic.
+	Dialect sqlinfo.Dialect
+}
+
+type actorKey struct{}
+
+// WithActor returns a context whose statements are logged as run by actor.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext returns the actor of ctx, if any.
+func ActorFromContext(ctx context.Context) (string, bool) {
+	actor, ok := ctx.Value(actorKey{}).(string)
+	return actor, ok
+}
+
+// Interceptor logs statements.
+type Interceptor struct {
+	// txs is accessed atomically and kept first for 64-bit alignment
+	txs uint64
+
+	sqlmw.NullInterceptor
+
+	cfg    Config
+	tables map[string]bool
+
+	mu   sync.Mutex
+	seq  uint64
+	head string
+}
+
+// New returns an Interceptor for the supplied configuration.
+func New(cfg Config) *Interceptor {
+	if cfg.Actor == nil {
+		cfg.Actor = func(ctx context.Context) string {
+			actor, _ := ActorFromContext(ctx)
+			return actor
+		}
+	}
+	in := &Interceptor{cfg: cfg, tables: make(map[string]bool)}
+	for _, t := range cfg.Tables {
+		in.tables[t] = true
+	}
+	if t, ok := cfg.Sink.(Tailer); ok {
+		in.seq, in.head = t.Tail()
+	}
+	return in
+}
+
+type txKey struct{}
+
+// txLog holds the records of a transaction until it ends.
+type txLog struct {
+	id      uint64
+	records []*Record
+}
+
+// audited reports whether the statement must be logged.
+func (in *Interceptor) audited(ctx context.Context, query string) bool {
+	st, ok := sqlinfo.FromContext(ctx)
+	if !ok {
+		st = sqlinfo.Parse(in.cfg.Dialect, query)
+	}
+	switch st.Kind {
+	case sqlinfo.TxControl:
+		return false
+	case sqlinfo.Read:
+		for _, t := range st.Tables {
+			if in.tables[t] {
+				return true
+			}
+		}
+		return false
+	}
+	return st.Verb != ""
+}
+
+// record logs a statement, or holds it until its transaction ends.
+func (in *Interceptor) record(ctx context.Context, query string, args []driver.NamedValue, res driver.Result, err error) {
+	if err == driver.ErrSkip {
+		// database/sql runs the statement again, prepared
+		return
+	}
+	r := &Record{Time: time.Now(), Actor: in.cfg.Actor(ctx), Query: query}
+	if in.cfg.Redact != nil {
+		r.Args = in.cfg.Redact(query, args)
+	}
+	if err != nil {
+		r.Error = err.Error()
+	} else if res != nil {
+		// drivers know the rows affected once the statement completed,
+		// an error only means they do not support it
+		if n, err := res.RowsAffected(); err == nil {
+			r.RowsAffected = &n
+		}
+	}
+
+	if sqlmw.InTx(ctx) {
+		if c, ok := sqlmw.ConnFromContext(ctx); ok {
+			tx, _ := c.Value(txKey{}).(*txLog)
+			if tx == nil {
+				tx = &txLog{id: atomic.AddUint64(&in.txs, 1)}
+				c.SetValue(txKey{}, tx)
+			}
+			r.Tx = tx.id
+			tx.records = append(tx.records, r)
+			return
+		}
+	}
+	in.write(r)
+}
+
+// end logs the end of the transaction of ctx, preceded by its statements
+// when it committed.
+func (in *Interceptor) end(ctx context.Context, commit bool, err error) {
+	c, ok := sqlmw.ConnFromContext(ctx)
+	if !ok {
+		return
+	}
+	tx, _ := c.Value(txKey{}).(*txLog)
+	if tx == nil {
+		return
+	}
+	c.SetValue(txKey{}, nil)
+
+	r := &Record{Time: time.Now(), Actor: in.cfg.Actor(ctx), Query: "ROLLBACK", Tx: tx.id}
+	if err != nil {
+		r.Error = err.Error()
+	}
+	if commit {
+		// a failed commit may have been applied, its statements are
+		// logged along with its error
+		r.Query = "COMMIT"
+		in.write(append(tx.records, r)...)
+		return
+	}
+	in.write(r)
+}
+
+// write chains records and appends them to the sink.
+func (in *Interceptor) write(records ...*Record) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	var lines []byte
+	seq, head := in.seq, in.head
+	for _, r := range records {
+		seq++
+		r.Seq, r.Prev = seq, head
+		line, hash, err := marshal(r)
+		if err != nil {
+			in.fail(err)
+			return
+		}
+		lines = append(lines, line...)
+		head = hash
+	}
+	if err := in.cfg.Sink.Append(lines); err != nil {
+		in.fail(err)
+		return
+	}
+	in.seq, in.head = seq, head
+}
+
+func (in *Interceptor) fail(err error) {
+	if in.cfg.OnError != nil {
+		in.cfg.OnError(err)
+	}
+}
+
+func (in *Interceptor) ConnExecContext(ctx context.Context, conn driver.ExecerContext, query string, args []driver.NamedValue) (driver.Result, error) {
+	res, err := conn.ExecContext(ctx, query, args)
+	if in.audited(ctx, query) {
+		in.record(ctx, query, args, res, err)
+	}
+	return res, err
+}
+
+func (in *Interceptor) StmtExecContext(ctx context.Context, stmt driver.StmtExecContext, query string, args []driver.NamedValue) (driver.Result, error) {
+	res, err := stmt.ExecContext(ctx, args)
+	if in.audited(ctx, query) {
+		in.record(ctx, query, args, res, err)
+	}
+	return res, err
+}
+
+func (in *Interceptor) ConnQueryContext(ctx context.Context, conn driver.QueryerContext, query string, args []driver.NamedValue) (context.Context, driver.Rows, error) {
+	rows, err := conn.QueryContext(ctx, query, args)
+	if in.audited(ctx, query) {
+		in.record(ctx, query, args, nil, err)
+	}
+	return ctx, rows, err
+}
+
+func (in *Interceptor) StmtQueryContext(ctx context.Context, stmt driver.StmtQueryContext, query string, args []driver.NamedValue) (context.Context, driver.Rows, error) {
+	rows, err := stmt.QueryContext(ctx, args)
+	if in.audited(ctx, query) {
+		in.record(ctx, query, args, nil, err)
+	}
+	return ctx, rows, err
+}
+
+func (in *Interceptor) TxCommit(ctx context.Context, tx driver.Tx) error {
+	err := tx.Commit()
+	in.end(ctx, true, err)
+	return err
+}
+
+func (in *Interceptor) TxRollback(ctx context.Context, tx driver.Tx) error {
+	err := tx.Rollback()
+	in.end(ctx, false, err)
+	return err
+}
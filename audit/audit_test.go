@@ -0,0 +1,237 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ngrok/sqlmw"
+	"github.com/ngrok/sqlmw/internal/fakedb"
+	"github.com/ngrok/sqlmw/sqlmwtest"
+)
+
+func openDB(t *testing.T, path string) (*sql.DB, *FileSink) {
+	sink, err := OpenFile(path)
+	if err != nil {
+		t.Fatalf("OpenFile failed: %v", err)
+	}
+	in := New(Config{
+		Sink:   sink,
+		Tables: []string{"users"},
+		Redact: func(_ string, args []driver.NamedValue) []interface{} {
+			redacted := make([]interface{}, len(args))
+			for i := range args {
+				redacted[i] = "***"
+			}
+			return redacted
+		},
+		OnError: func(err error) {
+			t.Errorf("Append failed: %v", err)
+		},
+	})
+	con := &fakedb.Connector{
+		Handler: func(_ context.Context, _ string, _ []driver.NamedValue) (*fakedb.Response, error) {
+			return &fakedb.Response{RowsAffected: 2}, nil
+		},
+	}
+	return sql.OpenDB(sqlmw.Connector(con, in)), sink
+}
+
+func readLog(t *testing.T, path string) []Record {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	if err := Verify(bytes.NewReader(b)); err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	var records []Record
+	for _, line := range strings.Split(strings.TrimSpace(string(b)), "\n") {
+		var r Record
+		if err := json.Unmarshal([]byte(line), &r); err != nil {
+			t.Fatalf("Unmarshal failed: %v", err)
+		}
+		records = append(records, r)
+	}
+	return records
+}
+
+func TestInterceptor(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit")
+	if err != nil {
+		t.Fatalf("TempDir failed: %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	path := filepath.Join(dir, "audit.log")
+
+	db, sink := openDB(t, path)
+	ctx := WithActor(context.Background(), "alice")
+	if _, err := db.ExecContext(ctx, "UPDATE t SET a = ?", 1); err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	for _, query := range []string{"SELECT * FROM users", "SELECT * FROM teams"} {
+		rows, err := db.QueryContext(ctx, query)
+		if err != nil {
+			t.Fatalf("Query failed: %v", err)
+		}
+		rows.Close()
+	}
+	for _, commit := range []bool{true, false} {
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			t.Fatalf("BeginTx failed: %v", err)
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM t"); err != nil {
+			t.Fatalf("Exec failed: %v", err)
+		}
+		if commit {
+			err = tx.Commit()
+		} else {
+			err = tx.Rollback()
+		}
+		if err != nil {
+			t.Fatalf("ending tx failed: %v", err)
+		}
+	}
+	if err := db.Close(); err != nil {
+		t.Errorf("Failed to close db: %v", err)
+	}
+	if err := sink.Close(); err != nil {
+		t.Errorf("Close failed: %v", err)
+	}
+
+	// the chain continues when the file is opened again
+	db, sink = openDB(t, path)
+	if _, err := db.ExecContext(context.Background(), "DROP TABLE t"); err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	if err := db.Close(); err != nil {
+		t.Errorf("Failed to close db: %v", err)
+	}
+	if err := sink.Close(); err != nil {
+		t.Errorf("Close failed: %v", err)
+	}
+
+	records := readLog(t, path)
+	var got []string
+	for _, r := range records {
+		got = append(got, r.Actor+" "+r.Query)
+	}
+	expected := []string{
+		"alice UPDATE t SET a = ?",
+		"alice SELECT * FROM users",
+		"alice DELETE FROM t",
+		"alice COMMIT",
+		"alice ROLLBACK",
+		" DROP TABLE t",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("unexpected records:\n got: %q\nwant: %q", got, expected)
+	}
+	if r := records[0]; r.RowsAffected == nil || *r.RowsAffected != 2 || !reflect.DeepEqual(r.Args, []interface{}{"***"}) {
+		t.Errorf("unexpected record %+v", r)
+	}
+	if r := records[5]; r.RowsAffected == nil || *r.RowsAffected != 2 {
+		t.Errorf("unexpected record %+v", r)
+	}
+	if records[2].Tx == 0 || records[2].Tx != records[3].Tx || records[4].Tx == records[3].Tx {
+		t.Errorf("unexpected transactions %d, %d, %d", records[2].Tx, records[3].Tx, records[4].Tx)
+	}
+
+	// tampering breaks the chain
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	for _, tampered := range [][]byte{
+		bytes.Replace(b, []byte("alice"), []byte("bob"), 1),
+		bytes.Replace(b, []byte(strings.SplitAfter(string(b), "\n")[1]), nil, 1),
+	} {
+		if err := Verify(bytes.NewReader(tampered)); err == nil {
+			t.Errorf("Verify succeeded on a tampered log")
+		}
+	}
+}
+
+type memSink struct {
+	bytes.Buffer
+}
+
+func (s *memSink) Append(lines []byte) error {
+	_, err := s.Write(lines)
+	return err
+}
+
+func TestInterceptorFailures(t *testing.T) {
+	sink := &memSink{}
+	in := New(Config{Sink: sink})
+	skipped := false
+	con := &fakedb.Connector{
+		Handler: func(_ context.Context, query string, _ []driver.NamedValue) (*fakedb.Response, error) {
+			switch query {
+			case "COMMIT":
+				return nil, driver.ErrBadConn
+			case "UPDATE t SET a = 1":
+				if !skipped {
+					// retried through a prepared statement
+					skipped = true
+					return nil, driver.ErrSkip
+				}
+			}
+			return &fakedb.Response{RowsAffected: 2}, nil
+		},
+	}
+	db := sql.OpenDB(sqlmw.Connector(con, in))
+	t.Cleanup(func() {
+		if err := db.Close(); err != nil {
+			t.Errorf("Failed to close db: %v", err)
+		}
+	})
+	ctx := context.Background()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatalf("BeginTx failed: %v", err)
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM t"); err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	if err := tx.Commit(); err != driver.ErrBadConn {
+		t.Fatalf("Commit error = %v, expected %v", err, driver.ErrBadConn)
+	}
+	if _, err := db.ExecContext(ctx, "UPDATE t SET a = 1"); err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+
+	var got []string
+	for _, line := range strings.Split(strings.TrimSpace(sink.String()), "\n") {
+		var r Record
+		if err := json.Unmarshal([]byte(line), &r); err != nil {
+			t.Fatalf("Unmarshal failed: %v", err)
+		}
+		got = append(got, r.Query+" "+r.Error)
+		if r.Query == "DELETE FROM t" && (r.RowsAffected == nil || *r.RowsAffected != 2) {
+			t.Errorf("unexpected record %+v", r)
+		}
+	}
+	expected := []string{
+		"DELETE FROM t ",
+		"COMMIT " + driver.ErrBadConn.Error(),
+		"UPDATE t SET a = 1 ",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("unexpected records:\n got: %q\nwant: %q", got, expected)
+	}
+}
+
+func TestConformance(t *testing.T) {
+	sqlmwtest.RunConformance(t, func() sqlmw.Interceptor { return New(Config{Sink: &memSink{}}) })
+}
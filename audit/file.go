@@ -0,0 +1,100 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+)
+
+// FileSink appends the audit log to a local file.
+type FileSink struct {
+	// Sync, if set, flushes the file to stable storage after every append.
+	Sync bool
+
+	mu   sync.Mutex
+	f    *os.File
+	seq  uint64
+	hash string
+}
+
+var _ Tailer = (*FileSink)(nil)
+
+// OpenFile opens the audit log at path, creating it if needed. The chain
+// of an Interceptor writing to the returned sink continues the log the file
+// holds.
+func OpenFile(path string) (*FileSink, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	s := &FileSink{f: f}
+	last, err := lastLine(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if len(last) > 0 {
+		var tail struct {
+			Seq  uint64 `json:"seq"`
+			Hash string `json:"hash"`
+		}
+		if err := json.Unmarshal(last, &tail); err != nil {
+			f.Close()
+			return nil, err
+		}
+		s.seq, s.hash = tail.Seq, tail.Hash
+	}
+	return s, nil
+}
+
+// lastLine returns the last line of f, without its newline.
+func lastLine(f *os.File) ([]byte, error) {
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	size := fi.Size()
+	for window := int64(4096); ; window *= 2 {
+		if window > size {
+			window = size
+		}
+		buf := make([]byte, window)
+		if _, err := f.ReadAt(buf, size-window); err != nil && err != io.EOF {
+			return nil, err
+		}
+		buf = bytes.TrimRight(buf, "\n")
+		if i := bytes.LastIndexByte(buf, '\n'); i >= 0 {
+			return buf[i+1:], nil
+		}
+		if window == size {
+			return buf, nil
+		}
+	}
+}
+
+// Tail returns the sequence number and the hash of the last record of the
+// file when it was opened.
+func (s *FileSink) Tail() (uint64, string) {
+	return s.seq, s.hash
+}
+
+func (s *FileSink) Append(lines []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.f.Write(lines); err != nil {
+		return err
+	}
+	if s.Sync {
+		return s.f.Sync()
+	}
+	return nil
+}
+
+// Close closes the file.
+func (s *FileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.f.Close()
+}
@@ -0,0 +1,87 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Record is a line of the audit log.
+type Record struct {
+	// Seq numbers the records of the log, from 1.
+	Seq uint64 `json:"seq"`
+	// Time is when the statement ran, or when the transaction ended.
+	Time time.Time `json:"time"`
+	// Actor is who ran the statement, see Config.Actor.
+	Actor string `json:"actor,omitempty"`
+	// Query is the statement, or COMMIT or ROLLBACK for the record ending a
+	// transaction.
+	Query string `json:"query"`
+	// Args are the redacted arguments of the statement, see Config.Redact.
+	Args []interface{} `json:"args,omitempty"`
+	// RowsAffected is the number of rows affected by a successful write.
+	RowsAffected *int64 `json:"rows_affected,omitempty"`
+	// Error is the error the statement or the transaction failed with.
+	Error string `json:"error,omitempty"`
+	// Tx numbers the transaction of the statement, if any.
+	Tx uint64 `json:"tx,omitempty"`
+	// Prev is the hash of the previous record.
+	Prev string `json:"prev"`
+}
+
+// hashSuffix is the end of a line, after the hash of the line.
+const hashSuffix = `"}` + "\n"
+
+// marshal returns the line of r and its hash, the SHA-256 of the line
+// without its hash field, which chains it to the previous record through
+// its Prev field.
+func marshal(r *Record) ([]byte, string, error) {
+	b, err := json.Marshal(r)
+	if err != nil {
+		return nil, "", err
+	}
+	sum := sha256.Sum256(b)
+	hash := hex.EncodeToString(sum[:])
+	line := append(b[:len(b)-1], `,"hash":"`...)
+	line = append(line, hash...)
+	line = append(line, hashSuffix...)
+	return line, hash, nil
+}
+
+// Verify checks the chain of the audit log read from r: the hash of every
+// record, that it links to the previous record and that no record is
+// missing. The first record may link to a record that is not part of r.
+func Verify(r io.Reader) error {
+	s := bufio.NewScanner(r)
+	s.Buffer(nil, 1<<26)
+	var prev string
+	var seq uint64
+	for n := 1; s.Scan(); n++ {
+		line := s.Bytes()
+		i := bytes.LastIndex(line, []byte(`,"hash":"`))
+		if i < 0 || !bytes.HasSuffix(line, []byte(`"}`)) {
+			return fmt.Errorf("audit: line %d: no hash", n)
+		}
+		hash := string(line[i+len(`,"hash":"`) : len(line)-2])
+		body := append(append([]byte(nil), line[:i]...), '}')
+		sum := sha256.Sum256(body)
+		if hex.EncodeToString(sum[:]) != hash {
+			return fmt.Errorf("audit: line %d: hash mismatch", n)
+		}
+
+		var rec Record
+		if err := json.Unmarshal(body, &rec); err != nil {
+			return fmt.Errorf("audit: line %d: %v", n, err)
+		}
+		if n > 1 && (rec.Prev != prev || rec.Seq != seq+1) {
+			return fmt.Errorf("audit: line %d: broken chain", n)
+		}
+		prev, seq = hash, rec.Seq
+	}
+	return s.Err()
+}
@@ -0,0 +1,323 @@
+// Package batch provides an sqlmw.Interceptor coalescing concurrent single
+// row INSERT statements into multi row INSERT statements.
+//
+// Batching is opt-in: a statement is only considered when it is run with a
+// context returned by WithBatching or when Config.Batchable accepts it. It
+// must be run through ConnExecContext, outside of a transaction, be a single
+// row INSERT whose placeholders all appear in its VALUES tuple, and have no
+// named arguments. Statements with the same text arriving within Window of
+// each other are executed together, on the connection of the first one, as
+// soon as the window elapses or MaxRows statements were collected.
+//
+// Every caller receives its own driver.Result. A batch of n rows reports one
+// row affected to each caller when the database reports n rows affected;
+// otherwise the rows affected cannot be attributed and RowsAffected returns
+// ErrRowsAffected. LastInsertId is derived from the id reported for the
+// batch according to Config.InsertID. When a batch fails, every caller
+// receives the error.
+package batch
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/ngrok/sqlmw"
+	"github.com/ngrok/sqlmw/internal/ctxutil"
+	"github.com/ngrok/sqlmw/sqlinfo"
+)
+
+const (
+	defaultWindow  = 5 * time.Millisecond
+	defaultMaxRows = 100
+)
+
+var (
+	// ErrRowsAffected is returned by the RowsAffected method of a batched
+	// statement result when the rows affected by the batch cannot be
+	// attributed to its statements.
+	ErrRowsAffected = errors.New("batch: rows affected by the batch cannot be attributed")
+
+	// ErrLastInsertId is returned by the LastInsertId method of a batched
+	// statement result when Config.InsertID is NoInsertID.
+	ErrLastInsertId = errors.New("batch: last insert id is not available for batched statements")
+)
+
+// InsertID is how a database reports the LastInsertId of a multi row INSERT.
+type InsertID int
+
+const (
+	// NoInsertID is for databases not supporting LastInsertId, like
+	// PostgreSQL.
+	NoInsertID InsertID = iota
+
+	// FirstInsertID is for databases reporting the id of the first row
+	// inserted, like MySQL. The ids of the following rows are assumed to be
+	// consecutive, which requires innodb_autoinc_lock_mode to be 0 or 1 for
+	// MySQL.
+	FirstInsertID
+
+	// LastInsertID is for databases reporting the id of the last row
+	// inserted, like SQLite.
+	LastInsertID
+)
+
+// Config configures an Interceptor.
+type Config struct {
+	// Window is how long the first statement of a batch waits for others.
+	// Defaults to 5 milliseconds.
+	Window time.Duration
+
+	// MaxRows is the number of statements at which a batch is executed
+	// without waiting for the end of Window. Defaults to 100.
+	MaxRows int
+
+	// InsertID is how the database reports LastInsertId.
+	InsertID InsertID
+
+	// Batchable, if set, opts statements into batching in addition to the
+	// ones run with a context returned by WithBatching.
+	Batchable func(query string) bool
+
+	// Dialect is used to find the literals, comments and placeholders of
+	// the statements. Defaults to sqlinfo.Generic.
+	Dialect sqlinfo.Dialect
+}
+
+type batchingKey struct{}
+
+// WithBatching returns a context opting the statements run with it into
+// batching.
+func WithBatching(ctx context.Context) context.Context {
+	return context.WithValue(ctx, batchingKey{}, true)
+}
+
+// Interceptor coalesces single row INSERT statements.
+type Interceptor struct {
+	sqlmw.NullInterceptor
+
+	cfg Config
+
+	mu      sync.Mutex
+	pending map[string]*batch
+}
+
+type batch struct {
+	ins  *insert
+	full chan struct{}
+
+	// guarded by Interceptor.mu, entries are removed when their caller
+	// withdraws before the batch is sealed
+	entries []*entry
+	sealed  bool
+}
+
+type entry struct {
+	args []driver.NamedValue
+	done chan struct{}
+	res  driver.Result
+	err  error
+}
+
+// New returns an Interceptor for the supplied configuration.
+func New(cfg Config) *Interceptor {
+	if cfg.Window <= 0 {
+		cfg.Window = defaultWindow
+	}
+	if cfg.MaxRows <= 0 {
+		cfg.MaxRows = defaultMaxRows
+	}
+	return &Interceptor{cfg: cfg, pending: make(map[string]*batch)}
+}
+
+func (in *Interceptor) ConnExecContext(ctx context.Context, conn driver.ExecerContext, query string, args []driver.NamedValue) (driver.Result, error) {
+	ins := in.batchable(ctx, query, args)
+	if ins == nil {
+		return conn.ExecContext(ctx, query, args)
+	}
+
+	e := &entry{args: args, done: make(chan struct{})}
+	in.mu.Lock()
+	b, ok := in.pending[query]
+	if ok {
+		in.join(query, b, e)
+		in.mu.Unlock()
+		return in.wait(ctx, b, e)
+	}
+	b = &batch{ins: ins, full: make(chan struct{})}
+	in.pending[query] = b
+	in.join(query, b, e)
+	in.mu.Unlock()
+
+	return in.lead(ctx, conn, query, b, e)
+}
+
+func (in *Interceptor) batchable(ctx context.Context, query string, args []driver.NamedValue) *insert {
+	if len(args) == 0 || sqlmw.InTx(ctx) {
+		return nil
+	}
+	if ctx.Value(batchingKey{}) == nil && (in.cfg.Batchable == nil || !in.cfg.Batchable(query)) {
+		return nil
+	}
+	for _, a := range args {
+		if a.Name != "" {
+			return nil
+		}
+	}
+	return parseInsert(in.cfg.Dialect, query, len(args))
+}
+
+// join adds e to b, which no other statement joins once it is full. in.mu
+// must be held.
+func (in *Interceptor) join(query string, b *batch, e *entry) {
+	b.entries = append(b.entries, e)
+	if len(b.entries) == in.cfg.MaxRows {
+		delete(in.pending, query)
+		close(b.full)
+	}
+}
+
+// lead waits for the batch to fill up and executes it on conn.
+func (in *Interceptor) lead(ctx context.Context, conn driver.ExecerContext, query string, b *batch, e *entry) (driver.Result, error) {
+	timer := time.NewTimer(in.cfg.Window)
+	select {
+	case <-timer.C:
+	case <-b.full:
+	case <-ctx.Done():
+	}
+	timer.Stop()
+
+	in.mu.Lock()
+	if in.pending[query] == b {
+		delete(in.pending, query)
+	}
+	b.sealed = true
+	withdrawn := ctx.Err() != nil
+	if withdrawn {
+		withdraw(b, e)
+	}
+	entries := b.entries
+	in.mu.Unlock()
+
+	if len(entries) > 0 {
+		execCtx := ctx
+		if len(entries) > 1 || withdrawn {
+			// the statements of the other callers must not fail because
+			// the caller whose connection is used went away
+			execCtx = ctxutil.Detach(ctx)
+		}
+		in.execute(execCtx, conn, query, b.ins, entries)
+	}
+	if withdrawn {
+		return nil, ctx.Err()
+	}
+	return e.res, e.err
+}
+
+// wait waits for the leader of b to execute it.
+func (in *Interceptor) wait(ctx context.Context, b *batch, e *entry) (driver.Result, error) {
+	select {
+	case <-e.done:
+		return e.res, e.err
+	case <-ctx.Done():
+	}
+
+	in.mu.Lock()
+	if !b.sealed {
+		withdraw(b, e)
+		in.mu.Unlock()
+		return nil, ctx.Err()
+	}
+	in.mu.Unlock()
+
+	// the statement is part of the batch being executed, its outcome must
+	// be reported
+	<-e.done
+	return e.res, e.err
+}
+
+// withdraw removes e from b, so that it no longer counts toward MaxRows.
+// in.mu must be held.
+func withdraw(b *batch, e *entry) {
+	for i, x := range b.entries {
+		if x == e {
+			b.entries = append(b.entries[:i], b.entries[i+1:]...)
+			return
+		}
+	}
+}
+
+func (in *Interceptor) execute(ctx context.Context, conn driver.ExecerContext, query string, ins *insert, entries []*entry) {
+	defer func() {
+		for _, e := range entries {
+			close(e.done)
+		}
+	}()
+
+	if len(entries) == 1 {
+		entries[0].res, entries[0].err = conn.ExecContext(ctx, query, entries[0].args)
+		return
+	}
+
+	nargs := len(entries[0].args)
+	args := make([]driver.NamedValue, 0, len(entries)*nargs)
+	for _, e := range entries {
+		for _, a := range e.args {
+			a.Ordinal = len(args) + 1
+			args = append(args, a)
+		}
+	}
+
+	res, err := conn.ExecContext(ctx, ins.build(len(entries), nargs), args)
+	if err != nil {
+		for _, e := range entries {
+			e.err = err
+		}
+		return
+	}
+
+	n := int64(len(entries))
+	affected, affectedErr := res.RowsAffected()
+	if affectedErr == nil && affected != n {
+		affectedErr = ErrRowsAffected
+	}
+	var id int64
+	idErr := ErrLastInsertId
+	if in.cfg.InsertID != NoInsertID {
+		id, idErr = res.LastInsertId()
+		if in.cfg.InsertID == LastInsertID {
+			id -= n - 1
+		}
+	}
+
+	for i, e := range entries {
+		e.res = result{
+			id:          id + int64(i),
+			idErr:       idErr,
+			affectedErr: affectedErr,
+		}
+	}
+}
+
+// result is the driver.Result of a single batched statement.
+type result struct {
+	id          int64
+	idErr       error
+	affectedErr error
+}
+
+func (r result) LastInsertId() (int64, error) {
+	if r.idErr != nil {
+		return 0, r.idErr
+	}
+	return r.id, nil
+}
+
+func (r result) RowsAffected() (int64, error) {
+	if r.affectedErr != nil {
+		return 0, r.affectedErr
+	}
+	return 1, nil
+}
@@ -0,0 +1,284 @@
+package batch
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ngrok/sqlmw"
+	"github.com/ngrok/sqlmw/internal/fakedb"
+	"github.com/ngrok/sqlmw/sqlinfo"
+	"github.com/ngrok/sqlmw/sqlmwtest"
+)
+
+func TestParseInsert(t *testing.T) {
+	tests := []struct {
+		dialect  sqlinfo.Dialect
+		query    string
+		nargs    int
+		expected string
+	}{
+		{
+			query:    "INSERT INTO events (kind, at) VALUES (?, now())",
+			nargs:    1,
+			expected: "INSERT INTO events (kind, at) VALUES (?, now()), (?, now()), (?, now())",
+		},
+		{
+			query:    "insert into events(kind, note) values($1, '$2 (values)') on conflict do nothing;",
+			nargs:    1,
+			expected: "insert into events(kind, note) values ($1, '$2 (values)'), ($2, '$2 (values)'), ($3, '$2 (values)') on conflict do nothing",
+		},
+		{
+			query:    "INSERT INTO events (a, b) VALUES ($1, $2)",
+			nargs:    2,
+			expected: "INSERT INTO events (a, b) VALUES ($1, $2), ($3, $4), ($5, $6)",
+		},
+		{
+			query:    `INSERT INTO paths (a, p) VALUES (?, 'C:\')`,
+			nargs:    1,
+			expected: `INSERT INTO paths (a, p) VALUES (?, 'C:\'), (?, 'C:\'), (?, 'C:\')`,
+		},
+		{
+			dialect:  sqlinfo.MySQL,
+			query:    `INSERT INTO notes (a, b) VALUES (?, 'it\'s (?)') -- ?`,
+			nargs:    1,
+			expected: `INSERT INTO notes (a, b) VALUES (?, 'it\'s (?)'), (?, 'it\'s (?)'), (?, 'it\'s (?)') -- ?`,
+		},
+		{query: "INSERT INTO events (a, b) VALUES ($2, $1)", nargs: 2},
+		{query: "INSERT INTO events (a) VALUES (:a)", nargs: 1},
+		{query: "INSERT INTO events (a) VALUES (?), (?)", nargs: 2},
+		{query: "INSERT INTO events (a) VALUES (?) RETURNING id", nargs: 1},
+		{query: "INSERT INTO events (a) SELECT ? FROM dual", nargs: 1},
+		{query: "INSERT INTO events (a) VALUES (?) ON DUPLICATE KEY UPDATE a = ?", nargs: 2},
+		{query: "UPDATE events SET a = ?", nargs: 1},
+		{query: "INSERT INTO events (a, b) VALUES (?, ?)", nargs: 1},
+	}
+
+	for _, test := range tests {
+		ins := parseInsert(test.dialect, test.query, test.nargs)
+		if test.expected == "" {
+			if ins != nil {
+				t.Errorf("%q should not be batchable", test.query)
+			}
+			continue
+		}
+		if ins == nil {
+			t.Errorf("%q should be batchable", test.query)
+			continue
+		}
+		if got := ins.build(3, test.nargs); got != test.expected {
+			t.Errorf("unexpected batch for %q:\n got: %s\nwant: %s", test.query, got, test.expected)
+		}
+	}
+}
+
+func openDB(t *testing.T, in *Interceptor, handler fakedb.Handler) (*sql.DB, *fakedb.Connector) {
+	con := &fakedb.Connector{Handler: handler}
+	db := sql.OpenDB(sqlmw.Connector(con, in))
+	t.Cleanup(func() {
+		if err := db.Close(); err != nil {
+			t.Errorf("Failed to close db: %v", err)
+		}
+	})
+	return db, con
+}
+
+func insertAll(db *sql.DB, n int) ([]sql.Result, []error) {
+	var wg sync.WaitGroup
+	results := make([]sql.Result, n)
+	errs := make([]error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			ctx := WithBatching(context.Background())
+			results[i], errs[i] = db.ExecContext(ctx, "INSERT INTO events (kind) VALUES (?)", int64(i))
+		}(i)
+	}
+	wg.Wait()
+	return results, errs
+}
+
+func TestBatch(t *testing.T) {
+	const n = 5
+	in := New(Config{Window: time.Minute, MaxRows: n, InsertID: LastInsertID})
+	db, con := openDB(t, in, func(_ context.Context, _ string, args []driver.NamedValue) (*fakedb.Response, error) {
+		return &fakedb.Response{RowsAffected: int64(len(args)), LastInsertId: 100}, nil
+	})
+
+	results, errs := insertAll(db, n)
+
+	calls := con.Calls()
+	if len(calls) != 1 {
+		t.Fatalf("expected a single statement, got %d", len(calls))
+	}
+	if calls[0].Query != "INSERT INTO events (kind) VALUES (?), (?), (?), (?), (?)" {
+		t.Errorf("unexpected statement %q", calls[0].Query)
+	}
+	var kinds []int
+	for i, a := range calls[0].Args {
+		if a.Ordinal != i+1 {
+			t.Errorf("unexpected ordinal %d for argument %d", a.Ordinal, i)
+		}
+		kinds = append(kinds, int(a.Value.(int64)))
+	}
+	sort.Ints(kinds)
+	for i, k := range kinds {
+		if k != i {
+			t.Fatalf("unexpected arguments %v", calls[0].Args)
+		}
+	}
+
+	var ids []int
+	for i, res := range results {
+		if errs[i] != nil {
+			t.Fatalf("Exec failed: %v", errs[i])
+		}
+		if affected, err := res.RowsAffected(); err != nil || affected != 1 {
+			t.Errorf("unexpected rows affected %d, %v", affected, err)
+		}
+		id, err := res.LastInsertId()
+		if err != nil {
+			t.Fatalf("LastInsertId failed: %v", err)
+		}
+		ids = append(ids, int(id))
+	}
+	sort.Ints(ids)
+	for i, id := range ids {
+		if id != 96+i {
+			t.Fatalf("unexpected ids %v", ids)
+		}
+	}
+}
+
+func TestBatchErrorFanOut(t *testing.T) {
+	const n = 3
+	in := New(Config{Window: time.Minute, MaxRows: n})
+	db, _ := openDB(t, in, func(context.Context, string, []driver.NamedValue) (*fakedb.Response, error) {
+		return nil, errors.New("duplicate key")
+	})
+
+	_, errs := insertAll(db, n)
+	for _, err := range errs {
+		if err == nil || err.Error() != "duplicate key" {
+			t.Errorf("expected the batch error, got %v", err)
+		}
+	}
+}
+
+func TestBatchFlushesOnTime(t *testing.T) {
+	in := New(Config{Window: time.Millisecond, MaxRows: 100, InsertID: FirstInsertID})
+	db, con := openDB(t, in, func(context.Context, string, []driver.NamedValue) (*fakedb.Response, error) {
+		return &fakedb.Response{RowsAffected: 1, LastInsertId: 7}, nil
+	})
+
+	ctx := WithBatching(context.Background())
+	res, err := db.ExecContext(ctx, "INSERT INTO events (kind) VALUES (?)", 1)
+	if err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	if id, _ := res.LastInsertId(); id != 7 {
+		t.Errorf("unexpected id %d", id)
+	}
+	if qs := con.Queries(); len(qs) != 1 || qs[0] != "INSERT INTO events (kind) VALUES (?)" {
+		t.Errorf("lone statement was rewritten: %v", qs)
+	}
+}
+
+func TestBatchSkipsTransactions(t *testing.T) {
+	in := New(Config{Window: time.Minute, MaxRows: 100})
+	db, _ := openDB(t, in, nil)
+
+	ctx := WithBatching(context.Background())
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatalf("BeginTx failed: %v", err)
+	}
+	defer tx.Rollback()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := tx.ExecContext(ctx, "INSERT INTO events (kind) VALUES (?)", 1)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Exec failed: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("statement inside a transaction waited for a batch")
+	}
+}
+
+// waitEntries waits for the pending batch of query to have n statements.
+func waitEntries(t *testing.T, in *Interceptor, query string, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		in.mu.Lock()
+		b := in.pending[query]
+		joined := b != nil && len(b.entries) == n
+		in.mu.Unlock()
+		if joined {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("the batch did not reach %d statements", n)
+}
+
+func TestBatchWithdrawn(t *testing.T) {
+	const query = "INSERT INTO events (kind) VALUES (?)"
+	in := New(Config{Window: time.Minute, MaxRows: 3})
+	db, con := openDB(t, in, func(_ context.Context, _ string, args []driver.NamedValue) (*fakedb.Response, error) {
+		return &fakedb.Response{RowsAffected: int64(len(args))}, nil
+	})
+
+	done := make(chan error, 3)
+	exec := func(ctx context.Context, kind int) {
+		_, err := db.ExecContext(WithBatching(ctx), query, kind)
+		done <- err
+	}
+	go exec(context.Background(), 0)
+	waitEntries(t, in, query, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	withdrawn := make(chan error, 1)
+	go func() {
+		_, err := db.ExecContext(WithBatching(ctx), query, -1)
+		withdrawn <- err
+	}()
+	waitEntries(t, in, query, 2)
+	cancel()
+	if err := <-withdrawn; err != context.Canceled {
+		t.Fatalf("expected the withdrawn statement to fail with %v, got %v", context.Canceled, err)
+	}
+
+	// the withdrawn statement does not count toward MaxRows
+	go exec(context.Background(), 1)
+	go exec(context.Background(), 2)
+	for i := 0; i < 3; i++ {
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Fatalf("Exec failed: %v", err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("the batch was not executed once it had MaxRows statements")
+		}
+	}
+	calls := con.Calls()
+	if len(calls) != 1 || len(calls[0].Args) != 3 {
+		t.Errorf("expected a single statement of 3 rows, got %v", calls)
+	}
+}
+
+func TestConformance(t *testing.T) {
+	sqlmwtest.RunConformance(t, func() sqlmw.Interceptor { return New(Config{}) })
+}
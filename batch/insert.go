@@ -0,0 +1,179 @@
+package batch
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/ngrok/sqlmw/sqlinfo"
+)
+
+// insert is a single row INSERT split around its VALUES tuple.
+type insert struct {
+	dialect sqlinfo.Dialect
+	prefix  string // up to and including VALUES
+	tuple   string // the parenthesized tuple
+	suffix  string // what follows the tuple, e.g. ON CONFLICT DO NOTHING
+	dollar  bool   // whether the tuple uses $n placeholders
+}
+
+// parseInsert splits query when it is a single row INSERT whose placeholders
+// are all in its VALUES tuple, and returns nil otherwise. nargs is the number
+// of arguments passed with query. The literals, comments and placeholders
+// of query are found with the rules of dialect.
+func parseInsert(dialect sqlinfo.Dialect, query string, nargs int) *insert {
+	query = strings.TrimRight(strings.TrimSpace(query), ";")
+	toks := sqlinfo.Tokens(dialect, query)
+	first := nextCode(toks, 0)
+	if first == len(toks) || !toks[first].Is("insert") {
+		return nil
+	}
+
+	values := -1
+	for i := first; i < len(toks); i++ {
+		if toks[i].Kind == sqlinfo.Placeholder || toks[i].Is("select") {
+			return nil
+		}
+		if toks[i].Is("values") {
+			values = i
+			break
+		}
+	}
+	if values < 0 || values+1 == len(toks) || !isPunct(toks[values+1], "(") {
+		return nil
+	}
+	open := values + 1
+	end := closingParen(toks, open)
+	if end < 0 {
+		return nil
+	}
+
+	ins := &insert{
+		dialect: dialect,
+		prefix:  query[:toks[values].Offset+len(toks[values].Text)],
+		tuple:   query[toks[open].Offset : toks[end].Offset+1],
+		suffix:  strings.TrimSpace(query[toks[end].Offset+1:]),
+	}
+	rest := toks[end+1:]
+	if n := nextCode(rest, 0); n < len(rest) && isPunct(rest[n], ",") {
+		// already a multi row insert
+		return nil
+	}
+	for _, t := range rest {
+		if t.Kind == sqlinfo.Placeholder || t.Is("returning") {
+			return nil
+		}
+	}
+
+	qmarks, dollars := 0, []int(nil)
+	for _, t := range toks[open:end] {
+		if t.Kind != sqlinfo.Placeholder {
+			continue
+		}
+		if t.Text == "?" {
+			qmarks++
+			continue
+		}
+		n, ok := dollarNumber(t.Text)
+		if !ok {
+			// named or numbered placeholders
+			return nil
+		}
+		dollars = append(dollars, n)
+	}
+	if qmarks > 0 && len(dollars) > 0 {
+		return nil
+	}
+	switch {
+	case qmarks == nargs:
+	case len(dollars) == nargs:
+		ins.dollar = true
+		for i, n := range dollars {
+			// the tuple must reference the arguments in order
+			if n != i+1 {
+				return nil
+			}
+		}
+	default:
+		return nil
+	}
+	return ins
+}
+
+// build returns the query inserting rows tuples.
+func (ins *insert) build(rows, nargs int) string {
+	var b strings.Builder
+	b.Grow(len(ins.prefix) + rows*(len(ins.tuple)+8) + len(ins.suffix) + 1)
+	b.WriteString(ins.prefix)
+	b.WriteByte(' ')
+	for r := 0; r < rows; r++ {
+		if r > 0 {
+			b.WriteString(", ")
+		}
+		if !ins.dollar {
+			b.WriteString(ins.tuple)
+			continue
+		}
+		ins.renumber(&b, r*nargs)
+	}
+	if ins.suffix != "" {
+		b.WriteByte(' ')
+		b.WriteString(ins.suffix)
+	}
+	return b.String()
+}
+
+// renumber writes the tuple with its $n placeholders shifted by offset.
+func (ins *insert) renumber(b *strings.Builder, offset int) {
+	last := 0
+	for _, t := range sqlinfo.Tokens(ins.dialect, ins.tuple) {
+		n, ok := dollarNumber(t.Text)
+		if t.Kind != sqlinfo.Placeholder || !ok {
+			continue
+		}
+		b.WriteString(ins.tuple[last:t.Offset])
+		b.WriteByte('$')
+		b.WriteString(strconv.Itoa(n + offset))
+		last = t.Offset + len(t.Text)
+	}
+	b.WriteString(ins.tuple[last:])
+}
+
+// dollarNumber returns n for a $n placeholder.
+func dollarNumber(text string) (int, bool) {
+	if len(text) < 2 || text[0] != '$' {
+		return 0, false
+	}
+	n, err := strconv.Atoi(text[1:])
+	return n, err == nil && n > 0
+}
+
+// nextCode returns the index of the first token from i that is not a
+// comment, or len(toks).
+func nextCode(toks []sqlinfo.Token, i int) int {
+	for i < len(toks) && toks[i].Kind == sqlinfo.Comment {
+		i++
+	}
+	return i
+}
+
+// closingParen returns the index of the parenthesis closing the one at
+// open, or -1.
+func closingParen(toks []sqlinfo.Token, open int) int {
+	depth := 0
+	for i := open; i < len(toks); i++ {
+		switch {
+		case isPunct(toks[i], "("):
+			depth++
+		case isPunct(toks[i], ")"):
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func isPunct(t sqlinfo.Token, p string) bool {
+	return t.Kind == sqlinfo.Punct && t.Text == p
+}
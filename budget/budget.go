@@ -0,0 +1,382 @@
+// Package budget provides an sqlmw.Interceptor accounting and capping the
+// database work of every inbound request.
+//
+// The work of the statements run with a context returned by WithAccount is
+// accumulated into its Account: the number of statements, the time spent in
+// the database, the rows scanned and the rows affected. The totals are read
+// back with Account.Totals, for access logs or response headers:
+//
+//	ctx := budget.WithAccount(r.Context())
+//	next.ServeHTTP(w, r.WithContext(ctx))
+//	a, _ := budget.FromContext(ctx)
+//	log.Printf("%s %s %v", r.Method, r.URL.Path, a.Totals())
+//
+// An account exceeding one of its Limits is reported to Config.OnExceed,
+// once per resource. In the Fail mode, its statements fail with an *Error
+// from then on, and so does the scan of its rows past Limits.RowsScanned.
+//
+// The rows affected by a statement are the ones read from its result with
+// RowsAffected, as database/sql does not read them otherwise.
+package budget
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ngrok/sqlmw"
+)
+
+// Resource is a resource accounted for.
+type Resource int
+
+const (
+	Statements Resource = iota
+	Duration
+	RowsScanned
+	RowsAffected
+)
+
+func (r Resource) String() string {
+	switch r {
+	case Statements:
+		return "statements"
+	case Duration:
+		return "duration"
+	case RowsScanned:
+		return "rows scanned"
+	case RowsAffected:
+		return "rows affected"
+	}
+	return "invalid"
+}
+
+// Totals is the database work of an account.
+type Totals struct {
+	// Statements counts the statements run, including the ones failed.
+	Statements   int64
+	Duration     time.Duration
+	RowsScanned  int64
+	RowsAffected int64
+}
+
+func (t Totals) String() string {
+	return fmt.Sprintf("statements=%d duration=%v rows_scanned=%d rows_affected=%d",
+		t.Statements, t.Duration, t.RowsScanned, t.RowsAffected)
+}
+
+// Limits caps the database work of an account. A zero limit is no limit.
+type Limits struct {
+	Statements   int64
+	Duration     time.Duration
+	RowsScanned  int64
+	RowsAffected int64
+}
+
+// Mode is what an Interceptor does with the accounts exceeding their
+// limits.
+type Mode int
+
+const (
+	// Warn reports them to Config.OnExceed.
+	Warn Mode = iota
+	// Fail reports them to Config.OnExceed and fails their statements with
+	// an *Error.
+	Fail
+)
+
+// Error is the error of the statements of an account exceeding its limits.
+type Error struct {
+	// Resource is the resource whose limit was exceeded.
+	Resource Resource
+	Totals   Totals
+	Limits   Limits
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("budget: %s budget exceeded (%v)", e.Resource, e.Totals)
+}
+
+// Account accumulates the database work of a request. It is safe for
+// concurrent use.
+type Account struct {
+	// accessed atomically and kept first for 64-bit alignment
+	statements   int64
+	duration     int64
+	rowsScanned  int64
+	rowsAffected int64
+	exceeded     uint32 // bit mask of the resources reported
+
+	limits    Limits
+	hasLimits bool
+}
+
+// Totals returns the work accumulated so far.
+func (a *Account) Totals() Totals {
+	return Totals{
+		Statements:   atomic.LoadInt64(&a.statements),
+		Duration:     time.Duration(atomic.LoadInt64(&a.duration)),
+		RowsScanned:  atomic.LoadInt64(&a.rowsScanned),
+		RowsAffected: atomic.LoadInt64(&a.rowsAffected),
+	}
+}
+
+type accountKey struct{}
+
+// WithAccount returns a context accumulating the work of its statements into
+// a new Account, within Config.Limits.
+func WithAccount(ctx context.Context) context.Context {
+	return context.WithValue(ctx, accountKey{}, &Account{})
+}
+
+// WithLimits returns a context accumulating the work of its statements into
+// a new Account, within limits instead of Config.Limits.
+func WithLimits(ctx context.Context, limits Limits) context.Context {
+	return context.WithValue(ctx, accountKey{}, &Account{limits: limits, hasLimits: true})
+}
+
+// FromContext returns the account of ctx, if any.
+func FromContext(ctx context.Context) (*Account, bool) {
+	a, ok := ctx.Value(accountKey{}).(*Account)
+	return a, ok
+}
+
+// Config configures an Interceptor.
+type Config struct {
+	// Mode is what to do with the accounts exceeding their limits. Defaults
+	// to Warn.
+	Mode Mode
+
+	// Limits are the limits of the accounts created with WithAccount.
+	Limits Limits
+
+	// OnExceed, if set, is called when an account exceeds one of its
+	// limits, once per resource.
+	OnExceed func(ctx context.Context, err *Error)
+}
+
+// Interceptor accounts the work of statements.
+type Interceptor struct {
+	sqlmw.NullInterceptor
+
+	cfg Config
+}
+
+// New returns an Interceptor for the supplied configuration.
+func New(cfg Config) *Interceptor {
+	return &Interceptor{cfg: cfg}
+}
+
+func (in *Interceptor) limits(a *Account) Limits {
+	if a.hasLimits {
+		return a.limits
+	}
+	return in.cfg.Limits
+}
+
+// exceeded returns the resources of a exceeding their limits.
+func (in *Interceptor) exceeded(a *Account, t Totals) []Resource {
+	l := in.limits(a)
+	var rs []Resource
+	if l.Statements > 0 && t.Statements > l.Statements {
+		rs = append(rs, Statements)
+	}
+	if l.Duration > 0 && t.Duration > l.Duration {
+		rs = append(rs, Duration)
+	}
+	if l.RowsScanned > 0 && t.RowsScanned > l.RowsScanned {
+		rs = append(rs, RowsScanned)
+	}
+	if l.RowsAffected > 0 && t.RowsAffected > l.RowsAffected {
+		rs = append(rs, RowsAffected)
+	}
+	return rs
+}
+
+// anyResource and rowsScanned select the resources failing a statement and
+// the scan of a row, see check.
+func anyResource(Resource) bool   { return true }
+func rowsScanned(r Resource) bool { return r == RowsScanned }
+
+// check reports the resources of a exceeding their limits and, in the Fail
+// mode, returns the error of the first of them selected by fails.
+func (in *Interceptor) check(ctx context.Context, a *Account, fails func(Resource) bool) error {
+	t := a.Totals()
+	var failed error
+	for _, r := range in.exceeded(a, t) {
+		err := &Error{Resource: r, Totals: t, Limits: in.limits(a)}
+		for {
+			mask := atomic.LoadUint32(&a.exceeded)
+			if mask&(1<<uint(r)) != 0 {
+				break
+			}
+			if atomic.CompareAndSwapUint32(&a.exceeded, mask, mask|1<<uint(r)) {
+				if in.cfg.OnExceed != nil {
+					in.cfg.OnExceed(ctx, err)
+				}
+				break
+			}
+		}
+		if failed == nil && in.cfg.Mode == Fail && fails(r) {
+			failed = err
+		}
+	}
+	return failed
+}
+
+// start counts a statement of the account of ctx and returns the error to
+// fail it with.
+func (in *Interceptor) start(ctx context.Context) (*Account, error) {
+	a, ok := FromContext(ctx)
+	if !ok {
+		return nil, nil
+	}
+	atomic.AddInt64(&a.statements, 1)
+	return a, in.check(ctx, a, anyResource)
+}
+
+// skipped uncounts a statement the driver skipped with driver.ErrSkip, as
+// database/sql runs it again, prepared.
+func (in *Interceptor) skipped(a *Account, err error) {
+	if a != nil && err == driver.ErrSkip {
+		atomic.AddInt64(&a.statements, -1)
+	}
+}
+
+// spent adds the time since start to a, if any.
+func (in *Interceptor) spent(a *Account, start time.Time) {
+	if a != nil {
+		atomic.AddInt64(&a.duration, int64(time.Since(start)))
+	}
+}
+
+// result is the result of a statement of an account, whose rows affected are
+// added to it.
+type result struct {
+	driver.Result
+	ctx     context.Context
+	account *Account
+	once    sync.Once
+}
+
+func (in *Interceptor) wrap(ctx context.Context, a *Account, res driver.Result, err error) (driver.Result, error) {
+	if a == nil || err != nil {
+		return res, err
+	}
+	return &result{Result: res, ctx: ctx, account: a}, nil
+}
+
+func (in *Interceptor) ConnExecContext(ctx context.Context, conn driver.ExecerContext, query string, args []driver.NamedValue) (driver.Result, error) {
+	a, err := in.start(ctx)
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	res, err := conn.ExecContext(ctx, query, args)
+	in.spent(a, start)
+	in.skipped(a, err)
+	return in.wrap(ctx, a, res, err)
+}
+
+func (in *Interceptor) StmtExecContext(ctx context.Context, stmt driver.StmtExecContext, query string, args []driver.NamedValue) (driver.Result, error) {
+	a, err := in.start(ctx)
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	res, err := stmt.ExecContext(ctx, args)
+	in.spent(a, start)
+	in.skipped(a, err)
+	return in.wrap(ctx, a, res, err)
+}
+
+func (in *Interceptor) ConnQueryContext(ctx context.Context, conn driver.QueryerContext, query string, args []driver.NamedValue) (context.Context, driver.Rows, error) {
+	a, err := in.start(ctx)
+	if err != nil {
+		return ctx, nil, err
+	}
+	start := time.Now()
+	rows, err := conn.QueryContext(ctx, query, args)
+	in.spent(a, start)
+	in.skipped(a, err)
+	return ctx, rows, err
+}
+
+func (in *Interceptor) StmtQueryContext(ctx context.Context, stmt driver.StmtQueryContext, query string, args []driver.NamedValue) (context.Context, driver.Rows, error) {
+	a, err := in.start(ctx)
+	if err != nil {
+		return ctx, nil, err
+	}
+	start := time.Now()
+	rows, err := stmt.QueryContext(ctx, args)
+	in.spent(a, start)
+	in.skipped(a, err)
+	return ctx, rows, err
+}
+
+func (in *Interceptor) ConnPrepareContext(ctx context.Context, conn driver.ConnPrepareContext, query string) (context.Context, driver.Stmt, error) {
+	a, _ := FromContext(ctx)
+	start := time.Now()
+	stmt, err := conn.PrepareContext(ctx, query)
+	in.spent(a, start)
+	return ctx, stmt, err
+}
+
+func (in *Interceptor) ConnBeginTx(ctx context.Context, conn driver.ConnBeginTx, txOpts driver.TxOptions) (context.Context, driver.Tx, error) {
+	a, _ := FromContext(ctx)
+	start := time.Now()
+	tx, err := conn.BeginTx(ctx, txOpts)
+	in.spent(a, start)
+	return ctx, tx, err
+}
+
+func (in *Interceptor) TxCommit(ctx context.Context, tx driver.Tx) error {
+	a, _ := FromContext(ctx)
+	start := time.Now()
+	err := tx.Commit()
+	in.spent(a, start)
+	return err
+}
+
+func (in *Interceptor) TxRollback(ctx context.Context, tx driver.Tx) error {
+	a, _ := FromContext(ctx)
+	start := time.Now()
+	err := tx.Rollback()
+	in.spent(a, start)
+	return err
+}
+
+func (in *Interceptor) RowsNext(ctx context.Context, rows driver.Rows, dest []driver.Value) error {
+	a, ok := FromContext(ctx)
+	if !ok {
+		return rows.Next(dest)
+	}
+	start := time.Now()
+	err := rows.Next(dest)
+	in.spent(a, start)
+	if err != nil {
+		return err
+	}
+	atomic.AddInt64(&a.rowsScanned, 1)
+	return in.check(ctx, a, rowsScanned)
+}
+
+func (in *Interceptor) ResultRowsAffected(res driver.Result) (int64, error) {
+	r, ok := res.(*result)
+	if !ok {
+		return res.RowsAffected()
+	}
+	n, err := r.Result.RowsAffected()
+	if err != nil {
+		return n, err
+	}
+	r.once.Do(func() {
+		atomic.AddInt64(&r.account.rowsAffected, n)
+		// the statement already ran, the excess fails the next one
+		in.check(r.ctx, r.account, anyResource)
+	})
+	return n, nil
+}
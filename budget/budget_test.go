@@ -0,0 +1,189 @@
+package budget
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+
+	"github.com/ngrok/sqlmw"
+	"github.com/ngrok/sqlmw/internal/fakedb"
+	"github.com/ngrok/sqlmw/sqlmwtest"
+)
+
+func openDB(t *testing.T, in *Interceptor) *sql.DB {
+	con := &fakedb.Connector{Handler: func(ctx context.Context, query string, args []driver.NamedValue) (*fakedb.Response, error) {
+		return &fakedb.Response{
+			Columns:      []string{"a"},
+			Rows:         [][]driver.Value{{int64(1)}, {int64(2)}},
+			RowsAffected: 3,
+		}, nil
+	}}
+	db := sql.OpenDB(sqlmw.Connector(con, in))
+	t.Cleanup(func() {
+		if err := db.Close(); err != nil {
+			t.Errorf("Failed to close db: %v", err)
+		}
+	})
+	return db
+}
+
+func count(t *testing.T, rows *sql.Rows, err error) int {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	defer rows.Close()
+	n := 0
+	for rows.Next() {
+		n++
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("Next failed: %v", err)
+	}
+	return n
+}
+
+func TestTotals(t *testing.T) {
+	in := New(Config{})
+	db := openDB(t, in)
+	ctx := WithAccount(context.Background())
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatalf("Begin failed: %v", err)
+	}
+	res, err := tx.ExecContext(ctx, "UPDATE t SET a = 1")
+	if err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if n, err := res.RowsAffected(); err != nil || n != 3 {
+			t.Fatalf("got rows affected %d, %v", n, err)
+		}
+	}
+	rows, err := tx.QueryContext(ctx, "SELECT a FROM t")
+	if n := count(t, rows, err); n != 2 {
+		t.Errorf("got %d rows, want 2", n)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+	// not accounted
+	rows, err = db.Query("SELECT a FROM t")
+	count(t, rows, err)
+
+	a, ok := FromContext(ctx)
+	if !ok {
+		t.Fatal("no account in context")
+	}
+	got := a.Totals()
+	if got.Statements != 2 || got.RowsScanned != 2 || got.RowsAffected != 3 || got.Duration <= 0 {
+		t.Errorf("got totals %v", got)
+	}
+}
+
+func TestWarn(t *testing.T) {
+	var exceeded []*Error
+	in := New(Config{Limits: Limits{Statements: 1}, OnExceed: func(ctx context.Context, err *Error) {
+		exceeded = append(exceeded, err)
+	}})
+	db := openDB(t, in)
+	ctx := WithAccount(context.Background())
+
+	for i := 0; i < 3; i++ {
+		if _, err := db.ExecContext(ctx, "UPDATE t SET a = 1"); err != nil {
+			t.Fatalf("Exec failed: %v", err)
+		}
+	}
+	if len(exceeded) != 1 || exceeded[0].Resource != Statements || exceeded[0].Totals.Statements != 2 {
+		t.Errorf("got exceeded %v", exceeded)
+	}
+}
+
+func TestFail(t *testing.T) {
+	in := New(Config{Mode: Fail, Limits: Limits{RowsScanned: 1}})
+	db := openDB(t, in)
+
+	ctx := WithAccount(context.Background())
+	rows, err := db.QueryContext(ctx, "SELECT a FROM t")
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	for rows.Next() {
+	}
+	var berr *Error
+	if err := rows.Err(); !errors.As(err, &berr) || berr.Resource != RowsScanned {
+		t.Errorf("got rows error %v, want the rows scanned budget exceeded", err)
+	}
+	if _, err := db.ExecContext(ctx, "UPDATE t SET a = 1"); !errors.As(err, &berr) {
+		t.Errorf("got exec error %v, want the rows scanned budget exceeded", err)
+	}
+
+	// the rows affected are known once the statement ran
+	ctx = WithLimits(context.Background(), Limits{RowsAffected: 2})
+	res, err := db.ExecContext(ctx, "UPDATE t SET a = 1")
+	if err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	if _, err := res.RowsAffected(); err != nil {
+		t.Fatalf("RowsAffected failed: %v", err)
+	}
+	_, err = db.ExecContext(ctx, "UPDATE t SET a = 1")
+	if !errors.As(err, &berr) || berr.Resource != RowsAffected || berr.Limits.RowsAffected != 2 {
+		t.Errorf("got exec error %v, want the rows affected budget exceeded", err)
+	}
+	if want := "budget: rows affected budget exceeded (statements=2 "; err == nil || err.Error()[:len(want)] != want {
+		t.Errorf("got error %v, want %q...", err, want)
+	}
+
+	// only the rows scanned budget fails the scan of the rows
+	ctx = WithLimits(context.Background(), Limits{RowsAffected: 2})
+	rows, err = db.QueryContext(ctx, "SELECT a FROM t")
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	res, err = db.ExecContext(ctx, "UPDATE t SET a = 1")
+	if err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	if _, err := res.RowsAffected(); err != nil {
+		t.Fatalf("RowsAffected failed: %v", err)
+	}
+	if n := count(t, rows, nil); n != 2 {
+		t.Errorf("got %d rows, want 2", n)
+	}
+}
+
+func TestSkip(t *testing.T) {
+	in := New(Config{})
+	skipped := false
+	con := &fakedb.Connector{Handler: func(ctx context.Context, query string, args []driver.NamedValue) (*fakedb.Response, error) {
+		if !skipped {
+			// retried through a prepared statement
+			skipped = true
+			return nil, driver.ErrSkip
+		}
+		return &fakedb.Response{}, nil
+	}}
+	db := sql.OpenDB(sqlmw.Connector(con, in))
+	t.Cleanup(func() {
+		if err := db.Close(); err != nil {
+			t.Errorf("Failed to close db: %v", err)
+		}
+	})
+
+	ctx := WithAccount(context.Background())
+	if _, err := db.ExecContext(ctx, "UPDATE t SET a = 1"); err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	a, _ := FromContext(ctx)
+	if n := a.Totals().Statements; n != 1 {
+		t.Errorf("got %d statements, want 1", n)
+	}
+}
+
+func TestConformance(t *testing.T) {
+	sqlmwtest.RunConformance(t, func() sqlmw.Interceptor { return New(Config{}) })
+}
@@ -0,0 +1,138 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/ngrok/sqlmw/rowset"
+)
+
+// Backend stores cached result sets. Implementations must be safe for
+// concurrent use.
+type Backend interface {
+	// Get returns the result set stored under key, if it has not expired.
+	Get(key string) (*rowset.Set, bool)
+
+	// Set stores set under key for ttl. tables are the tables the query
+	// read, see Invalidate.
+	Set(key string, set *rowset.Set, tables []string, ttl time.Duration)
+
+	// Invalidate removes every entry that read one of tables.
+	Invalidate(tables ...string)
+
+	// Purge removes every entry.
+	Purge()
+}
+
+// LRU is an in-memory Backend holding a bounded number of entries. When it
+// is full the least recently used entry is evicted.
+type LRU struct {
+	size int
+	now  func() time.Time
+
+	mu      sync.Mutex
+	order   *list.List
+	entries map[string]*list.Element
+	tables  map[string]map[string]struct{}
+}
+
+type lruEntry struct {
+	key     string
+	set     *rowset.Set
+	tables  []string
+	expires time.Time
+}
+
+var _ Backend = (*LRU)(nil)
+
+// NewLRU returns an LRU holding at most size entries.
+func NewLRU(size int) *LRU {
+	return &LRU{
+		size:    size,
+		now:     time.Now,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+		tables:  make(map[string]map[string]struct{}),
+	}
+}
+
+func (c *LRU) Get(key string) (*rowset.Set, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	e := el.Value.(*lruEntry)
+	if !c.now().Before(e.expires) {
+		c.remove(el)
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	return e.set, true
+}
+
+func (c *LRU) Set(key string, set *rowset.Set, tables []string, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.entries[key]; ok {
+		c.remove(el)
+	}
+	e := &lruEntry{key: key, set: set, tables: tables, expires: c.now().Add(ttl)}
+	c.entries[key] = c.order.PushFront(e)
+	for _, t := range tables {
+		keys, ok := c.tables[t]
+		if !ok {
+			keys = make(map[string]struct{})
+			c.tables[t] = keys
+		}
+		keys[key] = struct{}{}
+	}
+
+	for c.order.Len() > c.size {
+		c.remove(c.order.Back())
+	}
+}
+
+func (c *LRU) Invalidate(tables ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, t := range tables {
+		for key := range c.tables[t] {
+			c.remove(c.entries[key])
+		}
+	}
+}
+
+func (c *LRU) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.order.Init()
+	c.entries = make(map[string]*list.Element)
+	c.tables = make(map[string]map[string]struct{})
+}
+
+// Len returns the number of entries, including the expired ones not yet
+// evicted.
+func (c *LRU) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+func (c *LRU) remove(el *list.Element) {
+	e := c.order.Remove(el).(*lruEntry)
+	delete(c.entries, e.key)
+	for _, t := range e.tables {
+		keys := c.tables[t]
+		delete(keys, e.key)
+		if len(keys) == 0 {
+			delete(c.tables, t)
+		}
+	}
+}
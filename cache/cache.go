@@ -0,0 +1,377 @@
+// Package cache provides an sqlmw.Interceptor serving the results of read
+// queries from a cache.
+//
+// Caching is opt-in: a query is only cached when its fingerprint is listed in
+// Config.Fingerprints or when it is run with a context returned by WithTTL.
+// Entries are keyed by the query text, its arguments and the scope of its
+// context, see Config.Scope, and are invalidated whenever a statement writes
+// to one of the tables the query read. Queries run inside a transaction are
+// neither cached nor served from the cache.
+//
+// Writes made inside a transaction invalidate the cache when they are run,
+// and again when the transaction commits, so that a query run concurrently
+// from outside the transaction does not keep the previous data cached.
+//
+// Statements of an unknown kind, like SET or EXPLAIN, invalidate the tables
+// they reference. Routine calls like CALL, DO or EXECUTE may write to any
+// table and drop every entry.
+//
+// Statements are described with sqlinfo.Describe. Wrapping the Interceptor
+// with a sqlinfo.Interceptor for the dialect of the database describes them
+// according to that dialect, and only once per prepared statement.
+package cache
+
+import (
+	"context"
+	"database/sql/driver"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ngrok/sqlmw"
+	"github.com/ngrok/sqlmw/fingerprint"
+	"github.com/ngrok/sqlmw/internal/sqlquery"
+	"github.com/ngrok/sqlmw/rowset"
+	"github.com/ngrok/sqlmw/session"
+	"github.com/ngrok/sqlmw/sqlinfo"
+	"github.com/ngrok/sqlmw/tenant"
+)
+
+const (
+	defaultSize    = 1024
+	defaultTTL     = time.Minute
+	defaultMaxRows = 1000
+)
+
+// Config configures an Interceptor.
+type Config struct {
+	// Backend stores the cached results. Defaults to an LRU of 1024 entries.
+	Backend Backend
+
+	// TTL is the lifetime of the entries cached because of Fingerprints.
+	// Defaults to 1 minute.
+	TTL time.Duration
+
+	// Fingerprints lists the fingerprints of the queries to cache.
+	Fingerprints []string
+
+	// Fingerprint computes the fingerprint of a query. Defaults to
+	// fingerprint.Normalize.
+	Fingerprint func(query string) string
+
+	// MaxRows is the largest number of rows cached for a single query.
+	// Larger results are streamed to the caller and not cached. Defaults
+	// to 1000.
+	MaxRows int
+
+	// Scope returns what the results of a query depend on besides its
+	// text and its arguments, like the tenant or the session variables of
+	// its context. Queries only share entries within a scope. Defaults to
+	// DefaultScope.
+	Scope func(ctx context.Context) string
+}
+
+// DefaultScope scopes the queries by the tenant of tenant.FromContext, by
+// tenant.IsUnscoped and by the variables of session.FromContext.
+func DefaultScope(ctx context.Context) string {
+	var scope []driver.NamedValue
+	if t, ok := tenant.FromContext(ctx); ok {
+		scope = append(scope, driver.NamedValue{Name: "tenant", Value: t})
+	}
+	if tenant.IsUnscoped(ctx) {
+		scope = append(scope, driver.NamedValue{Name: "unscoped"})
+	}
+	vars := session.FromContext(ctx)
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		scope = append(scope, driver.NamedValue{Name: "session." + name, Value: vars[name]})
+	}
+	if len(scope) == 0 {
+		return ""
+	}
+	return sqlquery.Key("", scope)
+}
+
+// Stats are the counters of an Interceptor.
+type Stats struct {
+	Hits          uint64
+	Misses        uint64
+	Invalidations uint64
+}
+
+// Interceptor caches the results of read queries.
+type Interceptor struct {
+	// stats is accessed atomically and kept first for 64-bit alignment
+	stats Stats
+
+	sqlmw.NullInterceptor
+
+	cfg          Config
+	fingerprints map[string]bool
+
+	// mu orders the fills of the cache with the invalidations, see fill
+	mu          sync.Mutex
+	generations map[string]uint64
+	purges      uint64
+}
+
+// New returns an Interceptor for the supplied configuration.
+func New(cfg Config) *Interceptor {
+	if cfg.Backend == nil {
+		cfg.Backend = NewLRU(defaultSize)
+	}
+	if cfg.TTL <= 0 {
+		cfg.TTL = defaultTTL
+	}
+	if cfg.Fingerprint == nil {
+		cfg.Fingerprint = fingerprint.Normalize
+	}
+	if cfg.MaxRows <= 0 {
+		cfg.MaxRows = defaultMaxRows
+	}
+	if cfg.Scope == nil {
+		cfg.Scope = DefaultScope
+	}
+
+	in := &Interceptor{
+		cfg:          cfg,
+		fingerprints: make(map[string]bool),
+		generations:  make(map[string]uint64),
+	}
+	for _, fp := range cfg.Fingerprints {
+		in.fingerprints[fp] = true
+	}
+	return in
+}
+
+type ttlKey struct{}
+
+// WithTTL returns a context caching the queries run with it for ttl. A zero
+// ttl disables caching for queries whose fingerprint is listed in
+// Config.Fingerprints.
+func WithTTL(ctx context.Context, ttl time.Duration) context.Context {
+	return context.WithValue(ctx, ttlKey{}, ttl)
+}
+
+// Stats returns a snapshot of the counters.
+func (in *Interceptor) Stats() Stats {
+	return Stats{
+		Hits:          atomic.LoadUint64(&in.stats.Hits),
+		Misses:        atomic.LoadUint64(&in.stats.Misses),
+		Invalidations: atomic.LoadUint64(&in.stats.Invalidations),
+	}
+}
+
+func (in *Interceptor) ConnQueryContext(ctx context.Context, conn driver.QueryerContext, query string, args []driver.NamedValue) (context.Context, driver.Rows, error) {
+	rows, err := in.query(ctx, query, args, func() (driver.Rows, error) {
+		return conn.QueryContext(ctx, query, args)
+	})
+	return ctx, rows, err
+}
+
+func (in *Interceptor) StmtQueryContext(ctx context.Context, stmt driver.StmtQueryContext, query string, args []driver.NamedValue) (context.Context, driver.Rows, error) {
+	rows, err := in.query(ctx, query, args, func() (driver.Rows, error) {
+		return stmt.QueryContext(ctx, args)
+	})
+	return ctx, rows, err
+}
+
+func (in *Interceptor) ConnExecContext(ctx context.Context, conn driver.ExecerContext, query string, args []driver.NamedValue) (driver.Result, error) {
+	res, err := conn.ExecContext(ctx, query, args)
+	in.write(ctx, sqlinfo.Describe(ctx, query))
+	return res, err
+}
+
+func (in *Interceptor) StmtExecContext(ctx context.Context, stmt driver.StmtExecContext, query string, args []driver.NamedValue) (driver.Result, error) {
+	res, err := stmt.ExecContext(ctx, args)
+	in.write(ctx, sqlinfo.Describe(ctx, query))
+	return res, err
+}
+
+func (in *Interceptor) TxCommit(ctx context.Context, tx driver.Tx) error {
+	err := tx.Commit()
+	in.end(ctx, true)
+	return err
+}
+
+func (in *Interceptor) TxRollback(ctx context.Context, tx driver.Tx) error {
+	err := tx.Rollback()
+	in.end(ctx, false)
+	return err
+}
+
+func (in *Interceptor) query(ctx context.Context, query string, args []driver.NamedValue, run func() (driver.Rows, error)) (driver.Rows, error) {
+	st := sqlinfo.Describe(ctx, query)
+	if st.Kind != sqlinfo.Read || st.ForUpdate {
+		// e.g. INSERT ... RETURNING
+		rows, err := run()
+		in.write(ctx, st)
+		return rows, err
+	}
+
+	ttl := in.ttl(ctx, query)
+	if ttl <= 0 || sqlmw.InTx(ctx) {
+		return run()
+	}
+
+	key := sqlquery.Key(in.cfg.Scope(ctx), nil) + sqlquery.Key(query, args)
+	if set, ok := in.cfg.Backend.Get(key); ok {
+		atomic.AddUint64(&in.stats.Hits, 1)
+		return set.Rows(), nil
+	}
+	atomic.AddUint64(&in.stats.Misses, 1)
+
+	gen := in.generation(st.Tables)
+	rows, err := run()
+	if err != nil {
+		return nil, err
+	}
+	set, stopped, err := rowset.CaptureN(rows, in.cfg.MaxRows+1)
+	if err != nil {
+		rows.Close()
+		return nil, err
+	}
+	if stopped {
+		// too many rows to cache
+		return set.Continue(rows), nil
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+
+	in.fill(key, set, st.Tables, gen, ttl)
+	return set.Rows(), nil
+}
+
+// generation returns a number that changes whenever one of tables is
+// invalidated.
+func (in *Interceptor) generation(tables []string) uint64 {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	return in.generationLocked(tables)
+}
+
+func (in *Interceptor) generationLocked(tables []string) uint64 {
+	gen := in.purges
+	for _, t := range tables {
+		gen += in.generations[t]
+	}
+	return gen
+}
+
+// fill caches set unless one of tables was invalidated since gen was
+// returned by generation, in which case set may predate the write.
+func (in *Interceptor) fill(key string, set *rowset.Set, tables []string, gen uint64, ttl time.Duration) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	if in.generationLocked(tables) == gen {
+		in.cfg.Backend.Set(key, set, tables, ttl)
+	}
+}
+
+func (in *Interceptor) ttl(ctx context.Context, query string) time.Duration {
+	if ttl, ok := ctx.Value(ttlKey{}).(time.Duration); ok {
+		return ttl
+	}
+	if len(in.fingerprints) > 0 && in.fingerprints[in.cfg.Fingerprint(query)] {
+		return in.cfg.TTL
+	}
+	return 0
+}
+
+type txKey struct{}
+
+// txWrites are the tables written by a transaction.
+type txWrites struct {
+	tables []string
+	all    bool
+}
+
+// write invalidates the entries depending on the tables written by st, and
+// records them to invalidate them again when the transaction of ctx commits.
+func (in *Interceptor) write(ctx context.Context, st *sqlinfo.Statement) {
+	tables, ok := written(st)
+	if !ok {
+		return
+	}
+	if c, ok := sqlmw.ConnFromContext(ctx); ok && sqlmw.InTx(ctx) {
+		w, _ := c.Value(txKey{}).(*txWrites)
+		if w == nil {
+			w = &txWrites{}
+			c.SetValue(txKey{}, w)
+		}
+		w.tables = append(w.tables, tables...)
+		w.all = w.all || len(tables) == 0
+	}
+	in.invalidate(st)
+}
+
+// end invalidates the tables written by the transaction of ctx again when
+// it committed.
+func (in *Interceptor) end(ctx context.Context, commit bool) {
+	c, ok := sqlmw.ConnFromContext(ctx)
+	if !ok {
+		return
+	}
+	w, _ := c.Value(txKey{}).(*txWrites)
+	if w == nil {
+		return
+	}
+	c.SetValue(txKey{}, nil)
+	if !commit {
+		return
+	}
+	st := &sqlinfo.Statement{Kind: sqlinfo.Write}
+	if !w.all {
+		st.Tables = w.tables
+	}
+	in.invalidate(st)
+}
+
+// written returns the tables written by st, none when it may write to any
+// table. It returns false when st does not write.
+func written(st *sqlinfo.Statement) ([]string, bool) {
+	switch st.Kind {
+	case sqlinfo.Read, sqlinfo.TxControl:
+		return nil, false
+	case sqlinfo.Unknown:
+		if len(st.Tables) == 0 && !routines[st.Verb] {
+			return nil, false
+		}
+	}
+	return st.Tables, true
+}
+
+// routines are the verbs of the statements running code that may write to
+// any table.
+var routines = map[string]bool{
+	"CALL":    true,
+	"DO":      true,
+	"EXEC":    true,
+	"EXECUTE": true,
+}
+
+// invalidate drops the entries depending on the tables written by st. Every
+// entry is dropped when the tables are unknown.
+func (in *Interceptor) invalidate(st *sqlinfo.Statement) {
+	if _, ok := written(st); !ok {
+		return
+	}
+	atomic.AddUint64(&in.stats.Invalidations, 1)
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	if len(st.Tables) == 0 {
+		in.purges++
+		in.cfg.Backend.Purge()
+		return
+	}
+	for _, t := range st.Tables {
+		in.generations[t]++
+	}
+	in.cfg.Backend.Invalidate(st.Tables...)
+}
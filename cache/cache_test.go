@@ -0,0 +1,330 @@
+package cache
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ngrok/sqlmw"
+	"github.com/ngrok/sqlmw/fingerprint"
+	"github.com/ngrok/sqlmw/internal/fakedb"
+	"github.com/ngrok/sqlmw/session"
+	"github.com/ngrok/sqlmw/sqlinfo"
+	"github.com/ngrok/sqlmw/sqlmwtest"
+	"github.com/ngrok/sqlmw/tenant"
+)
+
+func openDB(t *testing.T, in sqlmw.Interceptor) (*sql.DB, *fakedb.Connector) {
+	con := &fakedb.Connector{Handler: func(_ context.Context, query string, args []driver.NamedValue) (*fakedb.Response, error) {
+		return &fakedb.Response{
+			Columns: []string{"id", "name"},
+			Rows:    [][]driver.Value{{int64(1), "alice"}},
+		}, nil
+	}}
+	db := sql.OpenDB(sqlmw.Connector(con, in))
+	t.Cleanup(func() {
+		if err := db.Close(); err != nil {
+			t.Errorf("Failed to close db: %v", err)
+		}
+	})
+	return db, con
+}
+
+func queryNames(t *testing.T, ctx context.Context, db interface {
+	QueryContext(context.Context, string, ...interface{}) (*sql.Rows, error)
+}, query string, args ...interface{}) []string {
+	t.Helper()
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var id int64
+		var name string
+		if err := rows.Scan(&id, &name); err != nil {
+			t.Fatalf("Scan failed: %v", err)
+		}
+		names = append(names, name)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("Rows failed: %v", err)
+	}
+	return names
+}
+
+func countQueries(con *fakedb.Connector, query string) int {
+	n := 0
+	for _, q := range con.Queries() {
+		if q == query {
+			n++
+		}
+	}
+	return n
+}
+
+func TestCacheByFingerprint(t *testing.T) {
+	const query = "SELECT id, name FROM users WHERE id = ?"
+	in := New(Config{Fingerprints: []string{fingerprint.Normalize(query)}})
+	db, con := openDB(t, in)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if names := queryNames(t, ctx, db, query, 1); !reflect.DeepEqual(names, []string{"alice"}) {
+			t.Fatalf("unexpected rows %v", names)
+		}
+	}
+	queryNames(t, ctx, db, query, 2)
+	if n := countQueries(con, query); n != 2 {
+		t.Errorf("expected 2 queries to reach the database, got %d", n)
+	}
+
+	// not opted in
+	queryNames(t, ctx, db, "SELECT id, name FROM accounts")
+	queryNames(t, ctx, db, "SELECT id, name FROM accounts")
+	if n := countQueries(con, "SELECT id, name FROM accounts"); n != 2 {
+		t.Errorf("expected 2 queries to reach the database, got %d", n)
+	}
+
+	if st := in.Stats(); st.Hits != 2 || st.Misses != 2 {
+		t.Errorf("unexpected stats %+v", st)
+	}
+}
+
+func TestCacheInvalidation(t *testing.T) {
+	const query = "SELECT u.id, u.name FROM public.users u JOIN teams ON teams.id = u.team_id"
+	in := New(Config{})
+	db, con := openDB(t, in)
+	ctx := WithTTL(context.Background(), time.Minute)
+
+	queryNames(t, ctx, db, query)
+	queryNames(t, ctx, db, query)
+	if n := countQueries(con, query); n != 1 {
+		t.Fatalf("expected 1 query to reach the database, got %d", n)
+	}
+
+	if _, err := db.ExecContext(ctx, "UPDATE accounts SET name = 'bob'"); err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	queryNames(t, ctx, db, query)
+	if n := countQueries(con, query); n != 1 {
+		t.Fatalf("unrelated write invalidated the cache")
+	}
+
+	if _, err := db.ExecContext(ctx, `UPDATE "teams" SET name = 'bob'`); err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	queryNames(t, ctx, db, query)
+	if n := countQueries(con, query); n != 2 {
+		t.Fatalf("write to a referenced table did not invalidate the cache")
+	}
+}
+
+func TestCacheInvalidatesOnCommit(t *testing.T) {
+	const query = "SELECT id, name FROM users"
+	in := New(Config{})
+	db, con := openDB(t, in)
+	ctx := WithTTL(context.Background(), time.Minute)
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatalf("BeginTx failed: %v", err)
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE users SET name = 'bob'"); err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	// cached from outside the transaction before it commits
+	queryNames(t, ctx, db, query)
+	queryNames(t, ctx, db, query)
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+	queryNames(t, ctx, db, query)
+
+	if n := countQueries(con, query); n != 2 {
+		t.Errorf("expected 2 queries to reach the database, got %d", n)
+	}
+}
+
+func TestCacheUnknownStatements(t *testing.T) {
+	const query = "SELECT id, name FROM users"
+	in := New(Config{})
+	db, con := openDB(t, in)
+	ctx := WithTTL(context.Background(), time.Minute)
+
+	for _, stmt := range []string{"SET search_path = app", "CALL refresh_users()"} {
+		queryNames(t, ctx, db, query)
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			t.Fatalf("Exec failed: %v", err)
+		}
+	}
+	queryNames(t, ctx, db, query)
+
+	// only CALL dropped the entry
+	if n := countQueries(con, query); n != 2 {
+		t.Errorf("expected 2 queries to reach the database, got %d", n)
+	}
+}
+
+func TestCacheSkipsTransactions(t *testing.T) {
+	const query = "SELECT id, name FROM users"
+	in := New(Config{})
+	db, con := openDB(t, in)
+	ctx := WithTTL(context.Background(), time.Minute)
+
+	queryNames(t, ctx, db, query)
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatalf("BeginTx failed: %v", err)
+	}
+	queryNames(t, ctx, tx, query)
+	queryNames(t, ctx, tx, query)
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+	queryNames(t, ctx, db, query)
+
+	if n := countQueries(con, query); n != 3 {
+		t.Errorf("expected 3 queries to reach the database, got %d", n)
+	}
+}
+
+func TestCacheKeepsColumnTypes(t *testing.T) {
+	in := New(Config{})
+	db, _ := openDB(t, in)
+	ctx := WithTTL(context.Background(), time.Minute)
+
+	for i := 0; i < 2; i++ {
+		rows, err := db.QueryContext(ctx, "SELECT id, name FROM users")
+		if err != nil {
+			t.Fatalf("Query failed: %v", err)
+		}
+		types, err := rows.ColumnTypes()
+		if err != nil {
+			t.Fatalf("ColumnTypes failed: %v", err)
+		}
+		if len(types) != 2 || types[1].Name() != "name" {
+			t.Errorf("unexpected column types %v", types)
+		}
+		rows.Close()
+	}
+}
+
+func TestCacheSkipsStaleFills(t *testing.T) {
+	const query = "SELECT id, name FROM users"
+	in := New(Config{})
+	write := true
+	con := &fakedb.Connector{Handler: func(ctx context.Context, query string, args []driver.NamedValue) (*fakedb.Response, error) {
+		if write {
+			// a write committed while the query runs
+			write = false
+			in.invalidate(sqlinfo.Describe(ctx, "UPDATE users SET name = 'bob'"))
+		}
+		return &fakedb.Response{
+			Columns: []string{"id", "name"},
+			Rows:    [][]driver.Value{{int64(1), "alice"}},
+		}, nil
+	}}
+	db := sql.OpenDB(sqlmw.Connector(con, in))
+	t.Cleanup(func() {
+		if err := db.Close(); err != nil {
+			t.Errorf("Failed to close db: %v", err)
+		}
+	})
+	ctx := WithTTL(context.Background(), time.Minute)
+
+	for i := 0; i < 3; i++ {
+		queryNames(t, ctx, db, query)
+	}
+	if n := countQueries(con, query); n != 2 {
+		t.Errorf("expected 2 queries to reach the database, got %d", n)
+	}
+}
+
+func TestCacheStreamsLargeResults(t *testing.T) {
+	const query = "SELECT id, name FROM users"
+	in := New(Config{MaxRows: 2})
+	con := &fakedb.Connector{Handler: func(_ context.Context, query string, args []driver.NamedValue) (*fakedb.Response, error) {
+		return &fakedb.Response{
+			Columns: []string{"id", "name"},
+			Rows:    [][]driver.Value{{int64(1), "alice"}, {int64(2), "bob"}, {int64(3), "carol"}, {int64(4), "dave"}},
+		}, nil
+	}}
+	db := sql.OpenDB(sqlmw.Connector(con, in))
+	t.Cleanup(func() {
+		if err := db.Close(); err != nil {
+			t.Errorf("Failed to close db: %v", err)
+		}
+	})
+	ctx := WithTTL(context.Background(), time.Minute)
+
+	for i := 0; i < 2; i++ {
+		names := queryNames(t, ctx, db, query)
+		if !reflect.DeepEqual(names, []string{"alice", "bob", "carol", "dave"}) {
+			t.Fatalf("unexpected rows %v", names)
+		}
+	}
+	if n := countQueries(con, query); n != 2 {
+		t.Errorf("expected 2 queries to reach the database, got %d", n)
+	}
+}
+
+func TestCacheScope(t *testing.T) {
+	const query = "SELECT id, name FROM users"
+	in := New(Config{})
+	db, con := openDB(t, in)
+	ctx := WithTTL(context.Background(), time.Minute)
+
+	queryNames(t, ctx, db, query)
+	queryNames(t, tenant.WithTenant(ctx, "42"), db, query)
+	queryNames(t, tenant.WithTenant(ctx, "43"), db, query)
+	queryNames(t, tenant.Unscoped(tenant.WithTenant(ctx, "42")), db, query)
+	queryNames(t, session.WithVars(ctx, session.Vars{"role": "admin"}), db, query)
+	if n := countQueries(con, query); n != 5 {
+		t.Errorf("expected 5 queries to reach the database, got %d", n)
+	}
+
+	queryNames(t, tenant.WithTenant(ctx, "42"), db, query)
+	queryNames(t, session.WithVars(ctx, session.Vars{"role": "admin"}), db, query)
+	if n := countQueries(con, query); n != 5 {
+		t.Errorf("expected 5 queries to reach the database, got %d", n)
+	}
+}
+
+func TestLRU(t *testing.T) {
+	now := time.Now()
+	c := NewLRU(2)
+	c.now = func() time.Time { return now }
+
+	c.Set("a", nil, []string{"users"}, time.Second)
+	c.Set("b", nil, []string{"teams"}, time.Minute)
+	c.Get("a")
+	c.Set("c", nil, []string{"users", "teams"}, time.Minute)
+	if _, ok := c.Get("b"); ok {
+		t.Error("least recently used entry was not evicted")
+	}
+
+	now = now.Add(2 * time.Second)
+	if _, ok := c.Get("a"); ok {
+		t.Error("expired entry was returned")
+	}
+
+	c.Set("d", nil, nil, time.Minute)
+	c.Invalidate("teams")
+	if _, ok := c.Get("c"); ok {
+		t.Error("invalidated entry was returned")
+	}
+	if _, ok := c.Get("d"); !ok || c.Len() != 1 {
+		t.Error("unrelated entry was invalidated")
+	}
+}
+
+func TestConformance(t *testing.T) {
+	sqlmwtest.RunConformance(t, func() sqlmw.Interceptor { return New(Config{}) })
+}
@@ -0,0 +1,168 @@
+// Package cassette records the interactions of an application with its
+// database once, so that tests can replay them later without a database.
+//
+// A Recorder is an sqlmw.Interceptor appending every statement to a
+// Cassette: its query and arguments, the columns, column types and rows it
+// returned, its result or its error, as well as the transaction boundaries.
+// The Cassette is then saved to a versioned JSON file:
+//
+//	c := &cassette.Cassette{}
+//	db := sql.OpenDB(sqlmw.Connector(connector, cassette.NewRecorder(c)))
+//	// ... run the test against the database ...
+//	err := c.Save("testdata/users.cassette.json")
+//
+// A Replayer is a driver.Connector serving the interactions of a Cassette,
+// matching the statements it receives by fingerprint and arguments:
+//
+//	c, err := cassette.Load("testdata/users.cassette.json")
+//	r := cassette.NewReplayer(c, cassette.Strict)
+//	db := sql.OpenDB(r)
+//
+// In the Strict mode, statements must arrive in the recorded order. In the
+// Lenient mode, they may arrive in any order and be repeated. A statement
+// that matches no interaction fails with a *MismatchError describing how it
+// differs from the closest one.
+//
+// Only the prepared statements that failed are recorded, the others are
+// recorded when they are run.
+package cassette
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// Version is the version of the cassettes written by this package.
+const Version = 1
+
+// Kind is the kind of an Interaction.
+type Kind string
+
+const (
+	Exec     Kind = "exec"
+	Query    Kind = "query"
+	Prepare  Kind = "prepare"
+	Begin    Kind = "begin"
+	Commit   Kind = "commit"
+	Rollback Kind = "rollback"
+)
+
+// Arg is an argument of a statement.
+type Arg struct {
+	Name    string `json:"name,omitempty"`
+	Ordinal int    `json:"ordinal"`
+	Value   Value  `json:"value"`
+}
+
+// Column describes a column of a result set. The optional metadata is nil
+// when the driver did not provide it.
+type Column struct {
+	Name             string `json:"name"`
+	DatabaseTypeName string `json:"databaseTypeName,omitempty"`
+	Length           *int64 `json:"length,omitempty"`
+	Nullable         *bool  `json:"nullable,omitempty"`
+	Precision        *int64 `json:"precision,omitempty"`
+	Scale            *int64 `json:"scale,omitempty"`
+	// ScanType is the name of the Go type of the column, see scanTypes.
+	ScanType string `json:"scanType,omitempty"`
+}
+
+// ResultSet is a result set returned by a query.
+type ResultSet struct {
+	Columns []Column  `json:"columns"`
+	Rows    [][]Value `json:"rows"`
+	// Error is the error that ended the rows, if any.
+	Error string `json:"error,omitempty"`
+
+	done bool
+}
+
+// Result is the result of an exec. A nil field is an error of the driver,
+// whose text is in the matching error field.
+type Result struct {
+	LastInsertId      *int64 `json:"lastInsertId,omitempty"`
+	LastInsertIdError string `json:"lastInsertIdError,omitempty"`
+	RowsAffected      *int64 `json:"rowsAffected,omitempty"`
+	RowsAffectedError string `json:"rowsAffectedError,omitempty"`
+}
+
+// Interaction is a recorded call to the database.
+type Interaction struct {
+	Kind       Kind        `json:"kind"`
+	Query      string      `json:"query,omitempty"`
+	Args       []Arg       `json:"args,omitempty"`
+	ResultSets []ResultSet `json:"resultSets,omitempty"`
+	Result     *Result     `json:"result,omitempty"`
+	Error      string      `json:"error,omitempty"`
+}
+
+// Cassette is a sequence of interactions. It is safe for concurrent use.
+type Cassette struct {
+	mu           sync.Mutex
+	interactions []*Interaction
+}
+
+type file struct {
+	Version      int            `json:"version"`
+	Interactions []*Interaction `json:"interactions"`
+}
+
+// Interactions returns the interactions recorded so far.
+func (c *Cassette) Interactions() []*Interaction {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]*Interaction(nil), c.interactions...)
+}
+
+func (c *Cassette) add(it *Interaction) {
+	c.mu.Lock()
+	c.interactions = append(c.interactions, it)
+	c.mu.Unlock()
+}
+
+// Write writes the cassette to w.
+func (c *Cassette) Write(w io.Writer) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(file{Version: Version, Interactions: c.interactions})
+}
+
+// Save writes the cassette to the file at path.
+func (c *Cassette) Save(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := c.Write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Read reads a cassette written by Write.
+func Read(r io.Reader) (*Cassette, error) {
+	var f file
+	if err := json.NewDecoder(r).Decode(&f); err != nil {
+		return nil, fmt.Errorf("cassette: %w", err)
+	}
+	if f.Version != Version {
+		return nil, fmt.Errorf("cassette: unsupported version %d", f.Version)
+	}
+	return &Cassette{interactions: f.Interactions}, nil
+}
+
+// Load reads the cassette saved at path.
+func Load(path string) (*Cassette, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Read(f)
+}
@@ -0,0 +1,272 @@
+package cassette
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ngrok/sqlmw"
+	"github.com/ngrok/sqlmw/internal/fakedb"
+	"github.com/ngrok/sqlmw/sqlmwtest"
+)
+
+var created = time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+
+func handle(_ context.Context, query string, args []driver.NamedValue) (*fakedb.Response, error) {
+	switch {
+	case strings.HasPrefix(query, "SELECT"):
+		return &fakedb.Response{
+			Columns: []string{"id", "name", "avatar", "created"},
+			Rows: [][]driver.Value{
+				{args[0].Value, "alice", []byte{0, 1}, created},
+				{args[0].Value, nil, []byte(nil), created},
+			},
+		}, nil
+	case strings.HasPrefix(query, "INSERT"):
+		return &fakedb.Response{RowsAffected: 1, LastInsertId: 7}, nil
+	case query == "BEGIN" || query == "COMMIT":
+		return nil, nil
+	}
+	return nil, errors.New("syntax error")
+}
+
+type user struct {
+	ID      int64
+	Name    sql.NullString
+	Avatar  []byte
+	Created time.Time
+}
+
+// run runs the statements of the tests and returns what they returned.
+func run(t *testing.T, db *sql.DB, id int64) []interface{} {
+	t.Helper()
+	ctx := context.Background()
+	var out []interface{}
+
+	rows, err := db.QueryContext(ctx, "SELECT id, name, avatar, created FROM users WHERE id = ?", id)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	for rows.Next() {
+		var u user
+		if err := rows.Scan(&u.ID, &u.Name, &u.Avatar, &u.Created); err != nil {
+			t.Fatalf("Scan failed: %v", err)
+		}
+		out = append(out, u)
+	}
+	if err := rows.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatalf("BeginTx failed: %v", err)
+	}
+	res, err := tx.ExecContext(ctx, "INSERT INTO users (name) VALUES ($1)", "bob")
+	if err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+	n, _ := res.RowsAffected()
+	last, _ := res.LastInsertId()
+	out = append(out, n, last)
+
+	_, err = db.ExecContext(ctx, "DROP users")
+	return append(out, err.Error())
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	c := &Cassette{}
+	db := sql.OpenDB(sqlmw.Connector(&fakedb.Connector{Handler: handle}, NewRecorder(c)))
+	t.Cleanup(func() {
+		if err := db.Close(); err != nil {
+			t.Errorf("Failed to close db: %v", err)
+		}
+	})
+	want := run(t, db, 1)
+
+	var buf bytes.Buffer
+	if err := c.Write(&buf); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	loaded, err := Read(&buf)
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+
+	for _, mode := range []Mode{Strict, Lenient} {
+		r := NewReplayer(loaded, mode)
+		replay := sql.OpenDB(r)
+		t.Cleanup(func() {
+			if err := replay.Close(); err != nil {
+				t.Errorf("Failed to close db: %v", err)
+			}
+		})
+		if got := run(t, replay, 1); !reflect.DeepEqual(got, want) {
+			t.Errorf("mode %d: replayed %v, want %v", mode, got, want)
+		}
+		if unused := r.Unused(); len(unused) != 0 {
+			t.Errorf("mode %d: got %d unused interactions", mode, len(unused))
+		}
+	}
+}
+
+func TestMismatch(t *testing.T) {
+	c, err := Read(strings.NewReader(`{"version": 1, "interactions": [
+		{"kind": "exec", "query": "UPDATE users SET name = ? WHERE id = ?", "args": [
+			{"ordinal": 1, "value": {"string": "bob"}},
+			{"ordinal": 2, "value": {"int64": 1}}
+		], "result": {"rowsAffected": 1}},
+		{"kind": "exec", "query": "DELETE FROM users"}
+	]}`))
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	ctx := context.Background()
+
+	db := sql.OpenDB(NewReplayer(c, Strict))
+	t.Cleanup(func() {
+		if err := db.Close(); err != nil {
+			t.Errorf("Failed to close db: %v", err)
+		}
+	})
+	_, err = db.ExecContext(ctx, "UPDATE users SET name = ? WHERE id = ?", "bob", 2)
+	var mismatch *MismatchError
+	if !errors.As(err, &mismatch) {
+		t.Fatalf("got error %v, want *MismatchError", err)
+	}
+	want := `  kind: exec
+  query: update users set name = ? where id = ?
+  arg 1: string("bob")
+- arg 2: int64(1)
++ arg 2: int64(2)
+`
+	if got := mismatch.Diff(); got != want {
+		t.Errorf("got diff\n%s\nwant\n%s", got, want)
+	}
+	if _, err := db.ExecContext(ctx, "DELETE FROM users"); !errors.As(err, &mismatch) {
+		t.Errorf("got error %v, want *MismatchError out of order", err)
+	}
+
+	db = sql.OpenDB(NewReplayer(c, Lenient))
+	for i := 0; i < 2; i++ {
+		if _, err := db.ExecContext(ctx, "DELETE FROM users"); err != nil {
+			t.Fatalf("Exec failed: %v", err)
+		}
+	}
+	if _, err := db.ExecContext(ctx, "INSERT INTO users (name) VALUES (?)", "bob"); !errors.As(err, &mismatch) {
+		t.Fatalf("got error %v, want *MismatchError", err)
+	}
+	if mismatch.Want.Query != "UPDATE users SET name = ? WHERE id = ?" {
+		t.Errorf("got closest interaction %q", mismatch.Want.Query)
+	}
+	if err := db.Close(); err != nil {
+		t.Errorf("Failed to close db: %v", err)
+	}
+
+	if _, err := Read(strings.NewReader(`{"version": 2}`)); err == nil {
+		t.Errorf("Read succeeded with an unsupported version")
+	}
+}
+
+func TestReplayMetadata(t *testing.T) {
+	c, err := Read(strings.NewReader(`{"version": 1, "interactions": [
+		{"kind": "query", "query": "SELECT name FROM users", "resultSets": [{
+			"columns": [{"name": "name", "databaseTypeName": "VARCHAR", "length": 64, "nullable": true, "scanType": "sql.NullString"}],
+			"rows": [[{"string": "alice"}]],
+			"error": "driver: bad connection"
+		}]}
+	]}`))
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	db := sql.OpenDB(NewReplayer(c, Strict))
+	t.Cleanup(func() {
+		if err := db.Close(); err != nil {
+			t.Errorf("Failed to close db: %v", err)
+		}
+	})
+
+	rows, err := db.Query("SELECT name FROM users")
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	defer rows.Close()
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		t.Fatalf("ColumnTypes failed: %v", err)
+	}
+	length, _ := types[0].Length()
+	nullable, _ := types[0].Nullable()
+	if types[0].DatabaseTypeName() != "VARCHAR" || length != 64 || !nullable ||
+		types[0].ScanType() != reflect.TypeOf(sql.NullString{}) {
+		t.Errorf("got column type %+v", types[0])
+	}
+	if !rows.Next() || rows.Next() {
+		t.Fatalf("got a row count other than 1")
+	}
+	if err := rows.Err(); err != driver.ErrBadConn {
+		t.Errorf("got rows error %v, want %v", err, driver.ErrBadConn)
+	}
+}
+
+func TestRecordResultSets(t *testing.T) {
+	source, err := Read(strings.NewReader(`{"version": 1, "interactions": [
+		{"kind": "query", "query": "CALL report()", "resultSets": [
+			{"columns": [{"name": "id"}], "rows": [[{"int64": 1}]]},
+			{"columns": [{"name": "skipped"}], "rows": []},
+			{"columns": [{"name": "total"}], "rows": [[{"int64": 2}]]}
+		]}
+	]}`))
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	c := &Cassette{}
+	db := sql.OpenDB(sqlmw.Connector(NewReplayer(source, Strict), NewRecorder(c)))
+	t.Cleanup(func() {
+		if err := db.Close(); err != nil {
+			t.Errorf("Failed to close db: %v", err)
+		}
+	})
+
+	rows, err := db.Query("CALL report()")
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	// the second result set is skipped without reading it
+	for rows.Next() {
+	}
+	if !rows.NextResultSet() || !rows.NextResultSet() {
+		t.Fatalf("expected 3 result sets: %v", rows.Err())
+	}
+	for rows.Next() {
+	}
+	if err := rows.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	recorded := c.Interactions()
+	if len(recorded) != 1 {
+		t.Fatalf("recorded %d interactions, want 1", len(recorded))
+	}
+	var got []string
+	for _, rs := range recorded[0].ResultSets {
+		got = append(got, fmt.Sprintf("%s:%d", rs.Columns[0].Name, len(rs.Rows)))
+	}
+	if want := []string{"id:1", "skipped:0", "total:1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("recorded result sets %v, want %v", got, want)
+	}
+}
+
+func TestConformance(t *testing.T) {
+	sqlmwtest.RunConformance(t, func() sqlmw.Interceptor { return NewRecorder(&Cassette{}) })
+}
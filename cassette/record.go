@@ -0,0 +1,231 @@
+package cassette
+
+import (
+	"context"
+	"database/sql/driver"
+	"io"
+	"reflect"
+
+	"github.com/ngrok/sqlmw"
+	"github.com/ngrok/sqlmw/rowset"
+)
+
+// Recorder records the interactions with a database to a Cassette.
+type Recorder struct {
+	sqlmw.NullInterceptor
+
+	c *Cassette
+}
+
+// NewRecorder returns a Recorder appending to c.
+func NewRecorder(c *Cassette) *Recorder {
+	return &Recorder{c: c}
+}
+
+type interactionKey struct{}
+
+func args(nvs []driver.NamedValue) []Arg {
+	if len(nvs) == 0 {
+		return nil
+	}
+	out := make([]Arg, len(nvs))
+	for i, nv := range nvs {
+		out[i] = Arg{Name: nv.Name, Ordinal: nv.Ordinal, Value: Value{copyValue(nv.Value)}}
+	}
+	return out
+}
+
+// copyValue copies the []byte values, whose memory drivers may reuse.
+func copyValue(v driver.Value) driver.Value {
+	if b, ok := v.([]byte); ok {
+		return append([]byte(nil), b...)
+	}
+	return v
+}
+
+func errorText(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+func columns(rows driver.Rows) []Column {
+	described := rowset.Describe(rows)
+	cols := make([]Column, len(described))
+	for i, d := range described {
+		c := Column{Name: d.Name, DatabaseTypeName: d.DatabaseTypeName}
+		if d.HasLength {
+			c.Length = &described[i].Length
+		}
+		if d.HasNullable {
+			c.Nullable = &described[i].Nullable
+		}
+		if d.HasPrecisionScale {
+			c.Precision, c.Scale = &described[i].Precision, &described[i].Scale
+		}
+		if d.ScanType != nil {
+			c.ScanType = d.ScanType.String()
+		}
+		cols[i] = c
+	}
+	return cols
+}
+
+func (r *Recorder) exec(query string, nvs []driver.NamedValue, res driver.Result, err error) {
+	it := &Interaction{Kind: Exec, Query: query, Args: args(nvs), Error: errorText(err)}
+	if err == nil {
+		it.Result = &Result{}
+		if id, err := res.LastInsertId(); err == nil {
+			it.Result.LastInsertId = &id
+		} else {
+			it.Result.LastInsertIdError = err.Error()
+		}
+		if n, err := res.RowsAffected(); err == nil {
+			it.Result.RowsAffected = &n
+		} else {
+			it.Result.RowsAffectedError = err.Error()
+		}
+	}
+	r.c.add(it)
+}
+
+// query records a query and returns the context of its rows and the rows,
+// wrapped to record their result sets.
+func (r *Recorder) query(ctx context.Context, query string, nvs []driver.NamedValue, rows driver.Rows, err error) (context.Context, driver.Rows) {
+	it := &Interaction{Kind: Query, Query: query, Args: args(nvs), Error: errorText(err)}
+	if err == nil {
+		it.ResultSets = []ResultSet{{Columns: columns(rows)}}
+		ctx = context.WithValue(ctx, interactionKey{}, it)
+		rows = &recordedRows{Rows: rows, c: r.c, it: it}
+	}
+	r.c.add(it)
+	return ctx, rows
+}
+
+// recordedRows records a result set every time the rows move to the next
+// one, including the result sets skipped without reading them. sqlmw only
+// exposes the optional methods of the rows of the driver, see
+// sqlmw.RowsUnwrapper.
+type recordedRows struct {
+	driver.Rows
+	c  *Cassette
+	it *Interaction
+}
+
+func (r *recordedRows) Unwrap() driver.Rows {
+	return r.Rows
+}
+
+func (r *recordedRows) HasNextResultSet() bool {
+	return r.Rows.(driver.RowsNextResultSet).HasNextResultSet()
+}
+
+func (r *recordedRows) NextResultSet() error {
+	err := r.Rows.(driver.RowsNextResultSet).NextResultSet()
+	if err != nil {
+		return err
+	}
+	r.c.mu.Lock()
+	defer r.c.mu.Unlock()
+	r.it.ResultSets = append(r.it.ResultSets, ResultSet{Columns: columns(r.Rows)})
+	return nil
+}
+
+func (r *recordedRows) ColumnTypeDatabaseTypeName(index int) string {
+	return r.Rows.(driver.RowsColumnTypeDatabaseTypeName).ColumnTypeDatabaseTypeName(index)
+}
+
+func (r *recordedRows) ColumnTypeLength(index int) (int64, bool) {
+	return r.Rows.(driver.RowsColumnTypeLength).ColumnTypeLength(index)
+}
+
+func (r *recordedRows) ColumnTypeNullable(index int) (bool, bool) {
+	return r.Rows.(driver.RowsColumnTypeNullable).ColumnTypeNullable(index)
+}
+
+func (r *recordedRows) ColumnTypePrecisionScale(index int) (int64, int64, bool) {
+	return r.Rows.(driver.RowsColumnTypePrecisionScale).ColumnTypePrecisionScale(index)
+}
+
+func (r *recordedRows) ColumnTypeScanType(index int) reflect.Type {
+	return r.Rows.(driver.RowsColumnTypeScanType).ColumnTypeScanType(index)
+}
+
+func (r *Recorder) ConnExecContext(ctx context.Context, conn driver.ExecerContext, query string, args []driver.NamedValue) (driver.Result, error) {
+	res, err := conn.ExecContext(ctx, query, args)
+	r.exec(query, args, res, err)
+	return res, err
+}
+
+func (r *Recorder) StmtExecContext(ctx context.Context, stmt driver.StmtExecContext, query string, args []driver.NamedValue) (driver.Result, error) {
+	res, err := stmt.ExecContext(ctx, args)
+	r.exec(query, args, res, err)
+	return res, err
+}
+
+func (r *Recorder) ConnQueryContext(ctx context.Context, conn driver.QueryerContext, query string, args []driver.NamedValue) (context.Context, driver.Rows, error) {
+	rows, err := conn.QueryContext(ctx, query, args)
+	ctx, rows = r.query(ctx, query, args, rows, err)
+	return ctx, rows, err
+}
+
+func (r *Recorder) StmtQueryContext(ctx context.Context, stmt driver.StmtQueryContext, query string, args []driver.NamedValue) (context.Context, driver.Rows, error) {
+	rows, err := stmt.QueryContext(ctx, args)
+	ctx, rows = r.query(ctx, query, args, rows, err)
+	return ctx, rows, err
+}
+
+func (r *Recorder) ConnPrepareContext(ctx context.Context, conn driver.ConnPrepareContext, query string) (context.Context, driver.Stmt, error) {
+	stmt, err := conn.PrepareContext(ctx, query)
+	if err != nil {
+		r.c.add(&Interaction{Kind: Prepare, Query: query, Error: err.Error()})
+	}
+	return ctx, stmt, err
+}
+
+func (r *Recorder) RowsNext(ctx context.Context, rows driver.Rows, dest []driver.Value) error {
+	err := rows.Next(dest)
+	it, ok := ctx.Value(interactionKey{}).(*Interaction)
+	if !ok {
+		return err
+	}
+
+	r.c.mu.Lock()
+	defer r.c.mu.Unlock()
+	rs := &it.ResultSets[len(it.ResultSets)-1]
+	if rs.done {
+		return err
+	}
+	switch err {
+	case nil:
+		row := make([]Value, len(dest))
+		for i, v := range dest {
+			row[i] = Value{copyValue(v)}
+		}
+		rs.Rows = append(rs.Rows, row)
+	case io.EOF:
+		rs.done = true
+	default:
+		rs.Error, rs.done = err.Error(), true
+	}
+	return err
+}
+
+func (r *Recorder) ConnBeginTx(ctx context.Context, conn driver.ConnBeginTx, txOpts driver.TxOptions) (context.Context, driver.Tx, error) {
+	tx, err := conn.BeginTx(ctx, txOpts)
+	r.c.add(&Interaction{Kind: Begin, Error: errorText(err)})
+	return ctx, tx, err
+}
+
+func (r *Recorder) TxCommit(ctx context.Context, tx driver.Tx) error {
+	err := tx.Commit()
+	r.c.add(&Interaction{Kind: Commit, Error: errorText(err)})
+	return err
+}
+
+func (r *Recorder) TxRollback(ctx context.Context, tx driver.Tx) error {
+	err := tx.Rollback()
+	r.c.add(&Interaction{Kind: Rollback, Error: errorText(err)})
+	return err
+}
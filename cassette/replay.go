@@ -0,0 +1,492 @@
+package cassette
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/ngrok/sqlmw/fingerprint"
+	"github.com/ngrok/sqlmw/rowset"
+)
+
+// Mode is how a Replayer matches the statements it receives.
+type Mode int
+
+const (
+	// Strict requires the statements to arrive in the recorded order, each
+	// interaction being served once.
+	Strict Mode = iota
+	// Lenient lets the statements arrive in any order. An interaction is
+	// served again once all the matching ones were.
+	Lenient
+)
+
+// MismatchError is the error of the statements a Replayer cannot match.
+type MismatchError struct {
+	Kind  Kind
+	Query string
+	Args  []driver.NamedValue
+
+	// Want is the closest interaction, nil when there was none.
+	Want *Interaction
+}
+
+func (e *MismatchError) Error() string {
+	msg := "cassette: unexpected " + string(e.Kind)
+	if e.Query != "" {
+		msg += fmt.Sprintf(" %q", e.Query)
+	}
+	return msg + "\n" + e.Diff()
+}
+
+// Diff returns the differences between the closest interaction, prefixed
+// with -, and the statement, prefixed with +.
+func (e *MismatchError) Diff() string {
+	var b strings.Builder
+	line := func(prefix, s string) {
+		b.WriteString(prefix + " " + s + "\n")
+	}
+	diff := func(want, got string) {
+		if want == got {
+			line(" ", got)
+			return
+		}
+		if want != "" {
+			line("-", want)
+		}
+		if got != "" {
+			line("+", got)
+		}
+	}
+
+	if e.Want == nil {
+		line("-", "(no interaction left)")
+		line("+", "kind: "+string(e.Kind))
+		if e.Query != "" {
+			line("+", "query: "+fingerprint.Normalize(e.Query))
+		}
+		return b.String()
+	}
+
+	diff("kind: "+string(e.Want.Kind), "kind: "+string(e.Kind))
+	if e.Want.Query != "" || e.Query != "" {
+		diff(query(e.Want.Query), query(e.Query))
+	}
+	n := len(e.Want.Args)
+	if len(e.Args) > n {
+		n = len(e.Args)
+	}
+	for i := 0; i < n; i++ {
+		var want, got string
+		if i < len(e.Want.Args) {
+			a := e.Want.Args[i]
+			want = formatArg(a.Ordinal, a.Name, a.Value.Value)
+		}
+		if i < len(e.Args) {
+			a := e.Args[i]
+			got = formatArg(a.Ordinal, a.Name, a.Value)
+		}
+		if i < len(e.Want.Args) && i < len(e.Args) && argEqual(e.Want.Args[i], e.Args[i]) {
+			want = got
+		}
+		diff(want, got)
+	}
+	return b.String()
+}
+
+func query(q string) string {
+	if q == "" {
+		return ""
+	}
+	return "query: " + fingerprint.Normalize(q)
+}
+
+func formatArg(ordinal int, name string, v driver.Value) string {
+	s := fmt.Sprintf("arg %d: ", ordinal)
+	if name != "" {
+		s = fmt.Sprintf("arg %d (%s): ", ordinal, name)
+	}
+	switch v := v.(type) {
+	case nil:
+		return s + "nil"
+	case string, []byte:
+		return s + fmt.Sprintf("%T(%q)", v, v)
+	case other:
+		return s + fmt.Sprintf("%v", v)
+	}
+	return s + fmt.Sprintf("%T(%v)", v, v)
+}
+
+func argEqual(a Arg, nv driver.NamedValue) bool {
+	return a.Ordinal == nv.Ordinal && a.Name == nv.Name && equal(a.Value.Value, nv.Value)
+}
+
+// knownErrors are the errors a Replayer returns as they are, as callers
+// compare them.
+var knownErrors = []error{
+	driver.ErrBadConn,
+	driver.ErrSkip,
+	driver.ErrRemoveArgument,
+	context.Canceled,
+	context.DeadlineExceeded,
+}
+
+// replayError returns the recorded error text as an error.
+func replayError(text string) error {
+	if text == "" {
+		return nil
+	}
+	for _, err := range knownErrors {
+		if err.Error() == text {
+			return err
+		}
+	}
+	return errors.New(text)
+}
+
+// Replayer is a driver.Connector and a driver.Driver serving the
+// interactions of a Cassette.
+type Replayer struct {
+	mode Mode
+
+	mu           sync.Mutex
+	interactions []*Interaction
+	fingerprints []string
+	used         []bool
+	next         int
+}
+
+var (
+	_ driver.Connector = (*Replayer)(nil)
+	_ driver.Driver    = (*Replayer)(nil)
+)
+
+// NewReplayer returns a Replayer serving the interactions of c.
+func NewReplayer(c *Cassette, mode Mode) *Replayer {
+	its := c.Interactions()
+	r := &Replayer{
+		mode:         mode,
+		interactions: its,
+		fingerprints: make([]string, len(its)),
+		used:         make([]bool, len(its)),
+	}
+	for i, it := range its {
+		r.fingerprints[i] = fingerprint.Normalize(it.Query)
+	}
+	return r
+}
+
+func (r *Replayer) Connect(context.Context) (driver.Conn, error) {
+	return &conn{r: r}, nil
+}
+
+func (r *Replayer) Driver() driver.Driver {
+	return r
+}
+
+func (r *Replayer) Open(string) (driver.Conn, error) {
+	return &conn{r: r}, nil
+}
+
+// Unused returns the interactions that were not served.
+func (r *Replayer) Unused() []*Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var unused []*Interaction
+	for i, it := range r.interactions {
+		if !r.used[i] {
+			unused = append(unused, it)
+		}
+	}
+	return unused
+}
+
+func (r *Replayer) matches(i int, kind Kind, fp string, args []driver.NamedValue) bool {
+	it := r.interactions[i]
+	if it.Kind != kind || r.fingerprints[i] != fp || len(it.Args) != len(args) {
+		return false
+	}
+	for j, a := range it.Args {
+		if !argEqual(a, args[j]) {
+			return false
+		}
+	}
+	return true
+}
+
+// match returns the interaction to serve a statement with.
+func (r *Replayer) match(kind Kind, query string, args []driver.NamedValue) (*Interaction, error) {
+	fp := fingerprint.Normalize(query)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	mismatch := &MismatchError{Kind: kind, Query: query, Args: args}
+	if r.mode == Strict {
+		if r.next >= len(r.interactions) {
+			return nil, mismatch
+		}
+		if !r.matches(r.next, kind, fp, args) {
+			mismatch.Want = r.interactions[r.next]
+			return nil, mismatch
+		}
+		r.used[r.next] = true
+		r.next++
+		return r.interactions[r.next-1], nil
+	}
+
+	reused := -1
+	for i := range r.interactions {
+		if !r.matches(i, kind, fp, args) {
+			continue
+		}
+		if !r.used[i] {
+			r.used[i] = true
+			return r.interactions[i], nil
+		}
+		if reused < 0 {
+			reused = i
+		}
+	}
+	if reused >= 0 {
+		return r.interactions[reused], nil
+	}
+	mismatch.Want = r.closest(kind, fp)
+	return nil, mismatch
+}
+
+// closest returns the first unused interaction of the same fingerprint, or
+// else of the same kind.
+func (r *Replayer) closest(kind Kind, fp string) *Interaction {
+	var sameKind *Interaction
+	for i, it := range r.interactions {
+		if r.used[i] || it.Kind != kind {
+			continue
+		}
+		if r.fingerprints[i] == fp {
+			return it
+		}
+		if sameKind == nil {
+			sameKind = it
+		}
+	}
+	return sameKind
+}
+
+// prepareError returns the recorded error of preparing query, if any.
+func (r *Replayer) prepareError(query string) error {
+	fp := fingerprint.Normalize(query)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.mode == Strict {
+		if r.next < len(r.interactions) && r.matches(r.next, Prepare, fp, nil) {
+			r.used[r.next] = true
+			r.next++
+			return replayError(r.interactions[r.next-1].Error)
+		}
+		return nil
+	}
+	for i := range r.interactions {
+		if r.matches(i, Prepare, fp, nil) {
+			r.used[i] = true
+			return replayError(r.interactions[i].Error)
+		}
+	}
+	return nil
+}
+
+func (r *Replayer) exec(query string, args []driver.NamedValue) (driver.Result, error) {
+	it, err := r.match(Exec, query, args)
+	if err != nil {
+		return nil, err
+	}
+	if err := replayError(it.Error); err != nil {
+		return nil, err
+	}
+	res := result{}
+	if it.Result != nil {
+		res.r = *it.Result
+	}
+	return res, nil
+}
+
+func (r *Replayer) query(query string, args []driver.NamedValue) (driver.Rows, error) {
+	it, err := r.match(Query, query, args)
+	if err != nil {
+		return nil, err
+	}
+	if err := replayError(it.Error); err != nil {
+		return nil, err
+	}
+	set := &rowset.Set{}
+	errs := make([]error, len(it.ResultSets))
+	for i, rs := range it.ResultSets {
+		set.ResultSets = append(set.ResultSets, resultSet(rs))
+		errs[i] = replayError(rs.Error)
+	}
+	return &rows{Rows: set.Rows().(*rowset.Rows), errs: errs}, nil
+}
+
+func (r *Replayer) tx(kind Kind) error {
+	it, err := r.match(kind, "", nil)
+	if err != nil {
+		return err
+	}
+	return replayError(it.Error)
+}
+
+func resultSet(rs ResultSet) rowset.ResultSet {
+	out := rowset.ResultSet{Columns: make([]rowset.Column, len(rs.Columns))}
+	for i, c := range rs.Columns {
+		col := rowset.Column{Name: c.Name, DatabaseTypeName: c.DatabaseTypeName, ScanType: scanTypes[c.ScanType]}
+		if c.Length != nil {
+			col.Length, col.HasLength = *c.Length, true
+		}
+		if c.Nullable != nil {
+			col.Nullable, col.HasNullable = *c.Nullable, true
+		}
+		if c.Precision != nil && c.Scale != nil {
+			col.Precision, col.Scale, col.HasPrecisionScale = *c.Precision, *c.Scale, true
+		}
+		out.Columns[i] = col
+	}
+	for _, row := range rs.Rows {
+		values := make([]driver.Value, len(row))
+		for i, v := range row {
+			values[i] = v.Value
+		}
+		out.Rows = append(out.Rows, values)
+	}
+	return out
+}
+
+type conn struct {
+	r *Replayer
+}
+
+var (
+	_ driver.ConnBeginTx        = (*conn)(nil)
+	_ driver.ConnPrepareContext = (*conn)(nil)
+	_ driver.ExecerContext      = (*conn)(nil)
+	_ driver.QueryerContext     = (*conn)(nil)
+	_ driver.Pinger             = (*conn)(nil)
+)
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *conn) PrepareContext(_ context.Context, query string) (driver.Stmt, error) {
+	if err := c.r.prepareError(query); err != nil {
+		return nil, err
+	}
+	return &stmt{r: c.r, query: query}, nil
+}
+
+func (c *conn) Close() error { return nil }
+
+func (c *conn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *conn) BeginTx(context.Context, driver.TxOptions) (driver.Tx, error) {
+	if err := c.r.tx(Begin); err != nil {
+		return nil, err
+	}
+	return tx{r: c.r}, nil
+}
+
+func (c *conn) Ping(context.Context) error { return nil }
+
+func (c *conn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	return c.r.exec(query, args)
+}
+
+func (c *conn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	return c.r.query(query, args)
+}
+
+type stmt struct {
+	r     *Replayer
+	query string
+}
+
+func (s *stmt) Close() error { return nil }
+
+func (s *stmt) NumInput() int { return -1 }
+
+func (s *stmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.r.exec(s.query, toNamed(args))
+}
+
+func (s *stmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.r.query(s.query, toNamed(args))
+}
+
+func (s *stmt) ExecContext(_ context.Context, args []driver.NamedValue) (driver.Result, error) {
+	return s.r.exec(s.query, args)
+}
+
+func (s *stmt) QueryContext(_ context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	return s.r.query(s.query, args)
+}
+
+type tx struct {
+	r *Replayer
+}
+
+func (t tx) Commit() error { return t.r.tx(Commit) }
+
+func (t tx) Rollback() error { return t.r.tx(Rollback) }
+
+type result struct {
+	r Result
+}
+
+func (r result) LastInsertId() (int64, error) {
+	if r.r.LastInsertId == nil {
+		return 0, replayError(r.r.LastInsertIdError)
+	}
+	return *r.r.LastInsertId, nil
+}
+
+func (r result) RowsAffected() (int64, error) {
+	if r.r.RowsAffected == nil {
+		return 0, replayError(r.r.RowsAffectedError)
+	}
+	return *r.r.RowsAffected, nil
+}
+
+// rows replays a query, ending its result sets with their recorded errors.
+type rows struct {
+	*rowset.Rows
+	errs []error
+	rs   int
+}
+
+func (r *rows) Next(dest []driver.Value) error {
+	err := r.Rows.Next(dest)
+	if err == io.EOF && r.rs < len(r.errs) && r.errs[r.rs] != nil {
+		return r.errs[r.rs]
+	}
+	return err
+}
+
+func (r *rows) NextResultSet() error {
+	err := r.Rows.NextResultSet()
+	if err == nil {
+		r.rs++
+	}
+	return err
+}
+
+func toNamed(args []driver.Value) []driver.NamedValue {
+	nvs := make([]driver.NamedValue, len(args))
+	for i, v := range args {
+		nvs[i] = driver.NamedValue{Ordinal: i + 1, Value: v}
+	}
+	return nvs
+}
@@ -0,0 +1,129 @@
+package cassette
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// Value is a driver.Value that keeps its type once encoded in JSON. Values
+// of types other than those of driver.Value are recorded with their %v
+// form.
+type Value struct {
+	driver.Value
+}
+
+type encodedValue struct {
+	Int64   *int64     `json:"int64,omitempty"`
+	Float64 *string    `json:"float64,omitempty"`
+	Bool    *bool      `json:"bool,omitempty"`
+	String  *string    `json:"string,omitempty"`
+	Bytes   *[]byte    `json:"bytes,omitempty"`
+	Time    *time.Time `json:"time,omitempty"`
+	Other   *string    `json:"other,omitempty"`
+}
+
+func (v Value) MarshalJSON() ([]byte, error) {
+	var e encodedValue
+	switch x := v.Value.(type) {
+	case nil:
+		return []byte("null"), nil
+	case int64:
+		e.Int64 = &x
+	case float64:
+		s := strconv.FormatFloat(x, 'g', -1, 64)
+		e.Float64 = &s
+	case bool:
+		e.Bool = &x
+	case string:
+		e.String = &x
+	case []byte:
+		if x == nil {
+			return []byte("null"), nil
+		}
+		e.Bytes = &x
+	case time.Time:
+		e.Time = &x
+	default:
+		s := fmt.Sprint(x)
+		e.Other = &s
+	}
+	return json.Marshal(e)
+}
+
+func (v *Value) UnmarshalJSON(b []byte) error {
+	if string(b) == "null" {
+		v.Value = nil
+		return nil
+	}
+	var e encodedValue
+	if err := json.Unmarshal(b, &e); err != nil {
+		return err
+	}
+	switch {
+	case e.Int64 != nil:
+		v.Value = *e.Int64
+	case e.Float64 != nil:
+		f, err := strconv.ParseFloat(*e.Float64, 64)
+		if err != nil {
+			return err
+		}
+		v.Value = f
+	case e.Bool != nil:
+		v.Value = *e.Bool
+	case e.String != nil:
+		v.Value = *e.String
+	case e.Bytes != nil:
+		v.Value = *e.Bytes
+	case e.Time != nil:
+		v.Value = *e.Time
+	case e.Other != nil:
+		v.Value = other(*e.Other)
+	default:
+		return fmt.Errorf("invalid value %s", b)
+	}
+	return nil
+}
+
+// other is a recorded value of a type other than those of driver.Value.
+type other string
+
+func (o other) String() string { return string(o) }
+
+// equal reports whether a recorded value matches a value received by a
+// Replayer.
+func equal(recorded, v driver.Value) bool {
+	switch r := recorded.(type) {
+	case other:
+		return string(r) == fmt.Sprint(v)
+	case time.Time:
+		t, ok := v.(time.Time)
+		return ok && r.Equal(t)
+	case float64:
+		f, ok := v.(float64)
+		return ok && (f == r || math.IsNaN(f) && math.IsNaN(r))
+	}
+	return reflect.DeepEqual(recorded, v)
+}
+
+// scanTypes are the column scan types a Replayer restores. The others are
+// replayed as unknown.
+var scanTypes = make(map[string]reflect.Type)
+
+func init() {
+	for _, v := range []interface{}{
+		int8(0), int16(0), int32(0), int64(0), int(0),
+		uint8(0), uint16(0), uint32(0), uint64(0), uint(0),
+		float32(0), float64(0), false, "", []byte(nil), time.Time{},
+		sql.NullBool{}, sql.NullFloat64{}, sql.NullInt32{}, sql.NullInt64{},
+		sql.NullString{}, sql.NullTime{}, sql.RawBytes(nil),
+	} {
+		t := reflect.TypeOf(v)
+		scanTypes[t.String()] = t
+	}
+}
@@ -0,0 +1,414 @@
+// Package chaos provides an sqlmw.Interceptor injecting database failures,
+// to test the retry, failover and error handling logic of an application.
+//
+// A Fault selects calls by hook, by statement fingerprint, every nth call
+// or with a probability, and injects latency, an error such as
+// driver.ErrBadConn, or both:
+//
+//	in := chaos.New(chaos.Config{Seed: 1, Faults: []chaos.Fault{
+//		// an ambiguous commit: the transaction commits but fails
+//		{Hooks: chaos.TxCommit, Every: 10, Err: driver.ErrBadConn, After: true},
+//		// a row stream cut after 100 rows
+//		{Hooks: chaos.RowsNext, Rows: 100, Err: io.ErrUnexpectedEOF},
+//		{Hooks: chaos.ConnectorConnect, Probability: 0.01, Err: chaos.ErrInjected},
+//		{Hooks: chaos.AllHooks, Fingerprint: "select * from users where id = ?", Latency: time.Second},
+//	}})
+//
+// The probabilities are drawn from a source seeded by Config.Seed, so that
+// a run with the same calls in the same order injects the same faults.
+//
+// Faults are changed at runtime with SetFaults and SetEnabled, and added to
+// or removed from the calls of a context with WithFaults and WithoutFaults.
+package chaos
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ngrok/sqlmw"
+	"github.com/ngrok/sqlmw/fingerprint"
+)
+
+// ErrInjected is an error to inject.
+var ErrInjected = errors.New("chaos: injected fault")
+
+// Hook is a set of interceptor hooks.
+type Hook uint
+
+const (
+	ConnectorConnect Hook = 1 << iota
+	ConnBeginTx
+	ConnPrepareContext
+	ConnExecContext
+	ConnQueryContext
+	StmtExecContext
+	StmtQueryContext
+	TxCommit
+	TxRollback
+	RowsNext
+
+	// AllHooks is the set of every hook.
+	AllHooks = 1<<iota - 1
+)
+
+// Fault is a failure to inject.
+type Fault struct {
+	// Hooks are the hooks whose calls the fault applies to.
+	Hooks Hook
+
+	// Fingerprint, if set, restricts the fault to the statements with this
+	// fingerprint, see fingerprint.Normalize.
+	Fingerprint string
+
+	// Every, if set, restricts the fault to every nth call it applies to.
+	Every int
+
+	// Probability, if set, is the probability of the fault being injected
+	// in a call it applies to. It is always injected otherwise.
+	Probability float64
+
+	// Latency is added to the call.
+	Latency time.Duration
+
+	// Err, if set, fails the call.
+	Err error
+
+	// After, if set, fails the call with Err once it is done, as in an
+	// ambiguous commit. The call is not made otherwise, the transactions
+	// failing to commit being rolled back.
+	After bool
+
+	// Rows, for RowsNext, is the number of rows read before Err is returned,
+	// or io.EOF if Err is not set. The RowsNext faults are selected once per
+	// query, and add their Latency to every row.
+	Rows int
+}
+
+// fault is a Fault with its state.
+type fault struct {
+	Fault
+	calls uint64
+}
+
+func newFaults(faults []Fault) []*fault {
+	out := make([]*fault, len(faults))
+	for i, f := range faults {
+		out[i] = &fault{Fault: f}
+	}
+	return out
+}
+
+type faultsKey struct{}
+
+type ctxFaults struct {
+	faults   []*fault
+	disabled bool
+}
+
+// WithFaults returns a context whose calls are subject to faults, in
+// addition to the ones of the Interceptor.
+func WithFaults(ctx context.Context, faults ...Fault) context.Context {
+	return context.WithValue(ctx, faultsKey{}, &ctxFaults{faults: newFaults(faults)})
+}
+
+// WithoutFaults returns a context whose calls are not subject to faults.
+func WithoutFaults(ctx context.Context) context.Context {
+	return context.WithValue(ctx, faultsKey{}, &ctxFaults{disabled: true})
+}
+
+// Config configures an Interceptor.
+type Config struct {
+	// Faults are the faults to inject.
+	Faults []Fault
+
+	// Seed seeds the source of the probabilities. Defaults to a time based
+	// seed.
+	Seed int64
+
+	// OnInject, if set, is called with every fault injected.
+	OnInject func(Hook, Fault)
+}
+
+// Interceptor injects faults.
+type Interceptor struct {
+	// disabled is accessed atomically
+	disabled int32
+
+	sqlmw.NullInterceptor
+
+	cfg    Config
+	faults atomic.Value // []*fault
+
+	mu  sync.Mutex
+	rng *rand.Rand
+}
+
+// New returns an Interceptor for the supplied configuration.
+func New(cfg Config) *Interceptor {
+	if cfg.Seed == 0 {
+		cfg.Seed = time.Now().UnixNano()
+	}
+	in := &Interceptor{cfg: cfg, rng: rand.New(rand.NewSource(cfg.Seed))}
+	in.faults.Store(newFaults(cfg.Faults))
+	return in
+}
+
+// SetFaults replaces the faults of the Interceptor.
+func (in *Interceptor) SetFaults(faults []Fault) {
+	in.faults.Store(newFaults(faults))
+}
+
+// SetEnabled enables or disables the injection of faults.
+func (in *Interceptor) SetEnabled(enabled bool) {
+	v := int32(1)
+	if enabled {
+		v = 0
+	}
+	atomic.StoreInt32(&in.disabled, v)
+}
+
+// pick returns the fault to inject in a call of hook, if any.
+func (in *Interceptor) pick(ctx context.Context, hook Hook, query string) *fault {
+	if atomic.LoadInt32(&in.disabled) != 0 {
+		return nil
+	}
+	var faults []*fault
+	if cf, ok := ctx.Value(faultsKey{}).(*ctxFaults); ok {
+		if cf.disabled {
+			return nil
+		}
+		faults = cf.faults
+	}
+	faults = append(faults, in.faults.Load().([]*fault)...)
+
+	var fp string
+	for _, f := range faults {
+		if f.Hooks&hook == 0 {
+			continue
+		}
+		if f.Fingerprint != "" {
+			if query == "" {
+				continue
+			}
+			if fp == "" {
+				fp = fingerprint.Normalize(query)
+			}
+			if f.Fingerprint != fp {
+				continue
+			}
+		}
+		if f.Every > 1 && atomic.AddUint64(&f.calls, 1)%uint64(f.Every) != 0 {
+			continue
+		}
+		if f.Probability > 0 {
+			in.mu.Lock()
+			skip := in.rng.Float64() >= f.Probability
+			in.mu.Unlock()
+			if skip {
+				continue
+			}
+		}
+		if in.cfg.OnInject != nil {
+			in.cfg.OnInject(hook, f.Fault)
+		}
+		return f
+	}
+	return nil
+}
+
+// before injects the latency of the fault of a call and returns the error
+// to fail it with before it is made.
+func (in *Interceptor) before(ctx context.Context, hook Hook, query string) (*fault, error) {
+	f := in.pick(ctx, hook, query)
+	if f == nil {
+		return nil, nil
+	}
+	if err := sleep(ctx, f.Latency); err != nil {
+		return nil, err
+	}
+	if f.Err != nil && !f.After {
+		return nil, f.Err
+	}
+	return f, nil
+}
+
+// after returns the error to fail a call with once it is made.
+func after(f *fault, err error) error {
+	if err == nil && f != nil && f.After {
+		return f.Err
+	}
+	return err
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+type rowsKey struct{}
+
+// rowsFault is the RowsNext fault of a query.
+type rowsFault struct {
+	f    *fault
+	read int
+}
+
+// withRows returns the context of the rows of query.
+func (in *Interceptor) withRows(ctx context.Context, query string) context.Context {
+	if f := in.pick(ctx, RowsNext, query); f != nil {
+		return context.WithValue(ctx, rowsKey{}, &rowsFault{f: f})
+	}
+	return ctx
+}
+
+func (in *Interceptor) ConnectorConnect(ctx context.Context, connect driver.Connector) (driver.Conn, error) {
+	f, err := in.before(ctx, ConnectorConnect, "")
+	if err != nil {
+		return nil, err
+	}
+	conn, err := connect.Connect(ctx)
+	if err := after(f, err); err != nil {
+		if conn != nil {
+			conn.Close()
+		}
+		return nil, err
+	}
+	return conn, nil
+}
+
+func (in *Interceptor) ConnBeginTx(ctx context.Context, conn driver.ConnBeginTx, txOpts driver.TxOptions) (context.Context, driver.Tx, error) {
+	f, err := in.before(ctx, ConnBeginTx, "")
+	if err != nil {
+		return ctx, nil, err
+	}
+	tx, err := conn.BeginTx(ctx, txOpts)
+	if err := after(f, err); err != nil {
+		if tx != nil {
+			tx.Rollback()
+		}
+		return ctx, nil, err
+	}
+	return ctx, tx, nil
+}
+
+func (in *Interceptor) ConnPrepareContext(ctx context.Context, conn driver.ConnPrepareContext, query string) (context.Context, driver.Stmt, error) {
+	f, err := in.before(ctx, ConnPrepareContext, query)
+	if err != nil {
+		return ctx, nil, err
+	}
+	stmt, err := conn.PrepareContext(ctx, query)
+	if err := after(f, err); err != nil {
+		if stmt != nil {
+			stmt.Close()
+		}
+		return ctx, nil, err
+	}
+	return ctx, stmt, nil
+}
+
+func (in *Interceptor) ConnExecContext(ctx context.Context, conn driver.ExecerContext, query string, args []driver.NamedValue) (driver.Result, error) {
+	f, err := in.before(ctx, ConnExecContext, query)
+	if err != nil {
+		return nil, err
+	}
+	res, err := conn.ExecContext(ctx, query, args)
+	if err := after(f, err); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (in *Interceptor) ConnQueryContext(ctx context.Context, conn driver.QueryerContext, query string, args []driver.NamedValue) (context.Context, driver.Rows, error) {
+	f, err := in.before(ctx, ConnQueryContext, query)
+	if err != nil {
+		return ctx, nil, err
+	}
+	rows, err := conn.QueryContext(ctx, query, args)
+	if err := after(f, err); err != nil {
+		if rows != nil {
+			rows.Close()
+		}
+		return ctx, nil, err
+	}
+	return in.withRows(ctx, query), rows, nil
+}
+
+func (in *Interceptor) StmtExecContext(ctx context.Context, stmt driver.StmtExecContext, query string, args []driver.NamedValue) (driver.Result, error) {
+	f, err := in.before(ctx, StmtExecContext, query)
+	if err != nil {
+		return nil, err
+	}
+	res, err := stmt.ExecContext(ctx, args)
+	if err := after(f, err); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (in *Interceptor) StmtQueryContext(ctx context.Context, stmt driver.StmtQueryContext, query string, args []driver.NamedValue) (context.Context, driver.Rows, error) {
+	f, err := in.before(ctx, StmtQueryContext, query)
+	if err != nil {
+		return ctx, nil, err
+	}
+	rows, err := stmt.QueryContext(ctx, args)
+	if err := after(f, err); err != nil {
+		if rows != nil {
+			rows.Close()
+		}
+		return ctx, nil, err
+	}
+	return in.withRows(ctx, query), rows, nil
+}
+
+func (in *Interceptor) RowsNext(ctx context.Context, rows driver.Rows, dest []driver.Value) error {
+	rf, ok := ctx.Value(rowsKey{}).(*rowsFault)
+	if !ok {
+		return rows.Next(dest)
+	}
+	if err := sleep(ctx, rf.f.Latency); err != nil {
+		return err
+	}
+	if rf.read >= rf.f.Rows && (rf.f.Rows > 0 || rf.f.Err != nil) {
+		if rf.f.Err != nil {
+			return rf.f.Err
+		}
+		return io.EOF
+	}
+	if err := rows.Next(dest); err != nil {
+		return err
+	}
+	rf.read++
+	return nil
+}
+
+func (in *Interceptor) TxCommit(ctx context.Context, tx driver.Tx) error {
+	f, err := in.before(ctx, TxCommit, "")
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	return after(f, tx.Commit())
+}
+
+func (in *Interceptor) TxRollback(ctx context.Context, tx driver.Tx) error {
+	f, err := in.before(ctx, TxRollback, "")
+	if err != nil {
+		return err
+	}
+	return after(f, tx.Rollback())
+}
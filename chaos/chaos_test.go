@@ -0,0 +1,173 @@
+package chaos
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ngrok/sqlmw"
+	"github.com/ngrok/sqlmw/internal/fakedb"
+	"github.com/ngrok/sqlmw/sqlmwtest"
+)
+
+func openDB(t *testing.T, in *Interceptor, handler fakedb.Handler) (*sql.DB, *fakedb.Connector) {
+	con := &fakedb.Connector{Handler: handler}
+	db := sql.OpenDB(sqlmw.Connector(con, in))
+	t.Cleanup(func() {
+		if err := db.Close(); err != nil {
+			t.Errorf("Failed to close db: %v", err)
+		}
+	})
+	return db, con
+}
+
+func TestAmbiguousCommit(t *testing.T) {
+	in := New(Config{Faults: []Fault{{Hooks: TxCommit, Err: ErrInjected, After: true}}})
+	db, con := openDB(t, in, nil)
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Begin failed: %v", err)
+	}
+	if _, err := tx.Exec("UPDATE t SET a = 1"); err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	if err := tx.Commit(); err != ErrInjected {
+		t.Errorf("got commit error %v, want %v", err, ErrInjected)
+	}
+	want := []string{"BEGIN", "UPDATE t SET a = 1", "COMMIT"}
+	if got := con.Queries(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got queries %q, want %q", got, want)
+	}
+
+	in.SetFaults([]Fault{{Hooks: TxCommit, Err: ErrInjected}})
+	tx, err = db.Begin()
+	if err != nil {
+		t.Fatalf("Begin failed: %v", err)
+	}
+	if err := tx.Commit(); err != ErrInjected {
+		t.Errorf("got commit error %v, want %v", err, ErrInjected)
+	}
+	want = append(want, "BEGIN", "ROLLBACK")
+	if got := con.Queries(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got queries %q, want %q", got, want)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	in := New(Config{Faults: []Fault{{Hooks: RowsNext, Fingerprint: "select n from t", Rows: 2, Err: io.ErrUnexpectedEOF}}})
+	db, _ := openDB(t, in, func(context.Context, string, []driver.NamedValue) (*fakedb.Response, error) {
+		return &fakedb.Response{Columns: []string{"n"}, Rows: [][]driver.Value{{1}, {2}, {3}, {4}}}, nil
+	})
+
+	count := func(query string) (int, error) {
+		rows, err := db.Query(query)
+		if err != nil {
+			t.Fatalf("Query failed: %v", err)
+		}
+		defer rows.Close()
+		n := 0
+		for rows.Next() {
+			n++
+		}
+		return n, rows.Err()
+	}
+	if n, err := count("SELECT n FROM t"); n != 2 || err != io.ErrUnexpectedEOF {
+		t.Errorf("got %d rows and error %v, want 2 rows and %v", n, err, io.ErrUnexpectedEOF)
+	}
+	if n, err := count("SELECT n FROM u"); n != 4 || err != nil {
+		t.Errorf("got %d rows and error %v, want 4 rows", n, err)
+	}
+}
+
+func TestSelection(t *testing.T) {
+	in := New(Config{Faults: []Fault{
+		{Hooks: ConnExecContext | StmtExecContext, Fingerprint: "delete from t", Every: 2, Err: ErrInjected},
+	}})
+	db, con := openDB(t, in, nil)
+	ctx := context.Background()
+
+	exec := func(ctx context.Context, query string, n int) []bool {
+		var succeeded []bool
+		for i := 0; i < n; i++ {
+			_, err := db.ExecContext(ctx, query)
+			succeeded = append(succeeded, err == nil)
+		}
+		return succeeded
+	}
+	if got, want := exec(WithoutFaults(ctx), "DELETE FROM t", 2), []bool{true, true}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got successes %v without faults, want %v", got, want)
+	}
+	if got, want := exec(ctx, "DELETE FROM t", 4), []bool{true, false, true, false}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got successes %v, want %v", got, want)
+	}
+	if got, want := exec(ctx, "UPDATE t SET a = 1", 2), []bool{true, true}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got successes %v for another fingerprint, want %v", got, want)
+	}
+	in.SetEnabled(false)
+	if got, want := exec(ctx, "DELETE FROM t", 2), []bool{true, true}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got successes %v while disabled, want %v", got, want)
+	}
+	in.SetEnabled(true)
+
+	db.SetMaxIdleConns(0)
+	if err := db.PingContext(WithFaults(ctx, Fault{Hooks: ConnectorConnect, Err: ErrInjected})); err != ErrInjected {
+		t.Errorf("got ping error %v, want %v", err, ErrInjected)
+	}
+	if got := len(con.Calls()); got != 2+2+2+2 {
+		t.Errorf("got %d calls, want 8", got)
+	}
+}
+
+func TestSeed(t *testing.T) {
+	run := func() []bool {
+		in := New(Config{Seed: 42, Faults: []Fault{{Hooks: ConnQueryContext, Probability: 0.5, Err: ErrInjected}}})
+		db, _ := openDB(t, in, nil)
+		var failed []bool
+		for i := 0; i < 50; i++ {
+			rows, err := db.Query("SELECT 1")
+			if err == nil {
+				rows.Close()
+			}
+			failed = append(failed, errors.Is(err, ErrInjected))
+		}
+		return failed
+	}
+	first, second := run(), run()
+	if !reflect.DeepEqual(first, second) {
+		t.Errorf("got different faults with the same seed")
+	}
+	n := 0
+	for _, failed := range first {
+		if failed {
+			n++
+		}
+	}
+	if n < 10 || n > 40 {
+		t.Errorf("got %d failures out of 50 with a probability of 0.5", n)
+	}
+}
+
+func TestLatency(t *testing.T) {
+	in := New(Config{Faults: []Fault{{Hooks: AllHooks, Latency: time.Hour}}})
+	db, _ := openDB(t, in, nil)
+	ctx, cancel := context.WithTimeout(WithoutFaults(context.Background()), 10*time.Millisecond)
+	defer cancel()
+	if err := db.PingContext(ctx); err != nil {
+		t.Fatalf("Ping failed: %v", err)
+	}
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := db.ExecContext(ctx, "DELETE FROM t"); err != context.DeadlineExceeded {
+		t.Errorf("got error %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestConformance(t *testing.T) {
+	sqlmwtest.RunConformance(t, func() sqlmw.Interceptor { return New(Config{}) })
+}
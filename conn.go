@@ -3,11 +3,53 @@ package sqlmw
 import (
 	"context"
 	"database/sql/driver"
+	"sync"
+	"sync/atomic"
 )
 
 type wrappedConn struct {
 	intr   Interceptor
 	parent driver.Conn
+	state  *connState
+}
+
+// connState is shared by every copy of a wrappedConn.
+type connState struct {
+	inTx   int32
+	handle Conn
+
+	mu     sync.Mutex
+	values map[interface{}]interface{}
+}
+
+func newWrappedConn(intr Interceptor, parent driver.Conn) wrappedConn {
+	state := &connState{}
+	state.handle = Conn{parent: parent, state: state}
+	return wrappedConn{intr: intr, parent: parent, state: state}
+}
+
+func (c wrappedConn) setInTx(inTx bool) {
+	if c.state == nil {
+		return
+	}
+	v := int32(0)
+	if inTx {
+		v = 1
+	}
+	atomic.StoreInt32(&c.state.inTx, v)
+}
+
+// withConn adds the handle on the connection to ctx, see ConnFromContext,
+// and marks ctx when the connection has an open transaction, see InTx.
+func (c wrappedConn) withConn(ctx context.Context) context.Context {
+	if c.state == nil {
+		return ctx
+	}
+	ctx = context.WithValue(ctx, connKey{}, &c.state.handle)
+	if atomic.LoadInt32(&c.state.inTx) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, txKey{}, true)
 }
 
 // Compile time validation that our types implement the expected interfaces
@@ -39,21 +81,23 @@ func (c wrappedConn) Begin() (driver.Tx, error) {
 	if err != nil {
 		return nil, err
 	}
-	return wrappedTx{intr: c.intr, parent: tx}, nil
+	c.setInTx(true)
+	return wrappedTx{intr: c.intr, parent: tx, conn: c}, nil
 }
 
 func (c wrappedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (tx driver.Tx, err error) {
 	wrappedParent := wrappedParentConn{c.parent}
-	ctx, tx, err = c.intr.ConnBeginTx(ctx, wrappedParent, opts)
+	ctx, tx, err = c.intr.ConnBeginTx(c.withConn(ctx), wrappedParent, opts)
 	if err != nil {
 		return nil, err
 	}
-	return wrappedTx{intr: c.intr, ctx: ctx, parent: tx}, nil
+	c.setInTx(true)
+	return wrappedTx{intr: c.intr, ctx: ctx, parent: tx, conn: c}, nil
 }
 
 func (c wrappedConn) PrepareContext(ctx context.Context, query string) (stmt driver.Stmt, err error) {
 	wrappedParent := wrappedParentConn{c.parent}
-	ctx, stmt, err = c.intr.ConnPrepareContext(ctx, wrappedParent, query)
+	ctx, stmt, err = c.intr.ConnPrepareContext(c.withConn(ctx), wrappedParent, query)
 	if err != nil {
 		return nil, err
 	}
@@ -73,7 +117,15 @@ func (c wrappedConn) Exec(query string, args []driver.Value) (driver.Result, err
 }
 
 func (c wrappedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (r driver.Result, err error) {
+	// Quick skip path: If the wrapped connection implements neither ExecerContext nor Execer, we have absolutely nothing to do
+	_, hasExecerContext := c.parent.(driver.ExecerContext)
+	_, hasExecer := c.parent.(driver.Execer)
+	if !hasExecerContext && !hasExecer {
+		return nil, driver.ErrSkip
+	}
+
 	wrappedParent := wrappedParentConn{c.parent}
+	ctx = c.withConn(ctx)
 	r, err = c.intr.ConnExecContext(ctx, wrappedParent, query, args)
 	if err != nil {
 		return nil, err
@@ -83,7 +135,7 @@ func (c wrappedConn) ExecContext(ctx context.Context, query string, args []drive
 
 func (c wrappedConn) Ping(ctx context.Context) (err error) {
 	if pinger, ok := c.parent.(driver.Pinger); ok {
-		return c.intr.ConnPing(ctx, pinger)
+		return c.intr.ConnPing(c.withConn(ctx), pinger)
 	}
 	return nil
 }
@@ -108,7 +160,7 @@ func (c wrappedConn) QueryContext(ctx context.Context, query string, args []driv
 	}
 
 	wrappedParent := wrappedParentConn{c.parent}
-	ctx, rows, err = c.intr.ConnQueryContext(ctx, wrappedParent, query, args)
+	ctx, rows, err = c.intr.ConnQueryContext(c.withConn(ctx), wrappedParent, query, args)
 	if err != nil {
 		return nil, err
 	}
@@ -151,6 +203,10 @@ func (c wrappedParentConn) ExecContext(ctx context.Context, query string, args [
 		return execContext.ExecContext(ctx, query, args)
 	}
 	// Fallback implementation
+	execer, ok := c.Conn.(driver.Execer)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
 	dargs, err := namedValueToValue(args)
 	if err != nil {
 		return nil, err
@@ -159,7 +215,7 @@ func (c wrappedParentConn) ExecContext(ctx context.Context, query string, args [
 	case <-ctx.Done():
 		return nil, ctx.Err()
 	default:
-		return c.Conn.(driver.Execer).Exec(query, dargs)
+		return execer.Exec(query, dargs)
 	}
 }
 
@@ -168,6 +224,10 @@ func (c wrappedParentConn) QueryContext(ctx context.Context, query string, args
 		return queryerContext.QueryContext(ctx, query, args)
 	}
 	// Fallback implementation
+	queryer, ok := c.Conn.(driver.Queryer)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
 	dargs, err := namedValueToValue(args)
 	if err != nil {
 		return nil, err
@@ -176,6 +236,67 @@ func (c wrappedParentConn) QueryContext(ctx context.Context, query string, args
 	case <-ctx.Done():
 		return nil, ctx.Err()
 	default:
-		return c.Conn.(driver.Queryer).Query(query, dargs)
+		return queryer.Query(query, dargs)
+	}
+}
+
+type connKey struct{}
+
+// Conn is a handle on the driver connection an intercepted call is made on.
+// It lets an interceptor run its own statements on the connection, e.g. to
+// set session variables before a statement, and keep state along with the
+// connection.
+type Conn struct {
+	parent driver.Conn
+	state  *connState
+}
+
+// ConnFromContext returns the connection an intercepted call is made on. ctx
+// must be the context received by one of the Conn, Stmt, Tx or Rows methods
+// of an Interceptor.
+func ConnFromContext(ctx context.Context) (*Conn, bool) {
+	c, ok := ctx.Value(connKey{}).(*Conn)
+	return c, ok
+}
+
+// ExecContext runs query on the connection. The statement does not go
+// through the interceptor of the layer the connection belongs to, but goes
+// through the interceptors of the inner layers. It returns driver.ErrSkip
+// when the driver connection cannot run statements without preparing them.
+func (c *Conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	return wrappedParentConn{c.parent}.ExecContext(ctx, query, args)
+}
+
+// QueryContext runs query on the connection, like ExecContext. The rows must
+// be closed before the intercepted call proceeds.
+func (c *Conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	return wrappedParentConn{c.parent}.QueryContext(ctx, query, args)
+}
+
+// InTx reports whether the connection has an open transaction.
+func (c *Conn) InTx() bool {
+	return atomic.LoadInt32(&c.state.inTx) != 0
+}
+
+// Value returns the value associated with key on the connection, or nil.
+func (c *Conn) Value(key interface{}) interface{} {
+	c.state.mu.Lock()
+	defer c.state.mu.Unlock()
+	return c.state.values[key]
+}
+
+// SetValue associates value with key on the connection, for as long as the
+// connection lives. A nil value removes the association. Keys follow the
+// rules of context.WithValue.
+func (c *Conn) SetValue(key, value interface{}) {
+	c.state.mu.Lock()
+	defer c.state.mu.Unlock()
+	if value == nil {
+		delete(c.state.values, key)
+		return
+	}
+	if c.state.values == nil {
+		c.state.values = make(map[interface{}]interface{})
 	}
+	c.state.values[key] = value
 }
@@ -9,8 +9,23 @@ import (
 
 var _ driver.SessionResetter = wrappedConn{}
 
+// ResetSessionInterceptor is implemented by the interceptors intercepting the
+// ResetSession method of the connections, which database/sql calls before
+// reusing a connection from its pool. It is optional so that implementations
+// of the Interceptor interface written before it keep compiling.
+type ResetSessionInterceptor interface {
+	ConnResetSession(context.Context, driver.SessionResetter) error
+}
+
 func (c wrappedConn) ResetSession(ctx context.Context) error {
-	conn, ok := c.parent.(driver.SessionResetter)
+	if intr, ok := c.intr.(ResetSessionInterceptor); ok {
+		return intr.ConnResetSession(c.withConn(ctx), wrappedParentConn{c.parent})
+	}
+	return wrappedParentConn{c.parent}.ResetSession(ctx)
+}
+
+func (c wrappedParentConn) ResetSession(ctx context.Context) error {
+	conn, ok := c.Conn.(driver.SessionResetter)
 	if !ok {
 		return nil
 	}
@@ -0,0 +1,36 @@
+// +build go1.10
+
+package sqlmw
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+)
+
+type resetSessionInterceptor struct {
+	NullInterceptor
+	inConn bool
+}
+
+func (i *resetSessionInterceptor) ConnResetSession(ctx context.Context, conn driver.SessionResetter) error {
+	_, i.inConn = ConnFromContext(ctx)
+	return conn.ResetSession(ctx)
+}
+
+func TestConnResetSession(t *testing.T) {
+	ti := &resetSessionInterceptor{}
+	c := newWrappedConn(ti, &fakeConn{})
+	if err := c.ResetSession(context.Background()); err != nil {
+		t.Fatalf("ResetSession failed: %v", err)
+	}
+	if !ti.inConn {
+		t.Error("ConnResetSession context has no connection")
+	}
+
+	// without ResetSessionInterceptor, the session of the parent is reset
+	c = newWrappedConn(NullInterceptor{}, &fakeConn{})
+	if err := c.ResetSession(context.Background()); err != nil {
+		t.Fatalf("ResetSession failed: %v", err)
+	}
+}
@@ -6,10 +6,25 @@ import (
 	"database/sql/driver"
 )
 
-var _ driver.SessionResetter = wrappedConn{}
+var _ driver.Validator = wrappedConn{}
+
+// IsValidInterceptor is implemented by the interceptors intercepting the
+// IsValid method of the connections, which database/sql calls before putting
+// a connection back into its pool. It is optional so that the Interceptor
+// interface does not depend on driver.Validator, added in Go 1.15.
+type IsValidInterceptor interface {
+	ConnIsValid(driver.Validator) bool
+}
 
 func (c wrappedConn) IsValid() bool {
-	conn, ok := c.parent.(driver.Validator)
+	if intr, ok := c.intr.(IsValidInterceptor); ok {
+		return intr.ConnIsValid(wrappedParentConn{c.parent})
+	}
+	return wrappedParentConn{c.parent}.IsValid()
+}
+
+func (c wrappedParentConn) IsValid() bool {
+	conn, ok := c.Conn.(driver.Validator)
 	if !ok {
 		// the default if driver.Validator is not supported
 		return true
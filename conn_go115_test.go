@@ -0,0 +1,32 @@
+// +build go1.15
+
+package sqlmw
+
+import (
+	"database/sql/driver"
+	"testing"
+)
+
+type isValidInterceptor struct {
+	NullInterceptor
+	valid bool
+}
+
+func (i *isValidInterceptor) ConnIsValid(conn driver.Validator) bool {
+	return i.valid && conn.IsValid()
+}
+
+func TestConnIsValid(t *testing.T) {
+	for _, valid := range []bool{true, false} {
+		c := wrappedConn{intr: &isValidInterceptor{valid: valid}, parent: &fakeConn{}}
+		if got := c.IsValid(); got != valid {
+			t.Errorf("IsValid() = %v, expected %v", got, valid)
+		}
+	}
+
+	// without IsValidInterceptor, the connection is as valid as its parent
+	c := wrappedConn{intr: NullInterceptor{}, parent: &fakeConn{}}
+	if !c.IsValid() {
+		t.Error("IsValid() = false, expected true")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"database/sql/driver"
+	"errors"
 	"testing"
 )
 
@@ -254,3 +255,91 @@ func TestConnBeginTx_PassWrappedTxContextRollback(t *testing.T) {
 		t.Error("TxRollback context not valid")
 	}
 }
+
+type connHandleInterceptor struct {
+	NullInterceptor
+	calls int
+	count []int
+}
+
+type connHandleKey struct{}
+
+func (i *connHandleInterceptor) ConnExecContext(ctx context.Context, conn driver.ExecerContext, query string, args []driver.NamedValue) (driver.Result, error) {
+	i.calls++
+	c, ok := ConnFromContext(ctx)
+	if !ok {
+		return nil, errors.New("no connection in context")
+	}
+	n, _ := c.Value(connHandleKey{}).(int)
+	c.SetValue(connHandleKey{}, n+1)
+	i.count = append(i.count, n+1)
+	if _, err := c.ExecContext(ctx, "SET x = 1", nil); err != nil {
+		return nil, err
+	}
+	return conn.ExecContext(ctx, query, args)
+}
+
+func TestConnFromContext(t *testing.T) {
+	driverName := driverName(t)
+
+	con := &fakeConn{}
+	ti := &connHandleInterceptor{}
+
+	sql.Register(
+		driverName,
+		Driver(&fakeDriver{conn: con}, ti),
+	)
+
+	db, err := sql.Open(driverName, "")
+	if err != nil {
+		t.Fatalf("Failed to open: %v", err)
+	}
+
+	t.Cleanup(func() {
+		if err := db.Close(); err != nil {
+			t.Errorf("Failed to close db: %v", err)
+		}
+	})
+
+	for i := 0; i < 3; i++ {
+		if _, err := db.ExecContext(context.Background(), ""); err != nil {
+			t.Fatalf("Exec failed: %s", err)
+		}
+	}
+
+	if ti.calls != 3 {
+		t.Errorf("expected the statements of the interceptor not to be intercepted, got %d calls", ti.calls)
+	}
+	expected := []int{1, 2, 3}
+	for i := range expected {
+		if ti.count[i] != expected[i] {
+			t.Errorf("call %d: value = %d, expected %d", i, ti.count[i], expected[i])
+		}
+	}
+}
+
+// prepareOnlyConn implements neither the Execer nor the Queryer interfaces.
+type prepareOnlyConn struct {
+	driver.Conn
+}
+
+func TestConnWithoutExecerOrQueryer(t *testing.T) {
+	c := &Conn{parent: prepareOnlyConn{}}
+	if _, err := c.ExecContext(context.Background(), "SET x = 1", nil); err != driver.ErrSkip {
+		t.Errorf("ExecContext error = %v, expected %v", err, driver.ErrSkip)
+	}
+	if _, err := c.QueryContext(context.Background(), "SELECT 1", nil); err != driver.ErrSkip {
+		t.Errorf("QueryContext error = %v, expected %v", err, driver.ErrSkip)
+	}
+}
+
+func TestConnExecContext_QuickSkip(t *testing.T) {
+	ti := &connHandleInterceptor{}
+	c := wrappedConn{intr: ti, parent: prepareOnlyConn{}, state: &connState{}}
+	if _, err := c.ExecContext(context.Background(), "SET x = 1", nil); err != driver.ErrSkip {
+		t.Errorf("ExecContext error = %v, expected %v", err, driver.ErrSkip)
+	}
+	if ti.calls != 0 {
+		t.Errorf("expected the statement not to be intercepted, got %d calls", ti.calls)
+	}
+}
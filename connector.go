@@ -16,13 +16,22 @@ var (
 	_ driver.Connector = wrappedConnector{}
 )
 
+// Connector returns the supplied driver.Connector with a new object that has all of its calls intercepted by the
+// supplied Interceptor object. The returned connector can be used with sql.OpenDB.
+func Connector(connector driver.Connector, intr Interceptor) driver.Connector {
+	return wrappedConnector{
+		parent:    connector,
+		driverRef: &wrappedDriver{parent: connector.Driver(), intr: intr},
+	}
+}
+
 func (c wrappedConnector) Connect(ctx context.Context) (conn driver.Conn, err error) {
 	conn, err = c.driverRef.intr.ConnectorConnect(ctx, c.parent)
 	if err != nil {
 		return nil, err
 	}
 
-	return wrappedConn{intr: c.driverRef.intr, parent: conn}, nil
+	return newWrappedConn(c.driverRef.intr, conn), nil
 }
 
 func (c wrappedConnector) Driver() driver.Driver {
@@ -4,6 +4,7 @@ package sqlmw
 
 import (
 	"context"
+	"database/sql"
 	"database/sql/driver"
 	"fmt"
 	"testing"
@@ -95,3 +96,29 @@ func (c *connMock) Connect(context.Context) (driver.Conn, error) {
 func (c *connMock) Driver() driver.Driver {
 	panic("not implemented")
 }
+
+func TestConnector(t *testing.T) {
+	con := &fakeConn{}
+	ti := &connTestInterceptor{T: t}
+	db := sql.OpenDB(Connector(dsnConnector{driver: &fakeDriver{conn: con}}, ti))
+	t.Cleanup(func() {
+		if err := db.Close(); err != nil {
+			t.Errorf("Failed to close db: %v", err)
+		}
+	})
+
+	rows, err := db.QueryContext(context.Background(), "")
+	if err != nil {
+		t.Fatalf("Query failed: %s", err)
+	}
+	rows.Next()
+	rows.Close()
+
+	if !ti.RowsCloseValid {
+		t.Error("connection was not intercepted")
+	}
+
+	if _, ok := db.Driver().(*wrappedDriver); !ok {
+		t.Errorf("expected the wrapped driver, got %T", db.Driver())
+	}
+}
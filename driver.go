@@ -34,5 +34,5 @@ func (d wrappedDriver) Open(name string) (driver.Conn, error) {
 		return nil, err
 	}
 
-	return wrappedConn{intr: d.intr, parent: conn}, nil
+	return newWrappedConn(d.intr, conn), nil
 }
@@ -0,0 +1,210 @@
+package encrypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// KeyProvider provides the keys of an Interceptor, by id. Keys are 16, 24 or
+// 32 bytes long, selecting AES-128, AES-192 or AES-256. The key of an id
+// must never change.
+type KeyProvider interface {
+	// Current returns the key to encrypt with and its id.
+	Current() (id string, key []byte, err error)
+	// Key returns the key of id, to decrypt with.
+	Key(id string) ([]byte, error)
+}
+
+// Keys is a static KeyProvider. Keys are rotated by adding a new key and
+// making it the current one, the previous keys still decrypting the values
+// they encrypted.
+type Keys struct {
+	// CurrentID is the id of the key to encrypt with.
+	CurrentID string
+	// Keys are the keys, by id.
+	Keys map[string][]byte
+}
+
+func (k Keys) Current() (string, []byte, error) {
+	key, err := k.Key(k.CurrentID)
+	return k.CurrentID, key, err
+}
+
+func (k Keys) Key(id string) ([]byte, error) {
+	key, ok := k.Keys[id]
+	if !ok {
+		return nil, fmt.Errorf("encrypt: unknown key %q", id)
+	}
+	return key, nil
+}
+
+// The encrypted values are laid out as:
+//
+//	magic | flags | len(key id) | key id | nonce | sealed
+//
+// where sealed is the AES-GCM ciphertext of the value followed by its tag.
+// The header preceding the nonce and the column of the value are
+// authenticated as the additional data of AES-GCM, so that a value cannot
+// be altered nor copied to another column.
+//
+// The AES-GCM key and the HMAC key deriving the nonces of the deterministic
+// encryption are derived from the key with HKDF-SHA256.
+const (
+	magic     = "\x00enc1"
+	nonceSize = 12
+
+	flagDeterministic = 1 << 0
+	flagString        = 1 << 1
+)
+
+var errMalformed = errors.New("encrypt: malformed encrypted value")
+
+// The HKDF info of the subkeys.
+const (
+	infoGCM   = "sqlmw/encrypt aes-gcm"
+	infoNonce = "sqlmw/encrypt nonce"
+)
+
+// hkdf derives a subkey of n bytes, up to 32, from key for info, as
+// specified by RFC 5869 with SHA-256 and no salt.
+func hkdf(key []byte, info string, n int) []byte {
+	extract := hmac.New(sha256.New, make([]byte, sha256.Size))
+	extract.Write(key)
+	expand := hmac.New(sha256.New, extract.Sum(nil))
+	expand.Write([]byte(info))
+	expand.Write([]byte{1})
+	return expand.Sum(nil)[:n]
+}
+
+// isEncrypted reports whether b is laid out as an encrypted value.
+func isEncrypted(b []byte) bool {
+	return len(b) > len(magic) && string(b[:len(magic)]) == magic
+}
+
+// ciphers caches the subkeys of every key, see derive.
+type ciphers struct {
+	keys KeyProvider
+
+	mu      sync.Mutex
+	subkeys map[string]*subkeys
+}
+
+// subkeys are the keys derived from a key.
+type subkeys struct {
+	aead  cipher.AEAD
+	nonce []byte
+}
+
+// derive returns the subkeys of key, whose id is id.
+func (c *ciphers) derive(id string, key []byte) (*subkeys, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if k, ok := c.subkeys[id]; ok {
+		return k, nil
+	}
+	switch len(key) {
+	case 16, 24, 32:
+	default:
+		return nil, fmt.Errorf("encrypt: key %q: %w", id, aes.KeySizeError(len(key)))
+	}
+	block, err := aes.NewCipher(hkdf(key, infoGCM, len(key)))
+	if err != nil {
+		return nil, fmt.Errorf("encrypt: key %q: %w", id, err)
+	}
+	a, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	if c.subkeys == nil {
+		c.subkeys = make(map[string]*subkeys)
+	}
+	k := &subkeys{aead: a, nonce: hkdf(key, infoNonce, sha256.Size)}
+	c.subkeys[id] = k
+	return k, nil
+}
+
+// additionalData returns the data authenticated along with a value: the
+// header of the value and the column it belongs to.
+func additionalData(header []byte, column string) []byte {
+	ad := make([]byte, 0, len(header)+len(column))
+	ad = append(ad, header...)
+	return append(ad, column...)
+}
+
+// seal encrypts plaintext, the value of column, with the current key. A
+// deterministic encryption derives the nonce from the column and the
+// plaintext, so that equal plaintexts of a column have equal ciphertexts
+// under the same key.
+func (c *ciphers) seal(column string, plaintext []byte, deterministic, str bool) ([]byte, error) {
+	id, key, err := c.keys.Current()
+	if err != nil {
+		return nil, err
+	}
+	if len(id) > 255 {
+		return nil, fmt.Errorf("encrypt: key id %q is too long", id)
+	}
+	k, err := c.derive(id, key)
+	if err != nil {
+		return nil, err
+	}
+
+	var flags byte
+	nonce := make([]byte, nonceSize)
+	if deterministic {
+		flags |= flagDeterministic
+		mac := hmac.New(sha256.New, k.nonce)
+		mac.Write([]byte(column))
+		mac.Write([]byte{0})
+		mac.Write(plaintext)
+		copy(nonce, mac.Sum(nil))
+	} else if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	if str {
+		flags |= flagString
+	}
+
+	out := make([]byte, 0, len(magic)+2+len(id)+nonceSize+len(plaintext)+k.aead.Overhead())
+	out = append(out, magic...)
+	out = append(out, flags, byte(len(id)))
+	out = append(out, id...)
+	ad := additionalData(out, column)
+	out = append(out, nonce...)
+	return k.aead.Seal(out, nonce, plaintext, ad), nil
+}
+
+// open decrypts a value of column encrypted by seal and reports whether it
+// was a string.
+func (c *ciphers) open(column string, b []byte) ([]byte, bool, error) {
+	if !isEncrypted(b) || len(b) < len(magic)+2 {
+		return nil, false, errMalformed
+	}
+	flags, n := b[len(magic)], int(b[len(magic)+1])
+	if len(b) < len(magic)+2+n+nonceSize {
+		return nil, false, errMalformed
+	}
+	header := b[:len(magic)+2+n]
+	id := string(header[len(magic)+2:])
+	nonce, sealed := b[len(header):len(header)+nonceSize], b[len(header)+nonceSize:]
+
+	key, err := c.keys.Key(id)
+	if err != nil {
+		return nil, false, err
+	}
+	k, err := c.derive(id, key)
+	if err != nil {
+		return nil, false, err
+	}
+	plaintext, err := k.aead.Open(nil, nonce, sealed, additionalData(header, column))
+	if err != nil {
+		return nil, false, fmt.Errorf("encrypt: key %q: %w", id, err)
+	}
+	return plaintext, flags&flagString != 0, nil
+}
@@ -0,0 +1,313 @@
+// Package encrypt provides an sqlmw.Interceptor encrypting columns at rest
+// with AES-GCM, without changes to the application.
+//
+// The arguments written to or compared to an encrypted column are encrypted
+// before they reach the database, the column of an argument being found by
+// sqlinfo.ParamColumns or by its parameter name. A statement referencing
+// the table of an encrypted column fails, rather than sending an argument
+// in plaintext, when the column of one of its string or byte slice
+// arguments cannot be told, as in "INSERT INTO users VALUES (?, ?)" or
+// "SET email = lower(?)". The values of the rows are decrypted before they
+// reach the application, the columns of a row being matched by the names
+// returned by its Columns method, so that an encrypted column selected
+// under an alias is not decrypted.
+//
+// Every encrypted value is bound to its column, see Column.Table, and
+// embeds the id of its key, so that keys can be
+// rotated: values are encrypted with the current key of the KeyProvider and
+// decrypted with the key they name. Values that are not encrypted are
+// returned as they are, which allows encrypting the rows of a table
+// progressively.
+//
+// The encryption of a column is randomized unless the column is
+// Deterministic. The deterministic encryption of a value always gives the
+// same result under the same key, which allows equality lookups like
+// "WHERE email = ?", at the cost of revealing which rows hold equal values.
+// Lookups only match the rows encrypted with the current key.
+package encrypt
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/ngrok/sqlmw"
+	"github.com/ngrok/sqlmw/sqlinfo"
+)
+
+// Column is an encrypted column.
+type Column struct {
+	// Name is the name of the column, regardless of case.
+	Name string
+	// Table is the name of the table of the column, regardless of case, as
+	// sqlinfo.Parse reports it. If set, the column is only encrypted in the
+	// statements referencing the table, and in every statement otherwise.
+	// The values are bound to the table and the name of their column and
+	// fail to decrypt when copied to another column.
+	Table string
+	// Deterministic enables the deterministic encryption of the column.
+	Deterministic bool
+}
+
+// Config configures an Interceptor.
+type Config struct {
+	// Keys provides the keys. It is required.
+	Keys KeyProvider
+
+	// Columns are the encrypted columns. Their values are strings or byte
+	// slices.
+	Columns []Column
+
+	// Base64, if set, stores the encrypted values as base64 text, for text
+	// columns. They are stored as bytes otherwise.
+	Base64 bool
+
+	// Dialect is used to find the tables of the statements and the columns
+	// of their arguments, unless the statements are described by
+	// sqlinfo.Interceptor. Defaults to sqlinfo.Generic.
+	Dialect sqlinfo.Dialect
+}
+
+// Interceptor encrypts and decrypts columns.
+type Interceptor struct {
+	sqlmw.NullInterceptor
+
+	cfg     Config
+	ciphers *ciphers
+}
+
+// New returns an Interceptor for the supplied configuration.
+func New(cfg Config) *Interceptor {
+	return &Interceptor{cfg: cfg, ciphers: &ciphers{keys: cfg.Keys}}
+}
+
+// scope returns the encrypted columns in scope of query.
+func (in *Interceptor) scope(ctx context.Context, query string) []Column {
+	var st *sqlinfo.Statement
+	var scope []Column
+	for _, c := range in.cfg.Columns {
+		if c.Table != "" {
+			if st == nil {
+				var ok bool
+				if st, ok = sqlinfo.FromContext(ctx); !ok {
+					st = sqlinfo.Parse(in.cfg.Dialect, query)
+				}
+			}
+			if !referenced(st.Tables, c.Table) {
+				continue
+			}
+		}
+		scope = append(scope, c)
+	}
+	return scope
+}
+
+func referenced(tables []string, table string) bool {
+	for _, t := range tables {
+		if strings.EqualFold(t, table) {
+			return true
+		}
+	}
+	return false
+}
+
+// lookup returns the columns of scope named name.
+func lookup(scope []Column, name string) []Column {
+	var cols []Column
+	for _, c := range scope {
+		if name != "" && strings.EqualFold(c.Name, name) {
+			cols = append(cols, c)
+		}
+	}
+	return cols
+}
+
+// id returns the identity of c its values are bound to.
+func (c Column) id() string {
+	return strings.ToLower(c.Table) + "." + strings.ToLower(c.Name)
+}
+
+// encryptArgs returns args with the values of the encrypted columns of
+// scope encrypted.
+func (in *Interceptor) encryptArgs(scope []Column, query string, args []driver.NamedValue) ([]driver.NamedValue, error) {
+	if len(args) == 0 || len(scope) == 0 {
+		return args, nil
+	}
+	var out []driver.NamedValue
+	cols := sqlinfo.ParamColumns(in.cfg.Dialect, query)
+	for i, arg := range args {
+		if arg.Value == nil {
+			continue
+		}
+		name := cols.Of(arg)
+		matches := lookup(scope, name)
+		if len(matches) == 0 && arg.Name != "" {
+			matches = lookup(scope, arg.Name)
+		}
+
+		var plaintext []byte
+		var str bool
+		switch v := arg.Value.(type) {
+		case string:
+			plaintext, str = []byte(v), true
+		case []byte:
+			plaintext = v
+		default:
+			if len(matches) > 0 {
+				return nil, fmt.Errorf("encrypt: cannot encrypt %T value of %s", arg.Value, matches[0].Name)
+			}
+			continue
+		}
+		switch {
+		case len(matches) == 0 && name == "":
+			// the argument may be the value of an encrypted column
+			return nil, fmt.Errorf("encrypt: cannot tell the column of argument %d of a statement on encrypted columns", i+1)
+		case len(matches) == 0:
+			continue
+		case len(matches) > 1:
+			return nil, fmt.Errorf("encrypt: column %s of argument %d is ambiguous", matches[0].Name, i+1)
+		}
+		c := matches[0]
+		sealed, err := in.ciphers.seal(c.id(), plaintext, c.Deterministic, str)
+		if err != nil {
+			return nil, err
+		}
+
+		if out == nil {
+			out = make([]driver.NamedValue, len(args))
+			copy(out, args)
+		}
+		out[i].Value = sealed
+		if in.cfg.Base64 {
+			out[i].Value = base64.StdEncoding.EncodeToString(sealed)
+		}
+	}
+	if out == nil {
+		return args, nil
+	}
+	return out, nil
+}
+
+// decrypt returns the decrypted value of an encrypted column, which is one
+// of cols.
+func (in *Interceptor) decrypt(cols []Column, v driver.Value) (driver.Value, error) {
+	var b []byte
+	switch v := v.(type) {
+	case []byte:
+		b = v
+	case string:
+		b = []byte(v)
+	default:
+		return v, nil
+	}
+	if in.cfg.Base64 {
+		decoded, err := base64.StdEncoding.DecodeString(string(b))
+		if err != nil {
+			return v, nil
+		}
+		b = decoded
+	}
+	if !isEncrypted(b) {
+		return v, nil
+	}
+	var plaintext []byte
+	var str bool
+	var err error
+	for _, c := range cols {
+		if plaintext, str, err = in.ciphers.open(c.id(), b); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if str {
+		return string(plaintext), nil
+	}
+	return plaintext, nil
+}
+
+func (in *Interceptor) ConnExecContext(ctx context.Context, conn driver.ExecerContext, query string, args []driver.NamedValue) (driver.Result, error) {
+	args, err := in.encryptArgs(in.scope(ctx, query), query, args)
+	if err != nil {
+		return nil, err
+	}
+	return conn.ExecContext(ctx, query, args)
+}
+
+func (in *Interceptor) ConnQueryContext(ctx context.Context, conn driver.QueryerContext, query string, args []driver.NamedValue) (context.Context, driver.Rows, error) {
+	scope := in.scope(ctx, query)
+	args, err := in.encryptArgs(scope, query, args)
+	if err != nil {
+		return ctx, nil, err
+	}
+	rows, err := conn.QueryContext(ctx, query, args)
+	return context.WithValue(ctx, rowsKey{}, &resultColumns{scope: scope}), rows, err
+}
+
+func (in *Interceptor) StmtExecContext(ctx context.Context, stmt driver.StmtExecContext, query string, args []driver.NamedValue) (driver.Result, error) {
+	args, err := in.encryptArgs(in.scope(ctx, query), query, args)
+	if err != nil {
+		return nil, err
+	}
+	return stmt.ExecContext(ctx, args)
+}
+
+func (in *Interceptor) StmtQueryContext(ctx context.Context, stmt driver.StmtQueryContext, query string, args []driver.NamedValue) (context.Context, driver.Rows, error) {
+	scope := in.scope(ctx, query)
+	args, err := in.encryptArgs(scope, query, args)
+	if err != nil {
+		return ctx, nil, err
+	}
+	rows, err := stmt.QueryContext(ctx, args)
+	return context.WithValue(ctx, rowsKey{}, &resultColumns{scope: scope}), rows, err
+}
+
+type rowsKey struct{}
+
+// resultColumns are the encrypted columns of the current result set of
+// rows, by index, among the encrypted columns in scope of the query.
+type resultColumns struct {
+	scope   []Column
+	names   []string
+	columns map[int][]Column
+}
+
+// encryptedColumns returns the encrypted columns of rows, reading them the
+// first time a row of a result set is read.
+func (in *Interceptor) encryptedColumns(ctx context.Context, rows driver.Rows, dest []driver.Value) map[int][]Column {
+	rc, ok := ctx.Value(rowsKey{}).(*resultColumns)
+	if !ok {
+		rc = &resultColumns{scope: in.cfg.Columns}
+	}
+	if rc.names == nil || len(rc.names) != len(dest) {
+		rc.names = rows.Columns()
+		rc.columns = make(map[int][]Column)
+		for i, name := range rc.names {
+			if cols := lookup(rc.scope, name); len(cols) > 0 && i < len(dest) {
+				rc.columns[i] = cols
+			}
+		}
+	}
+	return rc.columns
+}
+
+func (in *Interceptor) RowsNext(ctx context.Context, rows driver.Rows, dest []driver.Value) error {
+	if err := rows.Next(dest); err != nil {
+		if rc, ok := ctx.Value(rowsKey{}).(*resultColumns); ok {
+			// the next result set may have other columns
+			rc.names = nil
+		}
+		return err
+	}
+	for i, cols := range in.encryptedColumns(ctx, rows, dest) {
+		v, err := in.decrypt(cols, dest[i])
+		if err != nil {
+			return fmt.Errorf("encrypt: column %s: %w", cols[0].Name, err)
+		}
+		dest[i] = v
+	}
+	return nil
+}
@@ -0,0 +1,204 @@
+package encrypt
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/hex"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ngrok/sqlmw"
+	"github.com/ngrok/sqlmw/internal/fakedb"
+	"github.com/ngrok/sqlmw/sqlmwtest"
+)
+
+// table stores the rows inserted by "INSERT INTO users (email, ssn) VALUES
+// (?, ?)" and returns them for "SELECT email, ssn FROM users", optionally
+// "WHERE email = ?".
+type table struct {
+	mu   sync.Mutex
+	rows [][]driver.Value
+}
+
+func (tb *table) handle(_ context.Context, query string, args []driver.NamedValue) (*fakedb.Response, error) {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	if strings.HasPrefix(query, "INSERT") {
+		tb.rows = append(tb.rows, []driver.Value{args[0].Value, args[1].Value})
+		return &fakedb.Response{RowsAffected: 1}, nil
+	}
+	resp := &fakedb.Response{Columns: []string{"email", "SSN"}}
+	for _, row := range tb.rows {
+		if len(args) == 0 || bytes.Equal(row[0].([]byte), args[0].Value.([]byte)) {
+			resp.Rows = append(resp.Rows, row)
+		}
+	}
+	return resp, nil
+}
+
+func TestInterceptor(t *testing.T) {
+	keys := &Keys{CurrentID: "k1", Keys: map[string][]byte{
+		"k1": bytes.Repeat([]byte{1}, 32),
+		"k2": bytes.Repeat([]byte{2}, 16),
+	}}
+	in := New(Config{Keys: keys, Columns: []Column{{Name: "email", Deterministic: true}, {Name: "ssn"}}})
+	tb := &table{}
+	db := sql.OpenDB(sqlmw.Connector(&fakedb.Connector{Handler: tb.handle}, in))
+	t.Cleanup(func() {
+		if err := db.Close(); err != nil {
+			t.Errorf("Failed to close db: %v", err)
+		}
+	})
+	ctx := context.Background()
+
+	insert := func(email, ssn string) {
+		t.Helper()
+		if _, err := db.ExecContext(ctx, "INSERT INTO users (email, ssn) VALUES (?, ?)", email, ssn); err != nil {
+			t.Fatalf("Exec failed: %v", err)
+		}
+	}
+	lookup := func(email string) []string {
+		t.Helper()
+		query, args := "SELECT email, ssn FROM users WHERE email = ?", []interface{}{email}
+		if email == "" {
+			query, args = "SELECT email, ssn FROM users", nil
+		}
+		rows, err := db.QueryContext(ctx, query, args...)
+		if err != nil {
+			t.Fatalf("Query failed: %v", err)
+		}
+		defer rows.Close()
+		var got []string
+		for rows.Next() {
+			var email, ssn string
+			if err := rows.Scan(&email, &ssn); err != nil {
+				t.Fatalf("Scan failed: %v", err)
+			}
+			got = append(got, email+" "+ssn)
+		}
+		if err := rows.Err(); err != nil {
+			t.Fatalf("Next failed: %v", err)
+		}
+		return got
+	}
+
+	insert("a@example.com", "123")
+	insert("a@example.com", "123")
+	if row := tb.rows[0]; bytes.Contains(row[0].([]byte), []byte("a@example.com")) || !bytes.Equal(row[0].([]byte), tb.rows[1][0].([]byte)) {
+		t.Errorf("email is not deterministically encrypted: %q", row[0])
+	}
+	if bytes.Equal(tb.rows[0][1].([]byte), tb.rows[1][1].([]byte)) {
+		t.Errorf("ssn is deterministically encrypted: %q", tb.rows[0][1])
+	}
+	if got := lookup("a@example.com"); len(got) != 2 || got[0] != "a@example.com 123" {
+		t.Errorf("unexpected lookup %q", got)
+	}
+
+	// rows encrypted with a previous key are still decrypted
+	keys.CurrentID = "k2"
+	insert("b@example.com", "456")
+	if got := lookup("b@example.com"); len(got) != 1 || got[0] != "b@example.com 456" {
+		t.Errorf("unexpected lookup %q", got)
+	}
+	if got := lookup("a@example.com"); len(got) != 0 {
+		t.Errorf("unexpected lookup of a value encrypted with a previous key %q", got)
+	}
+
+	// values that are not encrypted are returned as they are
+	tb.rows = append(tb.rows, []driver.Value{[]byte("c@example.com"), []byte("789")})
+	if got := lookup(""); len(got) != 4 || got[2] != "b@example.com 456" || got[3] != "c@example.com 789" {
+		t.Errorf("unexpected rows %q", got)
+	}
+
+	if _, err := db.ExecContext(ctx, "INSERT INTO users (email, ssn) VALUES (?, ?)", "d@example.com", 42); err == nil {
+		t.Error("expected the encryption of an integer to fail")
+	}
+
+	tamper := []func(){
+		// the ciphertext
+		func() {
+			ssn := tb.rows[0][1].([]byte)
+			ssn[len(ssn)-1] ^= 1
+		},
+		// the header
+		func() { tb.rows[0][1].([]byte)[len(magic)] ^= flagString },
+		// the column
+		func() { tb.rows[0][1] = tb.rows[0][0] },
+	}
+	orig := tb.rows[0][1].([]byte)
+	for i, f := range tamper {
+		tb.rows[0][1] = append([]byte(nil), orig...)
+		f()
+		rows, err := db.QueryContext(ctx, "SELECT email, ssn FROM users")
+		if err != nil {
+			t.Fatalf("Query failed: %v", err)
+		}
+		for rows.Next() {
+		}
+		if rows.Err() == nil {
+			t.Errorf("tamper %d: expected the decryption of a tampered value to fail", i)
+		}
+		rows.Close()
+	}
+}
+
+func TestUnknownColumns(t *testing.T) {
+	keys := &Keys{CurrentID: "k1", Keys: map[string][]byte{"k1": bytes.Repeat([]byte{1}, 32)}}
+	in := New(Config{Keys: keys, Columns: []Column{{Name: "email", Table: "users"}}})
+	con := &fakedb.Connector{}
+	db := sql.OpenDB(sqlmw.Connector(con, in))
+	t.Cleanup(func() {
+		if err := db.Close(); err != nil {
+			t.Errorf("Failed to close db: %v", err)
+		}
+	})
+	ctx := context.Background()
+
+	for _, query := range []string{
+		"INSERT INTO users VALUES (?, ?)",
+		"INSERT INTO users (id, email) SELECT ?, ?",
+		"UPDATE users SET email = lower(?) WHERE id = ?",
+		"UPDATE users SET email = COALESCE(?, email) WHERE id = ?",
+	} {
+		if _, err := db.ExecContext(ctx, query, "a@example.com", 1); err == nil {
+			t.Errorf("expected %q to fail", query)
+		}
+	}
+
+	// the columns of the arguments are known, or the table has no encrypted
+	// column
+	if _, err := db.ExecContext(ctx, "INSERT INTO users (id, email) VALUES (?, ?)", 1, "a@example.com"); err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	if _, err := db.ExecContext(ctx, "INSERT INTO admins VALUES (?)", "a@example.com"); err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	calls := con.Calls()
+	if len(calls) != 2 {
+		t.Fatalf("got %d calls to the database, want 2", len(calls))
+	}
+	if _, ok := calls[0].Args[1].Value.([]byte); !ok {
+		t.Errorf("email of users was not encrypted: %v", calls[0].Args[1].Value)
+	}
+	if calls[1].Args[0].Value != "a@example.com" {
+		t.Errorf("email of admins was encrypted: %v", calls[1].Args[0].Value)
+	}
+}
+
+func TestHKDF(t *testing.T) {
+	// RFC 5869, test case 3
+	okm := hkdf(bytes.Repeat([]byte{0x0b}, 22), "", 32)
+	if got := hex.EncodeToString(okm); got != "8da4e775a563c18f715f802a063c5a31b8a11f5c5ee1879ec3454e5f3c738d2d" {
+		t.Errorf("unexpected okm %s", got)
+	}
+}
+
+func TestConformance(t *testing.T) {
+	keys := &Keys{CurrentID: "k1", Keys: map[string][]byte{"k1": bytes.Repeat([]byte{1}, 32)}}
+	sqlmwtest.RunConformance(t, func() sqlmw.Interceptor {
+		return New(Config{Keys: keys, Columns: []Column{{Name: "ssn"}}})
+	})
+}
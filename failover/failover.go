@@ -0,0 +1,304 @@
+// Package failover provides a driver.Connector that connects to the first
+// healthy database of an ordered list of connectors.
+//
+// A target is marked down after MaxFailures consecutive failures to connect
+// or to ping one of its connections. Down targets are probed in the
+// background and marked up again once they answer. Connections are always
+// made to the highest priority target that is up, and connections to any
+// other target are retired: their IsValid method returns false so that
+// database/sql discards them instead of reusing them.
+package failover
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/ngrok/sqlmw"
+)
+
+const (
+	defaultMaxFailures   = 3
+	defaultProbeInterval = 5 * time.Second
+	defaultProbeTimeout  = time.Second
+)
+
+// ErrNoTarget is returned by Connect when no target could be connected to.
+var ErrNoTarget = errors.New("failover: no target available")
+
+// Config configures a Connector.
+type Config struct {
+	// Targets are the connectors to fail over between, in priority order.
+	Targets []driver.Connector
+
+	// MaxFailures is the number of consecutive failures after which a target
+	// is marked down. Defaults to 3.
+	MaxFailures int
+
+	// ProbeInterval is the interval between two health probes of a down
+	// target. Defaults to 5 seconds.
+	ProbeInterval time.Duration
+
+	// ProbeTimeout bounds a single health probe. Defaults to 1 second.
+	ProbeTimeout time.Duration
+
+	// OnEvent is called whenever a target changes state.
+	OnEvent func(Event)
+}
+
+// State is the health of a target.
+type State int
+
+const (
+	Up State = iota
+	Down
+)
+
+func (s State) String() string {
+	if s == Up {
+		return "up"
+	}
+	return "down"
+}
+
+// Event describes a target changing state.
+type Event struct {
+	Target int
+	From   State
+	To     State
+	Err    error
+	Time   time.Time
+}
+
+// TargetState is a snapshot of the state of a target.
+type TargetState struct {
+	Target              int
+	State               State
+	Since               time.Time
+	ConsecutiveFailures int
+	LastError           error
+}
+
+// Connector is a driver.Connector failing over between its targets. Create it
+// with New and release it with Close. sql.DB closes it when it is closed.
+type Connector struct {
+	cfg     Config
+	targets []*target
+
+	mu     sync.Mutex
+	states []TargetState
+
+	stop chan struct{}
+	once sync.Once
+	wg   sync.WaitGroup
+}
+
+type target struct {
+	index     int
+	parent    driver.Connector
+	connector driver.Connector
+}
+
+var _ driver.Connector = (*Connector)(nil)
+
+// New returns a Connector for the supplied configuration and starts probing
+// the targets that go down.
+func New(cfg Config) *Connector {
+	if cfg.MaxFailures <= 0 {
+		cfg.MaxFailures = defaultMaxFailures
+	}
+	if cfg.ProbeInterval <= 0 {
+		cfg.ProbeInterval = defaultProbeInterval
+	}
+	if cfg.ProbeTimeout <= 0 {
+		cfg.ProbeTimeout = defaultProbeTimeout
+	}
+
+	c := &Connector{
+		cfg:    cfg,
+		states: make([]TargetState, len(cfg.Targets)),
+		stop:   make(chan struct{}),
+	}
+	now := time.Now()
+	for i, parent := range cfg.Targets {
+		t := &target{index: i, parent: parent}
+		t.connector = sqlmw.Connector(parent, &targetInterceptor{c: c, t: t})
+		c.targets = append(c.targets, t)
+		c.states[i] = TargetState{Target: i, State: Up, Since: now}
+	}
+
+	c.wg.Add(1)
+	go c.probe()
+	return c
+}
+
+// Connect connects to the highest priority target that is up. When every
+// target that is up fails, the targets that are down are tried as a last
+// resort.
+func (c *Connector) Connect(ctx context.Context) (driver.Conn, error) {
+	var lastErr error
+	for _, down := range []bool{false, true} {
+		for _, t := range c.targets {
+			if c.isDown(t) != down {
+				continue
+			}
+			conn, err := t.connector.Connect(ctx)
+			if err == nil {
+				return conn, nil
+			}
+			lastErr = err
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+		}
+	}
+	if lastErr == nil {
+		return nil, ErrNoTarget
+	}
+	return nil, lastErr
+}
+
+// Driver returns the driver of the first target.
+func (c *Connector) Driver() driver.Driver {
+	return c.targets[0].connector.Driver()
+}
+
+// Close stops the background probing.
+func (c *Connector) Close() error {
+	c.once.Do(func() { close(c.stop) })
+	c.wg.Wait()
+	return nil
+}
+
+// Targets returns a snapshot of the state of every target.
+func (c *Connector) Targets() []TargetState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]TargetState(nil), c.states...)
+}
+
+func (c *Connector) isDown(t *target) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.states[t.index].State == Down
+}
+
+// retired reports whether connections to t must be discarded, either because
+// it is down or because a higher priority target is up.
+func (c *Connector) retired(t *target) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i := 0; i < t.index; i++ {
+		if c.states[i].State == Up {
+			return true
+		}
+	}
+	return c.states[t.index].State == Down
+}
+
+func (c *Connector) succeeded(t *target) {
+	c.transition(t, nil)
+}
+
+func (c *Connector) failed(t *target, err error) {
+	c.transition(t, err)
+}
+
+func (c *Connector) transition(t *target, err error) {
+	c.mu.Lock()
+	st := &c.states[t.index]
+	from := st.State
+	if err == nil {
+		st.ConsecutiveFailures = 0
+		st.State = Up
+	} else {
+		st.ConsecutiveFailures++
+		st.LastError = err
+		if st.ConsecutiveFailures >= c.cfg.MaxFailures {
+			st.State = Down
+		}
+	}
+	to := st.State
+	if from != to {
+		st.Since = time.Now()
+	}
+	ev := Event{Target: t.index, From: from, To: to, Err: err, Time: st.Since}
+	c.mu.Unlock()
+
+	if from != to && c.cfg.OnEvent != nil {
+		c.cfg.OnEvent(ev)
+	}
+}
+
+func (c *Connector) probe() {
+	defer c.wg.Done()
+	ticker := time.NewTicker(c.cfg.ProbeInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-ticker.C:
+		}
+		for _, t := range c.targets {
+			if c.isDown(t) {
+				c.probeTarget(t)
+			}
+		}
+	}
+}
+
+// probeTarget checks a down target with its unwrapped connector so that the
+// probe is not accounted twice by the targetInterceptor.
+func (c *Connector) probeTarget(t *target) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.cfg.ProbeTimeout)
+	defer cancel()
+
+	conn, err := t.parent.Connect(ctx)
+	if err != nil {
+		c.failed(t, err)
+		return
+	}
+	defer conn.Close()
+
+	if pinger, ok := conn.(driver.Pinger); ok {
+		if err := pinger.Ping(ctx); err != nil {
+			c.failed(t, err)
+			return
+		}
+	}
+	c.succeeded(t)
+}
+
+// targetInterceptor tracks the health of a single target.
+type targetInterceptor struct {
+	sqlmw.NullInterceptor
+	c *Connector
+	t *target
+}
+
+func (in *targetInterceptor) ConnectorConnect(ctx context.Context, connector driver.Connector) (driver.Conn, error) {
+	conn, err := connector.Connect(ctx)
+	in.record(ctx, err)
+	return conn, err
+}
+
+func (in *targetInterceptor) ConnPing(ctx context.Context, conn driver.Pinger) error {
+	err := conn.Ping(ctx)
+	in.record(ctx, err)
+	return err
+}
+
+func (in *targetInterceptor) record(ctx context.Context, err error) {
+	if err != nil && ctx.Err() != nil {
+		// the caller gave up, this says nothing about the target
+		return
+	}
+	if err != nil {
+		in.c.failed(in.t, err)
+		return
+	}
+	in.c.succeeded(in.t)
+}
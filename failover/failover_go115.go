@@ -0,0 +1,15 @@
+// +build go1.15
+
+package failover
+
+import (
+	"database/sql/driver"
+
+	"github.com/ngrok/sqlmw"
+)
+
+var _ sqlmw.IsValidInterceptor = (*targetInterceptor)(nil)
+
+func (in *targetInterceptor) ConnIsValid(conn driver.Validator) bool {
+	return !in.c.retired(in.t) && conn.IsValid()
+}
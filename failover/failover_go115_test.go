@@ -0,0 +1,77 @@
+// +build go1.15
+
+package failover
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+	"time"
+)
+
+func TestFailover(t *testing.T) {
+	primary, secondary := newFlakyTarget(), newFlakyTarget()
+	evs := &events{}
+	c := New(Config{
+		Targets:       []driver.Connector{&primary.Connector, &secondary.Connector},
+		MaxFailures:   2,
+		ProbeInterval: time.Millisecond,
+		OnEvent:       evs.add,
+	})
+	defer c.Close()
+	ctx := context.Background()
+
+	conn, err := c.Connect(ctx)
+	if err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+	primaryConn := conn.(interface {
+		driver.Pinger
+		driver.Validator
+	})
+	if primary.Conns() != 1 {
+		t.Fatal("expected a connection to the primary")
+	}
+
+	primary.setDown(true)
+	if err := primaryConn.Ping(ctx); err == nil {
+		t.Fatal("expected Ping to fail")
+	}
+	if !primaryConn.IsValid() {
+		t.Fatal("primary demoted after a single failure")
+	}
+
+	conn, err = c.Connect(ctx)
+	if err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+	secondaryConn := conn.(driver.Validator)
+	if secondary.Conns() != 1 {
+		t.Fatal("expected a connection to the secondary")
+	}
+	if primaryConn.IsValid() {
+		t.Error("connection to a down target is still valid")
+	}
+	if st := c.Targets()[0]; st.State != Down || st.ConsecutiveFailures != 2 {
+		t.Errorf("unexpected primary state %+v", st)
+	}
+
+	primary.setDown(false)
+	waitFor(t, func() bool { return c.Targets()[0].State == Up })
+	if secondaryConn.IsValid() {
+		t.Error("connection to the secondary is still valid after fail back")
+	}
+	// the prober connected to the primary too
+	before := primary.Conns()
+	if _, err := c.Connect(ctx); err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+	if primary.Conns() != before+1 {
+		t.Error("expected to fail back to the primary")
+	}
+
+	got := evs.get()
+	if len(got) != 2 || got[0].To != Down || got[1].To != Up || got[0].Target != 0 {
+		t.Errorf("unexpected events %+v", got)
+	}
+}
@@ -0,0 +1,95 @@
+package failover
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ngrok/sqlmw/internal/fakedb"
+)
+
+// flakyTarget is a fakedb.Connector whose availability can be toggled.
+type flakyTarget struct {
+	fakedb.Connector
+	down int32
+}
+
+func newFlakyTarget() *flakyTarget {
+	t := &flakyTarget{}
+	t.OnConnect = func(context.Context) error {
+		if atomic.LoadInt32(&t.down) == 1 {
+			return errors.New("connection refused")
+		}
+		return nil
+	}
+	t.Handler = func(_ context.Context, query string, _ []driver.NamedValue) (*fakedb.Response, error) {
+		if atomic.LoadInt32(&t.down) == 1 {
+			return nil, errors.New("connection reset")
+		}
+		return nil, nil
+	}
+	return t
+}
+
+func (t *flakyTarget) setDown(down bool) {
+	v := int32(0)
+	if down {
+		v = 1
+	}
+	atomic.StoreInt32(&t.down, v)
+}
+
+type events struct {
+	mu  sync.Mutex
+	evs []Event
+}
+
+func (e *events) add(ev Event) {
+	e.mu.Lock()
+	e.evs = append(e.evs, ev)
+	e.mu.Unlock()
+}
+
+func (e *events) get() []Event {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]Event(nil), e.evs...)
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestFailoverAllDown(t *testing.T) {
+	primary, secondary := newFlakyTarget(), newFlakyTarget()
+	primary.setDown(true)
+	secondary.setDown(true)
+	c := New(Config{Targets: []driver.Connector{&primary.Connector, &secondary.Connector}, MaxFailures: 1})
+	defer c.Close()
+
+	for i := 0; i < 2; i++ {
+		if _, err := c.Connect(context.Background()); err == nil {
+			t.Fatal("expected Connect to fail")
+		}
+	}
+
+	// down targets are still tried as a last resort
+	secondary.setDown(false)
+	if _, err := c.Connect(context.Background()); err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+	if st := c.Targets()[1]; st.State != Up {
+		t.Errorf("expected the secondary to be up, got %+v", st)
+	}
+}
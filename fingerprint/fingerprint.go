@@ -0,0 +1,432 @@
+// Package fingerprint computes a stable identity for SQL statements.
+//
+// Statements differing only by their literal values, their placeholder
+// style, the length of their IN lists, their comments, their whitespace or
+// the case of their keywords share the same fingerprint:
+//
+//	SELECT * FROM users WHERE id IN (1, 2, 3) -- admin
+//	select *  from Users where ID in ($1, $2)
+//
+// both normalize to
+//
+//	select * from users where id in(...)
+//
+// Quoted identifiers keep their case. Backslashes escape quotes in the
+// literals of the MySQL dialect only, see OfDialect, and in the E'...'
+// literals of every dialect. The normalization is a single pass
+// over the statement and Hash does not allocate, so that it can be used on
+// every call of an interceptor:
+//
+//	func (in *metrics) StmtExecContext(ctx context.Context, stmt driver.StmtExecContext, query string, args []driver.NamedValue) (driver.Result, error) {
+//		fp := fingerprint.Hash(query)
+//		...
+//	}
+package fingerprint
+
+import (
+	"strconv"
+
+	"github.com/ngrok/sqlmw/sqlinfo"
+)
+
+// Fingerprint identifies a normalized statement.
+type Fingerprint struct {
+	// Hash is the 64-bit FNV-1a hash of Normalized.
+	Hash uint64
+
+	// Normalized is the normalized statement text.
+	Normalized string
+}
+
+// String returns the hexadecimal form of the hash.
+func (f Fingerprint) String() string {
+	return Hex(f.Hash)
+}
+
+// Hex formats a hash as 16 hexadecimal digits.
+func Hex(hash uint64) string {
+	s := strconv.FormatUint(hash, 16)
+	for len(s) < 16 {
+		s = "0" + s
+	}
+	return s
+}
+
+// Of returns the fingerprint of query, in the sqlinfo.Generic dialect.
+func Of(query string) Fingerprint {
+	return OfDialect(sqlinfo.Generic, query)
+}
+
+// Hash returns the hash of the normalized form of query, in the
+// sqlinfo.Generic dialect, without allocating.
+func Hash(query string) uint64 {
+	return HashDialect(sqlinfo.Generic, query)
+}
+
+// Normalize returns the normalized form of query, in the sqlinfo.Generic
+// dialect.
+func Normalize(query string) string {
+	return Of(query).Normalized
+}
+
+// OfDialect returns the fingerprint of query in dialect d.
+func OfDialect(d sqlinfo.Dialect, query string) Fingerprint {
+	n := normalizer{keep: true, hash: offset64, buf: make([]byte, 0, len(query)), backslash: d == sqlinfo.MySQL}
+	n.run(query)
+	return Fingerprint{Hash: n.hash, Normalized: string(n.buf)}
+}
+
+// HashDialect returns the hash of the normalized form of query in dialect
+// d, without allocating.
+func HashDialect(d sqlinfo.Dialect, query string) uint64 {
+	n := normalizer{hash: offset64, backslash: d == sqlinfo.MySQL}
+	n.run(query)
+	return n.hash
+}
+
+// NormalizeDialect returns the normalized form of query in dialect d.
+func NormalizeDialect(d sqlinfo.Dialect, query string) string {
+	return OfDialect(d, query).Normalized
+}
+
+const (
+	offset64 = 14695981039346656037
+	prime64  = 1099511628211
+)
+
+type kind uint8
+
+const (
+	kindEOF kind = iota
+	kindWord
+	kindQuoted // quoted identifier, keeps its case
+	kindValue  // literal or placeholder
+	kindPunct
+)
+
+type token struct {
+	kind       kind
+	start, end int
+}
+
+// normalizer writes the normalized form of a statement into an FNV-1a hash
+// and, when keep is set, into buf.
+type normalizer struct {
+	keep bool
+	// backslash escapes quotes in every literal, see scan
+	backslash bool
+	hash      uint64
+	buf       []byte
+	prev      kind
+	last      byte // last byte of the previous punctuation token
+}
+
+func (n *normalizer) writeByte(c byte) {
+	n.hash ^= uint64(c)
+	n.hash *= prime64
+	if n.keep {
+		n.buf = append(n.buf, c)
+	}
+}
+
+func (n *normalizer) writeString(s string) {
+	for i := 0; i < len(s); i++ {
+		n.writeByte(s[i])
+	}
+}
+
+func (n *normalizer) writeLower(s string) {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' {
+			c += 'a' - 'A'
+		}
+		n.writeByte(c)
+	}
+}
+
+func (n *normalizer) run(q string) {
+	pos := 0
+	for {
+		tok, next := scan(q, pos, n.backslash)
+		if tok.kind == kindEOF {
+			return
+		}
+		text := q[tok.start:tok.end]
+		if tok.kind == kindPunct && text == ";" {
+			if t, _ := scan(q, next, n.backslash); t.kind == kindEOF {
+				// trailing semicolon
+				return
+			}
+		}
+
+		n.space(tok.kind, text)
+		switch tok.kind {
+		case kindWord:
+			n.writeLower(text)
+			if equalFold(text, "in") {
+				if end, ok := valueList(q, next, n.backslash); ok {
+					n.writeString("(...)")
+					n.prev, n.last = kindPunct, ')'
+					pos = end
+					continue
+				}
+			}
+		case kindQuoted:
+			n.writeString(text)
+		case kindValue:
+			n.writeByte('?')
+		case kindPunct:
+			n.writeString(text)
+			n.last = text[len(text)-1]
+		}
+		n.prev = tok.kind
+		pos = next
+	}
+}
+
+// space writes the single space separating the previous token from the next
+// one, when they need one.
+func (n *normalizer) space(k kind, text string) {
+	switch {
+	case n.prev == kindEOF:
+		return
+	case n.prev == kindPunct && (n.last == '(' || n.last == '.'):
+		return
+	case k == kindPunct && (text == ")" || text == "," || text == "." || text == ";"):
+		return
+	case k == kindPunct && text == "(" && (n.prev == kindWord || n.prev == kindQuoted):
+		return
+	}
+	n.writeByte(' ')
+}
+
+// valueList reports whether the tokens starting at pos are a parenthesized
+// list of literals and placeholders, and returns the position following it.
+func valueList(q string, pos int, backslash bool) (int, bool) {
+	tok, pos := scan(q, pos, backslash)
+	if tok.kind != kindPunct || q[tok.start:tok.end] != "(" {
+		return 0, false
+	}
+	for {
+		tok, pos = scan(q, pos, backslash)
+		if tok.kind != kindValue {
+			return 0, false
+		}
+		tok, pos = scan(q, pos, backslash)
+		if tok.kind != kindPunct {
+			return 0, false
+		}
+		switch q[tok.start:tok.end] {
+		case ")":
+			return pos, true
+		case ",":
+		default:
+			return 0, false
+		}
+	}
+}
+
+// scan returns the token starting at or after pos, skipping whitespace and
+// comments, and the position following it. backslash enables the backslash
+// escapes of MySQL literals.
+func scan(q string, pos int, backslash bool) (token, int) {
+	i := pos
+	for i < len(q) {
+		c := q[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f':
+			i++
+			continue
+		case c == '-' && i+1 < len(q) && q[i+1] == '-':
+			for i < len(q) && q[i] != '\n' {
+				i++
+			}
+			continue
+		case c == '/' && i+1 < len(q) && q[i+1] == '*':
+			i = skipBlockComment(q, i)
+			continue
+		}
+		break
+	}
+	if i >= len(q) {
+		return token{kind: kindEOF}, i
+	}
+
+	start := i
+	c := q[i]
+	switch {
+	case c == '\'':
+		return token{kindValue, start, i}, skipQuoted(q, i, '\'', backslash)
+	case (c == 'e' || c == 'E') && i+1 < len(q) && q[i+1] == '\'':
+		// E'...' literals always have backslash escapes
+		return token{kindValue, start, i}, skipQuoted(q, i+1, '\'', true)
+	case (c == 'x' || c == 'X' || c == 'b' || c == 'B' || c == 'n' || c == 'N') && i+1 < len(q) && q[i+1] == '\'':
+		// X'...', B'...' and N'...' literals
+		return token{kindValue, start, i}, skipQuoted(q, i+1, '\'', backslash)
+	case c == '"' && backslash:
+		// MySQL "..." literals
+		return token{kindValue, start, i}, skipQuoted(q, i, '"', true)
+	case c == '"' || c == '`':
+		end := skipQuoted(q, i, c, false)
+		return token{kindQuoted, start, end}, end
+	case c == '[':
+		end := i + 1
+		for end < len(q) && q[end] != ']' {
+			end++
+		}
+		if end < len(q) {
+			end++
+		}
+		return token{kindQuoted, start, end}, end
+	case isDigit(c) || (c == '.' && i+1 < len(q) && isDigit(q[i+1])):
+		return token{kindValue, start, i}, skipNumber(q, i)
+	case c == '?':
+		i++
+		for i < len(q) && isDigit(q[i]) {
+			i++
+		}
+		return token{kindValue, start, i}, i
+	case c == '$':
+		if i+1 < len(q) && isDigit(q[i+1]) {
+			i++
+			for i < len(q) && isDigit(q[i]) {
+				i++
+			}
+			return token{kindValue, start, i}, i
+		}
+		if end, ok := skipDollarQuoted(q, i); ok {
+			return token{kindValue, start, i}, end
+		}
+	case (c == ':' || c == '@') && i+1 < len(q) && isWordStart(q[i+1]) && (i == 0 || q[i-1] != ':'):
+		// :name and @name placeholders, but not the :: cast operator
+		i++
+		for i < len(q) && isWordByte(q[i]) {
+			i++
+		}
+		return token{kindValue, start, i}, i
+	case isWordStart(c):
+		for i < len(q) && isWordByte(q[i]) {
+			i++
+		}
+		return token{kindWord, start, i}, i
+	}
+
+	// punctuation, with the usual two character operators kept together
+	if i+1 < len(q) {
+		switch q[i : i+2] {
+		case "<=", ">=", "<>", "!=", "::", "||", "->", "=>":
+			if q[i:i+2] == "->" && i+2 < len(q) && q[i+2] == '>' {
+				return token{kindPunct, start, i + 3}, i + 3
+			}
+			return token{kindPunct, start, i + 2}, i + 2
+		}
+	}
+	return token{kindPunct, start, i + 1}, i + 1
+}
+
+func skipBlockComment(q string, i int) int {
+	for j := i + 2; j+1 < len(q); j++ {
+		if q[j] == '*' && q[j+1] == '/' {
+			return j + 2
+		}
+	}
+	return len(q)
+}
+
+// skipQuoted returns the position following the quoted text starting at i.
+// Quotes are escaped by doubling them, or with a backslash when backslash is
+// set.
+func skipQuoted(q string, i int, quote byte, backslash bool) int {
+	for j := i + 1; j < len(q); j++ {
+		switch q[j] {
+		case '\\':
+			if backslash {
+				j++
+			}
+		case quote:
+			if j+1 < len(q) && q[j+1] == quote {
+				j++
+				continue
+			}
+			return j + 1
+		}
+	}
+	return len(q)
+}
+
+// skipDollarQuoted skips a PostgreSQL $tag$...$tag$ literal.
+func skipDollarQuoted(q string, i int) (int, bool) {
+	j := i + 1
+	for j < len(q) && isWordStart(q[j]) {
+		j++
+	}
+	if j >= len(q) || q[j] != '$' {
+		return 0, false
+	}
+	tag := q[i : j+1]
+	for k := j + 1; k+len(tag) <= len(q); k++ {
+		if q[k] == '$' && q[k:k+len(tag)] == tag {
+			return k + len(tag), true
+		}
+	}
+	return len(q), true
+}
+
+func skipNumber(q string, i int) int {
+	if q[i] == '0' && i+1 < len(q) && (q[i+1] == 'x' || q[i+1] == 'X') {
+		i += 2
+		for i < len(q) && isHex(q[i]) {
+			i++
+		}
+		return i
+	}
+	for i < len(q) && (isDigit(q[i]) || q[i] == '.') {
+		i++
+	}
+	if i < len(q) && (q[i] == 'e' || q[i] == 'E') {
+		j := i + 1
+		if j < len(q) && (q[j] == '+' || q[j] == '-') {
+			j++
+		}
+		if j < len(q) && isDigit(q[j]) {
+			i = j
+			for i < len(q) && isDigit(q[i]) {
+				i++
+			}
+		}
+	}
+	return i
+}
+
+func equalFold(s, lower string) bool {
+	if len(s) != len(lower) {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' {
+			c += 'a' - 'A'
+		}
+		if c != lower[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func isWordStart(c byte) bool {
+	return c == '_' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || c >= 0x80
+}
+
+func isWordByte(c byte) bool {
+	return isWordStart(c) || isDigit(c) || c == '$'
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+func isHex(c byte) bool {
+	return isDigit(c) || ('a' <= c && c <= 'f') || ('A' <= c && c <= 'F')
+}
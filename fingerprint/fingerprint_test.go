@@ -0,0 +1,126 @@
+package fingerprint
+
+import (
+	"testing"
+
+	"github.com/ngrok/sqlmw/sqlinfo"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		query    string
+		expected string
+	}{
+		{
+			query:    "SELECT * FROM users WHERE id IN (1, 2, 3) -- admin",
+			expected: "select * from users where id in(...)",
+		},
+		{
+			query:    "select *\n\tfrom Users where ID in ($1, $2);",
+			expected: "select * from users where id in(...)",
+		},
+		{
+			query:    "/* app=web */ SELECT name FROM users WHERE email = 'bob@example.com' AND age > 30.5e2",
+			expected: "select name from users where email = ? and age > ?",
+		},
+		{
+			query:    "SELECT name FROM users WHERE email = :email AND org = @org AND id = ?",
+			expected: "select name from users where email = ? and org = ? and id = ?",
+		},
+		{
+			query:    `SELECT "Name", count(*) FROM "Users" GROUP BY "Name"`,
+			expected: `select "Name", count(*) from "Users" group by "Name"`,
+		},
+		{
+			query:    "SELECT created::date FROM t WHERE note = E'it\\'s' OR body = $$a 'b'$$ OR x = X'ff'",
+			expected: "select created :: date from t where note = ? or body = ? or x = ?",
+		},
+		{
+			query:    "SELECT id FROM t WHERE id IN (SELECT id FROM u WHERE v IN (1, 2))",
+			expected: "select id from t where id in(select id from u where v in(...))",
+		},
+		{
+			query:    "SELECT a.b FROM t a WHERE c <> 'it''s' AND d >= 0x1F",
+			expected: "select a.b from t a where c <> ? and d >= ?",
+		},
+	}
+
+	for _, test := range tests {
+		fp := Of(test.query)
+		if fp.Normalized != test.expected {
+			t.Errorf("unexpected normalization of %q:\n got: %s\nwant: %s", test.query, fp.Normalized, test.expected)
+		}
+		if h := Hash(test.query); h != fp.Hash {
+			t.Errorf("Hash(%q) = %x, Of returned %x", test.query, h, fp.Hash)
+		}
+	}
+}
+
+func TestNormalizeDialect(t *testing.T) {
+	tests := []struct {
+		dialect  sqlinfo.Dialect
+		query    string
+		expected string
+	}{
+		{
+			dialect:  sqlinfo.MySQL,
+			query:    `SELECT id FROM t WHERE a = 'it\'s' AND b = 'c:\\' AND c = "d\"" AND e = 1`,
+			expected: "select id from t where a = ? and b = ? and c = ? and e = ?",
+		},
+		{
+			dialect:  sqlinfo.Generic,
+			query:    `SELECT id FROM t WHERE b = 'c:\' AND c = 1`,
+			expected: "select id from t where b = ? and c = ?",
+		},
+		{
+			dialect:  sqlinfo.PostgreSQL,
+			query:    `SELECT id FROM t WHERE b = 'c:\' AND c = E'\'' AND d = 1`,
+			expected: "select id from t where b = ? and c = ? and d = ?",
+		},
+	}
+
+	for _, test := range tests {
+		fp := OfDialect(test.dialect, test.query)
+		if fp.Normalized != test.expected {
+			t.Errorf("unexpected normalization of %q:\n got: %s\nwant: %s", test.query, fp.Normalized, test.expected)
+		}
+		if h := HashDialect(test.dialect, test.query); h != fp.Hash {
+			t.Errorf("HashDialect(%q) = %x, OfDialect returned %x", test.query, h, fp.Hash)
+		}
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	a := Of("SELECT * FROM users WHERE id IN (1, 2, 3)")
+	b := Of("select * from users where id in (?)")
+	if a != b {
+		t.Errorf("expected identical fingerprints, got %+v and %+v", a, b)
+	}
+	if c := Of("select * from accounts where id in (?)"); c.Hash == a.Hash {
+		t.Errorf("expected different fingerprints for %q and %q", a.Normalized, c.Normalized)
+	}
+	if s := a.String(); len(s) != 16 || s != Hex(a.Hash) {
+		t.Errorf("unexpected string %q", s)
+	}
+}
+
+const benchQuery = `SELECT u.id, u.name, o.total
+FROM users u JOIN orders o ON o.user_id = u.id -- recent orders
+WHERE u.org_id = $1 AND o.status IN ('paid', 'shipped', 'refunded') AND o.created_at > $2
+ORDER BY o.created_at DESC LIMIT 50`
+
+func BenchmarkHash(b *testing.B) {
+	b.ReportAllocs()
+	b.SetBytes(int64(len(benchQuery)))
+	for i := 0; i < b.N; i++ {
+		Hash(benchQuery)
+	}
+}
+
+func BenchmarkOf(b *testing.B) {
+	b.ReportAllocs()
+	b.SetBytes(int64(len(benchQuery)))
+	for i := 0; i < b.N; i++ {
+		Of(benchQuery)
+	}
+}
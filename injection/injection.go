@@ -0,0 +1,346 @@
+// Package injection provides an sqlmw.Interceptor flagging the statements
+// that look like they were built by concatenating untrusted input, as a
+// defense in depth against SQL injections. It looks for these signals:
+//
+//   - Literal: a statement holds string literals although statements of the
+//     same fingerprint were seen with placeholders only.
+//   - Stacked: a statement is followed by another one.
+//   - Tautology: a condition that always holds follows an OR, as in
+//     "OR 1=1" or "OR 'a'='a'".
+//   - Truncation: a line comment ends the statement, as in "admin'--".
+//   - Unknown: the fingerprint of a statement is not part of the allowlist,
+//     when one is set.
+//
+// In the Train mode, nothing is flagged and the fingerprints of the
+// statements are learnt, to be exported with allowlist.Write and loaded in
+// Config.Allowlist, whose statements are not flagged with the Literal
+// signal. As fingerprints leave out comments and literals, the Stacked,
+// Tautology and Truncation signals still apply to them, and the MySQL
+// statements with executable comments, /*!...*/ and /*+...*/, whose code is
+// read as such, are flagged with the Unknown signal. The Log, Alert and
+// Block modes report the flagged statements, and Block fails them.
+package injection
+
+import (
+	"context"
+	"database/sql/driver"
+	"strings"
+	"sync"
+
+	"github.com/ngrok/sqlmw"
+	"github.com/ngrok/sqlmw/fingerprint"
+	"github.com/ngrok/sqlmw/sqlinfo"
+)
+
+const defaultHistorySize = 10000
+
+// Mode is what an Interceptor does with the statements it flags.
+type Mode int
+
+const (
+	// Log reports the flagged statements to Config.OnDetect.
+	Log Mode = iota
+	// Alert reports them to Config.OnAlert as well.
+	Alert
+	// Block reports them to Config.OnDetect and Config.OnAlert, and fails
+	// them with an *Error.
+	Block
+	// Train learns the fingerprints of the statements, see allowlist.Write.
+	Train
+)
+
+// Signal is a hint that a statement was injected.
+type Signal int
+
+const (
+	// Literal is a string literal where the statements of the same
+	// fingerprint have placeholders.
+	Literal Signal = iota + 1
+	// Stacked is a statement followed by another one.
+	Stacked
+	// Tautology is a condition that always holds following an OR.
+	Tautology
+	// Truncation is a line comment ending the statement.
+	Truncation
+	// Unknown is a fingerprint that is not part of the allowlist.
+	Unknown
+)
+
+func (s Signal) String() string {
+	switch s {
+	case Literal:
+		return "literal"
+	case Stacked:
+		return "stacked"
+	case Tautology:
+		return "tautology"
+	case Truncation:
+		return "truncation"
+	case Unknown:
+		return "unknown"
+	}
+	return "invalid"
+}
+
+// Detection is a flagged statement.
+type Detection struct {
+	Query       string
+	Fingerprint string
+	Signals     []Signal
+}
+
+// Error is the error of the statements blocked by an Interceptor.
+type Error struct {
+	Detection
+}
+
+func (e *Error) Error() string {
+	signals := make([]string, len(e.Signals))
+	for i, s := range e.Signals {
+		signals[i] = s.String()
+	}
+	return "injection: statement blocked (" + strings.Join(signals, ", ") + ")"
+}
+
+// Config configures an Interceptor.
+type Config struct {
+	// Mode is what to do with the flagged statements. Defaults to Log.
+	Mode Mode
+
+	// OnDetect, if set, is called with every flagged statement.
+	OnDetect func(Detection)
+
+	// OnAlert, if set, is called with every flagged statement in the Alert
+	// and Block modes.
+	OnAlert func(Detection)
+
+	// Allowlist are the fingerprints of the statements that are not
+	// flagged with the Literal signal, see allowlist.Read. When set, the
+	// other statements are flagged with the Unknown signal.
+	Allowlist []string
+
+	// HistorySize bounds the number of fingerprints whose use of
+	// placeholders is remembered, for the Literal signal. Defaults to
+	// 10000.
+	HistorySize int
+
+	// Dialect is used to read the statements. Defaults to sqlinfo.Generic.
+	Dialect sqlinfo.Dialect
+}
+
+// Interceptor flags the statements that look injected.
+type Interceptor struct {
+	sqlmw.NullInterceptor
+
+	cfg       Config
+	allowlist map[string]bool
+
+	mu sync.RWMutex
+	// parameterized tells whether the statements of a fingerprint were
+	// seen with placeholders only
+	parameterized map[string]bool
+	learnt        map[string]bool
+}
+
+// New returns an Interceptor for the supplied configuration.
+func New(cfg Config) *Interceptor {
+	if cfg.HistorySize <= 0 {
+		cfg.HistorySize = defaultHistorySize
+	}
+	in := &Interceptor{
+		cfg:           cfg,
+		parameterized: make(map[string]bool),
+		learnt:        make(map[string]bool),
+	}
+	if len(cfg.Allowlist) > 0 {
+		in.allowlist = make(map[string]bool)
+		for _, fp := range cfg.Allowlist {
+			in.allowlist[fp] = true
+		}
+	}
+	return in
+}
+
+// Learnt returns the fingerprints learnt in the Train mode.
+func (in *Interceptor) Learnt() []string {
+	in.mu.RLock()
+	defer in.mu.RUnlock()
+	fps := make([]string, 0, len(in.learnt))
+	for fp := range in.learnt {
+		fps = append(fps, fp)
+	}
+	return fps
+}
+
+// check flags query and returns the error to fail it with.
+func (in *Interceptor) check(query string) error {
+	fp := fingerprint.NormalizeDialect(in.cfg.Dialect, query)
+	if in.cfg.Mode == Train {
+		in.mu.Lock()
+		in.learnt[fp] = true
+		in.mu.Unlock()
+		in.signals(fp, query)
+		return nil
+	}
+	// the fingerprint leaves out comments and literals: an allowlisted one
+	// only vouches for the use of literals, not for the structure
+	signals := in.signals(fp, query)
+	if allowed := in.allowlist[fp] && !in.executable(query); allowed {
+		signals = without(signals, Literal)
+	} else if in.allowlist != nil {
+		signals = append(signals, Unknown)
+	}
+	if len(signals) == 0 {
+		return nil
+	}
+
+	d := Detection{Query: query, Fingerprint: fp, Signals: signals}
+	if in.cfg.OnDetect != nil {
+		in.cfg.OnDetect(d)
+	}
+	if in.cfg.Mode != Log && in.cfg.OnAlert != nil {
+		in.cfg.OnAlert(d)
+	}
+	if in.cfg.Mode == Block {
+		return &Error{d}
+	}
+	return nil
+}
+
+// signals returns the signals of query, whose fingerprint is fp, and
+// updates the history of fp.
+func (in *Interceptor) signals(fp, query string) []Signal {
+	toks := sqlinfo.Tokens(in.cfg.Dialect, query)
+
+	var signals []Signal
+	literals, placeholders := 0, 0
+	stacked, tautology := false, false
+	end := false
+	for i, tok := range toks {
+		switch tok.Kind {
+		case sqlinfo.Comment:
+			continue
+		case sqlinfo.String:
+			literals++
+		case sqlinfo.Placeholder:
+			placeholders++
+		}
+		if end {
+			stacked = true
+		}
+		if tok.Kind == sqlinfo.Punct && tok.Text == ";" {
+			end = true
+		}
+		if tok.Is("or") && alwaysTrue(toks[i+1:]) {
+			tautology = true
+		}
+	}
+
+	in.mu.Lock()
+	parameterized, seen := in.parameterized[fp]
+	switch {
+	case !seen && len(in.parameterized) < in.cfg.HistorySize:
+		in.parameterized[fp] = literals == 0 && placeholders > 0
+	case seen && parameterized && literals > 0:
+		signals = append(signals, Literal)
+	}
+	in.mu.Unlock()
+
+	if stacked {
+		signals = append(signals, Stacked)
+	}
+	if tautology {
+		signals = append(signals, Tautology)
+	}
+	if n := len(toks); n > 0 && toks[n-1].Kind == sqlinfo.Comment && !toks[n-1].Executable() && !strings.HasPrefix(toks[n-1].Text, "/*") {
+		signals = append(signals, Truncation)
+	}
+	return signals
+}
+
+// executable reports whether query has MySQL executable comments, which
+// its fingerprint drops.
+func (in *Interceptor) executable(query string) bool {
+	if in.cfg.Dialect != sqlinfo.MySQL {
+		return false
+	}
+	l := sqlinfo.NewLexer(in.cfg.Dialect, query)
+	for {
+		tok, ok := l.Next()
+		if !ok {
+			return false
+		}
+		if tok.Executable() {
+			return true
+		}
+	}
+}
+
+// without returns signals without s.
+func without(signals []Signal, s Signal) []Signal {
+	out := signals[:0]
+	for _, sig := range signals {
+		if sig != s {
+			out = append(out, sig)
+		}
+	}
+	return out
+}
+
+// alwaysTrue reports whether the condition starting at toks always holds:
+// TRUE, a non zero number, or the comparison of a literal or a column to
+// itself.
+func alwaysTrue(toks []sqlinfo.Token) bool {
+	var cond []sqlinfo.Token
+	for _, tok := range toks {
+		if tok.Kind == sqlinfo.Comment {
+			continue
+		}
+		if len(cond) == 3 {
+			break
+		}
+		cond = append(cond, tok)
+	}
+	if len(cond) == 0 {
+		return false
+	}
+	if len(cond) == 3 && cond[1].Kind == sqlinfo.Punct && cond[1].Text == "=" {
+		a, b := cond[0], cond[2]
+		switch a.Kind {
+		case sqlinfo.String, sqlinfo.Number:
+			return b.Kind == a.Kind && b.Text == a.Text
+		case sqlinfo.Word, sqlinfo.QuotedIdent:
+			return b.Kind == a.Kind && b.Ident() == a.Ident()
+		}
+		return false
+	}
+	first := cond[0]
+	if first.Is("true") || (first.Kind == sqlinfo.Number && strings.Trim(first.Text, "0.") != "") {
+		// a condition on its own, not the operand of an expression
+		return len(cond) == 1 || cond[1].Kind != sqlinfo.Punct || cond[1].Text == ")" || cond[1].Text == ";"
+	}
+	return false
+}
+
+func (in *Interceptor) ConnExecContext(ctx context.Context, conn driver.ExecerContext, query string, args []driver.NamedValue) (driver.Result, error) {
+	if err := in.check(query); err != nil {
+		return nil, err
+	}
+	return conn.ExecContext(ctx, query, args)
+}
+
+func (in *Interceptor) ConnQueryContext(ctx context.Context, conn driver.QueryerContext, query string, args []driver.NamedValue) (context.Context, driver.Rows, error) {
+	if err := in.check(query); err != nil {
+		return ctx, nil, err
+	}
+	rows, err := conn.QueryContext(ctx, query, args)
+	return ctx, rows, err
+}
+
+func (in *Interceptor) ConnPrepareContext(ctx context.Context, conn driver.ConnPrepareContext, query string) (context.Context, driver.Stmt, error) {
+	if err := in.check(query); err != nil {
+		return ctx, nil, err
+	}
+	stmt, err := conn.PrepareContext(ctx, query)
+	return ctx, stmt, err
+}
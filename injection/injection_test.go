@@ -0,0 +1,197 @@
+package injection
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/ngrok/sqlmw"
+	"github.com/ngrok/sqlmw/allowlist"
+	"github.com/ngrok/sqlmw/internal/fakedb"
+	"github.com/ngrok/sqlmw/sqlinfo"
+	"github.com/ngrok/sqlmw/sqlmwtest"
+)
+
+func TestSignals(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []Signal
+	}{
+		{"SELECT * FROM users WHERE name = ?", nil},
+		{"SELECT * FROM users WHERE id = 1 OR name = 'x'", nil},
+		{"SELECT * FROM users WHERE name = '' OR 'a'='a'", []Signal{Tautology}},
+		{"SELECT * FROM users WHERE id = 1 OR true", []Signal{Tautology}},
+		{"SELECT * FROM users WHERE id = 1 OR 1 > id", nil},
+		{"SELECT * FROM users WHERE id = 1; DROP TABLE users", []Signal{Stacked}},
+		{"SELECT * FROM users WHERE id = 1;", nil},
+		{"SELECT * FROM users WHERE name = 'admin'--' AND password = ''", []Signal{Truncation}},
+		{"SELECT * FROM users /* all */", nil},
+	}
+	for _, tt := range tests {
+		in := New(Config{})
+		if got := in.signals("", tt.query); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("signals(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+
+	// the bodies of MySQL executable comments are code
+	mysql := []struct {
+		query string
+		want  []Signal
+	}{
+		{"SELECT * FROM users WHERE id = 1 /*! OR 1=1 */", []Signal{Tautology}},
+		{"SELECT * FROM users WHERE id = 1 /*!50001 ; DROP TABLE users */", []Signal{Stacked}},
+		{"SELECT /*+ NO_ICP(users) */ * FROM users WHERE id = 1", nil},
+	}
+	for _, tt := range mysql {
+		in := New(Config{Dialect: sqlinfo.MySQL})
+		if got := in.signals("", tt.query); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("signals(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestLiteral(t *testing.T) {
+	in := New(Config{})
+	if got := in.check("SELECT * FROM users WHERE name = ?"); got != nil {
+		t.Fatalf("check failed: %v", got)
+	}
+	var detections []Detection
+	in.cfg.OnDetect = func(d Detection) { detections = append(detections, d) }
+	in.check("SELECT * FROM users WHERE name = 'bob'")
+	want := []Detection{{
+		Query:       "SELECT * FROM users WHERE name = 'bob'",
+		Fingerprint: "select * from users where name = ?",
+		Signals:     []Signal{Literal},
+	}}
+	if !reflect.DeepEqual(detections, want) {
+		t.Errorf("got detections %+v, want %+v", detections, want)
+	}
+}
+
+func TestModes(t *testing.T) {
+	const query = "DELETE FROM users WHERE id = 1 OR 1=1"
+	for _, mode := range []Mode{Log, Alert, Block} {
+		var detected, alerted int
+		in := New(Config{
+			Mode:     mode,
+			OnDetect: func(Detection) { detected++ },
+			OnAlert:  func(Detection) { alerted++ },
+		})
+		con := &fakedb.Connector{}
+		db := sql.OpenDB(sqlmw.Connector(con, in))
+		t.Cleanup(func() {
+			if err := db.Close(); err != nil {
+				t.Errorf("Failed to close db: %v", err)
+			}
+		})
+
+		_, err := db.ExecContext(context.Background(), query)
+		var blocked *Error
+		if got := errors.As(err, &blocked); got != (mode == Block) {
+			t.Errorf("mode %d: got error %v", mode, err)
+		}
+		if mode == Block && !reflect.DeepEqual(blocked.Signals, []Signal{Tautology}) {
+			t.Errorf("mode %d: got signals %v, want [tautology]", mode, blocked.Signals)
+		}
+		if calls := len(con.Calls()); calls != map[Mode]int{Log: 1, Alert: 1, Block: 0}[mode] {
+			t.Errorf("mode %d: got %d calls", mode, calls)
+		}
+		wantAlerted := 1
+		if mode == Log {
+			wantAlerted = 0
+		}
+		if detected != 1 || alerted != wantAlerted {
+			t.Errorf("mode %d: got %d detections and %d alerts", mode, detected, alerted)
+		}
+	}
+}
+
+func TestAllowlist(t *testing.T) {
+	train := New(Config{Mode: Train})
+	db := sql.OpenDB(sqlmw.Connector(&fakedb.Connector{}, train))
+	t.Cleanup(func() {
+		if err := db.Close(); err != nil {
+			t.Errorf("Failed to close db: %v", err)
+		}
+	})
+	ctx := context.Background()
+	for _, q := range []string{
+		"SELECT name FROM users WHERE id = ?",
+		"SELECT name FROM users WHERE id = $1",
+		"UPDATE users SET name = ? WHERE id = ?",
+		"SELECT 1; SELECT 2",
+	} {
+		if _, err := db.ExecContext(ctx, q, 1); err != nil {
+			t.Fatalf("Exec failed: %v", err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := allowlist.Write(&buf, train.Learnt()); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	want := "select ?; select ?\n" +
+		"select name from users where id = ?\n" +
+		"update users set name = ? where id = ?\n"
+	if got := buf.String(); got != want {
+		t.Fatalf("got allowlist %q, want %q", got, want)
+	}
+	fps, err := allowlist.Read(bytes.NewBufferString("# learnt\n\n" + want))
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if !sort.StringsAreSorted(fps) || len(fps) != 3 {
+		t.Fatalf("got allowlist %q", fps)
+	}
+
+	in := New(Config{Mode: Block, Allowlist: fps})
+	for _, tt := range []struct {
+		query string
+		want  []Signal
+	}{
+		{"SELECT name FROM users WHERE id = :id", nil},
+		{"SELECT name FROM users WHERE id = 'admin'", nil},
+		{"SELECT name FROM users WHERE id = 'admin' --' AND password = ''", []Signal{Truncation}},
+		{"SELECT 3; SELECT 4", []Signal{Stacked}},
+		{"DELETE FROM users", []Signal{Unknown}},
+		{"SELECT 1; DROP TABLE users", []Signal{Stacked, Unknown}},
+	} {
+		err := in.check(tt.query)
+		var blocked *Error
+		if tt.want == nil {
+			if err != nil {
+				t.Errorf("check(%q) failed: %v", tt.query, err)
+			}
+		} else if !errors.As(err, &blocked) || !reflect.DeepEqual(blocked.Signals, tt.want) {
+			t.Errorf("check(%q) = %v, want signals %v", tt.query, err, tt.want)
+		}
+	}
+
+	mysql := New(Config{Mode: Block, Allowlist: fps, Dialect: sqlinfo.MySQL})
+	for _, tt := range []struct {
+		query string
+		want  []Signal
+	}{
+		{`SELECT name FROM users WHERE id = 'it\'s'`, nil},
+		{"SELECT name FROM users WHERE id = 1 /*!UNION SELECT password FROM admins*/", []Signal{Unknown}},
+	} {
+		err := mysql.check(tt.query)
+		var blocked *Error
+		if tt.want == nil {
+			if err != nil {
+				t.Errorf("check(%q) failed: %v", tt.query, err)
+			}
+		} else if !errors.As(err, &blocked) || !reflect.DeepEqual(blocked.Signals, tt.want) {
+			t.Errorf("check(%q) = %v, want signals %v", tt.query, err, tt.want)
+		}
+	}
+}
+
+func TestConformance(t *testing.T) {
+	sqlmwtest.RunConformance(t, func() sqlmw.Interceptor { return New(Config{}) })
+}
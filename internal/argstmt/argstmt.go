@@ -0,0 +1,84 @@
+// Package argstmt provides a prepared statement transforming its arguments,
+// for the interceptors changing the query of a prepared statement.
+package argstmt
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+
+	"github.com/ngrok/sqlmw"
+)
+
+// Stmt is a prepared statement whose arguments are transformed before every
+// execution.
+type Stmt struct {
+	driver.Stmt
+
+	// Args transforms the arguments.
+	Args func(ctx context.Context, args []driver.NamedValue) ([]driver.NamedValue, error)
+
+	// Inputs, if set, returns the number of inputs reported to the caller
+	// given the number of inputs of the parent statement, which is never -1.
+	Inputs func(n int) int
+}
+
+// Compile time validation that our types implement the expected interfaces
+var (
+	_ driver.StmtExecContext  = &Stmt{}
+	_ driver.StmtQueryContext = &Stmt{}
+	_ sqlmw.StmtUnwrapper     = &Stmt{}
+)
+
+func (s *Stmt) Unwrap() driver.Stmt {
+	return s.Stmt
+}
+
+func (s *Stmt) NumInput() int {
+	n := s.Stmt.NumInput()
+	if n < 0 || s.Inputs == nil {
+		return n
+	}
+	return s.Inputs(n)
+}
+
+func (s *Stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	args, err := s.Args(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+	if execer, ok := s.Stmt.(driver.StmtExecContext); ok {
+		return execer.ExecContext(ctx, args)
+	}
+	dargs, err := namedValueToValue(args)
+	if err != nil {
+		return nil, err
+	}
+	return s.Stmt.Exec(dargs) //nolint:staticcheck
+}
+
+func (s *Stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	args, err := s.Args(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+	if queryer, ok := s.Stmt.(driver.StmtQueryContext); ok {
+		return queryer.QueryContext(ctx, args)
+	}
+	dargs, err := namedValueToValue(args)
+	if err != nil {
+		return nil, err
+	}
+	return s.Stmt.Query(dargs) //nolint:staticcheck
+}
+
+func namedValueToValue(named []driver.NamedValue) ([]driver.Value, error) {
+	dargs := make([]driver.Value, len(named))
+	for n, param := range named {
+		if len(param.Name) > 0 {
+			return nil, errors.New("sql: driver does not support the use of Named Parameters")
+		}
+		dargs[n] = param.Value
+	}
+	return dargs, nil
+}
@@ -0,0 +1,30 @@
+// Package ctxutil provides the context helpers shared by the sqlmw
+// interceptors.
+package ctxutil
+
+import (
+	"context"
+	"time"
+)
+
+// Detach returns a context carrying the values of ctx but neither its
+// deadline nor its cancellation.
+func Detach(ctx context.Context) context.Context {
+	return detached{ctx}
+}
+
+type detached struct {
+	context.Context
+}
+
+func (detached) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (detached) Done() <-chan struct{} {
+	return nil
+}
+
+func (detached) Err() error {
+	return nil
+}
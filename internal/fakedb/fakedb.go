@@ -0,0 +1,254 @@
+// Package fakedb provides a scriptable database/sql/driver implementation
+// used by the tests of the sqlmw subpackages.
+package fakedb
+
+import (
+	"context"
+	"database/sql/driver"
+	"io"
+	"sync"
+)
+
+// Response is the scripted answer to a single statement.
+type Response struct {
+	Columns      []string
+	Rows         [][]driver.Value
+	RowsAffected int64
+	LastInsertId int64
+}
+
+// Handler computes the Response for a statement. A nil Response is treated
+// as an empty one.
+type Handler func(ctx context.Context, query string, args []driver.NamedValue) (*Response, error)
+
+// Call records a statement received by a Connector.
+type Call struct {
+	Query string
+	Args  []driver.NamedValue
+}
+
+// Connector is both a driver.Connector and a driver.Driver. Every statement
+// run on one of its connections is recorded and answered by Handler.
+type Connector struct {
+	Handler Handler
+
+	// OnConnect, if set, is called for every new connection. Connect fails
+	// with its error.
+	OnConnect func(ctx context.Context) error
+
+	// NumInput, if set, returns the number of inputs of the prepared
+	// statements, which is -1 otherwise.
+	NumInput func(query string) int
+
+	mu    sync.Mutex
+	calls []Call
+	conns int
+}
+
+var (
+	_ driver.Connector = (*Connector)(nil)
+	_ driver.Driver    = (*Connector)(nil)
+)
+
+func (c *Connector) Connect(ctx context.Context) (driver.Conn, error) {
+	if c.OnConnect != nil {
+		if err := c.OnConnect(ctx); err != nil {
+			return nil, err
+		}
+	}
+	c.mu.Lock()
+	c.conns++
+	c.mu.Unlock()
+	return &conn{c: c}, nil
+}
+
+func (c *Connector) Driver() driver.Driver {
+	return c
+}
+
+func (c *Connector) Open(_ string) (driver.Conn, error) {
+	return c.Connect(context.Background())
+}
+
+// Calls returns a copy of every statement received so far.
+func (c *Connector) Calls() []Call {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Call(nil), c.calls...)
+}
+
+// Queries returns the query text of every statement received so far.
+func (c *Connector) Queries() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	qs := make([]string, len(c.calls))
+	for i, call := range c.calls {
+		qs[i] = call.Query
+	}
+	return qs
+}
+
+// Conns returns the number of connections opened so far.
+func (c *Connector) Conns() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.conns
+}
+
+func (c *Connector) handle(ctx context.Context, query string, args []driver.NamedValue) (*Response, error) {
+	c.mu.Lock()
+	c.calls = append(c.calls, Call{Query: query, Args: append([]driver.NamedValue(nil), args...)})
+	c.mu.Unlock()
+
+	if c.Handler == nil {
+		return &Response{}, nil
+	}
+	resp, err := c.Handler(ctx, query, args)
+	if err != nil {
+		return nil, err
+	}
+	if resp == nil {
+		resp = &Response{}
+	}
+	return resp, nil
+}
+
+type conn struct {
+	c *Connector
+}
+
+var (
+	_ driver.ConnBeginTx        = (*conn)(nil)
+	_ driver.ConnPrepareContext = (*conn)(nil)
+	_ driver.ExecerContext      = (*conn)(nil)
+	_ driver.QueryerContext     = (*conn)(nil)
+	_ driver.Pinger             = (*conn)(nil)
+)
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *conn) PrepareContext(_ context.Context, query string) (driver.Stmt, error) {
+	return &stmt{c: c.c, query: query}, nil
+}
+
+func (c *conn) Close() error { return nil }
+
+func (c *conn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *conn) BeginTx(ctx context.Context, _ driver.TxOptions) (driver.Tx, error) {
+	if _, err := c.c.handle(ctx, "BEGIN", nil); err != nil {
+		return nil, err
+	}
+	return &tx{c: c.c}, nil
+}
+
+func (c *conn) Ping(ctx context.Context) error {
+	_, err := c.c.handle(ctx, "PING", nil)
+	return err
+}
+
+func (c *conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	resp, err := c.c.handle(ctx, query, args)
+	if err != nil {
+		return nil, err
+	}
+	return result{resp}, nil
+}
+
+func (c *conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	resp, err := c.c.handle(ctx, query, args)
+	if err != nil {
+		return nil, err
+	}
+	return &rows{cols: resp.Columns, vals: resp.Rows}, nil
+}
+
+type stmt struct {
+	c     *Connector
+	query string
+}
+
+func (s *stmt) Close() error { return nil }
+
+func (s *stmt) NumInput() int {
+	if s.c.NumInput != nil {
+		return s.c.NumInput(s.query)
+	}
+	return -1
+}
+
+func (s *stmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.ExecContext(context.Background(), toNamed(args))
+}
+
+func (s *stmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.QueryContext(context.Background(), toNamed(args))
+}
+
+func (s *stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	resp, err := s.c.handle(ctx, s.query, args)
+	if err != nil {
+		return nil, err
+	}
+	return result{resp}, nil
+}
+
+func (s *stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	resp, err := s.c.handle(ctx, s.query, args)
+	if err != nil {
+		return nil, err
+	}
+	return &rows{cols: resp.Columns, vals: resp.Rows}, nil
+}
+
+type tx struct {
+	c *Connector
+}
+
+func (t *tx) Commit() error {
+	_, err := t.c.handle(context.Background(), "COMMIT", nil)
+	return err
+}
+
+func (t *tx) Rollback() error {
+	_, err := t.c.handle(context.Background(), "ROLLBACK", nil)
+	return err
+}
+
+type result struct {
+	resp *Response
+}
+
+func (r result) LastInsertId() (int64, error) { return r.resp.LastInsertId, nil }
+
+func (r result) RowsAffected() (int64, error) { return r.resp.RowsAffected, nil }
+
+type rows struct {
+	cols []string
+	vals [][]driver.Value
+}
+
+func (r *rows) Columns() []string { return r.cols }
+
+func (r *rows) Close() error { return nil }
+
+func (r *rows) Next(dest []driver.Value) error {
+	if len(r.vals) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.vals[0])
+	r.vals = r.vals[1:]
+	return nil
+}
+
+func toNamed(args []driver.Value) []driver.NamedValue {
+	nvs := make([]driver.NamedValue, len(args))
+	for i, v := range args {
+		nvs[i] = driver.NamedValue{Ordinal: i + 1, Value: v}
+	}
+	return nvs
+}
@@ -0,0 +1,68 @@
+// Package sqlquery provides the statement helpers shared by the sqlmw
+// interceptors.
+package sqlquery
+
+import (
+	"database/sql/driver"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// Key encodes query and args so that two statements share a key only when
+// their text and their arguments are identical.
+func Key(query string, args []driver.NamedValue) string {
+	var b strings.Builder
+	b.Grow(len(query) + 16*len(args))
+	writeString(&b, query)
+	var buf [8]byte
+	for _, a := range args {
+		writeString(&b, a.Name)
+		binary.LittleEndian.PutUint64(buf[:], uint64(a.Ordinal))
+		b.Write(buf[:])
+
+		switch v := a.Value.(type) {
+		case nil:
+			b.WriteByte('n')
+		case int64:
+			b.WriteByte('i')
+			binary.LittleEndian.PutUint64(buf[:], uint64(v))
+			b.Write(buf[:])
+		case float64:
+			b.WriteByte('f')
+			binary.LittleEndian.PutUint64(buf[:], math.Float64bits(v))
+			b.Write(buf[:])
+		case bool:
+			b.WriteByte('b')
+			if v {
+				b.WriteByte(1)
+			} else {
+				b.WriteByte(0)
+			}
+		case []byte:
+			b.WriteByte('y')
+			writeString(&b, string(v))
+		case string:
+			b.WriteByte('s')
+			writeString(&b, v)
+		case time.Time:
+			b.WriteByte('t')
+			writeString(&b, v.Format(time.RFC3339Nano))
+		default:
+			b.WriteByte('v')
+			writeString(&b, fmt.Sprintf("%T:%v", v, v))
+		}
+	}
+	return b.String()
+}
+
+// writeString writes s prefixed by its length so that the encoding is not
+// ambiguous.
+func writeString(b *strings.Builder, s string) {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], uint64(len(s)))
+	b.Write(buf[:])
+	b.WriteString(s)
+}
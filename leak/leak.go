@@ -0,0 +1,331 @@
+// Package leak provides an sqlmw.Interceptor detecting the rows, prepared
+// statements and transactions that are never closed, which hold on to their
+// connection until the pool is exhausted.
+//
+// The Interceptor records the stack and the time every object was created
+// at, and forgets them once they are closed, committed or rolled back. The
+// objects open longer than Config.Threshold are reported by Leaks, and to
+// Config.OnLeak when Config.Interval is set:
+//
+//	in := leak.New(leak.Config{Threshold: time.Minute, Interval: time.Minute, OnLeak: func(l leak.Leak) {
+//		log.Print(l)
+//	}})
+//
+// In tests, VerifyNone fails the test when an object is still open:
+//
+//	in := leak.New(leak.Config{})
+//	db := sql.OpenDB(sqlmw.Connector(connector, in))
+//	// ... run the code under test ...
+//	leak.VerifyNone(t, in)
+package leak
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ngrok/sqlmw"
+)
+
+const (
+	defaultThreshold  = 30 * time.Second
+	defaultStackDepth = 32
+	verifyTimeout     = 100 * time.Millisecond
+)
+
+// Kind is the kind of an object.
+type Kind int
+
+const (
+	Rows Kind = iota
+	Stmt
+	Tx
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Rows:
+		return "rows"
+	case Stmt:
+		return "statement"
+	case Tx:
+		return "transaction"
+	}
+	return "invalid"
+}
+
+// Leak is an object that is still open.
+type Leak struct {
+	Kind Kind
+	// Query is the query of the rows or of the statement.
+	Query   string
+	Created time.Time
+	// Stack is the stack the object was created at, without the frames of
+	// database/sql and sqlmw.
+	Stack []runtime.Frame
+}
+
+func (l Leak) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s", l.Kind)
+	if l.Query != "" {
+		fmt.Fprintf(&b, " of %q", l.Query)
+	}
+	fmt.Fprintf(&b, " open for %v, created at:", time.Since(l.Created).Round(time.Millisecond))
+	for _, f := range l.Stack {
+		fmt.Fprintf(&b, "\n\t%s\n\t\t%s:%d", f.Function, f.File, f.Line)
+	}
+	return b.String()
+}
+
+// Config configures an Interceptor.
+type Config struct {
+	// Threshold is the age from which the open objects are reported by
+	// Leaks. Defaults to 30s.
+	Threshold time.Duration
+
+	// Interval, if set, is the interval of a background check reporting the
+	// leaks to OnLeak. Every leak is reported once.
+	Interval time.Duration
+
+	// OnLeak is called with the leaks found by the background check.
+	OnLeak func(Leak)
+
+	// StackDepth bounds the number of frames recorded. Defaults to 32.
+	StackDepth int
+}
+
+// object is an open object.
+type object struct {
+	kind     Kind
+	query    string
+	created  time.Time
+	pcs      []uintptr
+	reported bool
+}
+
+func (o *object) leak() Leak {
+	l := Leak{Kind: o.kind, Query: o.query, Created: o.created}
+	frames := runtime.CallersFrames(o.pcs)
+	for {
+		f, more := frames.Next()
+		if !internal(f.Function) {
+			l.Stack = append(l.Stack, f)
+		}
+		if !more {
+			return l
+		}
+	}
+}
+
+// internal reports whether function is part of the machinery between the
+// application and the Interceptor.
+func internal(function string) bool {
+	for _, prefix := range []string{"database/sql.", "github.com/ngrok/sqlmw.", "runtime."} {
+		if strings.HasPrefix(function, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Interceptor tracks the open rows, statements and transactions.
+type Interceptor struct {
+	sqlmw.NullInterceptor
+
+	cfg Config
+
+	mu   sync.Mutex
+	open map[*object]struct{}
+
+	stop chan struct{}
+	once sync.Once
+	wg   sync.WaitGroup
+}
+
+// New returns an Interceptor for the supplied configuration, and starts its
+// background check when Config.Interval is set.
+func New(cfg Config) *Interceptor {
+	if cfg.Threshold <= 0 {
+		cfg.Threshold = defaultThreshold
+	}
+	if cfg.StackDepth <= 0 {
+		cfg.StackDepth = defaultStackDepth
+	}
+	in := &Interceptor{
+		cfg:  cfg,
+		open: make(map[*object]struct{}),
+		stop: make(chan struct{}),
+	}
+	if cfg.Interval > 0 {
+		in.wg.Add(1)
+		go in.check()
+	}
+	return in
+}
+
+// Close stops the background check.
+func (in *Interceptor) Close() error {
+	in.once.Do(func() { close(in.stop) })
+	in.wg.Wait()
+	return nil
+}
+
+func (in *Interceptor) check() {
+	defer in.wg.Done()
+	ticker := time.NewTicker(in.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-in.stop:
+			return
+		case <-ticker.C:
+		}
+		for _, l := range in.collect(in.cfg.Threshold, true) {
+			if in.cfg.OnLeak != nil {
+				in.cfg.OnLeak(l)
+			}
+		}
+	}
+}
+
+// Open returns every open object, oldest first.
+func (in *Interceptor) Open() []Leak {
+	return in.collect(0, false)
+}
+
+// Leaks returns the objects open longer than Config.Threshold, oldest
+// first.
+func (in *Interceptor) Leaks() []Leak {
+	return in.collect(in.cfg.Threshold, false)
+}
+
+// collect returns the objects open longer than age. When once is set, it
+// skips the objects reported before and marks the others as reported.
+func (in *Interceptor) collect(age time.Duration, once bool) []Leak {
+	now := time.Now()
+	var objs []*object
+	in.mu.Lock()
+	for o := range in.open {
+		if now.Sub(o.created) < age || (once && o.reported) {
+			continue
+		}
+		o.reported = o.reported || once
+		objs = append(objs, o)
+	}
+	in.mu.Unlock()
+
+	sort.Slice(objs, func(i, j int) bool { return objs[i].created.Before(objs[j].created) })
+	leaks := make([]Leak, len(objs))
+	for i, o := range objs {
+		leaks[i] = o.leak()
+	}
+	return leaks
+}
+
+type objectKey struct{}
+
+// track records the creation of an object and returns the context of its
+// hooks.
+func (in *Interceptor) track(ctx context.Context, kind Kind, query string) context.Context {
+	o := &object{kind: kind, query: query, created: time.Now(), pcs: make([]uintptr, in.cfg.StackDepth)}
+	// skip runtime.Callers, track and the hook
+	o.pcs = o.pcs[:runtime.Callers(3, o.pcs)]
+	in.mu.Lock()
+	in.open[o] = struct{}{}
+	in.mu.Unlock()
+	return context.WithValue(ctx, objectKey{}, o)
+}
+
+// release forgets the object of ctx.
+func (in *Interceptor) release(ctx context.Context) {
+	if o, ok := ctx.Value(objectKey{}).(*object); ok {
+		in.mu.Lock()
+		delete(in.open, o)
+		in.mu.Unlock()
+	}
+}
+
+func (in *Interceptor) ConnBeginTx(ctx context.Context, conn driver.ConnBeginTx, txOpts driver.TxOptions) (context.Context, driver.Tx, error) {
+	tx, err := conn.BeginTx(ctx, txOpts)
+	if err != nil {
+		return ctx, nil, err
+	}
+	return in.track(ctx, Tx, ""), tx, nil
+}
+
+func (in *Interceptor) ConnPrepareContext(ctx context.Context, conn driver.ConnPrepareContext, query string) (context.Context, driver.Stmt, error) {
+	stmt, err := conn.PrepareContext(ctx, query)
+	if err != nil {
+		return ctx, nil, err
+	}
+	return in.track(ctx, Stmt, query), stmt, nil
+}
+
+func (in *Interceptor) ConnQueryContext(ctx context.Context, conn driver.QueryerContext, query string, args []driver.NamedValue) (context.Context, driver.Rows, error) {
+	rows, err := conn.QueryContext(ctx, query, args)
+	if err != nil {
+		return ctx, nil, err
+	}
+	return in.track(ctx, Rows, query), rows, nil
+}
+
+func (in *Interceptor) StmtQueryContext(ctx context.Context, stmt driver.StmtQueryContext, query string, args []driver.NamedValue) (context.Context, driver.Rows, error) {
+	rows, err := stmt.QueryContext(ctx, args)
+	if err != nil {
+		return ctx, nil, err
+	}
+	return in.track(ctx, Rows, query), rows, nil
+}
+
+func (in *Interceptor) RowsClose(ctx context.Context, rows driver.Rows) error {
+	in.release(ctx)
+	return rows.Close()
+}
+
+func (in *Interceptor) StmtClose(ctx context.Context, stmt driver.Stmt) error {
+	in.release(ctx)
+	return stmt.Close()
+}
+
+func (in *Interceptor) TxCommit(ctx context.Context, tx driver.Tx) error {
+	in.release(ctx)
+	return tx.Commit()
+}
+
+func (in *Interceptor) TxRollback(ctx context.Context, tx driver.Tx) error {
+	in.release(ctx)
+	return tx.Rollback()
+}
+
+// TestingT is the subset of testing.TB used by VerifyNone.
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+}
+
+// VerifyNone fails the test when objects tracked by in are still open,
+// whatever their age. It waits briefly for the objects that database/sql
+// closes in the background.
+func VerifyNone(t TestingT, in *Interceptor) {
+	t.Helper()
+	deadline := time.Now().Add(verifyTimeout)
+	for {
+		open := in.Open()
+		if len(open) == 0 {
+			return
+		}
+		if time.Now().After(deadline) {
+			for _, l := range open {
+				t.Errorf("leak: %v", l)
+			}
+			return
+		}
+		time.Sleep(verifyTimeout / 10)
+	}
+}
@@ -0,0 +1,168 @@
+package leak
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ngrok/sqlmw"
+	"github.com/ngrok/sqlmw/internal/fakedb"
+	"github.com/ngrok/sqlmw/sqlmwtest"
+)
+
+func openDB(t *testing.T, in *Interceptor) *sql.DB {
+	con := &fakedb.Connector{Handler: func(ctx context.Context, query string, args []driver.NamedValue) (*fakedb.Response, error) {
+		return &fakedb.Response{Columns: []string{"a"}, Rows: [][]driver.Value{{int64(1)}}}, nil
+	}}
+	db := sql.OpenDB(sqlmw.Connector(con, in))
+	t.Cleanup(func() {
+		if err := db.Close(); err != nil {
+			t.Errorf("Failed to close db: %v", err)
+		}
+	})
+	return db
+}
+
+func kinds(leaks []Leak) []Kind {
+	var ks []Kind
+	for _, l := range leaks {
+		ks = append(ks, l.Kind)
+	}
+	return ks
+}
+
+func TestOpen(t *testing.T) {
+	in := New(Config{})
+	db := openDB(t, in)
+
+	rows, err := db.Query("SELECT a FROM t")
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	stmt, err := db.Prepare("SELECT a FROM t WHERE a = ?")
+	if err != nil {
+		t.Fatalf("Prepare failed: %v", err)
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Begin failed: %v", err)
+	}
+
+	open := in.Open()
+	if got, want := fmt.Sprint(kinds(open)), "[rows statement transaction]"; got != want {
+		t.Fatalf("got open %s, want %s", got, want)
+	}
+	if open[0].Query != "SELECT a FROM t" || open[1].Query != "SELECT a FROM t WHERE a = ?" {
+		t.Errorf("got queries %q and %q", open[0].Query, open[1].Query)
+	}
+	for _, l := range open {
+		if len(l.Stack) == 0 || !strings.HasSuffix(l.Stack[0].Function, ".TestOpen") {
+			t.Errorf("got %v, want a stack starting at TestOpen", l)
+		}
+	}
+	if leaks := in.Leaks(); len(leaks) != 0 {
+		t.Errorf("got leaks %v before the threshold", leaks)
+	}
+
+	if err := rows.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if err := stmt.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if err := tx.Rollback(); err != nil {
+		t.Fatalf("Rollback failed: %v", err)
+	}
+	VerifyNone(t, in)
+}
+
+func TestLeaks(t *testing.T) {
+	in := New(Config{Threshold: 10 * time.Millisecond})
+	db := openDB(t, in)
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Begin failed: %v", err)
+	}
+	defer tx.Rollback()
+	rows, err := tx.Query("SELECT a FROM t")
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	for rows.Next() {
+	}
+	// rows read to the end are closed by database/sql
+	if got, want := fmt.Sprint(kinds(in.Open())), "[transaction]"; got != want {
+		t.Errorf("got open %s, want %s", got, want)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	if got, want := fmt.Sprint(kinds(in.Leaks())), "[transaction]"; got != want {
+		t.Errorf("got leaks %s, want %s", got, want)
+	}
+}
+
+func TestOnLeak(t *testing.T) {
+	var mu sync.Mutex
+	var leaks []Leak
+	in := New(Config{Threshold: time.Millisecond, Interval: 5 * time.Millisecond, OnLeak: func(l Leak) {
+		mu.Lock()
+		leaks = append(leaks, l)
+		mu.Unlock()
+	}})
+	defer in.Close()
+	db := openDB(t, in)
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Begin failed: %v", err)
+	}
+	defer tx.Rollback()
+
+	time.Sleep(50 * time.Millisecond)
+	if err := in.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if got, want := fmt.Sprint(kinds(leaks)), "[transaction]"; got != want {
+		t.Errorf("got reported leaks %s, want %s", got, want)
+	}
+}
+
+type recorder struct {
+	errors []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestVerifyNone(t *testing.T) {
+	in := New(Config{})
+	db := openDB(t, in)
+
+	stmt, err := db.Prepare("SELECT a FROM t")
+	if err != nil {
+		t.Fatalf("Prepare failed: %v", err)
+	}
+	defer stmt.Close()
+
+	var r recorder
+	VerifyNone(&r, in)
+	if len(r.errors) != 1 || !strings.HasPrefix(r.errors[0], `leak: statement of "SELECT a FROM t" open for`) ||
+		!strings.Contains(r.errors[0], "leak_test.go") {
+		t.Errorf("got errors %q", r.errors)
+	}
+}
+
+func TestConformance(t *testing.T) {
+	sqlmwtest.RunConformance(t, func() sqlmw.Interceptor { return New(Config{}) })
+}
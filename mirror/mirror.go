@@ -0,0 +1,404 @@
+// Package mirror provides an sqlmw.Interceptor that replays the statements
+// run against a primary database on a shadow database, without affecting the
+// callers of the primary.
+//
+// Every mirrored statement produces a Report comparing the results of both
+// databases: the column set, the number of rows, an order independent
+// checksum of the row values and the latency of each side. Only the first
+// result set of a query is compared, and its rows only when the caller read
+// all of them, see Outcome.Partial. Mirroring happens
+// on a bounded pool of workers fed by a bounded queue. When the queue is full
+// statements are dropped rather than slowing down the primary path.
+//
+// The statements of a transaction are held until it ends. They are mirrored
+// when it commits, each on its own on the shadow database, and dropped when
+// it rolls back.
+package mirror
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ngrok/sqlmw"
+)
+
+const (
+	defaultWorkers   = 4
+	defaultQueueSize = 256
+	defaultTimeout   = 5 * time.Second
+)
+
+// Config configures an Interceptor.
+type Config struct {
+	// Shadow is the connector to the secondary database. It is required.
+	Shadow driver.Connector
+
+	// Sink receives a Report for every mirrored statement. It is called from
+	// the mirroring workers and must be safe for concurrent use.
+	Sink Sink
+
+	// Writes enables mirroring of ConnExecContext and StmtExecContext in
+	// addition to queries.
+	Writes bool
+
+	// Workers bounds the number of statements run concurrently on the
+	// shadow database. Defaults to 4.
+	Workers int
+
+	// QueueSize bounds the number of statements waiting for a worker.
+	// Statements arriving while the queue is full are dropped. Defaults
+	// to 256.
+	QueueSize int
+
+	// Timeout bounds the execution of a single statement on the shadow
+	// database. Defaults to 5 seconds.
+	Timeout time.Duration
+}
+
+// Sink receives the outcome of mirrored statements.
+type Sink interface {
+	Report(Report)
+}
+
+// SinkFunc adapts a function to the Sink interface.
+type SinkFunc func(Report)
+
+func (f SinkFunc) Report(r Report) {
+	f(r)
+}
+
+// Interceptor mirrors statements to a shadow database. Create it with New
+// and release its resources with Close.
+type Interceptor struct {
+	// dropped is accessed atomically and kept first for 64-bit alignment
+	dropped uint64
+
+	sqlmw.NullInterceptor
+
+	cfg    Config
+	shadow *sql.DB
+	wg     sync.WaitGroup
+
+	mu     sync.RWMutex
+	jobs   chan job
+	closed bool
+}
+
+type obsKey struct{}
+
+// observation accumulates what the primary database returned for a query.
+type observation struct {
+	query   string
+	args    []driver.NamedValue
+	started time.Time
+	elapsed time.Duration
+	outcome Outcome
+	// eof is set once the first result set was read entirely
+	eof bool
+}
+
+type job struct {
+	write   bool
+	query   string
+	args    []driver.NamedValue
+	primary Outcome
+}
+
+// New starts the mirroring workers and returns the Interceptor.
+func New(cfg Config) *Interceptor {
+	if cfg.Workers <= 0 {
+		cfg.Workers = defaultWorkers
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = defaultQueueSize
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultTimeout
+	}
+
+	db := sql.OpenDB(cfg.Shadow)
+	db.SetMaxOpenConns(cfg.Workers)
+	db.SetMaxIdleConns(cfg.Workers)
+
+	in := &Interceptor{
+		cfg:    cfg,
+		shadow: db,
+		jobs:   make(chan job, cfg.QueueSize),
+	}
+	for i := 0; i < cfg.Workers; i++ {
+		in.wg.Add(1)
+		go in.work()
+	}
+	return in
+}
+
+// Close stops accepting statements, waits for the queued ones to be mirrored
+// and closes the shadow database.
+func (in *Interceptor) Close() error {
+	in.mu.Lock()
+	if !in.closed {
+		in.closed = true
+		close(in.jobs)
+	}
+	in.mu.Unlock()
+
+	in.wg.Wait()
+	return in.shadow.Close()
+}
+
+// Dropped returns the number of statements that were not mirrored because
+// the queue was full.
+func (in *Interceptor) Dropped() uint64 {
+	return atomic.LoadUint64(&in.dropped)
+}
+
+func (in *Interceptor) ConnQueryContext(ctx context.Context, conn driver.QueryerContext, query string, args []driver.NamedValue) (context.Context, driver.Rows, error) {
+	obs := &observation{query: query, args: copyArgs(args), started: time.Now()}
+	rows, err := conn.QueryContext(ctx, query, args)
+	return in.observe(ctx, obs, rows, err)
+}
+
+func (in *Interceptor) StmtQueryContext(ctx context.Context, stmt driver.StmtQueryContext, query string, args []driver.NamedValue) (context.Context, driver.Rows, error) {
+	obs := &observation{query: query, args: copyArgs(args), started: time.Now()}
+	rows, err := stmt.QueryContext(ctx, args)
+	return in.observe(ctx, obs, rows, err)
+}
+
+func (in *Interceptor) observe(ctx context.Context, obs *observation, rows driver.Rows, err error) (context.Context, driver.Rows, error) {
+	obs.elapsed = time.Since(obs.started)
+	if err == driver.ErrSkip {
+		// database/sql retries the statement, which is mirrored then
+		return ctx, nil, err
+	}
+	if err != nil {
+		obs.outcome.Err = err
+		obs.outcome.Latency = obs.elapsed
+		in.submit(ctx, job{query: obs.query, args: obs.args, primary: obs.outcome})
+		return ctx, nil, err
+	}
+	obs.outcome.Columns = rows.Columns()
+	return context.WithValue(ctx, obsKey{}, obs), rows, nil
+}
+
+func (in *Interceptor) RowsNext(ctx context.Context, rows driver.Rows, dest []driver.Value) error {
+	obs, ok := ctx.Value(obsKey{}).(*observation)
+	if !ok {
+		return rows.Next(dest)
+	}
+
+	started := time.Now()
+	err := rows.Next(dest)
+	obs.elapsed += time.Since(started)
+	if obs.eof {
+		// the rows of the next result sets are not compared
+		return err
+	}
+	switch err {
+	case nil:
+		obs.outcome.Rows++
+		obs.outcome.Checksum += rowChecksum(dest)
+	case io.EOF:
+		obs.eof = true
+	default:
+		obs.outcome.Err = err
+	}
+	return err
+}
+
+func (in *Interceptor) RowsClose(ctx context.Context, rows driver.Rows) error {
+	if obs, ok := ctx.Value(obsKey{}).(*observation); ok {
+		obs.outcome.Latency = obs.elapsed
+		obs.outcome.Partial = !obs.eof && obs.outcome.Err == nil
+		in.submit(ctx, job{query: obs.query, args: obs.args, primary: obs.outcome})
+	}
+	return rows.Close()
+}
+
+func (in *Interceptor) ConnExecContext(ctx context.Context, conn driver.ExecerContext, query string, args []driver.NamedValue) (driver.Result, error) {
+	if !in.cfg.Writes {
+		return conn.ExecContext(ctx, query, args)
+	}
+	started := time.Now()
+	res, err := conn.ExecContext(ctx, query, args)
+	in.mirrorExec(ctx, query, args, started, res, err)
+	return res, err
+}
+
+func (in *Interceptor) StmtExecContext(ctx context.Context, stmt driver.StmtExecContext, query string, args []driver.NamedValue) (driver.Result, error) {
+	if !in.cfg.Writes {
+		return stmt.ExecContext(ctx, args)
+	}
+	started := time.Now()
+	res, err := stmt.ExecContext(ctx, args)
+	in.mirrorExec(ctx, query, args, started, res, err)
+	return res, err
+}
+
+func (in *Interceptor) mirrorExec(ctx context.Context, query string, args []driver.NamedValue, started time.Time, res driver.Result, err error) {
+	if err == driver.ErrSkip {
+		// database/sql retries the statement, which is mirrored then
+		return
+	}
+	primary := Outcome{Latency: time.Since(started), Err: err}
+	if err == nil {
+		primary.Rows, primary.Err = res.RowsAffected()
+	}
+	in.submit(ctx, job{write: true, query: query, args: copyArgs(args), primary: primary})
+}
+
+type heldKey struct{}
+
+// submit enqueues j, or holds it until the transaction of ctx ends: the
+// statements of a transaction are mirrored once it commits, and not at all
+// when it rolls back.
+func (in *Interceptor) submit(ctx context.Context, j job) {
+	if sqlmw.InTx(ctx) {
+		if c, ok := sqlmw.ConnFromContext(ctx); ok {
+			held, _ := c.Value(heldKey{}).([]job)
+			if len(held) >= in.cfg.QueueSize {
+				atomic.AddUint64(&in.dropped, 1)
+				return
+			}
+			c.SetValue(heldKey{}, append(held, j))
+			return
+		}
+	}
+	in.enqueue(j)
+}
+
+// release returns the statements held for the transaction of ctx.
+func (in *Interceptor) release(ctx context.Context) []job {
+	c, ok := sqlmw.ConnFromContext(ctx)
+	if !ok {
+		return nil
+	}
+	held, _ := c.Value(heldKey{}).([]job)
+	c.SetValue(heldKey{}, nil)
+	return held
+}
+
+func (in *Interceptor) TxCommit(ctx context.Context, tx driver.Tx) error {
+	held := in.release(ctx)
+	err := tx.Commit()
+	if err == nil {
+		for _, j := range held {
+			in.enqueue(j)
+		}
+	}
+	return err
+}
+
+func (in *Interceptor) TxRollback(ctx context.Context, tx driver.Tx) error {
+	in.release(ctx)
+	return tx.Rollback()
+}
+
+func (in *Interceptor) enqueue(j job) {
+	in.mu.RLock()
+	defer in.mu.RUnlock()
+	if in.closed {
+		return
+	}
+
+	select {
+	case in.jobs <- j:
+	default:
+		atomic.AddUint64(&in.dropped, 1)
+	}
+}
+
+func (in *Interceptor) work() {
+	defer in.wg.Done()
+	for j := range in.jobs {
+		ctx, cancel := context.WithTimeout(context.Background(), in.cfg.Timeout)
+		var shadow Outcome
+		if j.write {
+			shadow = in.shadowExec(ctx, j)
+		} else {
+			shadow = in.shadowQuery(ctx, j)
+		}
+		cancel()
+
+		if in.cfg.Sink != nil {
+			in.cfg.Sink.Report(newReport(j, shadow))
+		}
+	}
+}
+
+func (in *Interceptor) shadowQuery(ctx context.Context, j job) (out Outcome) {
+	started := time.Now()
+	defer func() { out.Latency = time.Since(started) }()
+
+	rows, err := in.shadow.QueryContext(ctx, j.query, sqlArgs(j.args)...)
+	if err != nil {
+		out.Err = err
+		return out
+	}
+	defer rows.Close()
+
+	out.Columns, err = rows.Columns()
+	if err != nil {
+		out.Err = err
+		return out
+	}
+	vals := make([]driver.Value, len(out.Columns))
+	ptrs := make([]interface{}, len(out.Columns))
+	for i := range ptrs {
+		ptrs[i] = new(interface{})
+	}
+	for rows.Next() {
+		if err := rows.Scan(ptrs...); err != nil {
+			out.Err = err
+			return out
+		}
+		for i, p := range ptrs {
+			vals[i] = *(p.(*interface{}))
+		}
+		out.Rows++
+		out.Checksum += rowChecksum(vals)
+	}
+	out.Err = rows.Err()
+	return out
+}
+
+func (in *Interceptor) shadowExec(ctx context.Context, j job) (out Outcome) {
+	started := time.Now()
+	defer func() { out.Latency = time.Since(started) }()
+
+	res, err := in.shadow.ExecContext(ctx, j.query, sqlArgs(j.args)...)
+	if err != nil {
+		out.Err = err
+		return out
+	}
+	out.Rows, out.Err = res.RowsAffected()
+	return out
+}
+
+// copyArgs copies args so that they can be used after the call returns.
+func copyArgs(args []driver.NamedValue) []driver.NamedValue {
+	cp := make([]driver.NamedValue, len(args))
+	for i, a := range args {
+		if b, ok := a.Value.([]byte); ok {
+			a.Value = append([]byte(nil), b...)
+		}
+		cp[i] = a
+	}
+	return cp
+}
+
+func sqlArgs(args []driver.NamedValue) []interface{} {
+	out := make([]interface{}, len(args))
+	for i, a := range args {
+		if a.Name != "" {
+			out[i] = sql.Named(a.Name, a.Value)
+			continue
+		}
+		out[i] = a.Value
+	}
+	return out
+}
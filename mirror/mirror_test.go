@@ -0,0 +1,285 @@
+package mirror
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ngrok/sqlmw"
+	"github.com/ngrok/sqlmw/internal/fakedb"
+	"github.com/ngrok/sqlmw/sqlmwtest"
+)
+
+var driverCount int32
+
+func openDB(t *testing.T, primary *fakedb.Connector, in sqlmw.Interceptor) *sql.DB {
+	name := fmt.Sprintf("mirror-%s-%d", t.Name(), atomic.AddInt32(&driverCount, 1))
+	sql.Register(name, sqlmw.Driver(primary, in))
+	db, err := sql.Open(name, "")
+	if err != nil {
+		t.Fatalf("Failed to open: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := db.Close(); err != nil {
+			t.Errorf("Failed to close db: %v", err)
+		}
+	})
+	return db
+}
+
+func rowsHandler(rows ...[]driver.Value) fakedb.Handler {
+	return func(context.Context, string, []driver.NamedValue) (*fakedb.Response, error) {
+		return &fakedb.Response{Columns: []string{"id", "name"}, Rows: rows, RowsAffected: int64(len(rows))}, nil
+	}
+}
+
+type collector struct {
+	mu      sync.Mutex
+	reports []Report
+}
+
+func (c *collector) Report(r Report) {
+	c.mu.Lock()
+	c.reports = append(c.reports, r)
+	c.mu.Unlock()
+}
+
+func TestMirrorQuery(t *testing.T) {
+	tests := []struct {
+		name     string
+		shadow   fakedb.Handler
+		mismatch Mismatch
+	}{
+		{
+			name:   "identical rows in another order",
+			shadow: rowsHandler([]driver.Value{int64(2), "b"}, []driver.Value{int64(1), "a"}),
+		},
+		{
+			name:     "different values",
+			shadow:   rowsHandler([]driver.Value{int64(1), "a"}, []driver.Value{int64(2), "c"}),
+			mismatch: MismatchChecksum,
+		},
+		{
+			name:     "missing row",
+			shadow:   rowsHandler([]driver.Value{int64(1), "a"}),
+			mismatch: MismatchRows | MismatchChecksum,
+		},
+		{
+			name: "shadow error",
+			shadow: func(context.Context, string, []driver.NamedValue) (*fakedb.Response, error) {
+				return nil, fmt.Errorf("relation does not exist")
+			},
+			mismatch: MismatchError,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			primary := &fakedb.Connector{Handler: rowsHandler([]driver.Value{int64(1), "a"}, []driver.Value{int64(2), "b"})}
+			shadow := &fakedb.Connector{Handler: test.shadow}
+			sink := &collector{}
+			in := New(Config{Shadow: shadow, Sink: sink})
+			db := openDB(t, primary, in)
+
+			rows, err := db.QueryContext(context.Background(), "SELECT id, name FROM users WHERE id > ?", 0)
+			if err != nil {
+				t.Fatalf("Query failed: %v", err)
+			}
+			n := 0
+			for rows.Next() {
+				n++
+			}
+			if err := rows.Close(); err != nil {
+				t.Fatalf("Close failed: %v", err)
+			}
+			if n != 2 {
+				t.Fatalf("expected the primary rows, got %d rows", n)
+			}
+
+			if err := in.Close(); err != nil {
+				t.Fatalf("Close failed: %v", err)
+			}
+
+			if len(sink.reports) != 1 {
+				t.Fatalf("expected 1 report, got %d", len(sink.reports))
+			}
+			r := sink.reports[0]
+			if r.Mismatch != test.mismatch {
+				t.Errorf("expected mismatch %b, got %b", test.mismatch, r.Mismatch)
+			}
+			if r.Query != "SELECT id, name FROM users WHERE id > ?" || len(r.Args) != 1 {
+				t.Errorf("unexpected statement in report: %q %v", r.Query, r.Args)
+			}
+		})
+	}
+}
+
+func TestMirrorPartialRead(t *testing.T) {
+	handler := rowsHandler([]driver.Value{int64(1), "a"}, []driver.Value{int64(2), "b"})
+	sink := &collector{}
+	in := New(Config{Shadow: &fakedb.Connector{Handler: handler}, Sink: sink})
+	db := openDB(t, &fakedb.Connector{Handler: handler}, in)
+
+	var id int64
+	var name string
+	if err := db.QueryRowContext(context.Background(), "SELECT id, name FROM users").Scan(&id, &name); err != nil {
+		t.Fatalf("QueryRow failed: %v", err)
+	}
+	if err := in.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	if len(sink.reports) != 1 {
+		t.Fatalf("expected 1 report, got %d", len(sink.reports))
+	}
+	if r := sink.reports[0]; r.Mismatched() || !r.Primary.Partial {
+		t.Errorf("unexpected report %+v", r)
+	}
+}
+
+func TestMirrorWrites(t *testing.T) {
+	for _, writes := range []bool{false, true} {
+		t.Run(fmt.Sprint("writes=", writes), func(t *testing.T) {
+			primary := &fakedb.Connector{Handler: rowsHandler(nil)}
+			shadow := &fakedb.Connector{Handler: rowsHandler(nil, nil)}
+			sink := &collector{}
+			in := New(Config{Shadow: shadow, Sink: sink, Writes: writes})
+			db := openDB(t, primary, in)
+
+			if _, err := db.ExecContext(context.Background(), "DELETE FROM users"); err != nil {
+				t.Fatalf("Exec failed: %v", err)
+			}
+			if err := in.Close(); err != nil {
+				t.Fatalf("Close failed: %v", err)
+			}
+
+			if !writes {
+				if len(shadow.Calls()) != 0 || len(sink.reports) != 0 {
+					t.Fatal("writes were mirrored")
+				}
+				return
+			}
+			if len(sink.reports) != 1 {
+				t.Fatalf("expected 1 report, got %d", len(sink.reports))
+			}
+			if r := sink.reports[0]; !r.Write || r.Mismatch != MismatchRows {
+				t.Errorf("expected a rows affected mismatch, got %+v", r)
+			}
+		})
+	}
+}
+
+func TestMirrorDropsWhenQueueIsFull(t *testing.T) {
+	block := make(chan struct{})
+	primary := &fakedb.Connector{Handler: rowsHandler()}
+	shadow := &fakedb.Connector{Handler: func(context.Context, string, []driver.NamedValue) (*fakedb.Response, error) {
+		<-block
+		return nil, nil
+	}}
+	in := New(Config{Shadow: shadow, Workers: 1, QueueSize: 1})
+	db := openDB(t, primary, in)
+
+	for i := 0; i < 5; i++ {
+		rows, err := db.QueryContext(context.Background(), "SELECT 1")
+		if err != nil {
+			t.Fatalf("Query failed: %v", err)
+		}
+		rows.Close()
+	}
+
+	// one statement is running, one is queued
+	if dropped := in.Dropped(); dropped < 3 {
+		t.Errorf("expected at least 3 dropped statements, got %d", dropped)
+	}
+	close(block)
+	if err := in.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+}
+
+func TestMirrorSkipsErrSkip(t *testing.T) {
+	var calls int32
+	primary := &fakedb.Connector{Handler: func(ctx context.Context, query string, args []driver.NamedValue) (*fakedb.Response, error) {
+		// like drivers without client side interpolation, refuse the
+		// fast path so that database/sql prepares the statement
+		if atomic.AddInt32(&calls, 1)%2 == 1 {
+			return nil, driver.ErrSkip
+		}
+		return &fakedb.Response{Columns: []string{"id"}, RowsAffected: 1}, nil
+	}}
+	shadow := &fakedb.Connector{Handler: rowsHandler(nil)}
+	sink := &collector{}
+	in := New(Config{Shadow: shadow, Sink: sink, Writes: true})
+	db := openDB(t, primary, in)
+
+	if _, err := db.ExecContext(context.Background(), "DELETE FROM users WHERE id = ?", 1); err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	rows, err := db.QueryContext(context.Background(), "SELECT id FROM users WHERE id = ?", 1)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	rows.Close()
+	if err := in.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	if got := shadow.Queries(); len(got) != 2 {
+		t.Errorf("expected the statements to be mirrored once, got %q", got)
+	}
+	for _, r := range sink.reports {
+		if r.Primary.Err != nil {
+			t.Errorf("unexpected primary error in %+v", r)
+		}
+	}
+}
+
+func TestMirrorTransactions(t *testing.T) {
+	primary := &fakedb.Connector{Handler: rowsHandler(nil)}
+	shadow := &fakedb.Connector{Handler: rowsHandler(nil)}
+	in := New(Config{Shadow: shadow, Writes: true})
+	db := openDB(t, primary, in)
+	ctx := context.Background()
+
+	for _, commit := range []bool{false, true} {
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			t.Fatalf("Begin failed: %v", err)
+		}
+		if _, err := tx.ExecContext(ctx, fmt.Sprint("UPDATE users SET committed = ", commit)); err != nil {
+			t.Fatalf("Exec failed: %v", err)
+		}
+		if commit {
+			err = tx.Commit()
+		} else {
+			err = tx.Rollback()
+		}
+		if err != nil {
+			t.Fatalf("ending the transaction failed: %v", err)
+		}
+	}
+	if err := in.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	want := []string{"UPDATE users SET committed = true"}
+	if got := shadow.Queries(); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("got mirrored statements %q, want %q", got, want)
+	}
+}
+
+func TestConformance(t *testing.T) {
+	sqlmwtest.RunConformance(t, func() sqlmw.Interceptor {
+		in := New(Config{Shadow: &fakedb.Connector{}})
+		t.Cleanup(func() {
+			if err := in.Close(); err != nil {
+				t.Errorf("Close failed: %v", err)
+			}
+		})
+		return in
+	})
+}
@@ -0,0 +1,162 @@
+package mirror
+
+import (
+	"database/sql/driver"
+	"encoding/binary"
+	"hash/fnv"
+	"math"
+	"time"
+)
+
+// Outcome summarizes the result of a statement on one of the databases.
+type Outcome struct {
+	// Columns returned by a query. Always nil for writes.
+	Columns []string
+
+	// Rows is the number of rows returned by a query, in its first result
+	// set, or the number of rows affected by a write.
+	Rows int64
+
+	// Checksum is an order independent checksum of the returned rows.
+	Checksum uint64
+
+	// Partial is set when the rows of a query were closed before all of
+	// its first result set was read, e.g. by QueryRow. Rows and Checksum
+	// then only cover the rows read and are not compared.
+	Partial bool
+
+	// Latency is the time spent in the database driver.
+	Latency time.Duration
+
+	// Err is the error returned by the statement, if any.
+	Err error
+}
+
+// Mismatch is a bit set of the differences found between the primary and
+// the shadow outcomes.
+type Mismatch uint8
+
+const (
+	// MismatchError is set when only one of the databases failed.
+	MismatchError Mismatch = 1 << iota
+	// MismatchColumns is set when the column sets differ.
+	MismatchColumns
+	// MismatchRows is set when the row counts, or the rows affected, differ.
+	MismatchRows
+	// MismatchChecksum is set when the row checksums differ.
+	MismatchChecksum
+)
+
+// Report is produced for every mirrored statement.
+type Report struct {
+	Query    string
+	Args     []driver.NamedValue
+	Write    bool
+	Primary  Outcome
+	Shadow   Outcome
+	Mismatch Mismatch
+}
+
+// Mismatched reports whether the shadow outcome differs from the primary one.
+func (r Report) Mismatched() bool {
+	return r.Mismatch != 0
+}
+
+// LatencyDelta returns how much slower the shadow was than the primary. It
+// is negative when the shadow was faster.
+func (r Report) LatencyDelta() time.Duration {
+	return r.Shadow.Latency - r.Primary.Latency
+}
+
+func newReport(j job, shadow Outcome) Report {
+	r := Report{
+		Query:   j.query,
+		Args:    j.args,
+		Write:   j.write,
+		Primary: j.primary,
+		Shadow:  shadow,
+	}
+
+	if (r.Primary.Err == nil) != (r.Shadow.Err == nil) {
+		r.Mismatch |= MismatchError
+	}
+	if r.Primary.Err != nil || r.Shadow.Err != nil {
+		// nothing else is comparable once either side failed
+		return r
+	}
+	if !sameColumns(r.Primary.Columns, r.Shadow.Columns) {
+		r.Mismatch |= MismatchColumns
+	}
+	if r.Primary.Partial {
+		return r
+	}
+	if r.Primary.Rows != r.Shadow.Rows {
+		r.Mismatch |= MismatchRows
+	}
+	if r.Primary.Checksum != r.Shadow.Checksum {
+		r.Mismatch |= MismatchChecksum
+	}
+	return r
+}
+
+func sameColumns(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// rowChecksum hashes the values of a single row. Row checksums are summed so
+// that the checksum of a result set does not depend on the order of its rows.
+func rowChecksum(vals []driver.Value) uint64 {
+	h := fnv.New64a()
+	var buf [9]byte
+	for _, v := range vals {
+		n := 1
+		switch v := v.(type) {
+		case nil:
+			buf[0] = 0
+		case int64:
+			buf[0] = 1
+			binary.LittleEndian.PutUint64(buf[1:], uint64(v))
+			n = 9
+		case float64:
+			buf[0] = 2
+			binary.LittleEndian.PutUint64(buf[1:], math.Float64bits(v))
+			n = 9
+		case bool:
+			buf[0] = 3
+			if v {
+				buf[1] = 1
+			} else {
+				buf[1] = 0
+			}
+			n = 2
+		case []byte:
+			buf[0] = 4
+			binary.LittleEndian.PutUint64(buf[1:], uint64(len(v)))
+			h.Write(buf[:9])
+			h.Write(v)
+			continue
+		case string:
+			buf[0] = 4
+			binary.LittleEndian.PutUint64(buf[1:], uint64(len(v)))
+			h.Write(buf[:9])
+			h.Write([]byte(v))
+			continue
+		case time.Time:
+			buf[0] = 5
+			binary.LittleEndian.PutUint64(buf[1:], uint64(v.UnixNano()))
+			n = 9
+		default:
+			buf[0] = 6
+		}
+		h.Write(buf[:n])
+	}
+	return h.Sum64()
+}
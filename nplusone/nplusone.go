@@ -0,0 +1,397 @@
+// Package nplusone provides an sqlmw.Interceptor detecting N+1 queries: a
+// statement run once per row of a previous result, where a single batched
+// statement would do.
+//
+// The statements are grouped by fingerprint within a scope, usually an
+// inbound request, started with WithScope by a middleware such as Handler.
+// A fingerprint run more than Config.Threshold times with distinct arguments
+// within a scope is reported to Config.OnDetect, once per scope, with the
+// stack of the caller and, when possible, a batched form of the statement:
+//
+//	SELECT * FROM users WHERE id = ?
+//
+// is reported with the suggestion
+//
+//	select * from users where id in(...)
+//
+// In production, Config.SampleRate bounds the share of the scopes watched.
+// In tests, VerifyNone fails the test when a scope ran N+1 queries:
+//
+//	ctx := nplusone.WithScope(context.Background(), t.Name())
+//	// ... run the code under test with ctx ...
+//	nplusone.VerifyNone(t, ctx)
+package nplusone
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/ngrok/sqlmw"
+	"github.com/ngrok/sqlmw/fingerprint"
+	"github.com/ngrok/sqlmw/sqlinfo"
+)
+
+const (
+	defaultThreshold       = 10
+	defaultMaxFingerprints = 1000
+	defaultStackDepth      = 32
+)
+
+// Detection is a statement run once per row within a scope.
+type Detection struct {
+	// Scope is the name of the scope.
+	Scope string
+	// Query is the statement that crossed the threshold.
+	Query       string
+	Fingerprint string
+	// Count is the number of distinct arguments the statement was run with.
+	Count int
+	// Suggestion is the fingerprint of a batched form of the statement, or
+	// "" if none is known.
+	Suggestion string
+	// Stack is the stack of the caller that crossed the threshold, without
+	// the frames of database/sql and sqlmw.
+	Stack []runtime.Frame
+}
+
+func (d Detection) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%q ran %d times in %s", d.Fingerprint, d.Count, d.Scope)
+	if d.Suggestion != "" {
+		fmt.Fprintf(&b, ", batch it as %q", d.Suggestion)
+	}
+	b.WriteString(", called at:")
+	for _, f := range d.Stack {
+		fmt.Fprintf(&b, "\n\t%s\n\t\t%s:%d", f.Function, f.File, f.Line)
+	}
+	return b.String()
+}
+
+// Scope groups the statements of a request.
+type Scope struct {
+	name string
+
+	once    sync.Once
+	watched bool
+
+	mu         sync.Mutex
+	groups     map[string]*group
+	detections []Detection
+}
+
+// group are the statements of a fingerprint within a scope.
+type group struct {
+	first    []driver.NamedValue
+	args     map[string]bool
+	varying  map[driver.NamedValue]bool // keyed by argKey
+	reported bool
+}
+
+type scopeKey struct{}
+
+// WithScope returns a context starting a scope named name, whose statements
+// are grouped.
+func WithScope(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, scopeKey{}, &Scope{name: name, groups: make(map[string]*group)})
+}
+
+// ScopeFromContext returns the scope of ctx, if any.
+func ScopeFromContext(ctx context.Context) (*Scope, bool) {
+	s, ok := ctx.Value(scopeKey{}).(*Scope)
+	return s, ok
+}
+
+// Name returns the name of the scope.
+func (s *Scope) Name() string {
+	return s.name
+}
+
+// Detections returns the N+1 queries detected within the scope.
+func (s *Scope) Detections() []Detection {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Detection(nil), s.detections...)
+}
+
+// Handler returns an http.Handler running h with a scope per request, named
+// after its method and path.
+func Handler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := WithScope(r.Context(), r.Method+" "+r.URL.Path)
+		h.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// TestingT is the subset of testing.TB used by VerifyNone.
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+}
+
+// VerifyNone fails the test when N+1 queries were detected within the scope
+// of ctx.
+func VerifyNone(t TestingT, ctx context.Context) {
+	t.Helper()
+	s, ok := ScopeFromContext(ctx)
+	if !ok {
+		t.Errorf("nplusone: no scope in context")
+		return
+	}
+	for _, d := range s.Detections() {
+		t.Errorf("nplusone: %v", d)
+	}
+}
+
+// Config configures an Interceptor.
+type Config struct {
+	// Threshold is the number of distinct arguments a fingerprint may run
+	// with within a scope before it is reported. Defaults to 10.
+	Threshold int
+
+	// SampleRate is the share of the scopes that are watched, from 0 to 1.
+	// Defaults to 1, watching every scope.
+	SampleRate float64
+
+	// OnDetect, if set, is called with the N+1 queries detected.
+	OnDetect func(Detection)
+
+	// MaxFingerprints bounds the number of fingerprints grouped within a
+	// scope. Defaults to 1000.
+	MaxFingerprints int
+
+	// StackDepth bounds the number of frames recorded. Defaults to 32.
+	StackDepth int
+
+	// Dialect is used to suggest batched statements. Defaults to
+	// sqlinfo.Generic.
+	Dialect sqlinfo.Dialect
+}
+
+// Interceptor detects N+1 queries.
+type Interceptor struct {
+	sqlmw.NullInterceptor
+
+	cfg Config
+}
+
+// New returns an Interceptor for the supplied configuration.
+func New(cfg Config) *Interceptor {
+	if cfg.Threshold <= 0 {
+		cfg.Threshold = defaultThreshold
+	}
+	if cfg.SampleRate <= 0 {
+		cfg.SampleRate = 1
+	}
+	if cfg.MaxFingerprints <= 0 {
+		cfg.MaxFingerprints = defaultMaxFingerprints
+	}
+	if cfg.StackDepth <= 0 {
+		cfg.StackDepth = defaultStackDepth
+	}
+	return &Interceptor{cfg: cfg}
+}
+
+// observe groups a statement within the scope of ctx, and reports it when
+// it crosses the threshold.
+func (in *Interceptor) observe(ctx context.Context, query string, args []driver.NamedValue) {
+	s, ok := ScopeFromContext(ctx)
+	if !ok || len(args) == 0 {
+		return
+	}
+	s.once.Do(func() { s.watched = in.cfg.SampleRate >= 1 || rand.Float64() < in.cfg.SampleRate })
+	if !s.watched {
+		return
+	}
+
+	fp := fingerprint.Normalize(query)
+	s.mu.Lock()
+	g := s.groups[fp]
+	if g == nil {
+		if len(s.groups) >= in.cfg.MaxFingerprints {
+			s.mu.Unlock()
+			return
+		}
+		g = &group{first: copyArgs(args), args: make(map[string]bool), varying: make(map[driver.NamedValue]bool)}
+		s.groups[fp] = g
+	}
+	if g.reported {
+		s.mu.Unlock()
+		return
+	}
+	g.args[fmt.Sprintf("%#v", args)] = true
+	for i, arg := range args {
+		if i < len(g.first) && !reflect.DeepEqual(arg.Value, g.first[i].Value) {
+			g.varying[argKey(arg.Name, arg.Ordinal)] = true
+		}
+	}
+	count := len(g.args)
+	if count <= in.cfg.Threshold {
+		s.mu.Unlock()
+		return
+	}
+	g.reported, g.args = true, nil
+	varying := g.varying
+	s.mu.Unlock()
+
+	d := Detection{
+		Scope:       s.name,
+		Query:       query,
+		Fingerprint: fp,
+		Count:       count,
+		Suggestion:  suggest(in.cfg.Dialect, query, varying),
+		Stack:       in.stack(),
+	}
+	s.mu.Lock()
+	s.detections = append(s.detections, d)
+	s.mu.Unlock()
+	if in.cfg.OnDetect != nil {
+		in.cfg.OnDetect(d)
+	}
+}
+
+// copyArgs returns a copy of args.
+func copyArgs(args []driver.NamedValue) []driver.NamedValue {
+	out := make([]driver.NamedValue, len(args))
+	copy(out, args)
+	return out
+}
+
+// argKey identifies an argument by name, or by ordinal when it has none.
+func argKey(name string, ordinal int) driver.NamedValue {
+	if name != "" {
+		return driver.NamedValue{Name: name}
+	}
+	return driver.NamedValue{Ordinal: ordinal}
+}
+
+// stack returns the stack of the caller of the hook.
+func (in *Interceptor) stack() []runtime.Frame {
+	pcs := make([]uintptr, in.cfg.StackDepth)
+	// skip runtime.Callers, stack, observe and the hook
+	pcs = pcs[:runtime.Callers(4, pcs)]
+	var stack []runtime.Frame
+	frames := runtime.CallersFrames(pcs)
+	for {
+		f, more := frames.Next()
+		if !internal(f.Function) {
+			stack = append(stack, f)
+		}
+		if !more {
+			return stack
+		}
+	}
+}
+
+// internal reports whether function is part of the machinery between the
+// application and the Interceptor.
+func internal(function string) bool {
+	for _, prefix := range []string{"database/sql.", "github.com/ngrok/sqlmw.", "runtime."} {
+		if strings.HasPrefix(function, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// suggest returns the fingerprint of a batched form of query, whose
+// arguments in varying change from one run to the other: the comparisons
+// of a column to one of them turn into IN lists, and the VALUES of an
+// INSERT into a multi-row VALUES.
+func suggest(dialect sqlinfo.Dialect, query string, varying map[driver.NamedValue]bool) string {
+	if st := sqlinfo.Parse(dialect, query); st.Verb == "INSERT" {
+		return multiRow(fingerprint.Normalize(query))
+	}
+
+	var toks []sqlinfo.Token
+	for _, tok := range sqlinfo.Tokens(dialect, query) {
+		if tok.Kind != sqlinfo.Comment {
+			toks = append(toks, tok)
+		}
+	}
+
+	var b strings.Builder
+	last, positional := 0, 0
+	for i, tok := range toks {
+		if tok.Kind != sqlinfo.Placeholder {
+			continue
+		}
+		ordinal, name := tok.Placeholder()
+		if ordinal == 0 && name == "" {
+			positional++
+			ordinal = positional
+		}
+		if !varying[argKey(name, ordinal)] {
+			continue
+		}
+		if i < 2 || toks[i-1].Kind != sqlinfo.Punct || toks[i-1].Text != "=" ||
+			(toks[i-2].Kind != sqlinfo.Word && toks[i-2].Kind != sqlinfo.QuotedIdent) {
+			continue
+		}
+		b.WriteString(query[last:toks[i-1].Offset])
+		b.WriteString("IN (" + tok.Text + ")")
+		last = tok.Offset + len(tok.Text)
+	}
+	if last == 0 {
+		return ""
+	}
+	b.WriteString(query[last:])
+	return fingerprint.Normalize(b.String())
+}
+
+// multiRow returns the normalized INSERT fp with its VALUES row repeated, or
+// "" if it has none.
+func multiRow(fp string) string {
+	toks := sqlinfo.Tokens(sqlinfo.Generic, fp)
+	for i, tok := range toks {
+		if !tok.Is("values") || i+1 == len(toks) || toks[i+1].Text != "(" {
+			continue
+		}
+		depth := 0
+		for _, t := range toks[i+1:] {
+			if t.Kind != sqlinfo.Punct {
+				continue
+			}
+			switch t.Text {
+			case "(":
+				depth++
+			case ")":
+				depth--
+			}
+			if depth == 0 {
+				end := t.Offset + 1
+				row := fp[toks[i+1].Offset:end]
+				return fp[:end] + ", " + row + ", ..." + fp[end:]
+			}
+		}
+	}
+	return ""
+}
+
+func (in *Interceptor) ConnExecContext(ctx context.Context, conn driver.ExecerContext, query string, args []driver.NamedValue) (driver.Result, error) {
+	in.observe(ctx, query, args)
+	return conn.ExecContext(ctx, query, args)
+}
+
+func (in *Interceptor) ConnQueryContext(ctx context.Context, conn driver.QueryerContext, query string, args []driver.NamedValue) (context.Context, driver.Rows, error) {
+	in.observe(ctx, query, args)
+	rows, err := conn.QueryContext(ctx, query, args)
+	return ctx, rows, err
+}
+
+func (in *Interceptor) StmtExecContext(ctx context.Context, stmt driver.StmtExecContext, query string, args []driver.NamedValue) (driver.Result, error) {
+	in.observe(ctx, query, args)
+	return stmt.ExecContext(ctx, args)
+}
+
+func (in *Interceptor) StmtQueryContext(ctx context.Context, stmt driver.StmtQueryContext, query string, args []driver.NamedValue) (context.Context, driver.Rows, error) {
+	in.observe(ctx, query, args)
+	rows, err := stmt.QueryContext(ctx, args)
+	return ctx, rows, err
+}
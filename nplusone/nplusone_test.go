@@ -0,0 +1,146 @@
+package nplusone
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ngrok/sqlmw"
+	"github.com/ngrok/sqlmw/internal/fakedb"
+	"github.com/ngrok/sqlmw/sqlmwtest"
+)
+
+func openDB(t *testing.T, in *Interceptor) *sql.DB {
+	con := &fakedb.Connector{Handler: func(ctx context.Context, query string, args []driver.NamedValue) (*fakedb.Response, error) {
+		return &fakedb.Response{Columns: []string{"name"}, Rows: [][]driver.Value{{"alice"}}}, nil
+	}}
+	db := sql.OpenDB(sqlmw.Connector(con, in))
+	t.Cleanup(func() {
+		if err := db.Close(); err != nil {
+			t.Errorf("Failed to close db: %v", err)
+		}
+	})
+	return db
+}
+
+func TestDetect(t *testing.T) {
+	var detections []Detection
+	in := New(Config{Threshold: 3, OnDetect: func(d Detection) { detections = append(detections, d) }})
+	db := openDB(t, in)
+	ctx := WithScope(context.Background(), "GET /users")
+
+	for i := 0; i < 10; i++ {
+		var name string
+		if err := db.QueryRowContext(ctx, "SELECT name FROM users WHERE id = ? AND deleted = ?", i, false).Scan(&name); err != nil {
+			t.Fatalf("QueryRow failed: %v", err)
+		}
+		// the same arguments every time
+		if _, err := db.ExecContext(ctx, "UPDATE stats SET n = n + 1 WHERE id = ?", 1); err != nil {
+			t.Fatalf("Exec failed: %v", err)
+		}
+	}
+	// outside of a scope
+	for i := 0; i < 10; i++ {
+		if _, err := db.Exec("DELETE FROM users WHERE id = ?", i); err != nil {
+			t.Fatalf("Exec failed: %v", err)
+		}
+	}
+
+	if len(detections) != 1 {
+		t.Fatalf("got %d detections, want 1", len(detections))
+	}
+	d := detections[0]
+	if d.Scope != "GET /users" || d.Count != 4 || d.Fingerprint != "select name from users where id = ? and deleted = ?" {
+		t.Errorf("got detection %+v", d)
+	}
+	if want := "select name from users where id in(...) and deleted = ?"; d.Suggestion != want {
+		t.Errorf("got suggestion %q, want %q", d.Suggestion, want)
+	}
+	if len(d.Stack) == 0 || !strings.HasSuffix(d.Stack[0].Function, ".TestDetect") {
+		t.Errorf("got %v, want a stack starting at TestDetect", d)
+	}
+	s, _ := ScopeFromContext(ctx)
+	if got := s.Detections(); len(got) != 1 || got[0].Query != d.Query {
+		t.Errorf("got scope detections %v", got)
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	tests := []struct {
+		query   string
+		varying []driver.NamedValue
+		want    string
+	}{
+		{"SELECT * FROM t WHERE a = $1 AND b = $2", []driver.NamedValue{{Ordinal: 2}}, "select * from t where a = ? and b in(...)"},
+		{"SELECT * FROM t WHERE a = :a", []driver.NamedValue{{Name: "a"}}, "select * from t where a in(...)"},
+		{"SELECT * FROM t WHERE a > ?", []driver.NamedValue{{Ordinal: 1}}, ""},
+		{"INSERT INTO t (a, b) VALUES (?, ?) RETURNING id", nil, "insert into t(a, b) values(?, ?), (?, ?), ... returning id"},
+	}
+	for _, tt := range tests {
+		varying := make(map[driver.NamedValue]bool)
+		for _, v := range tt.varying {
+			varying[v] = true
+		}
+		if got := suggest(0, tt.query, varying); got != tt.want {
+			t.Errorf("suggest(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestSampleRate(t *testing.T) {
+	in := New(Config{Threshold: 1, SampleRate: 1e-9})
+	db := openDB(t, in)
+	ctx := WithScope(context.Background(), "sampled out")
+
+	for i := 0; i < 3; i++ {
+		if _, err := db.ExecContext(ctx, "DELETE FROM users WHERE id = ?", i); err != nil {
+			t.Fatalf("Exec failed: %v", err)
+		}
+	}
+	VerifyNone(t, ctx)
+}
+
+type recorder struct {
+	errors []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestHandler(t *testing.T) {
+	in := New(Config{Threshold: 1})
+	db := openDB(t, in)
+
+	var r recorder
+	h := Handler(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		for i := 0; i < 2; i++ {
+			if _, err := db.ExecContext(req.Context(), "DELETE FROM users WHERE id = ?", i); err != nil {
+				t.Errorf("Exec failed: %v", err)
+			}
+		}
+		VerifyNone(&r, req.Context())
+	}))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/users/purge", nil))
+
+	if len(r.errors) != 1 || !strings.HasPrefix(r.errors[0], `nplusone: "delete from users where id = ?" ran 2 times in POST /users/purge, batch it as "delete from users where id in(...)"`) {
+		t.Errorf("got errors %q", r.errors)
+	}
+
+	r.errors = nil
+	VerifyNone(&r, context.Background())
+	if len(r.errors) != 1 {
+		t.Errorf("got errors %q, want a missing scope", r.errors)
+	}
+}
+
+func TestConformance(t *testing.T) {
+	sqlmwtest.RunConformance(t, func() sqlmw.Interceptor { return New(Config{}) })
+}
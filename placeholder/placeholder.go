@@ -0,0 +1,248 @@
+// Package placeholder provides an sqlmw.Interceptor translating the
+// placeholders of statements to the style of the database, so that the same
+// statements run on databases with different placeholder styles:
+//
+//	SELECT * FROM users WHERE org = :org AND (owner = :user OR editor = :user)
+//
+// becomes, for PostgreSQL,
+//
+//	SELECT * FROM users WHERE org = $1 AND (owner = $2 OR editor = $2)
+//
+// and, for MySQL,
+//
+//	SELECT * FROM users WHERE org = ? AND (owner = ? OR editor = ?)
+//
+// The arguments are reordered, or repeated, to match the translated
+// statement and are passed to the driver as ordinal arguments: named
+// arguments work with drivers that do not support them. Placeholders are
+// found with the lexer of the source dialect, outside of the literals and
+// comments, which are left untouched and found with the rules of the target
+// dialect by default.
+//
+// ConnExecContext, ConnQueryContext and ConnPrepareContext statements are
+// translated, prepared statements once when they are prepared.
+package placeholder
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ngrok/sqlmw"
+	"github.com/ngrok/sqlmw/internal/argstmt"
+	"github.com/ngrok/sqlmw/sqlinfo"
+)
+
+// Config configures an Interceptor.
+type Config struct {
+	// Target is the dialect of the database. Statements get $n placeholders
+	// for PostgreSQL and ? placeholders otherwise.
+	Target sqlinfo.Dialect
+
+	// Source is the dialect used to find the placeholders of statements.
+	// Defaults to sqlinfo.Generic, which recognizes the ?, ?NNN, $n, :name
+	// and @name placeholders, outside of the literals and comments found
+	// with the rules of Target, e.g. its backslash escapes for MySQL. @name
+	// is a user variable, not a placeholder, when Target is MySQL, unless
+	// Source is sqlinfo.SQLite.
+	Source sqlinfo.Dialect
+}
+
+// Interceptor translates placeholders.
+type Interceptor struct {
+	sqlmw.NullInterceptor
+
+	cfg Config
+}
+
+// New returns an Interceptor for the supplied configuration.
+func New(cfg Config) *Interceptor {
+	return &Interceptor{cfg: cfg}
+}
+
+func (in *Interceptor) ConnExecContext(ctx context.Context, conn driver.ExecerContext, query string, args []driver.NamedValue) (driver.Result, error) {
+	tr := in.translate(query)
+	if tr == nil {
+		return conn.ExecContext(ctx, query, args)
+	}
+	args, err := tr.args(args)
+	if err != nil {
+		return nil, err
+	}
+	return conn.ExecContext(ctx, tr.query, args)
+}
+
+func (in *Interceptor) ConnQueryContext(ctx context.Context, conn driver.QueryerContext, query string, args []driver.NamedValue) (context.Context, driver.Rows, error) {
+	tr := in.translate(query)
+	if tr == nil {
+		rows, err := conn.QueryContext(ctx, query, args)
+		return ctx, rows, err
+	}
+	args, err := tr.args(args)
+	if err != nil {
+		return ctx, nil, err
+	}
+	rows, err := conn.QueryContext(ctx, tr.query, args)
+	return ctx, rows, err
+}
+
+func (in *Interceptor) ConnPrepareContext(ctx context.Context, conn driver.ConnPrepareContext, query string) (context.Context, driver.Stmt, error) {
+	tr := in.translate(query)
+	if tr == nil {
+		stmt, err := conn.PrepareContext(ctx, query)
+		return ctx, stmt, err
+	}
+	stmt, err := conn.PrepareContext(ctx, tr.query)
+	if err != nil {
+		return ctx, nil, err
+	}
+	return ctx, &argstmt.Stmt{
+		Stmt: stmt,
+		Args: func(_ context.Context, args []driver.NamedValue) ([]driver.NamedValue, error) {
+			return tr.args(args)
+		},
+		Inputs: func(int) int {
+			// the caller passes the arguments of the original query
+			return tr.inputs
+		},
+	}, nil
+}
+
+// translation is a statement with translated placeholders.
+type translation struct {
+	query  string
+	refs   []ref // the argument of every placeholder of query
+	inputs int   // the number of arguments of the original query
+}
+
+// ref designates an argument by ordinal or by name.
+type ref struct {
+	ordinal int
+	name    string
+}
+
+func (r ref) String() string {
+	if r.name != "" {
+		return strconv.Quote(r.name)
+	}
+	return strconv.Itoa(r.ordinal)
+}
+
+// translate returns the translation of query, or nil when it does not need
+// one.
+func (in *Interceptor) translate(query string) *translation {
+	dollar := in.cfg.Target == sqlinfo.PostgreSQL
+	tr := &translation{}
+	var b strings.Builder
+	last, positional, identity := 0, 0, true
+
+	source, src := in.source(query)
+	l := sqlinfo.NewLexer(source, src)
+	for {
+		tok, ok := l.Next()
+		if !ok {
+			break
+		}
+		if tok.Kind != sqlinfo.Placeholder {
+			continue
+		}
+
+		ordinal, name := tok.Placeholder()
+		if ordinal == 0 && name == "" {
+			positional++
+			ordinal = positional
+		}
+		r := ref{ordinal: ordinal, name: name}
+
+		b.WriteString(query[last:tok.Offset])
+		last = tok.Offset + len(tok.Text)
+		if !dollar {
+			tr.refs = append(tr.refs, r)
+			b.WriteByte('?')
+			identity = identity && tok.Text == "?"
+			continue
+		}
+		n := indexOf(tr.refs, r) + 1
+		if n == 0 {
+			tr.refs = append(tr.refs, r)
+			n = len(tr.refs)
+		}
+		b.WriteByte('$')
+		b.WriteString(strconv.Itoa(n))
+		identity = identity && r.name == "" && r.ordinal == n && tok.Text[0] == '$'
+	}
+	if identity {
+		return nil
+	}
+	b.WriteString(query[last:])
+	tr.query = b.String()
+	tr.inputs = sqlinfo.NumInput(source, src)
+	return tr
+}
+
+// source returns the dialect finding the placeholders of query and the text
+// to find them in. Without a Source, the literals, comments and MySQL user
+// variables found with the rules of Target are blanked out of query.
+func (in *Interceptor) source(query string) (sqlinfo.Dialect, string) {
+	if in.cfg.Source != sqlinfo.Generic || in.cfg.Target == sqlinfo.Generic {
+		return in.cfg.Source, query
+	}
+	var b []byte
+	l := sqlinfo.NewLexer(in.cfg.Target, query)
+	for {
+		tok, ok := l.Next()
+		if !ok {
+			break
+		}
+		switch {
+		case tok.Kind == sqlinfo.String || tok.Kind == sqlinfo.Comment || tok.Kind == sqlinfo.QuotedIdent,
+			tok.Kind == sqlinfo.Word && tok.Text[0] == '@':
+			if b == nil {
+				b = []byte(query)
+			}
+			for i := tok.Offset; i < tok.Offset+len(tok.Text); i++ {
+				b[i] = ' '
+			}
+		}
+	}
+	if b == nil {
+		return sqlinfo.Generic, query
+	}
+	return sqlinfo.Generic, string(b)
+}
+
+func indexOf(refs []ref, r ref) int {
+	for i, o := range refs {
+		if o == r {
+			return i
+		}
+	}
+	return -1
+}
+
+// args returns the arguments of the translated statement.
+func (tr *translation) args(args []driver.NamedValue) ([]driver.NamedValue, error) {
+	if len(args) != tr.inputs {
+		return nil, fmt.Errorf("placeholder: expected %d arguments, got %d", tr.inputs, len(args))
+	}
+	out := make([]driver.NamedValue, len(tr.refs))
+	for i, r := range tr.refs {
+		a, ok := lookup(args, r)
+		if !ok {
+			return nil, fmt.Errorf("placeholder: missing argument %v", r)
+		}
+		out[i] = driver.NamedValue{Ordinal: i + 1, Value: a.Value}
+	}
+	return out, nil
+}
+
+func lookup(args []driver.NamedValue, r ref) (driver.NamedValue, bool) {
+	for _, a := range args {
+		if (r.name != "" && a.Name == r.name) || (r.name == "" && a.Name == "" && a.Ordinal == r.ordinal) {
+			return a, true
+		}
+	}
+	return driver.NamedValue{}, false
+}
@@ -0,0 +1,158 @@
+package placeholder
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ngrok/sqlmw"
+	"github.com/ngrok/sqlmw/internal/fakedb"
+	"github.com/ngrok/sqlmw/sqlinfo"
+	"github.com/ngrok/sqlmw/sqlmwtest"
+)
+
+func TestTranslate(t *testing.T) {
+	tests := []struct {
+		target   sqlinfo.Dialect
+		source   sqlinfo.Dialect
+		query    string
+		expected string
+		refs     []ref
+	}{
+		{
+			target:   sqlinfo.PostgreSQL,
+			query:    "SELECT * FROM t WHERE a = ? AND b = '?' /* ? */ AND c = ?",
+			expected: "SELECT * FROM t WHERE a = $1 AND b = '?' /* ? */ AND c = $2",
+			refs:     []ref{{ordinal: 1}, {ordinal: 2}},
+		},
+		{
+			target:   sqlinfo.PostgreSQL,
+			query:    "SELECT * FROM t WHERE a = :user OR b = @org OR c = :user",
+			expected: "SELECT * FROM t WHERE a = $1 OR b = $2 OR c = $1",
+			refs:     []ref{{name: "user"}, {name: "org"}},
+		},
+		{
+			target:   sqlinfo.MySQL,
+			query:    "SELECT * FROM t WHERE a = :user OR b = :org OR c = :user",
+			expected: "SELECT * FROM t WHERE a = ? OR b = ? OR c = ?",
+			refs:     []ref{{name: "user"}, {name: "org"}, {name: "user"}},
+		},
+		{
+			target:   sqlinfo.MySQL,
+			query:    "SELECT $2::text, $1",
+			expected: "SELECT ?::text, ?",
+			refs:     []ref{{ordinal: 2}, {ordinal: 1}},
+		},
+		{
+			target:   sqlinfo.MySQL,
+			query:    `SELECT * FROM t WHERE a = 'it\'s :a' AND b = :b # :c`,
+			expected: `SELECT * FROM t WHERE a = 'it\'s :a' AND b = ? # :c`,
+			refs:     []ref{{name: "b"}},
+		},
+		{
+			target:   sqlinfo.MySQL,
+			query:    "SELECT @rank := @rank + 1, a FROM t WHERE b = :b",
+			expected: "SELECT @rank := @rank + 1, a FROM t WHERE b = ?",
+			refs:     []ref{{name: "b"}},
+		},
+		{
+			target:   sqlinfo.MySQL,
+			source:   sqlinfo.SQLite,
+			query:    "SELECT * FROM t WHERE a = @a",
+			expected: "SELECT * FROM t WHERE a = ?",
+			refs:     []ref{{name: "a"}},
+		},
+		{target: sqlinfo.PostgreSQL, query: "SELECT $1, $2, $1"},
+		{target: sqlinfo.MySQL, query: "SET @rank = 0"},
+		{target: sqlinfo.MySQL, query: "SELECT ?, ? FROM t WHERE a = ':a'"},
+		{target: sqlinfo.SQLite, query: "SELECT 1"},
+	}
+	for _, test := range tests {
+		in := New(Config{Target: test.target, Source: test.source})
+		tr := in.translate(test.query)
+		if test.expected == "" {
+			if tr != nil {
+				t.Errorf("%q should not be translated, got %q", test.query, tr.query)
+			}
+			continue
+		}
+		if tr == nil {
+			t.Errorf("%q should be translated", test.query)
+			continue
+		}
+		if tr.query != test.expected || !reflect.DeepEqual(tr.refs, test.refs) {
+			t.Errorf("unexpected translation of %q:\n got: %s %v\nwant: %s %v", test.query, tr.query, tr.refs, test.expected, test.refs)
+		}
+	}
+}
+
+func openDB(t *testing.T, in *Interceptor) (*sql.DB, *fakedb.Connector) {
+	con := &fakedb.Connector{
+		NumInput: func(query string) int {
+			return sqlinfo.NumInput(sqlinfo.MySQL, query)
+		},
+	}
+	db := sql.OpenDB(sqlmw.Connector(con, in))
+	t.Cleanup(func() {
+		if err := db.Close(); err != nil {
+			t.Errorf("Failed to close db: %v", err)
+		}
+	})
+	return db, con
+}
+
+func values(args []driver.NamedValue) []interface{} {
+	var vs []interface{}
+	for i, a := range args {
+		if a.Name != "" || a.Ordinal != i+1 {
+			return nil
+		}
+		vs = append(vs, a.Value)
+	}
+	return vs
+}
+
+func TestNamedArguments(t *testing.T) {
+	in := New(Config{Target: sqlinfo.MySQL})
+	db, con := openDB(t, in)
+	ctx := context.Background()
+
+	const query = "UPDATE t SET a = :a WHERE b = :b OR c = :a"
+	if _, err := db.ExecContext(ctx, query, sql.Named("b", "B"), sql.Named("a", "A")); err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+
+	stmt, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		t.Fatalf("Prepare failed: %v", err)
+	}
+	defer stmt.Close()
+	// database/sql checks the arguments against the 2 inputs of the query,
+	// not the 3 of the translated one
+	if _, err := stmt.ExecContext(ctx, sql.Named("a", "A"), sql.Named("b", "B")); err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	if _, err := stmt.ExecContext(ctx, sql.Named("a", "A"), sql.Named("c", "C")); err == nil || !strings.Contains(err.Error(), `missing argument "b"`) {
+		t.Errorf("expected a missing argument error, got %v", err)
+	}
+
+	calls := con.Calls()
+	if len(calls) != 2 {
+		t.Fatalf("expected 2 statements, got %d", len(calls))
+	}
+	for _, c := range calls {
+		if c.Query != "UPDATE t SET a = ? WHERE b = ? OR c = ?" {
+			t.Errorf("unexpected statement %q", c.Query)
+		}
+		if vs := values(c.Args); !reflect.DeepEqual(vs, []interface{}{"A", "B", "A"}) {
+			t.Errorf("unexpected arguments %v", c.Args)
+		}
+	}
+}
+
+func TestConformance(t *testing.T) {
+	sqlmwtest.RunConformance(t, func() sqlmw.Interceptor { return New(Config{}) })
+}
@@ -0,0 +1,235 @@
+package planwatch
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ngrok/sqlmw/fingerprint"
+	"github.com/ngrok/sqlmw/sqlinfo"
+)
+
+// explainQuery returns the EXPLAIN statement of query.
+func explainQuery(dialect sqlinfo.Dialect, query string) string {
+	switch dialect {
+	case sqlinfo.PostgreSQL:
+		return "EXPLAIN (FORMAT JSON) " + query
+	case sqlinfo.MySQL:
+		return "EXPLAIN FORMAT=JSON " + query
+	case sqlinfo.SQLite:
+		return "EXPLAIN QUERY PLAN " + query
+	}
+	return "EXPLAIN " + query
+}
+
+// Plan is the execution plan of a statement.
+type Plan struct {
+	// Hash identifies the shape of the plan.
+	Hash string
+	// Shape is the shape of the plan: its nodes, the tables and indexes
+	// they read and how, without the estimates of their cost.
+	Shape string
+	// Raw is the output of EXPLAIN.
+	Raw string
+}
+
+// readPlan reads the plan of the rows of an EXPLAIN statement.
+func readPlan(dialect sqlinfo.Dialect, rows driver.Rows) (Plan, error) {
+	cols := rows.Columns()
+	var table [][]string
+	dest := make([]driver.Value, len(cols))
+	for {
+		if err := rows.Next(dest); err == io.EOF {
+			break
+		} else if err != nil {
+			return Plan{}, err
+		}
+		row := make([]string, len(dest))
+		for i, v := range dest {
+			row[i] = text(v)
+		}
+		table = append(table, row)
+	}
+
+	var raw, shape string
+	switch dialect {
+	case sqlinfo.PostgreSQL, sqlinfo.MySQL:
+		var b strings.Builder
+		for _, row := range table {
+			if len(row) > 0 {
+				b.WriteString(row[0])
+			}
+		}
+		raw = b.String()
+		var err error
+		if shape, err = jsonShape(raw); err != nil {
+			return Plan{}, fmt.Errorf("planwatch: reading plan: %w", err)
+		}
+	case sqlinfo.SQLite:
+		raw, shape = sqliteShape(cols, table)
+	default:
+		raw, shape = textShape(table)
+	}
+
+	h := fnv.New64a()
+	io.WriteString(h, shape)
+	return Plan{Hash: fingerprint.Hex(h.Sum64()), Shape: shape, Raw: raw}, nil
+}
+
+func text(v driver.Value) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case []byte:
+		return string(v)
+	case string:
+		return v
+	}
+	return fmt.Sprint(v)
+}
+
+// shapeKeys are the keys of the JSON plans of PostgreSQL and MySQL telling
+// their shape.
+var shapeKeys = map[string]bool{
+	// PostgreSQL
+	"Node Type":           true,
+	"Relation Name":       true,
+	"Index Name":          true,
+	"Join Type":           true,
+	"Strategy":            true,
+	"Parent Relationship": true,
+	"Scan Direction":      true,
+	"Subplan Name":        true,
+	"CTE Name":            true,
+	// MySQL
+	"access_type":           true,
+	"table_name":            true,
+	"key":                   true,
+	"using_index":           true,
+	"using_filesort":        true,
+	"using_temporary_table": true,
+	"using_join_buffer":     true,
+	"message":               true,
+}
+
+// jsonShape returns the shape of a JSON plan: one line per node holding one
+// of shapeKeys, indented by depth.
+func jsonShape(raw string) (string, error) {
+	dec := json.NewDecoder(strings.NewReader(raw))
+	dec.UseNumber()
+	var plan interface{}
+	if err := dec.Decode(&plan); err != nil {
+		return "", err
+	}
+	var b bytes.Buffer
+	walkJSON(&b, plan, 0)
+	return b.String(), nil
+}
+
+func walkJSON(b *bytes.Buffer, v interface{}, depth int) {
+	switch v := v.(type) {
+	case []interface{}:
+		for _, e := range v {
+			walkJSON(b, e, depth)
+		}
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		var attrs []string
+		for _, k := range keys {
+			if !shapeKeys[k] {
+				continue
+			}
+			switch e := v[k].(type) {
+			case string:
+				attrs = append(attrs, k+"="+e)
+			case bool:
+				attrs = append(attrs, k+"="+strconv.FormatBool(e))
+			}
+		}
+		if len(attrs) > 0 {
+			b.WriteString(strings.Repeat("  ", depth))
+			b.WriteString(strings.Join(attrs, " "))
+			b.WriteByte('\n')
+			depth++
+		}
+		for _, k := range keys {
+			switch v[k].(type) {
+			case []interface{}, map[string]interface{}:
+				walkJSON(b, v[k], depth)
+			}
+		}
+	}
+}
+
+// sqliteShape returns the output and the shape of EXPLAIN QUERY PLAN: the
+// details of its nodes, indented by depth.
+func sqliteShape(cols []string, table [][]string) (raw, shape string) {
+	id, parent, detail := -1, -1, len(cols)-1
+	for i, c := range cols {
+		switch c {
+		case "id":
+			id = i
+		case "parent":
+			parent = i
+		case "detail":
+			detail = i
+		}
+	}
+
+	var rb, sb strings.Builder
+	depths := make(map[string]int)
+	for _, row := range table {
+		rb.WriteString(strings.Join(row, "|"))
+		rb.WriteByte('\n')
+		if detail < 0 || detail >= len(row) {
+			continue
+		}
+		depth := 0
+		if id >= 0 && parent >= 0 {
+			if d, ok := depths[row[parent]]; ok {
+				depth = d + 1
+			}
+			depths[row[id]] = depth
+		}
+		sb.WriteString(strings.Repeat("  ", depth))
+		sb.WriteString(row[detail])
+		sb.WriteByte('\n')
+	}
+	return rb.String(), sb.String()
+}
+
+// textShape returns the output and the shape of a plain EXPLAIN: its text
+// with the numbers left out.
+func textShape(table [][]string) (raw, shape string) {
+	var rb, sb strings.Builder
+	for _, row := range table {
+		line := strings.Join(row, "|")
+		rb.WriteString(line)
+		rb.WriteByte('\n')
+		digits := false
+		for i := 0; i < len(line); i++ {
+			c := line[i]
+			isDigit := c >= '0' && c <= '9' || (digits && c == '.' && i+1 < len(line) && line[i+1] >= '0' && line[i+1] <= '9')
+			if isDigit && !digits {
+				sb.WriteByte('N')
+			}
+			if !isDigit {
+				sb.WriteByte(c)
+			}
+			digits = isDigit
+		}
+		sb.WriteByte('\n')
+	}
+	return rb.String(), sb.String()
+}
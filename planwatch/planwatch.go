@@ -0,0 +1,278 @@
+// Package planwatch provides an sqlmw.Interceptor watching the execution
+// plans of the queries, to catch a plan regression, like an index scan
+// turning into a sequential scan, before the users do.
+//
+// The plan of a fingerprint is sampled at most once per Config.Interval: its
+// statement is followed by an EXPLAIN run on the same connection, with the
+// same arguments, once its rows are closed, so that the application has read
+// its results before the EXPLAIN runs. The plans of PostgreSQL and MySQL are
+// read as JSON, the ones of SQLite with EXPLAIN QUERY PLAN. The shape of a
+// plan leaves out its estimates, and a Change is reported to Config.OnChange
+// when the shape of the plan of a fingerprint differs from the previous one.
+//
+// The EXPLAIN runs within the Close of the rows, which returns once it
+// completes or Config.Timeout elapses: the caller sampled pays for it, at
+// most once per Config.Throttle across all the callers.
+//
+// The EXPLAINs are spaced by Config.Throttle whatever their fingerprint, so
+// that they add negligible load. Only the read queries are sampled: the
+// statements with side effects, the ones locking rows and the ones run in a
+// transaction, where a failed EXPLAIN would abort a PostgreSQL transaction,
+// are skipped. The statements the driver cannot run without preparing them,
+// for which sqlmw.Conn returns driver.ErrSkip, are not sampled, and nothing
+// is sampled anymore once it returns it for a statement without arguments:
+// some drivers, like the one of MySQL, only prepare the statements with
+// arguments.
+package planwatch
+
+import (
+	"context"
+	"database/sql/driver"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ngrok/sqlmw"
+	"github.com/ngrok/sqlmw/fingerprint"
+	"github.com/ngrok/sqlmw/sqlinfo"
+)
+
+const (
+	defaultInterval        = 10 * time.Minute
+	defaultThrottle        = time.Second
+	defaultMaxFingerprints = 1000
+	defaultTimeout         = time.Second
+)
+
+// Change is a change of the plan of a fingerprint.
+type Change struct {
+	Fingerprint string
+	// Query is the statement whose plan changed.
+	Query    string
+	Previous Plan
+	Current  Plan
+}
+
+// Config configures an Interceptor.
+type Config struct {
+	// Dialect selects the EXPLAIN statement. Defaults to sqlinfo.Generic,
+	// for which a plain EXPLAIN is run and its text is compared with the
+	// numbers left out.
+	Dialect sqlinfo.Dialect
+
+	// Interval is the minimum time between two samples of the plan of a
+	// fingerprint. Defaults to 10 minutes.
+	Interval time.Duration
+
+	// Throttle is the minimum time between two EXPLAINs, whatever their
+	// fingerprint. Defaults to 1 second.
+	Throttle time.Duration
+
+	// Timeout bounds the execution of an EXPLAIN, and so the time the
+	// Close of the rows it follows is delayed by. Defaults to 1 second.
+	Timeout time.Duration
+
+	// MaxFingerprints bounds the number of fingerprints whose plan is
+	// watched. Defaults to 1000.
+	MaxFingerprints int
+
+	// OnChange, if set, is called with the plan changes.
+	OnChange func(Change)
+
+	// OnError, if set, is called with the errors of the EXPLAINs. The
+	// statements are not failed by them.
+	OnError func(error)
+}
+
+// watched is the plan of a fingerprint.
+type watched struct {
+	sampled time.Time
+	plan    Plan
+	known   bool
+}
+
+// Interceptor watches the plans of the queries.
+type Interceptor struct {
+	// unsupported is accessed atomically, set when the driver cannot run
+	// the EXPLAINs
+	unsupported int32
+
+	sqlmw.NullInterceptor
+
+	cfg Config
+
+	mu   sync.Mutex
+	last time.Time // of the last EXPLAIN
+	fps  map[string]*watched
+}
+
+// New returns an Interceptor for the supplied configuration.
+func New(cfg Config) *Interceptor {
+	if cfg.Interval <= 0 {
+		cfg.Interval = defaultInterval
+	}
+	if cfg.Throttle <= 0 {
+		cfg.Throttle = defaultThrottle
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultTimeout
+	}
+	if cfg.MaxFingerprints <= 0 {
+		cfg.MaxFingerprints = defaultMaxFingerprints
+	}
+	return &Interceptor{cfg: cfg, fps: make(map[string]*watched)}
+}
+
+// Plans returns the last plan sampled of every fingerprint.
+func (in *Interceptor) Plans() map[string]Plan {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	plans := make(map[string]Plan, len(in.fps))
+	for fp, w := range in.fps {
+		if w.known {
+			plans[fp] = w.plan
+		}
+	}
+	return plans
+}
+
+// due reports whether the plan of fp must be sampled now, and reserves the
+// sample.
+func (in *Interceptor) due(fp string) bool {
+	now := time.Now()
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	if now.Sub(in.last) < in.cfg.Throttle {
+		return false
+	}
+	w := in.fps[fp]
+	if w == nil {
+		if len(in.fps) >= in.cfg.MaxFingerprints {
+			return false
+		}
+		w = &watched{}
+		in.fps[fp] = w
+	} else if now.Sub(w.sampled) < in.cfg.Interval {
+		return false
+	}
+	in.last, w.sampled = now, now
+	return true
+}
+
+type pendingKey struct{}
+
+// pending is a sample due after the rows of a query are closed.
+type pending struct {
+	fp    string
+	query string
+	args  []driver.NamedValue
+}
+
+// reserve returns the context of the rows of query, carrying the sample of
+// its plan when it is due.
+func (in *Interceptor) reserve(ctx context.Context, query string, args []driver.NamedValue) context.Context {
+	if atomic.LoadInt32(&in.unsupported) != 0 {
+		return ctx
+	}
+	st, ok := sqlinfo.FromContext(ctx)
+	if !ok {
+		st = sqlinfo.Parse(in.cfg.Dialect, query)
+	}
+	if st.Kind != sqlinfo.Read || st.ForUpdate {
+		return ctx
+	}
+	c, ok := sqlmw.ConnFromContext(ctx)
+	if !ok || c.InTx() {
+		return ctx
+	}
+	fp := fingerprint.Normalize(query)
+	if strings.Contains(fp, ";") || !in.due(fp) {
+		return ctx
+	}
+	return context.WithValue(ctx, pendingKey{}, &pending{fp: fp, query: query, args: copyArgs(args)})
+}
+
+// sample samples the plan of p on the connection of ctx.
+func (in *Interceptor) sample(ctx context.Context, p *pending) {
+	c, ok := sqlmw.ConnFromContext(ctx)
+	if !ok {
+		return
+	}
+	plan, err := in.explain(ctx, c, p.query, p.args)
+	if err == driver.ErrSkip {
+		if len(p.args) == 0 {
+			// the driver cannot run a statement on its own, nor ever will
+			atomic.StoreInt32(&in.unsupported, 1)
+		}
+		return
+	}
+	if err != nil {
+		if in.cfg.OnError != nil {
+			in.cfg.OnError(err)
+		}
+		return
+	}
+
+	in.mu.Lock()
+	w := in.fps[p.fp]
+	prev, known := w.plan, w.known
+	w.plan, w.known = plan, true
+	in.mu.Unlock()
+
+	if known && prev.Hash != plan.Hash && in.cfg.OnChange != nil {
+		in.cfg.OnChange(Change{Fingerprint: p.fp, Query: p.query, Previous: prev, Current: plan})
+	}
+}
+
+// explain returns the plan of query, run on c.
+func (in *Interceptor) explain(ctx context.Context, c *sqlmw.Conn, query string, args []driver.NamedValue) (Plan, error) {
+	ctx, cancel := context.WithTimeout(ctx, in.cfg.Timeout)
+	defer cancel()
+	rows, err := c.QueryContext(ctx, explainQuery(in.cfg.Dialect, query), args)
+	if err != nil {
+		return Plan{}, err
+	}
+	plan, err := readPlan(in.cfg.Dialect, rows)
+	if cerr := rows.Close(); err == nil {
+		err = cerr
+	}
+	return plan, err
+}
+
+// copyArgs copies args so that they can be used after the call returns.
+func copyArgs(args []driver.NamedValue) []driver.NamedValue {
+	cp := make([]driver.NamedValue, len(args))
+	for i, a := range args {
+		if b, ok := a.Value.([]byte); ok {
+			a.Value = append([]byte(nil), b...)
+		}
+		cp[i] = a
+	}
+	return cp
+}
+
+func (in *Interceptor) ConnQueryContext(ctx context.Context, conn driver.QueryerContext, query string, args []driver.NamedValue) (context.Context, driver.Rows, error) {
+	rows, err := conn.QueryContext(ctx, query, args)
+	if err != nil {
+		return ctx, nil, err
+	}
+	return in.reserve(ctx, query, args), rows, nil
+}
+
+func (in *Interceptor) StmtQueryContext(ctx context.Context, stmt driver.StmtQueryContext, query string, args []driver.NamedValue) (context.Context, driver.Rows, error) {
+	rows, err := stmt.QueryContext(ctx, args)
+	if err != nil {
+		return ctx, nil, err
+	}
+	return in.reserve(ctx, query, args), rows, nil
+}
+
+func (in *Interceptor) RowsClose(ctx context.Context, rows driver.Rows) error {
+	err := rows.Close()
+	if p, ok := ctx.Value(pendingKey{}).(*pending); ok && err == nil {
+		// the rows were read, the connection is still held for them
+		in.sample(ctx, p)
+	}
+	return err
+}
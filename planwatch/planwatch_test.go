@@ -0,0 +1,211 @@
+package planwatch
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ngrok/sqlmw"
+	"github.com/ngrok/sqlmw/internal/fakedb"
+	"github.com/ngrok/sqlmw/sqlinfo"
+)
+
+func openDB(t *testing.T, in *Interceptor, explain func(query string) (*fakedb.Response, error)) (*sql.DB, *fakedb.Connector) {
+	con := &fakedb.Connector{Handler: func(ctx context.Context, query string, args []driver.NamedValue) (*fakedb.Response, error) {
+		if strings.HasPrefix(query, "EXPLAIN") {
+			return explain(query)
+		}
+		return &fakedb.Response{Columns: []string{"a"}, Rows: [][]driver.Value{{int64(1)}}}, nil
+	}}
+	db := sql.OpenDB(sqlmw.Connector(con, in))
+	t.Cleanup(func() {
+		if err := db.Close(); err != nil {
+			t.Errorf("Failed to close db: %v", err)
+		}
+	})
+	return db, con
+}
+
+func query(t *testing.T, db interface {
+	Query(string, ...interface{}) (*sql.Rows, error)
+}, q string, args ...interface{}) {
+	t.Helper()
+	rows, err := db.Query(q, args...)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if err := rows.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+}
+
+const pgPlan = `[{"Plan": {"Node Type": "%s", "Relation Name": "users", %s"Startup Cost": 0.29, "Total Cost": %d, "Plan Rows": 1}}]`
+
+func TestChange(t *testing.T) {
+	var changes []Change
+	in := New(Config{Dialect: sqlinfo.PostgreSQL, Interval: time.Nanosecond, Throttle: time.Nanosecond, OnChange: func(c Change) {
+		changes = append(changes, c)
+	}})
+	cost := 8
+	node, index := "Index Scan", `"Index Name": "users_email_idx", `
+	db, con := openDB(t, in, func(query string) (*fakedb.Response, error) {
+		cost++
+		return &fakedb.Response{Columns: []string{"QUERY PLAN"}, Rows: [][]driver.Value{{[]byte(fmt.Sprintf(pgPlan, node, index, cost))}}}, nil
+	})
+
+	query(t, db, "SELECT * FROM users WHERE email = $1", "a@example.com")
+	query(t, db, "SELECT * FROM users WHERE email = $1", "b@example.com")
+	if len(changes) != 0 {
+		t.Fatalf("got changes %v with a stable plan", changes)
+	}
+	node, index = "Seq Scan", ""
+	query(t, db, "SELECT * FROM users WHERE email = $1", "c@example.com")
+
+	if len(changes) != 1 {
+		t.Fatalf("got %d changes, want 1", len(changes))
+	}
+	c := changes[0]
+	if c.Fingerprint != "select * from users where email = ?" || c.Query != "SELECT * FROM users WHERE email = $1" {
+		t.Errorf("got change of %q (%q)", c.Fingerprint, c.Query)
+	}
+	if want := "Index Name=users_email_idx Node Type=Index Scan Relation Name=users\n"; c.Previous.Shape != want {
+		t.Errorf("got previous shape %q, want %q", c.Previous.Shape, want)
+	}
+	if want := "Node Type=Seq Scan Relation Name=users\n"; c.Current.Shape != want {
+		t.Errorf("got current shape %q, want %q", c.Current.Shape, want)
+	}
+	if got := in.Plans()[c.Fingerprint]; got.Hash != c.Current.Hash || !strings.Contains(got.Raw, `"Total Cost": 11`) {
+		t.Errorf("got plan %+v", got)
+	}
+
+	calls := con.Calls()
+	if got, want := calls[len(calls)-1].Query, "EXPLAIN (FORMAT JSON) SELECT * FROM users WHERE email = $1"; got != want {
+		t.Errorf("got explain %q, want %q", got, want)
+	}
+	if got := calls[len(calls)-1].Args; len(got) != 1 || got[0].Value != "c@example.com" {
+		t.Errorf("got explain args %v", got)
+	}
+}
+
+func TestSkip(t *testing.T) {
+	in := New(Config{Dialect: sqlinfo.PostgreSQL, Interval: time.Hour, Throttle: time.Nanosecond})
+	explains := 0
+	db, _ := openDB(t, in, func(query string) (*fakedb.Response, error) {
+		explains++
+		return &fakedb.Response{Columns: []string{"QUERY PLAN"}, Rows: [][]driver.Value{{`[{"Plan": {"Node Type": "Result"}}]`}}}, nil
+	})
+
+	query(t, db, "SELECT 1")
+	// within the interval
+	query(t, db, "SELECT 2")
+	// side effects
+	query(t, db, "DELETE FROM users RETURNING id")
+	query(t, db, "SELECT * FROM users FOR UPDATE")
+	query(t, db, "SELECT nextval('s'); SELECT 1")
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Begin failed: %v", err)
+	}
+	query(t, tx, "SELECT * FROM users")
+	if err := tx.Rollback(); err != nil {
+		t.Fatalf("Rollback failed: %v", err)
+	}
+	if explains != 1 {
+		t.Errorf("got %d explains, want 1", explains)
+	}
+
+	// throttled whatever the fingerprint
+	in = New(Config{Dialect: sqlinfo.PostgreSQL, Throttle: time.Hour})
+	explains = 0
+	db, _ = openDB(t, in, func(query string) (*fakedb.Response, error) {
+		explains++
+		return &fakedb.Response{Columns: []string{"QUERY PLAN"}, Rows: [][]driver.Value{{`[{"Plan": {"Node Type": "Result"}}]`}}}, nil
+	})
+	query(t, db, "SELECT 1")
+	query(t, db, "SELECT * FROM users")
+	if explains != 1 {
+		t.Errorf("got %d explains, want 1", explains)
+	}
+}
+
+func TestSQLite(t *testing.T) {
+	var changes []Change
+	in := New(Config{Dialect: sqlinfo.SQLite, Interval: time.Nanosecond, Throttle: time.Nanosecond, OnChange: func(c Change) {
+		changes = append(changes, c)
+	}})
+	plans := [][][]driver.Value{
+		{{int64(3), int64(0), int64(0), "SEARCH orders USING INDEX orders_user_idx (user_id=?)"}},
+		{{int64(2), int64(0), int64(0), "SCAN orders"}, {int64(5), int64(2), int64(0), "USE TEMP B-TREE FOR ORDER BY"}},
+	}
+	db, _ := openDB(t, in, func(query string) (*fakedb.Response, error) {
+		if query != "EXPLAIN QUERY PLAN SELECT * FROM orders WHERE user_id = ?" {
+			return nil, fmt.Errorf("unexpected explain %q", query)
+		}
+		rows := plans[0]
+		plans = plans[1:]
+		return &fakedb.Response{Columns: []string{"id", "parent", "notused", "detail"}, Rows: rows}, nil
+	})
+
+	query(t, db, "SELECT * FROM orders WHERE user_id = ?", 1)
+	query(t, db, "SELECT * FROM orders WHERE user_id = ?", 2)
+	if len(changes) != 1 {
+		t.Fatalf("got %d changes, want 1", len(changes))
+	}
+	if got, want := changes[0].Current.Shape, "SCAN orders\n  USE TEMP B-TREE FOR ORDER BY\n"; got != want {
+		t.Errorf("got shape %q, want %q", got, want)
+	}
+}
+
+func TestError(t *testing.T) {
+	var errs []error
+	in := New(Config{Dialect: sqlinfo.MySQL, OnError: func(err error) { errs = append(errs, err) }})
+	explainErr := errors.New("syntax error")
+	db, con := openDB(t, in, func(query string) (*fakedb.Response, error) {
+		return nil, explainErr
+	})
+
+	query(t, db, "SELECT * FROM users")
+	if !reflect.DeepEqual(errs, []error{explainErr}) {
+		t.Errorf("got errors %v, want %v", errs, explainErr)
+	}
+	want := []string{"SELECT * FROM users", "EXPLAIN FORMAT=JSON SELECT * FROM users"}
+	if got := con.Queries(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got queries %q, want %q", got, want)
+	}
+}
+
+func TestTextShape(t *testing.T) {
+	_, shape := textShape([][]string{{"Seq Scan on users  (cost=0.00..35.50 rows=2550 width=4)"}})
+	if want := "Seq Scan on users  (cost=N..N rows=N width=N)\n"; shape != want {
+		t.Errorf("got shape %q, want %q", shape, want)
+	}
+}
+
+func TestUnsupported(t *testing.T) {
+	var errs []error
+	in := New(Config{Interval: time.Nanosecond, Throttle: time.Nanosecond, OnError: func(err error) { errs = append(errs, err) }})
+	db, con := openDB(t, in, func(query string) (*fakedb.Response, error) {
+		return nil, driver.ErrSkip
+	})
+
+	// only the statements with arguments may need to be prepared
+	query(t, db, "SELECT * FROM users WHERE id = ?", 1)
+	query(t, db, "SELECT * FROM users")
+	query(t, db, "SELECT * FROM users")
+	if len(errs) != 0 {
+		t.Errorf("got errors %v", errs)
+	}
+	want := []string{
+		"SELECT * FROM users WHERE id = ?", "EXPLAIN SELECT * FROM users WHERE id = ?",
+		"SELECT * FROM users", "EXPLAIN SELECT * FROM users", "SELECT * FROM users",
+	}
+	if got := con.Queries(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got queries %q, want %q", got, want)
+	}
+}
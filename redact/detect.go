@@ -0,0 +1,92 @@
+package redact
+
+import (
+	"database/sql/driver"
+	"regexp"
+	"strings"
+)
+
+// Detector reports whether a value holds sensitive data, whatever its name.
+type Detector func(v driver.Value) bool
+
+// Regexp returns a Detector matching the values whose text matches re.
+func Regexp(re *regexp.Regexp) Detector {
+	return func(v driver.Value) bool {
+		return v != nil && re.MatchString(text(v))
+	}
+}
+
+var emailPattern = regexp.MustCompile(`[^@\s]+@[^@\s]+\.[a-zA-Z]{2,}`)
+
+// textual returns the text of v when v is a string or a []byte. The text
+// detectors ignore the other values, like the int64 ids whose digits may
+// pass for a card number.
+func textual(v driver.Value) (string, bool) {
+	switch v := v.(type) {
+	case string:
+		return v, true
+	case []byte:
+		return string(v), true
+	}
+	return "", false
+}
+
+// Email detects the strings holding an email address.
+func Email(v driver.Value) bool {
+	s, ok := textual(v)
+	return ok && emailPattern.MatchString(s)
+}
+
+// Luhn detects the strings that are card numbers: 13 to 19 digits, possibly
+// grouped by spaces or dashes, passing the Luhn checksum.
+func Luhn(v driver.Value) bool {
+	s, ok := textual(v)
+	if !ok {
+		return false
+	}
+	sum, n := 0, 0
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '-':
+			continue
+		case c < '0' || c > '9':
+			return false
+		}
+		d := int(c - '0')
+		if n%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		n++
+	}
+	return n >= 13 && n <= 19 && sum%10 == 0
+}
+
+// JWT detects the strings shaped like a JSON Web Token: three base64url
+// segments separated by dots, the first one encoding a JSON object.
+func JWT(v driver.Value) bool {
+	s, ok := textual(v)
+	if !ok {
+		return false
+	}
+	parts := strings.Split(strings.TrimPrefix(s, "Bearer "), ".")
+	if len(parts) != 3 || !strings.HasPrefix(parts[0], "eyJ") {
+		return false
+	}
+	for _, p := range parts[:2] {
+		if p == "" {
+			return false
+		}
+		for i := 0; i < len(p); i++ {
+			c := p[i]
+			if !('a' <= c && c <= 'z') && !('A' <= c && c <= 'Z') && !('0' <= c && c <= '9') && c != '-' && c != '_' {
+				return false
+			}
+		}
+	}
+	return true
+}
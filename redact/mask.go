@@ -0,0 +1,95 @@
+package redact
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql/driver"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Mask returns the masked form of a value.
+type Mask func(v driver.Value) driver.Value
+
+// Drop masks values with nil.
+func Drop(driver.Value) driver.Value {
+	return nil
+}
+
+// Hash masks values with the hex encoded HMAC-SHA256 of their text under
+// key, or their SHA-256 if key is empty. Equal values have equal hashes, so
+// that they can still be correlated.
+func Hash(key []byte) Mask {
+	return func(v driver.Value) driver.Value {
+		if v == nil {
+			return nil
+		}
+		var h hash.Hash
+		if len(key) == 0 {
+			h = sha256.New()
+		} else {
+			h = hmac.New(sha256.New, key)
+		}
+		h.Write([]byte(text(v)))
+		return hex.EncodeToString(h.Sum(nil))
+	}
+}
+
+// Truncate masks values with the first n characters of their text followed
+// by an ellipsis.
+func Truncate(n int) Mask {
+	return func(v driver.Value) driver.Value {
+		if v == nil {
+			return nil
+		}
+		s := text(v)
+		if utf8.RuneCountInString(s) <= n {
+			return s
+		}
+		i := 0
+		for j := 0; j < n; j++ {
+			_, size := utf8.DecodeRuneInString(s[i:])
+			i += size
+		}
+		return s[:i] + "…"
+	}
+}
+
+// FormatPreserving masks values keeping their shape: every letter of their
+// text but the last keep characters is replaced by x, or X in upper case,
+// and every digit by 0, so that "4111-1111-1111-1234" becomes
+// "0000-0000-0000-1234" with keep set to 4.
+func FormatPreserving(keep int) Mask {
+	return func(v driver.Value) driver.Value {
+		if v == nil {
+			return nil
+		}
+		runes := []rune(text(v))
+		for i := 0; i < len(runes)-keep; i++ {
+			switch r := runes[i]; {
+			case unicode.IsDigit(r):
+				runes[i] = '0'
+			case unicode.IsUpper(r):
+				runes[i] = 'X'
+			case unicode.IsLetter(r):
+				runes[i] = 'x'
+			}
+		}
+		return string(runes)
+	}
+}
+
+// text returns the text of a value.
+func text(v driver.Value) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,238 @@
+// Package redact masks the sensitive values of statement arguments and of
+// rows, like emails, tokens or card numbers, before interceptors log or
+// trace them:
+//
+//	r := redact.New(redact.Config{Rules: []redact.Rule{
+//		{Names: []string{"email", "password"}, Mask: redact.Hash(key)},
+//		{Detect: redact.Luhn, Mask: redact.FormatPreserving(4)},
+//		{Detect: redact.JWT},
+//	}})
+//	audit.New(audit.Config{Sink: sink, Redact: r.Values})
+//
+// A Rule matches values by name, by position within the statements of a
+// fingerprint, or by content through a Detector. The name of an argument is
+// its parameter name, or the column it is compared to, assigned to or
+// inserted into, see sqlinfo.ParamColumns. The name of a row value is the
+// name of its column.
+//
+// The Interceptor of this package masks the rows returned to the
+// application, keeping the driver types of their values, see Interceptor.
+package redact
+
+import (
+	"context"
+	"database/sql/driver"
+	"reflect"
+	"strings"
+
+	"github.com/ngrok/sqlmw"
+	"github.com/ngrok/sqlmw/fingerprint"
+	"github.com/ngrok/sqlmw/sqlinfo"
+)
+
+// Rule masks the values it matches. It matches a value when any of its
+// criteria does.
+type Rule struct {
+	// Names matches the values by name, regardless of case.
+	Names []string
+
+	// Fingerprint and Positions match the arguments at Positions, counted
+	// from 1, of the statements with this fingerprint, see
+	// fingerprint.Normalize.
+	Fingerprint string
+	Positions   []int
+
+	// Detect, if set, matches the values by content.
+	Detect Detector
+
+	// Mask masks the values. Defaults to Drop.
+	Mask Mask
+}
+
+func (r *Rule) matchesName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, n := range r.Names {
+		if strings.EqualFold(n, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Rule) matchesPosition(fp string, ordinal int) bool {
+	if r.Fingerprint == "" || r.Fingerprint != fp {
+		return false
+	}
+	for _, p := range r.Positions {
+		if p == ordinal {
+			return true
+		}
+	}
+	return false
+}
+
+// Config configures a Redactor.
+type Config struct {
+	// Rules are the rules to apply. The first rule matching a value masks
+	// it.
+	Rules []Rule
+
+	// Dialect is used to find the columns of the arguments. Defaults to
+	// sqlinfo.Generic.
+	Dialect sqlinfo.Dialect
+}
+
+// Redactor masks values according to rules. It is safe for concurrent use.
+type Redactor struct {
+	cfg          Config
+	fingerprints bool
+	names        bool
+}
+
+// New returns a Redactor for the supplied configuration.
+func New(cfg Config) *Redactor {
+	r := &Redactor{cfg: cfg}
+	for i := range cfg.Rules {
+		rule := &cfg.Rules[i]
+		if rule.Mask == nil {
+			rule.Mask = Drop
+		}
+		r.fingerprints = r.fingerprints || rule.Fingerprint != ""
+		r.names = r.names || len(rule.Names) > 0
+	}
+	return r
+}
+
+// Args returns a copy of the arguments of query with their sensitive values
+// masked.
+func (r *Redactor) Args(query string, args []driver.NamedValue) []driver.NamedValue {
+	out := make([]driver.NamedValue, len(args))
+	copy(out, args)
+
+	var fp string
+	if r.fingerprints {
+		fp = fingerprint.Normalize(query)
+	}
+	var cols sqlinfo.Columns
+	if r.names {
+		cols = sqlinfo.ParamColumns(r.cfg.Dialect, query)
+	}
+	for i := range out {
+		arg := &out[i]
+		column := cols.Of(*arg)
+		for j := range r.cfg.Rules {
+			rule := &r.cfg.Rules[j]
+			if rule.matchesName(arg.Name) || rule.matchesName(column) ||
+				rule.matchesPosition(fp, arg.Ordinal) || (rule.Detect != nil && rule.Detect(arg.Value)) {
+				arg.Value = rule.Mask(arg.Value)
+				break
+			}
+		}
+	}
+	return out
+}
+
+// Values returns the values of the arguments of query with their sensitive
+// values masked.
+func (r *Redactor) Values(query string, args []driver.NamedValue) []interface{} {
+	masked := r.Args(query, args)
+	values := make([]interface{}, len(masked))
+	for i, arg := range masked {
+		values[i] = arg.Value
+	}
+	return values
+}
+
+// Row masks in place the sensitive values of a row with the given columns.
+func (r *Redactor) Row(columns []string, row []driver.Value) {
+	for i := range row {
+		var column string
+		if i < len(columns) {
+			column = columns[i]
+		}
+		for j := range r.cfg.Rules {
+			rule := &r.cfg.Rules[j]
+			if rule.matchesName(column) || (rule.Detect != nil && rule.Detect(row[i])) {
+				row[i] = rule.Mask(row[i])
+				break
+			}
+		}
+	}
+}
+
+// Interceptor masks the sensitive values of the rows returned to the
+// application. The masked values keep the type the driver returned, so
+// that they scan into the same destinations: text masks give []byte values
+// for []byte values, and a value whose mask returns another type, such as
+// the Hash of an int64, is dropped and returned as nil.
+type Interceptor struct {
+	sqlmw.NullInterceptor
+
+	r *Redactor
+}
+
+// NewInterceptor returns an Interceptor masking rows with r.
+func NewInterceptor(r *Redactor) *Interceptor {
+	return &Interceptor{r: r}
+}
+
+type rowsKey struct{}
+
+// resultColumns are the columns of the current result set of rows.
+type resultColumns struct {
+	names []string
+}
+
+func (in *Interceptor) ConnQueryContext(ctx context.Context, conn driver.QueryerContext, query string, args []driver.NamedValue) (context.Context, driver.Rows, error) {
+	rows, err := conn.QueryContext(ctx, query, args)
+	return context.WithValue(ctx, rowsKey{}, &resultColumns{}), rows, err
+}
+
+func (in *Interceptor) StmtQueryContext(ctx context.Context, stmt driver.StmtQueryContext, _ string, args []driver.NamedValue) (context.Context, driver.Rows, error) {
+	rows, err := stmt.QueryContext(ctx, args)
+	return context.WithValue(ctx, rowsKey{}, &resultColumns{}), rows, err
+}
+
+func (in *Interceptor) RowsNext(ctx context.Context, rows driver.Rows, dest []driver.Value) error {
+	rc, ok := ctx.Value(rowsKey{}).(*resultColumns)
+	if !ok {
+		rc = &resultColumns{}
+	}
+	if err := rows.Next(dest); err != nil {
+		// the next result set may have other columns
+		rc.names = nil
+		return err
+	}
+	if rc.names == nil || len(rc.names) != len(dest) {
+		rc.names = rows.Columns()
+	}
+
+	orig := make([]driver.Value, len(dest))
+	copy(orig, dest)
+	in.r.Row(rc.names, dest)
+	for i, v := range dest {
+		dest[i] = sameType(orig[i], v)
+	}
+	return nil
+}
+
+// sameType returns masked, the masked form of v, in the type of v, or nil
+// when it cannot be.
+func sameType(v, masked driver.Value) driver.Value {
+	if masked == nil || reflect.TypeOf(v) == reflect.TypeOf(masked) {
+		return masked
+	}
+	switch m := masked.(type) {
+	case string:
+		if _, ok := v.([]byte); ok {
+			return []byte(m)
+		}
+	case []byte:
+		if _, ok := v.(string); ok {
+			return string(m)
+		}
+	}
+	return nil
+}
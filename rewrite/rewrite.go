@@ -0,0 +1,211 @@
+// Package rewrite provides an sqlmw.Interceptor rewriting statements and
+// their arguments according to rules, e.g. to swap a table name during a
+// migration, to guard unbounded selects with a LIMIT or to add a predicate.
+//
+// Rules are applied in order to ConnExecContext, ConnQueryContext and
+// ConnPrepareContext statements, every rule seeing the output of the
+// previous ones. A rule matches a statement by fingerprint, by regular
+// expression, or both. Every rule changing the text or the arguments of a
+// statement is reported to Config.OnRewrite.
+//
+// The query of a prepared statement is rewritten once, when it is prepared,
+// and its arguments on every execution. The number of inputs the prepared
+// statement reports accounts for the placeholders added or removed by the
+// rewrite, so that database/sql keeps checking the arguments of the caller
+// against the original statement.
+package rewrite
+
+import (
+	"context"
+	"database/sql/driver"
+	"reflect"
+	"regexp"
+
+	"github.com/ngrok/sqlmw"
+	"github.com/ngrok/sqlmw/fingerprint"
+	"github.com/ngrok/sqlmw/internal/argstmt"
+	"github.com/ngrok/sqlmw/sqlinfo"
+)
+
+// Rule rewrites the statements it matches.
+type Rule struct {
+	// Name identifies the rule in the events reported to Config.OnRewrite.
+	Name string
+
+	// Fingerprint, if set, restricts the rule to the statements with this
+	// fingerprint, see fingerprint.Normalize.
+	Fingerprint string
+
+	// Pattern, if set, restricts the rule to the statements it matches.
+	Pattern *regexp.Regexp
+
+	// Replace, if set, replaces the matches of Pattern in the statement, as
+	// done by regexp.Regexp.ReplaceAllString.
+	Replace string
+
+	// Query, if set, returns the rewritten statement.
+	Query func(query string) string
+
+	// Args, if set, returns the rewritten arguments of the statement.
+	Args func(ctx context.Context, args []driver.NamedValue) ([]driver.NamedValue, error)
+}
+
+// matches reports whether the rule matches query. fp returns the
+// fingerprint of query.
+func (r *Rule) matches(query string, fp func() string) bool {
+	if r.Pattern != nil && !r.Pattern.MatchString(query) {
+		return false
+	}
+	return r.Fingerprint == "" || fp() == r.Fingerprint
+}
+
+// Event reports a rule changing a statement.
+type Event struct {
+	// Rule is the name of the rule.
+	Rule string
+	// Query is the statement the rule was applied to.
+	Query string
+	// Rewritten is the statement returned by the rule, the same as Query
+	// when the rule only changed the arguments.
+	Rewritten string
+	// Prepared is set when the statement is being prepared.
+	Prepared bool
+}
+
+// Config configures an Interceptor.
+type Config struct {
+	// Rules are the rules to apply, in order.
+	Rules []Rule
+
+	// OnRewrite, if set, is called for every rule changing the text or
+	// the arguments of a statement. The rules changing only the arguments
+	// are reported when they run, on every execution of a prepared
+	// statement.
+	OnRewrite func(Event)
+
+	// Dialect is used to count the placeholders of prepared statements.
+	// Defaults to sqlinfo.Generic.
+	Dialect sqlinfo.Dialect
+}
+
+// Interceptor rewrites statements.
+type Interceptor struct {
+	sqlmw.NullInterceptor
+
+	cfg Config
+}
+
+// New returns an Interceptor for the supplied configuration.
+func New(cfg Config) *Interceptor {
+	return &Interceptor{cfg: cfg}
+}
+
+// argRule is a rule whose Args must be applied to the arguments.
+type argRule struct {
+	*Rule
+	// query is the statement the rule was applied to when the rule left
+	// its text as is, to report the rule once it changes the arguments
+	query string
+}
+
+// rewriteQuery applies the rules to query and returns the rewritten query
+// and the rules whose Args must be applied to the arguments. The query is
+// normalized once, and again only after a rule rewrote it.
+func (in *Interceptor) rewriteQuery(query string, prepared bool) (string, []argRule) {
+	var argRules []argRule
+	var normalized, fp string
+	var ok bool
+	fingerprintOf := func() string {
+		if !ok || normalized != query {
+			ok = true
+			normalized, fp = query, fingerprint.Normalize(query)
+		}
+		return fp
+	}
+	for i := range in.cfg.Rules {
+		r := &in.cfg.Rules[i]
+		if !r.matches(query, fingerprintOf) {
+			continue
+		}
+		rewritten := query
+		if r.Pattern != nil && r.Replace != "" {
+			rewritten = r.Pattern.ReplaceAllString(rewritten, r.Replace)
+		}
+		if r.Query != nil {
+			rewritten = r.Query(rewritten)
+		}
+		changed := rewritten != query
+		if r.Args != nil {
+			ar := argRule{Rule: r}
+			if !changed {
+				ar.query = query
+			}
+			argRules = append(argRules, ar)
+		}
+		if changed && in.cfg.OnRewrite != nil {
+			in.cfg.OnRewrite(Event{Rule: r.Name, Query: query, Rewritten: rewritten, Prepared: prepared})
+		}
+		query = rewritten
+	}
+	return query, argRules
+}
+
+func (in *Interceptor) rewriteArgs(ctx context.Context, rules []argRule, args []driver.NamedValue) ([]driver.NamedValue, error) {
+	for _, r := range rules {
+		report := r.query != "" && in.cfg.OnRewrite != nil
+		var orig []driver.NamedValue
+		if report {
+			// the rule may modify args in place
+			orig = append(orig, args...)
+		}
+		var err error
+		if args, err = r.Args(ctx, args); err != nil {
+			return nil, err
+		}
+		if report && !reflect.DeepEqual(orig, args) {
+			in.cfg.OnRewrite(Event{Rule: r.Name, Query: r.query, Rewritten: r.query})
+		}
+	}
+	return args, nil
+}
+
+func (in *Interceptor) ConnExecContext(ctx context.Context, conn driver.ExecerContext, query string, args []driver.NamedValue) (driver.Result, error) {
+	query, argRules := in.rewriteQuery(query, false)
+	args, err := in.rewriteArgs(ctx, argRules, args)
+	if err != nil {
+		return nil, err
+	}
+	return conn.ExecContext(ctx, query, args)
+}
+
+func (in *Interceptor) ConnQueryContext(ctx context.Context, conn driver.QueryerContext, query string, args []driver.NamedValue) (context.Context, driver.Rows, error) {
+	query, argRules := in.rewriteQuery(query, false)
+	args, err := in.rewriteArgs(ctx, argRules, args)
+	if err != nil {
+		return ctx, nil, err
+	}
+	rows, err := conn.QueryContext(ctx, query, args)
+	return ctx, rows, err
+}
+
+func (in *Interceptor) ConnPrepareContext(ctx context.Context, conn driver.ConnPrepareContext, query string) (context.Context, driver.Stmt, error) {
+	rewritten, argRules := in.rewriteQuery(query, true)
+	stmt, err := conn.PrepareContext(ctx, rewritten)
+	if err != nil || (rewritten == query && len(argRules) == 0) {
+		return ctx, stmt, err
+	}
+	// the caller passes the arguments of the original query
+	delta := sqlinfo.NumInput(in.cfg.Dialect, rewritten) - sqlinfo.NumInput(in.cfg.Dialect, query)
+	return ctx, &argstmt.Stmt{
+		Stmt: stmt,
+		Args: func(ctx context.Context, args []driver.NamedValue) ([]driver.NamedValue, error) {
+			return in.rewriteArgs(ctx, argRules, args)
+		},
+		Inputs: func(n int) int {
+			if n-delta < 0 {
+				return -1
+			}
+			return n - delta
+		},
+	}, nil
+}
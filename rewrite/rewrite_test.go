@@ -0,0 +1,175 @@
+package rewrite
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/ngrok/sqlmw"
+	"github.com/ngrok/sqlmw/fingerprint"
+	"github.com/ngrok/sqlmw/internal/fakedb"
+	"github.com/ngrok/sqlmw/sqlinfo"
+	"github.com/ngrok/sqlmw/sqlmwtest"
+)
+
+func TestLimit(t *testing.T) {
+	rule := Limit(sqlinfo.PostgreSQL, 100)
+	tests := map[string]string{
+		"SELECT * FROM users":                          "SELECT * FROM users LIMIT 100",
+		"SELECT * FROM users; -- all":                  "SELECT * FROM users LIMIT 100; -- all",
+		"SELECT * FROM (SELECT * FROM t LIMIT 5) s":    "SELECT * FROM (SELECT * FROM t LIMIT 5) s LIMIT 100",
+		"SELECT * FROM users LIMIT 10":                 "SELECT * FROM users LIMIT 10",
+		"SELECT * FROM users FETCH FIRST 10 ROWS ONLY": "SELECT * FROM users FETCH FIRST 10 ROWS ONLY",
+		"SELECT * FROM users FOR UPDATE":               "SELECT * FROM users FOR UPDATE",
+		"DELETE FROM users":                            "DELETE FROM users",
+	}
+	for query, expected := range tests {
+		if got := rule.Query(query); got != expected {
+			t.Errorf("unexpected rewrite of %q:\n got: %s\nwant: %s", query, got, expected)
+		}
+	}
+}
+
+func TestRenameTable(t *testing.T) {
+	rule := RenameTable(sqlinfo.PostgreSQL, "users", "accounts")
+	query := `SELECT u.id FROM public.users u JOIN "users" x ON true WHERE name = 'users'`
+	expected := `SELECT u.id FROM public.accounts u JOIN accounts x ON true WHERE name = 'users'`
+	if got := rule.Query(query); got != expected {
+		t.Errorf("unexpected rewrite:\n got: %s\nwant: %s", got, expected)
+	}
+}
+
+func openDB(t *testing.T, in *Interceptor) (*sql.DB, *fakedb.Connector) {
+	con := &fakedb.Connector{
+		NumInput: func(query string) int {
+			return strings.Count(query, "?")
+		},
+	}
+	db := sql.OpenDB(sqlmw.Connector(con, in))
+	t.Cleanup(func() {
+		if err := db.Close(); err != nil {
+			t.Errorf("Failed to close db: %v", err)
+		}
+	})
+	return db, con
+}
+
+type tenantKey struct{}
+
+// tenantRule restricts the statements on invoices to the tenant of the
+// context.
+var tenantRule = Rule{
+	Name:        "tenant",
+	Fingerprint: fingerprint.Normalize("SELECT * FROM invoices WHERE id = ?"),
+	Query: func(query string) string {
+		return query + " AND tenant_id = ?"
+	},
+	Args: func(ctx context.Context, args []driver.NamedValue) ([]driver.NamedValue, error) {
+		return append(args, driver.NamedValue{Ordinal: len(args) + 1, Value: ctx.Value(tenantKey{})}), nil
+	},
+}
+
+func TestRewrite(t *testing.T) {
+	var events []Event
+	in := New(Config{
+		Rules: []Rule{
+			{Name: "migrate", Pattern: regexp.MustCompile(`\binvoices_old\b`), Replace: "invoices"},
+			tenantRule,
+		},
+		OnRewrite: func(e Event) {
+			events = append(events, e)
+		},
+	})
+	db, con := openDB(t, in)
+	ctx := context.WithValue(context.Background(), tenantKey{}, "acme")
+
+	rows, err := db.QueryContext(ctx, "SELECT * FROM invoices_old WHERE id = ?", 1)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if err := rows.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	stmt, err := db.PrepareContext(ctx, "SELECT * FROM invoices WHERE id = ?")
+	if err != nil {
+		t.Fatalf("Prepare failed: %v", err)
+	}
+	defer stmt.Close()
+	for i := 0; i < 2; i++ {
+		// database/sql checks the arguments against the number of inputs
+		rows, err := stmt.QueryContext(ctx, 2)
+		if err != nil {
+			t.Fatalf("Query failed: %v", err)
+		}
+		if err := rows.Close(); err != nil {
+			t.Fatalf("Close failed: %v", err)
+		}
+	}
+	if _, err := stmt.QueryContext(ctx, 2, 3); err == nil {
+		t.Error("expected an error for the extra argument")
+	}
+
+	const rewritten = "SELECT * FROM invoices WHERE id = ? AND tenant_id = ?"
+	calls := con.Calls()
+	if len(calls) != 3 {
+		t.Fatalf("expected 3 statements, got %d", len(calls))
+	}
+	for _, c := range calls {
+		if c.Query != rewritten || len(c.Args) != 2 || c.Args[1].Value != "acme" {
+			t.Errorf("unexpected statement %q with %v", c.Query, c.Args)
+		}
+	}
+
+	expected := []Event{
+		{Rule: "migrate", Query: "SELECT * FROM invoices_old WHERE id = ?", Rewritten: "SELECT * FROM invoices WHERE id = ?"},
+		{Rule: "tenant", Query: "SELECT * FROM invoices WHERE id = ?", Rewritten: rewritten},
+		{Rule: "tenant", Query: "SELECT * FROM invoices WHERE id = ?", Rewritten: rewritten, Prepared: true},
+	}
+	if !reflect.DeepEqual(events, expected) {
+		t.Errorf("unexpected events:\n got: %+v\nwant: %+v", events, expected)
+	}
+}
+
+func TestRewriteReportsChanges(t *testing.T) {
+	var events []Event
+	in := New(Config{
+		Rules: []Rule{
+			Limit(sqlinfo.PostgreSQL, 100),
+			{
+				Name:    "lower",
+				Pattern: regexp.MustCompile(`\bemail = \?`),
+				Args: func(_ context.Context, args []driver.NamedValue) ([]driver.NamedValue, error) {
+					if s, ok := args[0].Value.(string); ok {
+						args[0].Value = strings.ToLower(s)
+					}
+					return args, nil
+				},
+			},
+		},
+		OnRewrite: func(e Event) {
+			events = append(events, e)
+		},
+	})
+	db, _ := openDB(t, in)
+
+	const query = "SELECT * FROM users WHERE email = ? LIMIT 1"
+	for _, email := range []string{"bob@example.com", "Bob@Example.com"} {
+		if _, err := db.Exec(query, email); err != nil {
+			t.Fatalf("Exec failed: %v", err)
+		}
+	}
+
+	expected := []Event{{Rule: "lower", Query: query, Rewritten: query}}
+	if !reflect.DeepEqual(events, expected) {
+		t.Errorf("unexpected events:\n got: %+v\nwant: %+v", events, expected)
+	}
+}
+
+func TestConformance(t *testing.T) {
+	sqlmwtest.RunConformance(t, func() sqlmw.Interceptor { return New(Config{}) })
+}
@@ -0,0 +1,77 @@
+package rewrite
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/ngrok/sqlmw/sqlinfo"
+)
+
+// Limit returns a Rule adding a LIMIT clause to the SELECT statements that
+// have neither a LIMIT nor a FETCH clause, nor a row locking clause.
+func Limit(dialect sqlinfo.Dialect, n int) Rule {
+	limit := " LIMIT " + strconv.Itoa(n)
+	return Rule{
+		Name: "limit",
+		Query: func(query string) string {
+			st := sqlinfo.Parse(dialect, query)
+			if st.Verb != "SELECT" || st.Kind != sqlinfo.Read || st.ForUpdate {
+				return query
+			}
+
+			end, depth := len(query), 0
+			l := sqlinfo.NewLexer(dialect, query)
+			for {
+				tok, ok := l.Next()
+				if !ok {
+					break
+				}
+				switch {
+				case tok.Kind == sqlinfo.Comment:
+					continue
+				case tok.Kind == sqlinfo.Punct && tok.Text == "(":
+					depth++
+				case tok.Kind == sqlinfo.Punct && tok.Text == ")":
+					depth--
+				case depth == 0 && (tok.Is("limit") || tok.Is("fetch")):
+					return query
+				}
+				end = tok.Offset + len(tok.Text)
+				if tok.Kind == sqlinfo.Punct && tok.Text == ";" {
+					end = tok.Offset
+				}
+			}
+			return query[:end] + limit + query[end:]
+		},
+	}
+}
+
+// RenameTable returns a Rule replacing the identifier from by to. Every
+// identifier designating from is replaced, including column names and
+// aliases named alike.
+func RenameTable(dialect sqlinfo.Dialect, from, to string) Rule {
+	return Rule{
+		Name: "rename-table",
+		Query: func(query string) string {
+			var b strings.Builder
+			last := 0
+			l := sqlinfo.NewLexer(dialect, query)
+			for {
+				tok, ok := l.Next()
+				if !ok {
+					break
+				}
+				if (tok.Kind == sqlinfo.Word || tok.Kind == sqlinfo.QuotedIdent) && tok.Ident() == from {
+					b.WriteString(query[last:tok.Offset])
+					b.WriteString(to)
+					last = tok.Offset + len(tok.Text)
+				}
+			}
+			if last == 0 {
+				return query
+			}
+			b.WriteString(query[last:])
+			return b.String()
+		},
+	}
+}
@@ -0,0 +1,296 @@
+// Package rowset buffers the driver.Rows returned by a query so that they can
+// be replayed any number of times.
+//
+// The replayed driver.Rows keep the column names and the column type metadata
+// of the original ones, and implement every optional driver.Rows interface.
+// Metadata the original rows did not provide is reported as unknown.
+package rowset
+
+import (
+	"database/sql/driver"
+	"io"
+	"reflect"
+)
+
+// Column describes a column of a result set.
+type Column struct {
+	Name string
+
+	// DatabaseTypeName is empty when unknown.
+	DatabaseTypeName string
+
+	Length    int64
+	HasLength bool
+
+	Nullable    bool
+	HasNullable bool
+
+	Precision         int64
+	Scale             int64
+	HasPrecisionScale bool
+
+	// ScanType is nil when unknown.
+	ScanType reflect.Type
+}
+
+// ResultSet is a single buffered result set.
+type ResultSet struct {
+	Columns []Column
+	Rows    [][]driver.Value
+}
+
+// Set holds every result set returned by a query. A Set must not be modified
+// once it is shared.
+type Set struct {
+	ResultSets []ResultSet
+}
+
+var scanTypeAny = reflect.TypeOf(new(interface{})).Elem()
+
+// Capture reads rows until they are exhausted, including the following result
+// sets when the driver supports them. It does not close rows.
+func Capture(rows driver.Rows) (*Set, error) {
+	set, _, err := capture(rows, -1)
+	return set, err
+}
+
+// CaptureN is like Capture but stops once it has read n rows, reporting
+// whether it did. The rows left can be read with Set.Continue.
+func CaptureN(rows driver.Rows, n int) (set *Set, stopped bool, err error) {
+	return capture(rows, n)
+}
+
+func capture(rows driver.Rows, n int) (*Set, bool, error) {
+	set := &Set{}
+	for {
+		rs, err := captureResultSet(rows, &n)
+		if err != nil {
+			return nil, false, err
+		}
+		set.ResultSets = append(set.ResultSets, rs)
+		if n == 0 {
+			return set, true, nil
+		}
+
+		nrs, ok := rows.(driver.RowsNextResultSet)
+		if !ok || !nrs.HasNextResultSet() {
+			return set, false, nil
+		}
+		if err := nrs.NextResultSet(); err == io.EOF {
+			return set, false, nil
+		} else if err != nil {
+			return nil, false, err
+		}
+	}
+}
+
+// captureResultSet reads the current result set of rows, stopping once n
+// rows were read. A negative n does not limit the rows.
+func captureResultSet(rows driver.Rows, n *int) (ResultSet, error) {
+	rs := ResultSet{Columns: Describe(rows)}
+	for *n != 0 {
+		dest := make([]driver.Value, len(rs.Columns))
+		err := rows.Next(dest)
+		if err == io.EOF {
+			return rs, nil
+		}
+		if err != nil {
+			return ResultSet{}, err
+		}
+		for i, v := range dest {
+			// drivers are allowed to reuse the memory of []byte values
+			if b, ok := v.([]byte); ok {
+				dest[i] = append([]byte(nil), b...)
+			}
+		}
+		rs.Rows = append(rs.Rows, dest)
+		*n--
+	}
+	return rs, nil
+}
+
+// Describe returns the columns of the current result set of rows.
+func Describe(rows driver.Rows) []Column {
+	names := rows.Columns()
+	cols := make([]Column, len(names))
+	for i, name := range names {
+		cols[i] = describeColumn(rows, i, name)
+	}
+	return cols
+}
+
+func describeColumn(rows driver.Rows, i int, name string) Column {
+	c := Column{Name: name}
+	if r, ok := rows.(driver.RowsColumnTypeDatabaseTypeName); ok {
+		c.DatabaseTypeName = r.ColumnTypeDatabaseTypeName(i)
+	}
+	if r, ok := rows.(driver.RowsColumnTypeLength); ok {
+		c.Length, c.HasLength = r.ColumnTypeLength(i)
+	}
+	if r, ok := rows.(driver.RowsColumnTypeNullable); ok {
+		c.Nullable, c.HasNullable = r.ColumnTypeNullable(i)
+	}
+	if r, ok := rows.(driver.RowsColumnTypePrecisionScale); ok {
+		c.Precision, c.Scale, c.HasPrecisionScale = r.ColumnTypePrecisionScale(i)
+	}
+	if r, ok := rows.(driver.RowsColumnTypeScanType); ok {
+		c.ScanType = r.ColumnTypeScanType(i)
+	}
+	return c
+}
+
+// Len returns the total number of rows across all the result sets.
+func (s *Set) Len() int {
+	n := 0
+	for _, rs := range s.ResultSets {
+		n += len(rs.Rows)
+	}
+	return n
+}
+
+// Rows returns a new driver.Rows replaying s from its first row. Every call
+// returns an independent cursor.
+func (s *Set) Rows() driver.Rows {
+	return &Rows{set: s}
+}
+
+// Continue returns a driver.Rows replaying s and then returning the rows
+// left in rows, which s was read from by CaptureN. Closing it closes rows.
+func (s *Set) Continue(rows driver.Rows) driver.Rows {
+	return &Rows{set: s, rest: rows}
+}
+
+// Rows replays a Set.
+type Rows struct {
+	set    *Set
+	rs     int
+	row    int
+	closed bool
+
+	// rest holds the rows left after the set, see Set.Continue, and
+	// restSet the columns of their result sets following the set.
+	rest    driver.Rows
+	restSet *ResultSet
+}
+
+// Compile time validation that our types implement the expected interfaces
+var (
+	_ driver.Rows                           = (*Rows)(nil)
+	_ driver.RowsNextResultSet              = (*Rows)(nil)
+	_ driver.RowsColumnTypeDatabaseTypeName = (*Rows)(nil)
+	_ driver.RowsColumnTypeLength           = (*Rows)(nil)
+	_ driver.RowsColumnTypeNullable         = (*Rows)(nil)
+	_ driver.RowsColumnTypePrecisionScale   = (*Rows)(nil)
+	_ driver.RowsColumnTypeScanType         = (*Rows)(nil)
+)
+
+func (r *Rows) current() *ResultSet {
+	if r.rs < len(r.set.ResultSets) {
+		return &r.set.ResultSets[r.rs]
+	}
+	if r.restSet != nil {
+		return r.restSet
+	}
+	return &ResultSet{}
+}
+
+// resting reports whether the rows of the current result set that are not
+// in the set are read from rest.
+func (r *Rows) resting() bool {
+	return r.rest != nil && r.rs >= len(r.set.ResultSets)-1
+}
+
+func (r *Rows) Columns() []string {
+	cols := r.current().Columns
+	names := make([]string, len(cols))
+	for i, c := range cols {
+		names[i] = c.Name
+	}
+	return names
+}
+
+func (r *Rows) Close() error {
+	r.closed = true
+	if r.rest != nil {
+		return r.rest.Close()
+	}
+	return nil
+}
+
+func (r *Rows) Next(dest []driver.Value) error {
+	rs := r.current()
+	if r.closed {
+		return io.EOF
+	}
+	if r.row >= len(rs.Rows) {
+		if r.resting() {
+			return r.rest.Next(dest)
+		}
+		return io.EOF
+	}
+	for i, v := range rs.Rows[r.row] {
+		// hand out copies so that callers cannot corrupt the Set
+		if b, ok := v.([]byte); ok {
+			v = append([]byte(nil), b...)
+		}
+		dest[i] = v
+	}
+	r.row++
+	return nil
+}
+
+func (r *Rows) HasNextResultSet() bool {
+	if r.resting() {
+		nrs, ok := r.rest.(driver.RowsNextResultSet)
+		return ok && nrs.HasNextResultSet()
+	}
+	return r.rs+1 < len(r.set.ResultSets)
+}
+
+func (r *Rows) NextResultSet() error {
+	if r.resting() {
+		nrs, ok := r.rest.(driver.RowsNextResultSet)
+		if !ok {
+			return io.EOF
+		}
+		if err := nrs.NextResultSet(); err != nil {
+			return err
+		}
+		r.rs++
+		r.row = 0
+		r.restSet = &ResultSet{Columns: Describe(r.rest)}
+		return nil
+	}
+	if !r.HasNextResultSet() {
+		return io.EOF
+	}
+	r.rs++
+	r.row = 0
+	return nil
+}
+
+func (r *Rows) ColumnTypeDatabaseTypeName(index int) string {
+	return r.current().Columns[index].DatabaseTypeName
+}
+
+func (r *Rows) ColumnTypeLength(index int) (length int64, ok bool) {
+	c := r.current().Columns[index]
+	return c.Length, c.HasLength
+}
+
+func (r *Rows) ColumnTypeNullable(index int) (nullable, ok bool) {
+	c := r.current().Columns[index]
+	return c.Nullable, c.HasNullable
+}
+
+func (r *Rows) ColumnTypePrecisionScale(index int) (precision, scale int64, ok bool) {
+	c := r.current().Columns[index]
+	return c.Precision, c.Scale, c.HasPrecisionScale
+}
+
+func (r *Rows) ColumnTypeScanType(index int) reflect.Type {
+	if t := r.current().Columns[index].ScanType; t != nil {
+		return t
+	}
+	return scanTypeAny
+}
@@ -0,0 +1,154 @@
+package rowset
+
+import (
+	"database/sql/driver"
+	"io"
+	"reflect"
+	"testing"
+)
+
+// multiRows returns two result sets and reuses the memory of its []byte
+// values, like some drivers do.
+type multiRows struct {
+	sets [][][]driver.Value
+	buf  []byte
+}
+
+func (r *multiRows) Columns() []string {
+	if len(r.sets[0]) == 0 {
+		return nil
+	}
+	return []string{"id", "name"}[:len(r.sets[0][0])]
+}
+
+func (r *multiRows) Close() error { return nil }
+
+func (r *multiRows) Next(dest []driver.Value) error {
+	if len(r.sets[0]) == 0 {
+		return io.EOF
+	}
+	row := r.sets[0][0]
+	r.sets[0] = r.sets[0][1:]
+	for i, v := range row {
+		if s, ok := v.(string); ok {
+			r.buf = append(r.buf[:0], s...)
+			v = r.buf
+		}
+		dest[i] = v
+	}
+	return nil
+}
+
+func (r *multiRows) HasNextResultSet() bool { return len(r.sets) > 1 }
+
+func (r *multiRows) NextResultSet() error {
+	r.sets = r.sets[1:]
+	return nil
+}
+
+func (r *multiRows) ColumnTypeDatabaseTypeName(index int) string {
+	return []string{"INT8", "TEXT"}[index]
+}
+
+func TestCaptureAndReplay(t *testing.T) {
+	rows := &multiRows{sets: [][][]driver.Value{
+		{{int64(1), "alice"}, {int64(2), "bob"}},
+		{{int64(3)}},
+	}}
+
+	set, err := Capture(rows)
+	if err != nil {
+		t.Fatalf("Capture failed: %v", err)
+	}
+	if set.Len() != 3 || len(set.ResultSets) != 2 {
+		t.Fatalf("unexpected set %+v", set)
+	}
+
+	for i := 0; i < 2; i++ {
+		r := set.Rows().(*Rows)
+		if cols := r.Columns(); !reflect.DeepEqual(cols, []string{"id", "name"}) {
+			t.Errorf("unexpected columns %v", cols)
+		}
+		if name := r.ColumnTypeDatabaseTypeName(1); name != "TEXT" {
+			t.Errorf("unexpected database type name %q", name)
+		}
+		if _, ok := r.ColumnTypeLength(1); ok {
+			t.Error("length reported although the driver did not provide it")
+		}
+		if st := r.ColumnTypeScanType(0); st != scanTypeAny {
+			t.Errorf("unexpected scan type %v", st)
+		}
+
+		var got []driver.Value
+		dest := make([]driver.Value, 2)
+		for r.Next(dest) == nil {
+			got = append(got, dest[0], string(dest[1].([]byte)))
+		}
+		expected := []driver.Value{int64(1), "alice", int64(2), "bob"}
+		if !reflect.DeepEqual(got, expected) {
+			t.Errorf("replay %d: got %v, expected %v", i, got, expected)
+		}
+
+		if !r.HasNextResultSet() {
+			t.Fatal("missing second result set")
+		}
+		if err := r.NextResultSet(); err != nil {
+			t.Fatalf("NextResultSet failed: %v", err)
+		}
+		dest = dest[:1]
+		if err := r.Next(dest); err != nil || dest[0] != int64(3) {
+			t.Errorf("unexpected second result set row %v, %v", dest, err)
+		}
+		if err := r.Next(dest); err != io.EOF {
+			t.Errorf("expected io.EOF, got %v", err)
+		}
+		if r.HasNextResultSet() {
+			t.Error("unexpected third result set")
+		}
+	}
+}
+
+func TestCaptureNAndContinue(t *testing.T) {
+	rows := &multiRows{sets: [][][]driver.Value{
+		{{int64(1), "alice"}, {int64(2), "bob"}, {int64(3), "carol"}},
+		{{int64(4)}},
+	}}
+
+	set, stopped, err := CaptureN(rows, 2)
+	if err != nil {
+		t.Fatalf("CaptureN failed: %v", err)
+	}
+	if !stopped || set.Len() != 2 {
+		t.Fatalf("unexpected set %+v, stopped %v", set, stopped)
+	}
+
+	r := set.Continue(rows).(*Rows)
+	var got []driver.Value
+	dest := make([]driver.Value, 2)
+	for r.Next(dest) == nil {
+		got = append(got, dest[0], string(dest[1].([]byte)))
+	}
+	expected := []driver.Value{int64(1), "alice", int64(2), "bob", int64(3), "carol"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("got %v, expected %v", got, expected)
+	}
+
+	if !r.HasNextResultSet() {
+		t.Fatal("missing second result set")
+	}
+	if err := r.NextResultSet(); err != nil {
+		t.Fatalf("NextResultSet failed: %v", err)
+	}
+	if cols := r.Columns(); !reflect.DeepEqual(cols, []string{"id"}) {
+		t.Errorf("unexpected columns %v", cols)
+	}
+	dest = dest[:1]
+	if err := r.Next(dest); err != nil || dest[0] != int64(4) {
+		t.Errorf("unexpected second result set row %v, %v", dest, err)
+	}
+
+	set, stopped, err = CaptureN(&multiRows{sets: [][][]driver.Value{{{int64(1)}}}}, 2)
+	if err != nil || stopped || set.Len() != 1 {
+		t.Errorf("unexpected set %+v, stopped %v, error %v", set, stopped, err)
+	}
+}
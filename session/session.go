@@ -0,0 +1,273 @@
+// Package session provides an sqlmw.Interceptor giving the statements the
+// session variables of their context, like statement_timeout, search_path,
+// application_name or role, although database/sql runs them on pooled
+// connections shared by every request:
+//
+//	ctx = session.WithVars(ctx, session.Vars{"statement_timeout": "5s"})
+//	rows, err := db.QueryContext(ctx, query)
+//
+// The variables set on every connection are tracked, and before a statement
+// or a transaction runs, only the variables that differ from the ones its
+// context asks for are set, the others being left as they are. The
+// variables set for a previous statement and not asked for anymore are
+// restored to Config.Defaults, or to the default of the server, when the
+// connection is taken from the pool, see driver.SessionResetter.
+//
+// The variables set or reset within a transaction that is rolled back are
+// restored by the database: the variables of the connection are unknown
+// then, and every variable ever set on it is set or reset again before the
+// next statement.
+package session
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"sort"
+
+	"github.com/ngrok/sqlmw"
+)
+
+// Vars are session variables, by name.
+type Vars map[string]string
+
+type varsKey struct{}
+
+// WithVars returns a context running statements with vars, in addition to
+// the variables of the parent context.
+func WithVars(ctx context.Context, vars Vars) context.Context {
+	if parent := FromContext(ctx); parent != nil {
+		merged := make(Vars, len(parent)+len(vars))
+		for k, v := range parent {
+			merged[k] = v
+		}
+		for k, v := range vars {
+			merged[k] = v
+		}
+		vars = merged
+	}
+	return context.WithValue(ctx, varsKey{}, vars)
+}
+
+// FromContext returns the variables of ctx, if any.
+func FromContext(ctx context.Context) Vars {
+	vars, _ := ctx.Value(varsKey{}).(Vars)
+	return vars
+}
+
+// Config configures an Interceptor.
+type Config struct {
+	// Defaults are the variables of the statements whose context does not
+	// set them.
+	Defaults Vars
+
+	// Set returns the statement setting a variable. Defaults to the
+	// set_config function of PostgreSQL.
+	Set func(name, value string) (query string, args []driver.NamedValue)
+
+	// Reset returns the statement restoring the default of the server for
+	// a variable. Defaults to RESET, as supported by PostgreSQL.
+	Reset func(name string) string
+}
+
+// Postgres sets a variable with the set_config function of PostgreSQL,
+// which parses lists like search_path.
+func Postgres(name, value string) (string, []driver.NamedValue) {
+	return "SELECT set_config($1, $2, false)", []driver.NamedValue{
+		{Ordinal: 1, Value: name},
+		{Ordinal: 2, Value: value},
+	}
+}
+
+// Interceptor sets session variables.
+type Interceptor struct {
+	sqlmw.NullInterceptor
+
+	cfg Config
+}
+
+// New returns an Interceptor for the supplied configuration.
+func New(cfg Config) *Interceptor {
+	if cfg.Set == nil {
+		cfg.Set = Postgres
+	}
+	if cfg.Reset == nil {
+		cfg.Reset = func(name string) string {
+			return "RESET " + name
+		}
+	}
+	return &Interceptor{cfg: cfg}
+}
+
+type stateKey struct{}
+
+// state is the variables set on a connection.
+type state struct {
+	vars Vars
+	// seen are the names of the variables ever set on the connection.
+	seen map[string]bool
+	// stale is set when the variables may have been restored by the
+	// database.
+	stale bool
+}
+
+// apply sets the variables of ctx on its connection.
+func (in *Interceptor) apply(ctx context.Context) error {
+	c, ok := sqlmw.ConnFromContext(ctx)
+	if !ok {
+		return nil
+	}
+	st, _ := c.Value(stateKey{}).(*state)
+	if st == nil {
+		st = &state{vars: make(Vars), seen: make(map[string]bool)}
+		c.SetValue(stateKey{}, st)
+	}
+
+	want := FromContext(ctx)
+	names := make([]string, 0, len(st.seen))
+	for name := range st.seen {
+		if _, ok := st.vars[name]; ok || st.stale {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, ok := want[name]; ok {
+			continue
+		}
+		if _, ok := in.cfg.Defaults[name]; ok {
+			continue
+		}
+		if !validName(name) {
+			return fmt.Errorf("session: invalid variable name %q", name)
+		}
+		if _, err := c.ExecContext(ctx, in.cfg.Reset(name), nil); err != nil {
+			return failed(st, name, err)
+		}
+		delete(st.vars, name)
+	}
+	set := func(name, value string) error {
+		if current, ok := st.vars[name]; ok && current == value && !st.stale {
+			return nil
+		}
+		query, args := in.cfg.Set(name, value)
+		if _, err := c.ExecContext(ctx, query, args); err != nil {
+			return failed(st, name, err)
+		}
+		st.vars[name] = value
+		st.seen[name] = true
+		return nil
+	}
+	for _, name := range sortedNames(in.cfg.Defaults) {
+		if _, ok := want[name]; !ok {
+			if err := set(name, in.cfg.Defaults[name]); err != nil {
+				return err
+			}
+		}
+	}
+	for _, name := range sortedNames(want) {
+		if err := set(name, want[name]); err != nil {
+			return err
+		}
+	}
+	st.stale = false
+	return nil
+}
+
+// failed marks the variables of a connection stale after a failure.
+func failed(st *state, name string, err error) error {
+	st.stale = true
+	return fmt.Errorf("session: setting %s: %w", name, err)
+}
+
+func sortedNames(vars Vars) []string {
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// validName reports whether name can be written in a statement as is.
+func validName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if !('a' <= c && c <= 'z') && !('A' <= c && c <= 'Z') && !('0' <= c && c <= '9') && c != '_' && c != '.' {
+			return false
+		}
+	}
+	return true
+}
+
+var _ sqlmw.ResetSessionInterceptor = (*Interceptor)(nil)
+
+func (in *Interceptor) ConnResetSession(ctx context.Context, conn driver.SessionResetter) error {
+	if err := conn.ResetSession(ctx); err != nil {
+		return err
+	}
+	// ctx is the one of the next statement of the connection
+	if err := in.apply(ctx); err != nil {
+		// the variables are unknown, the connection must not be reused
+		return driver.ErrBadConn
+	}
+	return nil
+}
+
+func (in *Interceptor) ConnBeginTx(ctx context.Context, conn driver.ConnBeginTx, opts driver.TxOptions) (context.Context, driver.Tx, error) {
+	if err := in.apply(ctx); err != nil {
+		return ctx, nil, err
+	}
+	tx, err := conn.BeginTx(ctx, opts)
+	return ctx, tx, err
+}
+
+func (in *Interceptor) TxRollback(ctx context.Context, tx driver.Tx) error {
+	if c, ok := sqlmw.ConnFromContext(ctx); ok {
+		if st, ok := c.Value(stateKey{}).(*state); ok {
+			st.stale = true
+		}
+	}
+	return tx.Rollback()
+}
+
+func (in *Interceptor) ConnPrepareContext(ctx context.Context, conn driver.ConnPrepareContext, query string) (context.Context, driver.Stmt, error) {
+	if err := in.apply(ctx); err != nil {
+		return ctx, nil, err
+	}
+	stmt, err := conn.PrepareContext(ctx, query)
+	return ctx, stmt, err
+}
+
+func (in *Interceptor) ConnExecContext(ctx context.Context, conn driver.ExecerContext, query string, args []driver.NamedValue) (driver.Result, error) {
+	if err := in.apply(ctx); err != nil {
+		return nil, err
+	}
+	return conn.ExecContext(ctx, query, args)
+}
+
+func (in *Interceptor) ConnQueryContext(ctx context.Context, conn driver.QueryerContext, query string, args []driver.NamedValue) (context.Context, driver.Rows, error) {
+	if err := in.apply(ctx); err != nil {
+		return ctx, nil, err
+	}
+	rows, err := conn.QueryContext(ctx, query, args)
+	return ctx, rows, err
+}
+
+func (in *Interceptor) StmtExecContext(ctx context.Context, stmt driver.StmtExecContext, _ string, args []driver.NamedValue) (driver.Result, error) {
+	if err := in.apply(ctx); err != nil {
+		return nil, err
+	}
+	return stmt.ExecContext(ctx, args)
+}
+
+func (in *Interceptor) StmtQueryContext(ctx context.Context, stmt driver.StmtQueryContext, _ string, args []driver.NamedValue) (context.Context, driver.Rows, error) {
+	if err := in.apply(ctx); err != nil {
+		return ctx, nil, err
+	}
+	rows, err := stmt.QueryContext(ctx, args)
+	return ctx, rows, err
+}
@@ -0,0 +1,116 @@
+package session
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"testing"
+
+	"github.com/ngrok/sqlmw"
+	"github.com/ngrok/sqlmw/internal/fakedb"
+	"github.com/ngrok/sqlmw/sqlmwtest"
+)
+
+func TestInterceptor(t *testing.T) {
+	in := New(Config{Defaults: Vars{"application_name": "web"}})
+	con := &fakedb.Connector{}
+	db := sql.OpenDB(sqlmw.Connector(con, in))
+	t.Cleanup(func() {
+		if err := db.Close(); err != nil {
+			t.Errorf("Failed to close db: %v", err)
+		}
+	})
+	db.SetMaxOpenConns(1)
+	ctx := context.Background()
+	timeout := WithVars(ctx, Vars{"statement_timeout": "5s"})
+	app := WithVars(ctx, Vars{"search_path": "app"})
+
+	for _, ctx := range []context.Context{timeout, timeout, app} {
+		if _, err := db.ExecContext(ctx, "DELETE FROM t"); err != nil {
+			t.Fatalf("Exec failed: %v", err)
+		}
+	}
+
+	tx, err := db.BeginTx(app, nil)
+	if err != nil {
+		t.Fatalf("BeginTx failed: %v", err)
+	}
+	if _, err := tx.ExecContext(WithVars(app, Vars{"search_path": "other"}), "DELETE FROM t"); err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	if err := tx.Rollback(); err != nil {
+		t.Fatalf("Rollback failed: %v", err)
+	}
+	if _, err := db.ExecContext(app, "DELETE FROM t"); err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+
+	var got []string
+	for _, call := range con.Calls() {
+		if len(call.Args) == 2 {
+			got = append(got, "set "+call.Args[0].Value.(string)+" "+call.Args[1].Value.(string))
+		} else {
+			got = append(got, call.Query)
+		}
+	}
+	expected := []string{
+		"set application_name web", "set statement_timeout 5s", "DELETE FROM t",
+		"DELETE FROM t",
+		"RESET statement_timeout", "set search_path app", "DELETE FROM t",
+		"BEGIN", "set search_path other", "DELETE FROM t", "ROLLBACK",
+		// the variables are unknown after a rollback
+		"RESET statement_timeout", "set application_name web", "set search_path app", "DELETE FROM t",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("unexpected statements:\n got: %q\nwant: %q", got, expected)
+	}
+}
+
+func TestRollbackRestoresReset(t *testing.T) {
+	in := New(Config{})
+	con := &fakedb.Connector{}
+	db := sql.OpenDB(sqlmw.Connector(con, in))
+	t.Cleanup(func() {
+		if err := db.Close(); err != nil {
+			t.Errorf("Failed to close db: %v", err)
+		}
+	})
+	db.SetMaxOpenConns(1)
+	ctx := context.Background()
+	timeout := WithVars(ctx, Vars{"statement_timeout": "5s"})
+
+	tx, err := db.BeginTx(timeout, nil)
+	if err != nil {
+		t.Fatalf("BeginTx failed: %v", err)
+	}
+	// resets statement_timeout, which the rollback restores
+	if _, err := tx.ExecContext(ctx, "DELETE FROM t"); err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	if err := tx.Rollback(); err != nil {
+		t.Fatalf("Rollback failed: %v", err)
+	}
+	if _, err := db.ExecContext(ctx, "DELETE FROM t"); err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+
+	var got []string
+	for _, call := range con.Calls() {
+		if len(call.Args) == 2 {
+			got = append(got, "set "+call.Args[0].Value.(string)+" "+call.Args[1].Value.(string))
+		} else {
+			got = append(got, call.Query)
+		}
+	}
+	expected := []string{
+		"set statement_timeout 5s", "BEGIN", "RESET statement_timeout", "DELETE FROM t", "ROLLBACK",
+		"RESET statement_timeout", "DELETE FROM t",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("unexpected statements:\n got: %q\nwant: %q", got, expected)
+	}
+}
+
+func TestConformance(t *testing.T) {
+	sqlmwtest.RunConformance(t, func() sqlmw.Interceptor { return New(Config{}) })
+}
@@ -0,0 +1,247 @@
+// Package singleflight provides an sqlmw.Interceptor collapsing identical
+// concurrent read queries into a single database round trip.
+//
+// The first caller of a query, the leader, runs it on its own connection and
+// buffers the result. Callers running the same query with the same arguments
+// while the leader is in flight wait for that result instead of running the
+// query themselves. Every caller receives its own replay of the buffered rows.
+//
+// A caller whose context is cancelled stops waiting, but the shared query is
+// only cancelled once every caller waiting for it is gone. The deadline its
+// context reports to the driver is the latest deadline of the callers that
+// joined it, none when one of them has none. Because the query
+// runs on the leader's connection, the leader returns only once the query has
+// completed, even when its own context was cancelled earlier.
+//
+// Only queries of the same scope, see Config.Scope, are collapsed. Queries
+// run inside a transaction, statements that are not reads and reads taking
+// row locks are never collapsed. Statements are described with
+// sqlinfo.Describe.
+//
+// The replayed rows implement the optional driver.Rows interfaces of the rows
+// of the shared query, see sqlmw.RowsUnwrapper.
+package singleflight
+
+import (
+	"context"
+	"database/sql/driver"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ngrok/sqlmw"
+	"github.com/ngrok/sqlmw/cache"
+	"github.com/ngrok/sqlmw/internal/ctxutil"
+	"github.com/ngrok/sqlmw/internal/sqlquery"
+	"github.com/ngrok/sqlmw/rowset"
+	"github.com/ngrok/sqlmw/sqlinfo"
+)
+
+// Config configures an Interceptor.
+type Config struct {
+	// Scope returns what the results of a query depend on besides its
+	// text and arguments, such as the tenant or the session variables of
+	// ctx. Only queries of the same scope are collapsed. Defaults to
+	// cache.DefaultScope.
+	Scope func(ctx context.Context) string
+}
+
+// Stats are the counters of an Interceptor.
+type Stats struct {
+	// Queries is the number of queries run on the database.
+	Queries uint64
+	// Shared is the number of queries answered with the result of another
+	// caller's query.
+	Shared uint64
+}
+
+// Interceptor collapses identical concurrent read queries.
+type Interceptor struct {
+	// stats is accessed atomically and kept first for 64-bit alignment
+	stats Stats
+
+	sqlmw.NullInterceptor
+
+	cfg   Config
+	mu    sync.Mutex
+	calls map[string]*call
+}
+
+type call struct {
+	done chan struct{}
+	set  *rowset.Set
+	rows driver.Rows // the closed rows set was read from
+	err  error
+
+	// guarded by Interceptor.mu
+	waiters   int
+	cancelled bool
+	cancel    context.CancelFunc
+	deadline  time.Time // zero when a caller has no deadline
+}
+
+// New returns an Interceptor.
+func New(cfg Config) *Interceptor {
+	if cfg.Scope == nil {
+		cfg.Scope = cache.DefaultScope
+	}
+	return &Interceptor{cfg: cfg, calls: make(map[string]*call)}
+}
+
+// Stats returns a snapshot of the counters.
+func (in *Interceptor) Stats() Stats {
+	return Stats{
+		Queries: atomic.LoadUint64(&in.stats.Queries),
+		Shared:  atomic.LoadUint64(&in.stats.Shared),
+	}
+}
+
+func (in *Interceptor) ConnQueryContext(ctx context.Context, conn driver.QueryerContext, query string, args []driver.NamedValue) (context.Context, driver.Rows, error) {
+	rows, err := in.query(ctx, query, args, func(ctx context.Context) (driver.Rows, error) {
+		return conn.QueryContext(ctx, query, args)
+	})
+	return ctx, rows, err
+}
+
+func (in *Interceptor) StmtQueryContext(ctx context.Context, stmt driver.StmtQueryContext, query string, args []driver.NamedValue) (context.Context, driver.Rows, error) {
+	rows, err := in.query(ctx, query, args, func(ctx context.Context) (driver.Rows, error) {
+		return stmt.QueryContext(ctx, args)
+	})
+	return ctx, rows, err
+}
+
+func (in *Interceptor) query(ctx context.Context, query string, args []driver.NamedValue, run func(context.Context) (driver.Rows, error)) (driver.Rows, error) {
+	if st := sqlinfo.Describe(ctx, query); sqlmw.InTx(ctx) || st.Kind != sqlinfo.Read || st.ForUpdate {
+		return run(ctx)
+	}
+
+	key := sqlquery.Key(in.cfg.Scope(ctx), nil) + sqlquery.Key(query, args)
+	in.mu.Lock()
+	if c, ok := in.calls[key]; ok && !c.cancelled {
+		c.waiters++
+		in.extend(c, ctx)
+		in.mu.Unlock()
+		atomic.AddUint64(&in.stats.Shared, 1)
+		return in.wait(ctx, c)
+	}
+
+	cctx, cancel := context.WithCancel(ctxutil.Detach(ctx))
+	c := &call{done: make(chan struct{}), waiters: 1, cancel: cancel}
+	c.deadline, _ = ctx.Deadline()
+	sctx := sharedContext{Context: cctx, in: in, c: c}
+	in.calls[key] = c
+	in.mu.Unlock()
+	atomic.AddUint64(&in.stats.Queries, 1)
+
+	// the leader stops waiting when its context is cancelled, like the
+	// other callers, but it still has to run the query to completion
+	stop := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			in.leave(c)
+		case <-stop:
+		}
+	}()
+
+	set, rows, err := capture(sctx, run)
+	close(stop)
+	cancel()
+
+	in.mu.Lock()
+	if in.calls[key] == c {
+		delete(in.calls, key)
+	}
+	in.mu.Unlock()
+	c.set, c.rows, c.err = set, rows, err
+	close(c.done)
+
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return c.replay(), nil
+}
+
+func (in *Interceptor) wait(ctx context.Context, c *call) (driver.Rows, error) {
+	select {
+	case <-c.done:
+		if c.err != nil {
+			return nil, c.err
+		}
+		return c.replay(), nil
+	case <-ctx.Done():
+		in.leave(c)
+		return nil, ctx.Err()
+	}
+}
+
+// extend postpones the deadline of the shared query to the deadline of
+// ctx, the context of a new caller. in.mu must be held.
+func (in *Interceptor) extend(c *call, ctx context.Context) {
+	if c.deadline.IsZero() {
+		return
+	}
+	d, ok := ctx.Deadline()
+	if !ok {
+		c.deadline = time.Time{}
+	} else if d.After(c.deadline) {
+		c.deadline = d
+	}
+}
+
+// sharedContext is the context of a shared query. It is cancelled once
+// every caller is gone, see leave, and reports the deadline of the call.
+type sharedContext struct {
+	context.Context
+	in *Interceptor
+	c  *call
+}
+
+func (s sharedContext) Deadline() (time.Time, bool) {
+	s.in.mu.Lock()
+	defer s.in.mu.Unlock()
+	return s.c.deadline, !s.c.deadline.IsZero()
+}
+
+// leave cancels the shared query once no caller is waiting for it anymore.
+func (in *Interceptor) leave(c *call) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	c.waiters--
+	if c.waiters == 0 {
+		c.cancelled = true
+		c.cancel()
+	}
+}
+
+func capture(ctx context.Context, run func(context.Context) (driver.Rows, error)) (*rowset.Set, driver.Rows, error) {
+	rows, err := run(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	set, err := rowset.Capture(rows)
+	closeErr := rows.Close()
+	if err != nil {
+		return nil, nil, err
+	}
+	return set, rows, closeErr
+}
+
+// replay returns a new replay of the result of c.
+func (c *call) replay() driver.Rows {
+	return replay{Rows: c.set.Rows().(*rowset.Rows), rows: c.rows}
+}
+
+// replay replays the result of a shared query. It unwraps to the rows of the
+// query so that sqlmw only exposes the optional interfaces they implement.
+type replay struct {
+	*rowset.Rows
+	rows driver.Rows
+}
+
+func (r replay) Unwrap() driver.Rows {
+	return r.rows
+}
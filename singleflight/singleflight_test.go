@@ -0,0 +1,266 @@
+package singleflight
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ngrok/sqlmw"
+	"github.com/ngrok/sqlmw/internal/fakedb"
+	"github.com/ngrok/sqlmw/sqlmwtest"
+	"github.com/ngrok/sqlmw/tenant"
+)
+
+// blockingDB answers every query with a single row once release is closed,
+// and reports whether the context of the query was cancelled.
+type blockingDB struct {
+	db        *sql.DB
+	con       *fakedb.Connector
+	release   chan struct{}
+	cancelled chan bool
+}
+
+func newBlockingDB(t *testing.T, in *Interceptor) *blockingDB {
+	b := &blockingDB{release: make(chan struct{}), cancelled: make(chan bool, 100)}
+	b.con = &fakedb.Connector{Handler: func(ctx context.Context, _ string, _ []driver.NamedValue) (*fakedb.Response, error) {
+		select {
+		case <-b.release:
+			b.cancelled <- false
+		case <-ctx.Done():
+			b.cancelled <- true
+			return nil, ctx.Err()
+		}
+		return &fakedb.Response{Columns: []string{"n"}, Rows: [][]driver.Value{{int64(42)}}}, nil
+	}}
+	b.db = sql.OpenDB(sqlmw.Connector(b.con, in))
+	t.Cleanup(func() {
+		if err := b.db.Close(); err != nil {
+			t.Errorf("Failed to close db: %v", err)
+		}
+	})
+	return b
+}
+
+func (b *blockingDB) query(ctx context.Context, query string) (int64, error) {
+	var n int64
+	err := b.db.QueryRowContext(ctx, query, 1).Scan(&n)
+	return n, err
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestCollapse(t *testing.T) {
+	const callers = 10
+	in := New(Config{})
+	b := newBlockingDB(t, in)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			n, err := b.query(context.Background(), "SELECT n FROM counters WHERE id = ?")
+			if err == nil && n != 42 {
+				err = errors.New("unexpected value")
+			}
+			errs <- err
+		}()
+	}
+	waitFor(t, func() bool { return in.Stats().Shared == callers-1 })
+	close(b.release)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("Query failed: %v", err)
+		}
+	}
+	if calls := len(b.con.Calls()); calls != 1 {
+		t.Errorf("expected a single query to reach the database, got %d", calls)
+	}
+}
+
+func TestScope(t *testing.T) {
+	in := New(Config{})
+	b := newBlockingDB(t, in)
+	const query = "SELECT n FROM counters WHERE id = ?"
+
+	var wg sync.WaitGroup
+	for _, name := range []string{"acme", "globex"} {
+		ctx := tenant.WithTenant(context.Background(), name)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := b.query(ctx, query); err != nil {
+				t.Errorf("Query failed: %v", err)
+			}
+		}()
+	}
+	waitFor(t, func() bool { return in.Stats().Queries == 2 })
+	close(b.release)
+	wg.Wait()
+
+	if st := in.Stats(); st.Shared != 0 {
+		t.Errorf("queries of different tenants were collapsed: %+v", st)
+	}
+}
+
+func TestConformance(t *testing.T) {
+	sqlmwtest.RunConformance(t, func() sqlmw.Interceptor { return New(Config{}) })
+}
+
+func TestDoesNotCollapseWrites(t *testing.T) {
+	in := New(Config{})
+	b := newBlockingDB(t, in)
+	close(b.release)
+
+	for i := 0; i < 2; i++ {
+		if _, err := b.query(context.Background(), "INSERT INTO counters (n) VALUES (?) RETURNING n"); err != nil {
+			t.Fatalf("Query failed: %v", err)
+		}
+	}
+	if st := in.Stats(); st.Queries != 0 || st.Shared != 0 {
+		t.Errorf("write was collapsed: %+v", st)
+	}
+}
+
+func TestCancellation(t *testing.T) {
+	tests := []struct {
+		name         string
+		cancelLeader bool
+	}{
+		{name: "follower cancelled"},
+		{name: "leader cancelled", cancelLeader: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			in := New(Config{})
+			b := newBlockingDB(t, in)
+			const query = "SELECT n FROM counters"
+
+			leaderCtx, cancelLeader := context.WithCancel(context.Background())
+			defer cancelLeader()
+			leaderErr := make(chan error, 1)
+			go func() {
+				_, err := b.query(leaderCtx, query)
+				leaderErr <- err
+			}()
+			waitFor(t, func() bool { return in.Stats().Queries == 1 })
+
+			followerCtx, cancelFollower := context.WithCancel(context.Background())
+			defer cancelFollower()
+			followerErr := make(chan error, 1)
+			go func() {
+				_, err := b.query(followerCtx, query)
+				followerErr <- err
+			}()
+			waitFor(t, func() bool { return in.Stats().Shared == 1 })
+
+			if test.cancelLeader {
+				cancelLeader()
+			} else {
+				cancelFollower()
+				if err := <-followerErr; !errors.Is(err, context.Canceled) {
+					t.Fatalf("expected the follower to be cancelled, got %v", err)
+				}
+			}
+			close(b.release)
+
+			if <-b.cancelled {
+				t.Fatal("shared query was cancelled while a caller was waiting")
+			}
+			if test.cancelLeader {
+				if err := <-followerErr; err != nil {
+					t.Errorf("follower failed: %v", err)
+				}
+				if err := <-leaderErr; !errors.Is(err, context.Canceled) {
+					t.Errorf("expected the leader to be cancelled, got %v", err)
+				}
+			} else if err := <-leaderErr; err != nil {
+				t.Errorf("leader failed: %v", err)
+			}
+		})
+	}
+}
+
+func TestCancelsWhenEveryoneLeft(t *testing.T) {
+	in := New(Config{})
+	b := newBlockingDB(t, in)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() {
+		_, err := b.query(ctx, "SELECT n FROM counters")
+		errc <- err
+	}()
+	waitFor(t, func() bool { return in.Stats().Queries == 1 })
+	cancel()
+
+	if !<-b.cancelled {
+		t.Error("shared query was not cancelled")
+	}
+	if err := <-errc; !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestDeadline(t *testing.T) {
+	in := New(Config{})
+	release := make(chan struct{})
+	deadlines := make(chan time.Time, 1)
+	con := &fakedb.Connector{Handler: func(ctx context.Context, _ string, _ []driver.NamedValue) (*fakedb.Response, error) {
+		<-release
+		d, _ := ctx.Deadline()
+		deadlines <- d
+		return &fakedb.Response{Columns: []string{"n"}, Rows: [][]driver.Value{{int64(42)}}}, nil
+	}}
+	db := sql.OpenDB(sqlmw.Connector(con, in))
+	t.Cleanup(func() {
+		if err := db.Close(); err != nil {
+			t.Errorf("Failed to close db: %v", err)
+		}
+	})
+	const query = "SELECT n FROM counters"
+
+	now := time.Now()
+	var wg sync.WaitGroup
+	for i, d := range []time.Time{now.Add(time.Hour), now.Add(2 * time.Hour), now.Add(30 * time.Minute)} {
+		ctx, cancel := context.WithDeadline(context.Background(), d)
+		defer cancel()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var n int64
+			if err := db.QueryRowContext(ctx, query).Scan(&n); err != nil {
+				t.Errorf("Query failed: %v", err)
+			}
+		}()
+		if i == 0 {
+			waitFor(t, func() bool { return in.Stats().Queries == 1 })
+		} else {
+			waitFor(t, func() bool { return in.Stats().Shared == uint64(i) })
+		}
+	}
+	close(release)
+	wg.Wait()
+
+	if d := <-deadlines; !d.Equal(now.Add(2 * time.Hour)) {
+		t.Errorf("got deadline %v, want %v", d, now.Add(2*time.Hour))
+	}
+}
@@ -0,0 +1,207 @@
+// Package sqlcomment provides an sqlmw.Interceptor tagging statements with a
+// comment following the sqlcommenter specification, so that database tools
+// like pg_stat_statements or the MySQL slow log show where a statement came
+// from:
+//
+//	SELECT * FROM users /*application='billing',route='%2Finvoices'*/
+//
+// Tags are collected from the context of the statement by the
+// Config.Extractors and from the tags added with WithTags. Keys and values
+// are URL encoded and single quotes are escaped. Tags are sorted by key.
+//
+// ConnQueryContext, ConnExecContext and ConnPrepareContext statements are
+// tagged. A statement that already has a comment is left untouched.
+//
+// Prepared statements are only tagged with the keys listed in
+// Config.PreparedKeys. A tag whose value changes with every call, like a
+// trace id, would give every prepared statement a distinct text and defeat
+// the statement caches of drivers and databases.
+package sqlcomment
+
+import (
+	"context"
+	"database/sql/driver"
+	"sort"
+	"strings"
+
+	"github.com/ngrok/sqlmw"
+	"github.com/ngrok/sqlmw/sqlinfo"
+)
+
+// Extractor returns the tags of the statements run with ctx.
+type Extractor func(ctx context.Context) map[string]string
+
+// Static returns an Extractor adding tags to every statement, e.g. the name
+// of the application.
+func Static(tags map[string]string) Extractor {
+	return func(context.Context) map[string]string {
+		return tags
+	}
+}
+
+// Config configures an Interceptor.
+type Config struct {
+	// Extractors collect the tags of a statement from its context. When
+	// several extractors return the same key, the last one wins.
+	Extractors []Extractor
+
+	// Keys, if set, is the allowlist of the keys added to statements.
+	Keys []string
+
+	// PreparedKeys is the allowlist of the keys added to prepared
+	// statements, which are not tagged when it is empty. Keys applies to
+	// them as well.
+	PreparedKeys []string
+
+	// Dialect is used to find the comments of statements. Defaults to
+	// sqlinfo.Generic.
+	Dialect sqlinfo.Dialect
+}
+
+type tagsKey struct{}
+
+// WithTags returns a context tagging the statements run with it with tags,
+// in addition to the tags of the parent context. They take precedence over
+// the tags returned by the Config.Extractors.
+func WithTags(ctx context.Context, tags map[string]string) context.Context {
+	if parent, ok := ctx.Value(tagsKey{}).(map[string]string); ok {
+		merged := make(map[string]string, len(parent)+len(tags))
+		for k, v := range parent {
+			merged[k] = v
+		}
+		for k, v := range tags {
+			merged[k] = v
+		}
+		tags = merged
+	}
+	return context.WithValue(ctx, tagsKey{}, tags)
+}
+
+// Interceptor tags statements with a comment.
+type Interceptor struct {
+	sqlmw.NullInterceptor
+
+	cfg      Config
+	keys     map[string]bool
+	prepared map[string]bool
+}
+
+// New returns an Interceptor for the supplied configuration.
+func New(cfg Config) *Interceptor {
+	in := &Interceptor{cfg: cfg, prepared: make(map[string]bool)}
+	if len(cfg.Keys) > 0 {
+		in.keys = make(map[string]bool)
+		for _, k := range cfg.Keys {
+			in.keys[k] = true
+		}
+	}
+	for _, k := range cfg.PreparedKeys {
+		in.prepared[k] = true
+	}
+	return in
+}
+
+func (in *Interceptor) ConnExecContext(ctx context.Context, conn driver.ExecerContext, query string, args []driver.NamedValue) (driver.Result, error) {
+	return conn.ExecContext(ctx, in.tag(ctx, query, nil), args)
+}
+
+func (in *Interceptor) ConnQueryContext(ctx context.Context, conn driver.QueryerContext, query string, args []driver.NamedValue) (context.Context, driver.Rows, error) {
+	rows, err := conn.QueryContext(ctx, in.tag(ctx, query, nil), args)
+	return ctx, rows, err
+}
+
+func (in *Interceptor) ConnPrepareContext(ctx context.Context, conn driver.ConnPrepareContext, query string) (context.Context, driver.Stmt, error) {
+	if len(in.prepared) > 0 {
+		query = in.tag(ctx, query, in.prepared)
+	}
+	stmt, err := conn.PrepareContext(ctx, query)
+	return ctx, stmt, err
+}
+
+// tag returns query with the comment holding the tags of ctx, restricted to
+// allowed when it is set.
+func (in *Interceptor) tag(ctx context.Context, query string, allowed map[string]bool) string {
+	var tags map[string]string
+	add := func(src map[string]string) {
+		for k, v := range src {
+			if k == "" || (in.keys != nil && !in.keys[k]) || (allowed != nil && !allowed[k]) {
+				continue
+			}
+			if tags == nil {
+				tags = make(map[string]string)
+			}
+			tags[k] = v
+		}
+	}
+	for _, extract := range in.cfg.Extractors {
+		add(extract(ctx))
+	}
+	if ctxTags, ok := ctx.Value(tagsKey{}).(map[string]string); ok {
+		add(ctxTags)
+	}
+	if len(tags) == 0 {
+		return query
+	}
+
+	// the comment goes before a trailing semicolon
+	end := len(query)
+	l := sqlinfo.NewLexer(in.cfg.Dialect, query)
+	for {
+		tok, ok := l.Next()
+		if !ok {
+			break
+		}
+		if tok.Kind == sqlinfo.Comment {
+			return query
+		}
+		end = tok.Offset + len(tok.Text)
+		if tok.Kind == sqlinfo.Punct && tok.Text == ";" {
+			end = tok.Offset
+		}
+	}
+	return query[:end] + " " + Comment(tags) + query[end:]
+}
+
+// Comment returns the sqlcommenter comment holding tags.
+func Comment(tags map[string]string) string {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString("/*")
+	for i, k := range keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		escape(&b, k)
+		b.WriteString("='")
+		escape(&b, tags[k])
+		b.WriteByte('\'')
+	}
+	b.WriteString("*/")
+	return b.String()
+}
+
+// escape writes s URL encoded like the encodeURIComponent function of
+// JavaScript, with its single quotes escaped by a backslash. The slash being
+// encoded, the result cannot close the comment.
+func escape(b *strings.Builder, s string) {
+	const hex = "0123456789ABCDEF"
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\'':
+			b.WriteString(`\'`)
+		case ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9'),
+			strings.IndexByte("-_.!~*()", c) >= 0:
+			b.WriteByte(c)
+		default:
+			b.WriteByte('%')
+			b.WriteByte(hex[c>>4])
+			b.WriteByte(hex[c&15])
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package sqlcomment
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"testing"
+
+	"github.com/ngrok/sqlmw"
+	"github.com/ngrok/sqlmw/internal/fakedb"
+	"github.com/ngrok/sqlmw/sqlmwtest"
+)
+
+func openDB(t *testing.T, in *Interceptor) (*sql.DB, *fakedb.Connector) {
+	con := &fakedb.Connector{}
+	db := sql.OpenDB(sqlmw.Connector(con, in))
+	t.Cleanup(func() {
+		if err := db.Close(); err != nil {
+			t.Errorf("Failed to close db: %v", err)
+		}
+	})
+	return db, con
+}
+
+type traceKey struct{}
+
+func traceExtractor(ctx context.Context) map[string]string {
+	if id, ok := ctx.Value(traceKey{}).(string); ok {
+		return map[string]string{"traceparent": id}
+	}
+	return nil
+}
+
+func TestComment(t *testing.T) {
+	got := Comment(map[string]string{
+		"route":      "/param*d",
+		"controller": "index",
+		"name'":      "it's a */ test",
+	})
+	expected := `/*controller='index',name\'='it\'s%20a%20*%2F%20test',route='%2Fparam*d'*/`
+	if got != expected {
+		t.Errorf("unexpected comment:\n got: %s\nwant: %s", got, expected)
+	}
+}
+
+func TestTagging(t *testing.T) {
+	in := New(Config{
+		Extractors:   []Extractor{Static(map[string]string{"application": "billing", "secret": "x"}), traceExtractor},
+		Keys:         []string{"application", "route", "traceparent"},
+		PreparedKeys: []string{"application"},
+	})
+	db, con := openDB(t, in)
+
+	ctx := context.WithValue(context.Background(), traceKey{}, "00-01")
+	ctx = WithTags(ctx, map[string]string{"route": "/invoices"})
+
+	if _, err := db.ExecContext(ctx, "DELETE FROM invoices WHERE id = ?;", 1); err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	if _, err := db.ExecContext(ctx, "DELETE FROM invoices -- cleanup"); err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	stmt, err := db.PrepareContext(ctx, "DELETE FROM invoices WHERE id = ?")
+	if err != nil {
+		t.Fatalf("Prepare failed: %v", err)
+	}
+	if _, err := stmt.ExecContext(ctx, 2); err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	if err := stmt.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	expected := []string{
+		"DELETE FROM invoices WHERE id = ? /*application='billing',route='%2Finvoices',traceparent='00-01'*/;",
+		"DELETE FROM invoices -- cleanup",
+		"DELETE FROM invoices WHERE id = ? /*application='billing'*/",
+	}
+	if qs := con.Queries(); !reflect.DeepEqual(qs, expected) {
+		t.Errorf("unexpected statements:\n got: %q\nwant: %q", qs, expected)
+	}
+}
+
+func TestPreparedUntaggedByDefault(t *testing.T) {
+	in := New(Config{Extractors: []Extractor{Static(map[string]string{"application": "billing"})}})
+	db, con := openDB(t, in)
+
+	stmt, err := db.Prepare("DELETE FROM invoices")
+	if err != nil {
+		t.Fatalf("Prepare failed: %v", err)
+	}
+	if _, err := stmt.Exec(); err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	if err := stmt.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if qs := con.Queries(); len(qs) != 1 || qs[0] != "DELETE FROM invoices" {
+		t.Errorf("prepared statement was tagged: %q", qs)
+	}
+}
+
+func TestConformance(t *testing.T) {
+	sqlmwtest.RunConformance(t, func() sqlmw.Interceptor { return New(Config{}) })
+}
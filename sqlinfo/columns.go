@@ -0,0 +1,159 @@
+package sqlinfo
+
+import "database/sql/driver"
+
+// Columns are the columns the arguments of a statement are compared to,
+// assigned to or inserted into, see ParamColumns.
+type Columns struct {
+	// ByOrdinal are the columns of the ordinal arguments.
+	ByOrdinal map[int]string
+	// ByName are the columns of the named arguments.
+	ByName map[string]string
+}
+
+// Of returns the column of arg, or "" if it is unknown.
+func (c Columns) Of(arg driver.NamedValue) string {
+	if arg.Name != "" {
+		return c.ByName[arg.Name]
+	}
+	return c.ByOrdinal[arg.Ordinal]
+}
+
+// ParamColumns returns the columns the arguments of query are compared to,
+// as in "column = ?" or "column IN (?, ?)", assigned to by an UPDATE, or
+// inserted into by the VALUES of an INSERT with a column list. It is a
+// shallow look at query and the arguments it cannot tell the column of are
+// left out.
+func ParamColumns(dialect Dialect, query string) Columns {
+	cols := Columns{ByOrdinal: make(map[int]string), ByName: make(map[string]string)}
+
+	var toks []Token
+	l := Lexer{dialect: dialect, src: query}
+	for {
+		tok, ok := l.Next()
+		if !ok {
+			break
+		}
+		if tok.Kind != Comment {
+			toks = append(toks, tok)
+		}
+	}
+
+	var (
+		insertCols []string // the column list of an INSERT
+		inValues   bool     // within the VALUES of an INSERT
+		depth      int
+		index      int // of the value within a row of the VALUES
+		positional int
+	)
+	for i, tok := range toks {
+		switch {
+		case isPunct(tok, "("):
+			depth++
+			if depth == 1 && !inValues && insertCols == nil && i >= 2 && isIdent(toks[i-1]) && precededByInto(toks, i-1) {
+				insertCols = columnList(toks[i+1:])
+			}
+			if inValues && depth == 1 {
+				index = 0
+			}
+		case isPunct(tok, ")"):
+			depth--
+		case isPunct(tok, ",") && inValues && depth == 1:
+			index++
+		case depth == 0 && (tok.Is("values") || tok.Is("value")):
+			inValues = insertCols != nil
+		case depth == 0 && inValues && tok.Kind == Word:
+			// ON CONFLICT, RETURNING...
+			inValues = false
+		}
+		if tok.Kind != Placeholder {
+			continue
+		}
+
+		ordinal, name := tok.Placeholder()
+		if ordinal == 0 && name == "" {
+			positional++
+			ordinal = positional
+		}
+		var column string
+		if inValues && depth == 1 && index < len(insertCols) {
+			column = insertCols[index]
+		} else {
+			column = comparedColumn(toks, i)
+		}
+		if column == "" {
+			continue
+		}
+		if name != "" {
+			cols.ByName[name] = column
+		} else {
+			cols.ByOrdinal[ordinal] = column
+		}
+	}
+	return cols
+}
+
+// precededByInto reports whether the table name at toks[i], possibly
+// qualified, follows INTO.
+func precededByInto(toks []Token, i int) bool {
+	for i >= 2 && isPunct(toks[i-1], ".") {
+		i -= 2
+	}
+	return i >= 1 && toks[i-1].Is("into")
+}
+
+// columnList returns the names of the column list starting at toks.
+func columnList(toks []Token) []string {
+	var cols []string
+	for _, tok := range toks {
+		switch {
+		case isPunct(tok, ")"):
+			return cols
+		case isIdent(tok):
+			cols = append(cols, tok.Ident())
+		}
+	}
+	return cols
+}
+
+// comparedColumn returns the column the value at toks[i] is compared to or
+// assigned to, as in "column = ?" or "column IN (?, ?)".
+func comparedColumn(toks []Token, i int) string {
+	// skip the preceding values of an IN list
+	j := i - 1
+	for j >= 1 && isPunct(toks[j], ",") {
+		j -= 2
+	}
+	if j >= 1 && isPunct(toks[j], "(") && toks[j-1].Is("in") {
+		j--
+	}
+	if j < 1 {
+		return ""
+	}
+	if op := toks[j]; !isComparison(op) {
+		return ""
+	}
+	if col := toks[j-1]; isIdent(col) && !col.Is("not") {
+		return col.Ident()
+	}
+	if j >= 2 && toks[j-1].Is("not") && isIdent(toks[j-2]) {
+		return toks[j-2].Ident()
+	}
+	return ""
+}
+
+func isComparison(tok Token) bool {
+	if tok.Kind == Punct {
+		switch tok.Text {
+		case "=", "<>", "!=", "<", ">", "<=", ">=":
+			return true
+		}
+		return false
+	}
+	return tok.Is("like") || tok.Is("ilike") || tok.Is("in")
+}
+
+// isIdent reports whether tok may name a column.
+func isIdent(tok Token) bool {
+	return tok.Kind == Word || tok.Kind == QuotedIdent
+}
@@ -0,0 +1,433 @@
+package sqlinfo
+
+// Dialect selects the lexical rules of a SQL flavor.
+type Dialect int
+
+const (
+	// Generic accepts the syntax shared by the other dialects: '...'
+	// literals without backslash escapes, "..." and `...` quoted
+	// identifiers, E'...' and $tag$...$tag$ literals, and ?, $n, :name and
+	// @name placeholders.
+	Generic Dialect = iota
+
+	// PostgreSQL has "..." quoted identifiers, E'...' and $tag$...$tag$
+	// literals, nested block comments and $n placeholders. ? is an
+	// operator.
+	PostgreSQL
+
+	// MySQL has `...` quoted identifiers, '...' and "..." literals with
+	// backslash escapes, # comments and ? placeholders. @name is a user
+	// variable. The bodies of /*!...*/ executable comments and /*+...*/
+	// optimizer hints are code.
+	MySQL
+
+	// SQLite has "...", `...` and [...] quoted identifiers, and ?, ?NNN,
+	// :name, @name and $name placeholders.
+	SQLite
+)
+
+func (d Dialect) String() string {
+	switch d {
+	case Generic:
+		return "generic"
+	case PostgreSQL:
+		return "postgresql"
+	case MySQL:
+		return "mysql"
+	case SQLite:
+		return "sqlite"
+	}
+	return "unknown"
+}
+
+// TokenKind is the lexical class of a Token.
+type TokenKind int
+
+const (
+	// Word is a keyword or an unquoted identifier.
+	Word TokenKind = iota + 1
+	// QuotedIdent is a quoted identifier.
+	QuotedIdent
+	// String is a string, bit string or blob literal.
+	String
+	// Number is a numeric literal.
+	Number
+	// Placeholder is a bind parameter.
+	Placeholder
+	// Punct is an operator or a punctuation character.
+	Punct
+	// Comment is a line or block comment, or a delimiter of a MySQL
+	// executable comment, see Token.Executable.
+	Comment
+)
+
+func (k TokenKind) String() string {
+	switch k {
+	case Word:
+		return "word"
+	case QuotedIdent:
+		return "quoted identifier"
+	case String:
+		return "string"
+	case Number:
+		return "number"
+	case Placeholder:
+		return "placeholder"
+	case Punct:
+		return "punctuation"
+	case Comment:
+		return "comment"
+	}
+	return "unknown"
+}
+
+// Token is a lexical element of a statement.
+type Token struct {
+	Kind TokenKind
+	// Text is the source text of the token, including its quotes.
+	Text string
+	// Offset is the byte offset of the token in the statement.
+	Offset int
+}
+
+// Is reports whether t is the keyword kw, which must be lower case.
+func (t Token) Is(kw string) bool {
+	return t.Kind == Word && equalFold(t.Text, kw)
+}
+
+// Executable reports whether t opens or closes a MySQL executable comment
+// or optimizer hint, whose body the lexer returns as code.
+func (t Token) Executable() bool {
+	if t.Kind != Comment {
+		return false
+	}
+	return t.Text == "*/" || (len(t.Text) >= 3 && t.Text[:2] == "/*" && (t.Text[2] == '!' || t.Text[2] == '+'))
+}
+
+// Ident returns the name designated by a Word or QuotedIdent token. Unquoted
+// names are lower cased, quoted names are returned without their quotes.
+func (t Token) Ident() string {
+	switch t.Kind {
+	case Word:
+		return lower(t.Text)
+	case QuotedIdent:
+		if len(t.Text) < 2 {
+			return t.Text
+		}
+		quote := t.Text[0]
+		if quote == '[' {
+			return t.Text[1 : len(t.Text)-1]
+		}
+		body := t.Text[1 : len(t.Text)-1]
+		var b []byte
+		for i := 0; i < len(body); i++ {
+			if body[i] == quote && i+1 < len(body) && body[i+1] == quote {
+				if b == nil {
+					b = append(b, body[:i]...)
+				}
+				i++
+			}
+			if b != nil {
+				b = append(b, body[i])
+			}
+		}
+		if b == nil {
+			return body
+		}
+		return string(b)
+	}
+	return t.Text
+}
+
+// Lexer splits a statement into tokens. Whitespace is skipped, comments are
+// returned as tokens. Unterminated literals, quoted identifiers and comments
+// extend to the end of the statement.
+type Lexer struct {
+	dialect Dialect
+	src     string
+	pos     int
+	// executable is set within a MySQL executable comment
+	executable bool
+}
+
+// NewLexer returns a Lexer for src.
+func NewLexer(dialect Dialect, src string) *Lexer {
+	return &Lexer{dialect: dialect, src: src}
+}
+
+// Tokens returns all the tokens of src, comments included.
+func Tokens(dialect Dialect, src string) []Token {
+	var toks []Token
+	l := Lexer{dialect: dialect, src: src}
+	for {
+		tok, ok := l.Next()
+		if !ok {
+			return toks
+		}
+		toks = append(toks, tok)
+	}
+}
+
+// Next returns the next token, or false at the end of the statement.
+func (l *Lexer) Next() (Token, bool) {
+	q := l.src
+	i := l.pos
+	for i < len(q) && isSpace(q[i]) {
+		i++
+	}
+	if i >= len(q) {
+		l.pos = i
+		return Token{}, false
+	}
+
+	kind, end := l.scan(i)
+	l.pos = end
+	return Token{Kind: kind, Text: q[i:end], Offset: i}, true
+}
+
+func (l *Lexer) scan(i int) (TokenKind, int) {
+	q, d := l.src, l.dialect
+	c := q[i]
+	var next byte
+	if i+1 < len(q) {
+		next = q[i+1]
+	}
+
+	switch {
+	case c == '/' && next == '*' && d == MySQL && !l.executable && i+2 < len(q) && (q[i+2] == '!' || q[i+2] == '+'):
+		// the opening of an executable comment, with its version number
+		end := i + 3
+		for end < len(q) && isDigit(q[end]) && q[i+2] == '!' {
+			end++
+		}
+		l.executable = true
+		return Comment, end
+	case c == '*' && next == '/' && l.executable:
+		l.executable = false
+		return Comment, i + 2
+	case c == '-' && next == '-' && (d != MySQL || i+2 >= len(q) || isSpace(q[i+2]) || q[i+2] < ' '):
+		return Comment, lineEnd(q, i)
+	case c == '#' && d == MySQL:
+		return Comment, lineEnd(q, i)
+	case c == '/' && next == '*':
+		return Comment, l.blockCommentEnd(i)
+
+	case c == '\'':
+		return String, quotedEnd(q, i, '\'', d == MySQL)
+	case (c == 'e' || c == 'E') && next == '\'' && (d == PostgreSQL || d == Generic):
+		return String, quotedEnd(q, i+1, '\'', true)
+	case (c == 'x' || c == 'X' || c == 'b' || c == 'B' || c == 'n' || c == 'N') && next == '\'':
+		return String, quotedEnd(q, i+1, '\'', d == MySQL)
+	case c == '"':
+		if d == MySQL {
+			return String, quotedEnd(q, i, '"', true)
+		}
+		return QuotedIdent, quotedEnd(q, i, '"', false)
+	case c == '`' && d != PostgreSQL:
+		return QuotedIdent, quotedEnd(q, i, '`', false)
+	case c == '[' && d == SQLite:
+		end := i + 1
+		for end < len(q) && q[end] != ']' {
+			end++
+		}
+		if end < len(q) {
+			end++
+		}
+		return QuotedIdent, end
+
+	case isDigit(c) || (c == '.' && isDigit(next)):
+		return Number, numberEnd(q, i)
+
+	case c == '?' && d != PostgreSQL:
+		end := i + 1
+		for end < len(q) && isDigit(q[end]) {
+			end++
+		}
+		return Placeholder, end
+	case c == '$' && d != MySQL:
+		if isDigit(next) && d != SQLite {
+			end := i + 1
+			for end < len(q) && isDigit(q[end]) {
+				end++
+			}
+			return Placeholder, end
+		}
+		if d == SQLite && (isDigit(next) || isWordStart(next)) {
+			return Placeholder, wordEnd(q, i+1)
+		}
+		if end, ok := dollarQuotedEnd(q, i); ok {
+			return String, end
+		}
+	case (c == ':' || c == '@') && isWordStart(next) && (d == SQLite || d == Generic):
+		return Placeholder, wordEnd(q, i+1)
+	case c == '@' && d == MySQL && (isWordStart(next) || next == '@'):
+		// user and system variables
+		end := i + 1
+		for end < len(q) && q[end] == '@' {
+			end++
+		}
+		return Word, wordEnd(q, end)
+
+	case isWordStart(c):
+		return Word, wordEnd(q, i)
+	}
+
+	for _, op := range operators {
+		if len(q)-i >= len(op) && q[i:i+len(op)] == op {
+			return Punct, i + len(op)
+		}
+	}
+	return Punct, i + 1
+}
+
+// operators are the multi character operators, longest first.
+var operators = []string{
+	"->>", "#>>", "<=>",
+	"<=", ">=", "<>", "!=", "::", "||", "&&", "->", "=>", ":=", "<<", ">>", "#>", "@>", "<@", "?|", "?&",
+}
+
+func (l *Lexer) blockCommentEnd(i int) int {
+	q := l.src
+	depth := 0
+	for j := i; j+1 < len(q); j++ {
+		switch {
+		case q[j] == '/' && q[j+1] == '*':
+			if depth > 0 && l.dialect != PostgreSQL {
+				j++
+				continue
+			}
+			depth++
+			j++
+		case q[j] == '*' && q[j+1] == '/':
+			depth--
+			j++
+			if depth == 0 {
+				return j + 1
+			}
+		}
+	}
+	return len(q)
+}
+
+func lineEnd(q string, i int) int {
+	for i < len(q) && q[i] != '\n' {
+		i++
+	}
+	return i
+}
+
+// quotedEnd returns the position following the quoted text starting at i.
+// Quotes are escaped by doubling them, or with a backslash when backslash is
+// set.
+func quotedEnd(q string, i int, quote byte, backslash bool) int {
+	for j := i + 1; j < len(q); j++ {
+		switch q[j] {
+		case '\\':
+			if backslash {
+				j++
+			}
+		case quote:
+			if j+1 < len(q) && q[j+1] == quote {
+				j++
+				continue
+			}
+			return j + 1
+		}
+	}
+	return len(q)
+}
+
+// dollarQuotedEnd returns the position following the $tag$...$tag$ literal
+// starting at i.
+func dollarQuotedEnd(q string, i int) (int, bool) {
+	j := i + 1
+	for j < len(q) && isWordStart(q[j]) {
+		j++
+	}
+	if j >= len(q) || q[j] != '$' {
+		return 0, false
+	}
+	tag := q[i : j+1]
+	for k := j + 1; k+len(tag) <= len(q); k++ {
+		if q[k:k+len(tag)] == tag {
+			return k + len(tag), true
+		}
+	}
+	return len(q), true
+}
+
+func numberEnd(q string, i int) int {
+	if q[i] == '0' && i+1 < len(q) && (q[i+1] == 'x' || q[i+1] == 'X') {
+		i += 2
+		for i < len(q) && (isDigit(q[i]) || ('a' <= q[i] && q[i] <= 'f') || ('A' <= q[i] && q[i] <= 'F')) {
+			i++
+		}
+		return i
+	}
+	for i < len(q) && (isDigit(q[i]) || q[i] == '.') {
+		i++
+	}
+	if i < len(q) && (q[i] == 'e' || q[i] == 'E') {
+		j := i + 1
+		if j < len(q) && (q[j] == '+' || q[j] == '-') {
+			j++
+		}
+		if j < len(q) && isDigit(q[j]) {
+			i = j
+			for i < len(q) && isDigit(q[i]) {
+				i++
+			}
+		}
+	}
+	return i
+}
+
+func wordEnd(q string, i int) int {
+	for i < len(q) && (isWordStart(q[i]) || isDigit(q[i]) || q[i] == '$') {
+		i++
+	}
+	return i
+}
+
+func isWordStart(c byte) bool {
+	return c == '_' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || c >= 0x80
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v'
+}
+
+func lower(s string) string {
+	for i := 0; i < len(s); i++ {
+		if 'A' <= s[i] && s[i] <= 'Z' {
+			b := []byte(s)
+			for j := i; j < len(b); j++ {
+				if 'A' <= b[j] && b[j] <= 'Z' {
+					b[j] += 'a' - 'A'
+				}
+			}
+			return string(b)
+		}
+	}
+	return s
+}
+
+func equalFold(s, lower string) bool {
+	if len(s) != len(lower) {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' {
+			c += 'a' - 'A'
+		}
+		if c != lower[i] {
+			return false
+		}
+	}
+	return true
+}
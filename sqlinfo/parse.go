@@ -0,0 +1,465 @@
+package sqlinfo
+
+import (
+	"strings"
+)
+
+// Kind is the class of a statement.
+type Kind int
+
+const (
+	// Unknown is for statements that are not recognized, like SET, CALL or
+	// EXPLAIN, and for queries made of statements of different kinds.
+	Unknown Kind = iota
+	// Read is for statements returning data without modifying it, like
+	// SELECT, VALUES and SHOW.
+	Read
+	// Write is for statements modifying data, like INSERT, UPDATE, DELETE
+	// and MERGE, including WITH queries containing one of them.
+	Write
+	// DDL is for statements modifying the schema or the privileges, like
+	// CREATE, ALTER, DROP, TRUNCATE and GRANT.
+	DDL
+	// TxControl is for statements controlling transactions, like BEGIN,
+	// COMMIT, ROLLBACK and SAVEPOINT.
+	TxControl
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Read:
+		return "read"
+	case Write:
+		return "write"
+	case DDL:
+		return "ddl"
+	case TxControl:
+		return "tx control"
+	}
+	return "unknown"
+}
+
+// Statement describes a query. Statements are shared and must not be
+// modified.
+type Statement struct {
+	// Verb is the upper case keyword of the statement, like SELECT or
+	// INSERT. For a WITH query it is the verb of the main statement. It is
+	// empty for an empty query.
+	Verb string
+
+	// Kind is the class of the statement.
+	Kind Kind
+
+	// Tables are the tables the statement references, in order of first
+	// appearance. Their schema is stripped, unquoted names are lower cased
+	// and quoted names are unquoted. The names of common table expressions
+	// are not included.
+	Tables []string
+
+	// Returning is set when the statement has a RETURNING clause.
+	Returning bool
+
+	// ForUpdate is set when the statement has a row locking clause, like FOR
+	// UPDATE, FOR SHARE or LOCK IN SHARE MODE.
+	ForUpdate bool
+}
+
+// Parse describes query. It only looks at the shape of the statement and
+// never fails: what it does not recognize is left out of the description.
+// When query holds several statements separated by semicolons, Verb is the
+// verb of the first one, Kind is Unknown unless they all have the same kind,
+// and Tables, Returning and ForUpdate cover all of them.
+func Parse(dialect Dialect, query string) *Statement {
+	var toks []Token
+	l := Lexer{dialect: dialect, src: query}
+	for {
+		tok, ok := l.Next()
+		if !ok {
+			break
+		}
+		if tok.Kind != Comment {
+			toks = append(toks, tok)
+		}
+	}
+
+	var st *Statement
+	start, depth := 0, 0
+	for i := 0; i <= len(toks); i++ {
+		if i < len(toks) {
+			switch {
+			case isPunct(toks[i], "("):
+				depth++
+				continue
+			case isPunct(toks[i], ")"):
+				depth--
+				continue
+			case !isPunct(toks[i], ";") || depth > 0:
+				continue
+			}
+		}
+		if i > start {
+			st = merge(st, parseStatement(toks[start:i]))
+		}
+		start = i + 1
+	}
+	if st == nil {
+		return &Statement{}
+	}
+	return st
+}
+
+func merge(st, next *Statement) *Statement {
+	if st == nil {
+		return next
+	}
+	if st.Kind != next.Kind {
+		st.Kind = Unknown
+	}
+	for _, t := range next.Tables {
+		st.addTable(t)
+	}
+	st.Returning = st.Returning || next.Returning
+	st.ForUpdate = st.ForUpdate || next.ForUpdate
+	return st
+}
+
+func (st *Statement) addTable(name string) {
+	for _, t := range st.Tables {
+		if t == name {
+			return
+		}
+	}
+	st.Tables = append(st.Tables, name)
+}
+
+// parseStatement describes a single statement, toks holding no comment.
+func parseStatement(toks []Token) *Statement {
+	st := &Statement{}
+
+	// the verb, skipping the parentheses of e.g. (SELECT 1) UNION (SELECT 2)
+	v := 0
+	for v < len(toks) && isPunct(toks[v], "(") {
+		v++
+	}
+	if v == len(toks) || toks[v].Kind != Word {
+		return st
+	}
+	verb := lower(toks[v].Text)
+	var ctes []string
+	if verb == "with" {
+		var main int
+		ctes, main = commonTableExpressions(toks, v+1)
+		if main < len(toks) && toks[main].Kind == Word {
+			v, verb = main, lower(toks[main].Text)
+		} else {
+			verb = ""
+		}
+	}
+	st.Verb = strings.ToUpper(verb)
+	st.Kind = kindOf(verb, toks, v)
+
+	w := walker{toks: toks, st: st, verb: v}
+	w.walk()
+
+	if len(ctes) > 0 {
+		tables := st.Tables[:0]
+		for _, t := range st.Tables {
+			if !contains(ctes, t) {
+				tables = append(tables, t)
+			}
+		}
+		st.Tables = tables
+	}
+	if len(st.Tables) == 0 {
+		st.Tables = nil
+	}
+	if st.Kind == Read && w.modifying {
+		// data modifying common table expression
+		st.Kind = Write
+	}
+	return st
+}
+
+// commonTableExpressions returns the names of the common table expressions
+// of a WITH query starting at i and the position of its main statement.
+func commonTableExpressions(toks []Token, i int) ([]string, int) {
+	var names []string
+	if i < len(toks) && toks[i].Is("recursive") {
+		i++
+	}
+	for i < len(toks) {
+		if !isName(toks[i]) {
+			return names, i
+		}
+		names = append(names, toks[i].Ident())
+		i++
+		if i < len(toks) && isPunct(toks[i], "(") {
+			i = closing(toks, i) + 1
+		}
+		if i >= len(toks) || !toks[i].Is("as") {
+			return names, i
+		}
+		i++
+		for i < len(toks) && (toks[i].Is("not") || toks[i].Is("materialized")) {
+			i++
+		}
+		if i >= len(toks) || !isPunct(toks[i], "(") {
+			return names, i
+		}
+		i = closing(toks, i) + 1
+		if i >= len(toks) || !isPunct(toks[i], ",") {
+			return names, i
+		}
+		i++
+	}
+	return names, i
+}
+
+// closing returns the position of the parenthesis closing the one at i.
+func closing(toks []Token, i int) int {
+	depth := 0
+	for ; i < len(toks); i++ {
+		switch {
+		case isPunct(toks[i], "("):
+			depth++
+		case isPunct(toks[i], ")"):
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return len(toks)
+}
+
+func kindOf(verb string, toks []Token, v int) Kind {
+	var next Token
+	if v+1 < len(toks) {
+		next = toks[v+1]
+	}
+	switch verb {
+	case "select", "values", "table", "show", "describe", "desc":
+		return Read
+	case "insert", "update", "delete", "merge", "replace", "upsert", "load":
+		return Write
+	case "copy":
+		// COPY ... FROM loads data, COPY ... TO dumps it
+		for _, t := range toks[v+1:] {
+			if t.Is("from") {
+				return Write
+			}
+		}
+		return Read
+	case "create", "alter", "drop", "truncate", "rename", "comment", "grant", "revoke":
+		return DDL
+	case "begin", "commit", "end", "rollback", "savepoint", "release", "abort":
+		return TxControl
+	case "start", "set", "prepare":
+		if next.Is("transaction") {
+			return TxControl
+		}
+	}
+	return Unknown
+}
+
+// walker finds the tables and the clauses of a statement.
+type walker struct {
+	toks []Token
+	st   *Statement
+	verb int // position of the verb
+
+	calls     []bool // for every open parenthesis, whether it is a call
+	index     bool   // whether an INDEX keyword was seen
+	modifying bool   // whether a nested statement modifies data
+}
+
+func (w *walker) walk() {
+	toks := w.toks
+	for i := 0; i < len(toks); i++ {
+		t := toks[i]
+		switch t.Kind {
+		case Punct:
+			switch t.Text {
+			case "(":
+				w.calls = append(w.calls, i > 0 && isCall(toks[i-1]))
+			case ")":
+				if len(w.calls) > 0 {
+					w.calls = w.calls[:len(w.calls)-1]
+				}
+			}
+			continue
+		case Word:
+		default:
+			continue
+		}
+
+		var prev, next Token
+		if i > 0 {
+			prev = toks[i-1]
+		}
+		if i+1 < len(toks) {
+			next = toks[i+1]
+		}
+		statementStart := i == w.verb || isPunct(prev, "(")
+		inCall := len(w.calls) > 0 && w.calls[len(w.calls)-1]
+
+		switch lower(t.Text) {
+		case "returning":
+			if len(w.calls) == 0 {
+				w.st.Returning = true
+			}
+		case "for":
+			if next.Is("update") || next.Is("share") || next.Is("no") || next.Is("key") {
+				w.st.ForUpdate = true
+			}
+		case "lock":
+			if next.Is("in") && i+2 < len(toks) && toks[i+2].Is("share") {
+				w.st.ForUpdate = true
+			}
+		case "index":
+			w.index = true
+		case "from":
+			if !inCall && !prev.Is("distinct") {
+				i = w.tables(i+1, true, true) - 1
+			}
+		case "join", "straight_join":
+			i = w.tables(i+1, false, true) - 1
+		case "using":
+			i = w.tables(i+1, true, true) - 1
+		case "into":
+			i = w.tables(i+1, false, false) - 1
+		case "table", "tables":
+			i = w.tables(i+1, true, false) - 1
+		case "references":
+			i = w.tables(i+1, false, false) - 1
+		case "on":
+			if w.index && w.toks[w.verb].Is("create") {
+				i = w.tables(i+1, false, false) - 1
+			}
+		case "update":
+			if statementStart || isPunct(prev, ")") {
+				w.nested(i)
+				i = w.tables(i+1, true, false) - 1
+			}
+		case "insert", "replace", "delete", "merge", "truncate":
+			if !statementStart || isPunct(next, "(") {
+				// e.g. the replace function
+				continue
+			}
+			w.nested(i)
+			j := i + 1
+			for j < len(toks) && toks[j].Kind == Word && skippedBeforeTable[lower(toks[j].Text)] {
+				j++
+			}
+			if j < len(toks) && !toks[j].Is("into") && !toks[j].Is("from") && !toks[j].Is("table") {
+				// INSERT t VALUES, TRUNCATE t and DELETE t FROM
+				i = w.tables(j, true, false) - 1
+			}
+		}
+	}
+}
+
+// nested records a data modifying statement found at i inside another one.
+func (w *walker) nested(i int) {
+	if i != w.verb {
+		w.modifying = true
+	}
+}
+
+// tables records the tables referenced from i, which follows a keyword
+// introducing tables, and returns the position following them. list is set
+// when a comma separated list of tables may follow. fn is set where a
+// function call may appear in place of a table.
+func (w *walker) tables(i int, list, fn bool) int {
+	toks := w.toks
+	for {
+		for i < len(toks) && toks[i].Kind == Word && skippedBeforeTable[lower(toks[i].Text)] {
+			i++
+		}
+		if i >= len(toks) || !isName(toks[i]) {
+			return i
+		}
+		name := toks[i]
+		j := i + 1
+		for j+1 < len(toks) && isPunct(toks[j], ".") && isName(toks[j+1]) {
+			name = toks[j+1]
+			j += 2
+		}
+		if fn && j < len(toks) && isPunct(toks[j], "(") {
+			return i
+		}
+		w.st.addTable(name.Ident())
+
+		// alias
+		switch {
+		case j+1 < len(toks) && toks[j].Is("as") && isName(toks[j+1]):
+			j += 2
+		case j < len(toks) && isName(toks[j]):
+			j++
+		}
+		if !list || j >= len(toks) || !isPunct(toks[j], ",") {
+			return j
+		}
+		i = j + 1
+	}
+}
+
+// skippedBeforeTable are the modifiers that may appear between a keyword
+// introducing a table and the table.
+var skippedBeforeTable = map[string]bool{
+	"only": true, "lateral": true, "if": true, "not": true, "exists": true,
+	"low_priority": true, "high_priority": true, "delayed": true, "quick": true,
+	"ignore": true, "or": true, "replace": true, "abort": true, "fail": true, "rollback": true,
+}
+
+// keywords are the words that are neither table names nor aliases, and are
+// not function names when followed by a parenthesis.
+var keywords = map[string]bool{
+	"all": true, "and": true, "any": true, "array": true, "as": true, "asc": true,
+	"by": true, "case": true, "check": true, "conflict": true, "constraint": true,
+	"cross": true, "default": true, "delete": true, "desc": true, "distinct": true,
+	"do": true, "duplicate": true, "else": true, "end": true, "except": true,
+	"exists": true, "fetch": true, "for": true, "force": true, "foreign": true,
+	"from": true, "full": true, "group": true, "having": true, "if": true,
+	"ignore": true, "in": true, "index": true, "inner": true, "insert": true,
+	"intersect": true, "into": true, "is": true, "join": true, "key": true,
+	"lateral": true, "left": true, "limit": true, "lock": true, "merge": true,
+	"minus": true, "natural": true, "not": true, "null": true, "offset": true,
+	"on": true, "only": true, "or": true, "order": true, "outer": true,
+	"outfile": true, "partition": true, "primary": true, "qualify": true,
+	"recursive": true, "references": true, "returning": true, "right": true,
+	"select": true, "set": true, "some": true, "straight_join": true,
+	"table": true, "tablesample": true, "then": true, "union": true,
+	"unique": true, "update": true, "use": true, "using": true, "values": true,
+	"when": true, "where": true, "window": true, "with": true,
+}
+
+// isName reports whether t may name a table or an alias.
+func isName(t Token) bool {
+	switch t.Kind {
+	case QuotedIdent:
+		return true
+	case Word:
+		return t.Text[0] != '@' && !keywords[lower(t.Text)]
+	}
+	return false
+}
+
+// isCall reports whether a parenthesis following t opens the arguments of a
+// function call.
+func isCall(t Token) bool {
+	return isName(t)
+}
+
+func isPunct(t Token, p string) bool {
+	return t.Kind == Punct && t.Text == p
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
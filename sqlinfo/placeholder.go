@@ -0,0 +1,50 @@
+package sqlinfo
+
+// Placeholder returns the argument a Placeholder token refers to: its ordinal
+// for the ?NNN and $n placeholders, or its name without its prefix for the
+// named ones. The ordinal of a plain ? placeholder is 0, it refers to the
+// argument following the one of the previous ? placeholder.
+func (t Token) Placeholder() (ordinal int, name string) {
+	if t.Kind != Placeholder || t.Text == "?" {
+		return 0, ""
+	}
+	rest := t.Text[1:]
+	n := 0
+	for i := 0; i < len(rest); i++ {
+		if !isDigit(rest[i]) {
+			return 0, rest
+		}
+		n = n*10 + int(rest[i]-'0')
+	}
+	return n, ""
+}
+
+// NumInput returns the number of arguments of query: the number of ?
+// placeholders, plus the highest numbered placeholder, plus the number of
+// distinct named placeholders.
+func NumInput(dialect Dialect, query string) int {
+	var positional, numbered int
+	var names []string
+	l := Lexer{dialect: dialect, src: query}
+	for {
+		tok, ok := l.Next()
+		if !ok {
+			break
+		}
+		if tok.Kind != Placeholder {
+			continue
+		}
+		ordinal, name := tok.Placeholder()
+		switch {
+		case name != "":
+			if !contains(names, name) {
+				names = append(names, name)
+			}
+		case ordinal > numbered:
+			numbered = ordinal
+		case ordinal == 0:
+			positional++
+		}
+	}
+	return positional + numbered + len(names)
+}
@@ -0,0 +1,141 @@
+// Package sqlinfo describes SQL statements: their verb, their kind, the
+// tables they reference and whether they have a RETURNING or a row locking
+// clause.
+//
+// Parse describes a statement according to the lexical rules of a Dialect.
+// It is a shallow parser, built on the Lexer of this package, that only
+// looks at the shape of a statement and never fails.
+//
+// The Interceptor of this package describes every statement running through
+// it and passes the description to the interceptors of the inner layers and
+// to its own RowsNext and RowsClose hooks through the context, where
+// FromContext finds it. Prepared statements are described once, in
+// ConnPrepareContext, and the description is reused by every execution:
+//
+//	db := sql.OpenDB(sqlmw.Connector(
+//		sqlmw.Connector(connector, cache.New(cache.Config{})),
+//		sqlinfo.New(sqlinfo.PostgreSQL),
+//	))
+package sqlinfo
+
+import (
+	"context"
+	"database/sql/driver"
+	"sync"
+
+	"github.com/ngrok/sqlmw"
+)
+
+type statementKey struct{}
+
+// NewContext returns a context carrying st.
+func NewContext(ctx context.Context, st *Statement) context.Context {
+	return context.WithValue(ctx, statementKey{}, st)
+}
+
+// FromContext returns the description carried by ctx, if any.
+func FromContext(ctx context.Context) (*Statement, bool) {
+	st, ok := ctx.Value(statementKey{}).(*Statement)
+	return st, ok
+}
+
+// Describe returns the description carried by ctx, or parses query with the
+// Generic dialect when ctx carries none.
+func Describe(ctx context.Context, query string) *Statement {
+	if st, ok := FromContext(ctx); ok {
+		return st
+	}
+	return Parse(Generic, query)
+}
+
+// Interceptor describes the statements running through it.
+type Interceptor struct {
+	sqlmw.NullInterceptor
+
+	dialect Dialect
+
+	mu       sync.RWMutex
+	prepared map[string]*prepared
+}
+
+// prepared is the description shared by the prepared statements of a query.
+type prepared struct {
+	st   *Statement
+	refs int
+}
+
+type preparedKey struct{}
+
+// New returns an Interceptor describing statements according to dialect.
+func New(dialect Dialect) *Interceptor {
+	return &Interceptor{dialect: dialect, prepared: make(map[string]*prepared)}
+}
+
+func (in *Interceptor) ConnPrepareContext(ctx context.Context, conn driver.ConnPrepareContext, query string) (context.Context, driver.Stmt, error) {
+	in.mu.Lock()
+	p, ok := in.prepared[query]
+	if !ok {
+		p = &prepared{st: Parse(in.dialect, query)}
+		in.prepared[query] = p
+	}
+	p.refs++
+	in.mu.Unlock()
+
+	ctx = NewContext(ctx, p.st)
+	stmt, err := conn.PrepareContext(ctx, query)
+	if err != nil {
+		in.release(query)
+		return ctx, nil, err
+	}
+	return context.WithValue(ctx, preparedKey{}, query), stmt, nil
+}
+
+func (in *Interceptor) StmtClose(ctx context.Context, stmt driver.Stmt) error {
+	err := stmt.Close()
+	if query, ok := ctx.Value(preparedKey{}).(string); ok {
+		in.release(query)
+	}
+	return err
+}
+
+func (in *Interceptor) release(query string) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	if p, ok := in.prepared[query]; ok {
+		p.refs--
+		if p.refs == 0 {
+			delete(in.prepared, query)
+		}
+	}
+}
+
+// describe returns the description of a prepared query, or parses query.
+func (in *Interceptor) describe(query string) *Statement {
+	in.mu.RLock()
+	p, ok := in.prepared[query]
+	in.mu.RUnlock()
+	if ok {
+		return p.st
+	}
+	return Parse(in.dialect, query)
+}
+
+func (in *Interceptor) StmtExecContext(ctx context.Context, stmt driver.StmtExecContext, query string, args []driver.NamedValue) (driver.Result, error) {
+	return stmt.ExecContext(NewContext(ctx, in.describe(query)), args)
+}
+
+func (in *Interceptor) StmtQueryContext(ctx context.Context, stmt driver.StmtQueryContext, query string, args []driver.NamedValue) (context.Context, driver.Rows, error) {
+	ctx = NewContext(ctx, in.describe(query))
+	rows, err := stmt.QueryContext(ctx, args)
+	return ctx, rows, err
+}
+
+func (in *Interceptor) ConnExecContext(ctx context.Context, conn driver.ExecerContext, query string, args []driver.NamedValue) (driver.Result, error) {
+	return conn.ExecContext(NewContext(ctx, Parse(in.dialect, query)), query, args)
+}
+
+func (in *Interceptor) ConnQueryContext(ctx context.Context, conn driver.QueryerContext, query string, args []driver.NamedValue) (context.Context, driver.Rows, error) {
+	ctx = NewContext(ctx, Parse(in.dialect, query))
+	rows, err := conn.QueryContext(ctx, query, args)
+	return ctx, rows, err
+}
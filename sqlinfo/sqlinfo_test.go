@@ -0,0 +1,208 @@
+package sqlinfo
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/ngrok/sqlmw"
+	"github.com/ngrok/sqlmw/internal/fakedb"
+)
+
+func TestLexer(t *testing.T) {
+	tests := []struct {
+		dialect  Dialect
+		query    string
+		expected []TokenKind
+	}{
+		{PostgreSQL, `SELECT "a""b", E'it\'s', $$x$$, $1 ? 2 -- c`, []TokenKind{Word, QuotedIdent, Punct, String, Punct, String, Punct, Placeholder, Punct, Number, Comment}},
+		{PostgreSQL, `/* a /* nested */ comment */ x::int`, []TokenKind{Comment, Word, Punct, Word}},
+		{MySQL, "SELECT `a`, \"it\\\"s\", @v # c", []TokenKind{Word, QuotedIdent, Punct, String, Punct, Word, Comment}},
+		{MySQL, "SELECT 1 --2", []TokenKind{Word, Number, Punct, Punct, Number}},
+		{MySQL, "SELECT /*+ NO_ICP(t) */ a FROM t /*!50001 UNION SELECT 1*/", []TokenKind{Word, Comment, Word, Punct, Word, Punct, Comment, Word, Word, Word, Comment, Word, Word, Number, Comment}},
+		{Generic, "SELECT a /*!UNION SELECT 1*/", []TokenKind{Word, Word, Comment}},
+		{SQLite, `SELECT [a b], :name, @n, $v, ?2`, []TokenKind{Word, QuotedIdent, Punct, Placeholder, Punct, Placeholder, Punct, Placeholder, Punct, Placeholder}},
+		{Generic, `SELECT 'C:\' x, "y"`, []TokenKind{Word, String, Word, Punct, QuotedIdent}},
+	}
+	for _, test := range tests {
+		var kinds []TokenKind
+		for _, tok := range Tokens(test.dialect, test.query) {
+			kinds = append(kinds, tok.Kind)
+		}
+		if !reflect.DeepEqual(kinds, test.expected) {
+			t.Errorf("unexpected %s tokens for %q:\n got: %v\nwant: %v", test.dialect, test.query, kinds, test.expected)
+		}
+	}
+
+	for _, tok := range Tokens(MySQL, "SELECT /*!50001 a*/ /* b */") {
+		if tok.Executable() != (tok.Text == "/*!50001" || tok.Text == "*/") {
+			t.Errorf("unexpected Executable for %q", tok.Text)
+		}
+	}
+
+	toks := Tokens(Generic, `"My""Table"`)
+	if len(toks) != 1 || toks[0].Ident() != `My"Table` {
+		t.Errorf("unexpected identifier %v", toks)
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		dialect Dialect
+		query   string
+		want    Statement
+	}{
+		{Generic, "SELECT * FROM users WHERE name = 'from teams'", Statement{Verb: "SELECT", Kind: Read, Tables: []string{"users"}}},
+		{Generic, "select a.id from a, b x -- join c", Statement{Verb: "SELECT", Kind: Read, Tables: []string{"a", "b"}}},
+		{Generic, "WITH x AS (SELECT 1 FROM z) SELECT * FROM x JOIN y ON true", Statement{Verb: "SELECT", Kind: Read, Tables: []string{"z", "y"}}},
+		{Generic, "WITH d AS (DELETE FROM old RETURNING *) SELECT * FROM d", Statement{Verb: "SELECT", Kind: Write, Tables: []string{"old"}}},
+		{Generic, "SELECT extract(year FROM created), (SELECT max(id) FROM b) FROM public.a", Statement{Verb: "SELECT", Kind: Read, Tables: []string{"b", "a"}}},
+		{Generic, "SELECT * FROM generate_series(1, 3) g JOIN t USING (id)", Statement{Verb: "SELECT", Kind: Read, Tables: []string{"t"}}},
+		{PostgreSQL, "SELECT * FROM users FOR NO KEY UPDATE", Statement{Verb: "SELECT", Kind: Read, Tables: []string{"users"}, ForUpdate: true}},
+		{MySQL, "SELECT * FROM users LOCK IN SHARE MODE", Statement{Verb: "SELECT", Kind: Read, Tables: []string{"users"}, ForUpdate: true}},
+		{PostgreSQL, "INSERT INTO users (name) VALUES ($1) ON CONFLICT (name) DO UPDATE SET name = $1 RETURNING id", Statement{Verb: "INSERT", Kind: Write, Tables: []string{"users"}, Returning: true}},
+		{MySQL, "INSERT IGNORE users VALUES (?) ON DUPLICATE KEY UPDATE n = n + 1", Statement{Verb: "INSERT", Kind: Write, Tables: []string{"users"}}},
+		{SQLite, "INSERT OR REPLACE INTO [Users] (a) SELECT a FROM \"Staging\"", Statement{Verb: "INSERT", Kind: Write, Tables: []string{"Users", "Staging"}}},
+		{MySQL, "UPDATE LOW_PRIORITY a, b SET a.x = b.x", Statement{Verb: "UPDATE", Kind: Write, Tables: []string{"a", "b"}}},
+		{MySQL, "DELETE FROM `db`.`users`", Statement{Verb: "DELETE", Kind: Write, Tables: []string{"users"}}},
+		{PostgreSQL, "DELETE FROM t USING u WHERE t.id = u.id", Statement{Verb: "DELETE", Kind: Write, Tables: []string{"t", "u"}}},
+		{PostgreSQL, "MERGE INTO t USING s ON t.id = s.id WHEN MATCHED THEN DELETE", Statement{Verb: "MERGE", Kind: Write, Tables: []string{"t", "s"}}},
+		{Generic, "TRUNCATE users", Statement{Verb: "TRUNCATE", Kind: DDL, Tables: []string{"users"}}},
+		{Generic, "DROP TABLE IF EXISTS a, b CASCADE", Statement{Verb: "DROP", Kind: DDL, Tables: []string{"a", "b"}}},
+		{Generic, "CREATE TABLE t (id int REFERENCES u (id) ON DELETE CASCADE)", Statement{Verb: "CREATE", Kind: DDL, Tables: []string{"t", "u"}}},
+		{PostgreSQL, "CREATE UNIQUE INDEX CONCURRENTLY i ON t USING btree (a)", Statement{Verb: "CREATE", Kind: DDL, Tables: []string{"t"}}},
+		{Generic, "BEGIN", Statement{Verb: "BEGIN", Kind: TxControl}},
+		{MySQL, "START TRANSACTION READ ONLY", Statement{Verb: "START", Kind: TxControl}},
+		{Generic, "SET search_path = app", Statement{Verb: "SET", Kind: Unknown}},
+		{Generic, "SELECT 1; DELETE FROM t;", Statement{Verb: "SELECT", Kind: Unknown, Tables: []string{"t"}}},
+		{Generic, "SELECT 1 FROM a WHERE x IS DISTINCT FROM y", Statement{Verb: "SELECT", Kind: Read, Tables: []string{"a"}}},
+		{Generic, "(SELECT a FROM x) UNION (SELECT a FROM y)", Statement{Verb: "SELECT", Kind: Read, Tables: []string{"x", "y"}}},
+		{Generic, "/* only a comment */", Statement{}},
+	}
+	for _, test := range tests {
+		st := Parse(test.dialect, test.query)
+		if !reflect.DeepEqual(*st, test.want) {
+			t.Errorf("unexpected %s description of %q:\n got: %+v\nwant: %+v", test.dialect, test.query, *st, test.want)
+		}
+	}
+}
+
+// recorder records the descriptions its hooks receive.
+type recorder struct {
+	sqlmw.NullInterceptor
+
+	mu    sync.Mutex
+	verbs []string
+}
+
+func (r *recorder) record(ctx context.Context) {
+	verb := "none"
+	if st, ok := FromContext(ctx); ok {
+		verb = st.Verb
+	}
+	r.mu.Lock()
+	r.verbs = append(r.verbs, verb)
+	r.mu.Unlock()
+}
+
+func (r *recorder) ConnPrepareContext(ctx context.Context, conn driver.ConnPrepareContext, query string) (context.Context, driver.Stmt, error) {
+	r.record(ctx)
+	stmt, err := conn.PrepareContext(ctx, query)
+	return ctx, stmt, err
+}
+
+func (r *recorder) StmtExecContext(ctx context.Context, stmt driver.StmtExecContext, query string, args []driver.NamedValue) (driver.Result, error) {
+	r.record(ctx)
+	return stmt.ExecContext(ctx, args)
+}
+
+func (r *recorder) ConnQueryContext(ctx context.Context, conn driver.QueryerContext, query string, args []driver.NamedValue) (context.Context, driver.Rows, error) {
+	r.record(ctx)
+	rows, err := conn.QueryContext(ctx, query, args)
+	return ctx, rows, err
+}
+
+func TestInterceptor(t *testing.T) {
+	in := New(PostgreSQL)
+	rec := &recorder{}
+	con := &fakedb.Connector{}
+	db := sql.OpenDB(sqlmw.Connector(sqlmw.Connector(con, rec), in))
+	t.Cleanup(func() {
+		if err := db.Close(); err != nil {
+			t.Errorf("Failed to close db: %v", err)
+		}
+	})
+	ctx := context.Background()
+
+	stmt, err := db.PrepareContext(ctx, "DELETE FROM users WHERE id = $1")
+	if err != nil {
+		t.Fatalf("Prepare failed: %v", err)
+	}
+	in.mu.RLock()
+	p := in.prepared["DELETE FROM users WHERE id = $1"]
+	in.mu.RUnlock()
+	if p == nil || p.refs != 1 {
+		t.Fatalf("prepared statement was not described once: %+v", p)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := stmt.ExecContext(ctx, i); err != nil {
+			t.Fatalf("Exec failed: %v", err)
+		}
+	}
+	if err := stmt.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if n := len(in.prepared); n != 0 {
+		t.Errorf("expected the description to be released, %d left", n)
+	}
+
+	rows, err := db.QueryContext(ctx, "SELECT 1")
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if err := rows.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	if expected := []string{"DELETE", "DELETE", "DELETE", "SELECT"}; !reflect.DeepEqual(rec.verbs, expected) {
+		t.Errorf("unexpected descriptions %v, expected %v", rec.verbs, expected)
+	}
+}
+
+func TestNumInput(t *testing.T) {
+	tests := []struct {
+		dialect  Dialect
+		query    string
+		expected int
+	}{
+		{MySQL, "SELECT ? FROM t WHERE a = ? AND b = '?'", 2},
+		{PostgreSQL, "SELECT $2, $1, $2", 2},
+		{SQLite, "SELECT :a, @b, :a, ?3", 5},
+	}
+	for _, test := range tests {
+		if n := NumInput(test.dialect, test.query); n != test.expected {
+			t.Errorf("NumInput(%q) = %d, expected %d", test.query, n, test.expected)
+		}
+	}
+}
+
+func TestParamColumns(t *testing.T) {
+	tests := []struct {
+		query     string
+		byOrdinal map[int]string
+		byName    map[string]string
+	}{
+		{"SELECT * FROM users WHERE email = ? AND id IN (?, ?) AND name NOT LIKE ?", map[int]string{1: "email", 2: "id", 3: "id", 4: "name"}, map[string]string{}},
+		{"INSERT INTO public.users (id, \"Email\") VALUES ($1, $2), ($3, lower($4)) RETURNING id", map[int]string{1: "id", 2: "Email", 3: "id"}, map[string]string{}},
+		{"UPDATE users SET token = :token WHERE f(a, :id)", map[int]string{}, map[string]string{"token": "token"}},
+	}
+	for _, test := range tests {
+		cols := ParamColumns(Generic, test.query)
+		if !reflect.DeepEqual(cols.ByOrdinal, test.byOrdinal) || !reflect.DeepEqual(cols.ByName, test.byName) {
+			t.Errorf("unexpected columns of %q: %v %v", test.query, cols.ByOrdinal, cols.ByName)
+		}
+	}
+}
@@ -0,0 +1,384 @@
+package sqlmwtest
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/ngrok/sqlmw"
+	"github.com/ngrok/sqlmw/rowset"
+)
+
+// RunConformance checks that the interceptors returned by newInterceptor
+// honor the contract of sqlmw.Interceptor, running a subtest for every
+// combination of the optional interfaces of the driver connections, with
+// rows implementing every optional interface, and for every combination of
+// the optional interfaces of the driver rows, on connections implementing
+// every optional interface. Rows are wrapped regardless of the connection
+// they come from, so the full cross product of both is not run.
+//
+// Every subtest runs queries, execs, prepared statements and transactions
+// through a new interceptor and reports the contract violations, such as:
+//
+//   - a hook returning a context that is not derived from the one it was
+//     given, which breaks the hooks it is passed to, like RowsNext;
+//   - rows or statements wrapped without implementing sqlmw.RowsUnwrapper or
+//     sqlmw.StmtUnwrapper, which hides the optional interfaces of the
+//     driver, such as driver.RowsNextResultSet or driver.NamedValueChecker;
+//   - driver.ErrBadConn not returned as is, which prevents database/sql
+//     from retrying on another connection;
+//   - a statement failing or not reaching the driver.
+//
+// The interceptors must let the statements reach the driver and must not run
+// statements of their own. They may change their arguments and their text,
+// as long as the fingerprint is kept.
+func RunConformance(t *testing.T, newInterceptor func() sqlmw.Interceptor) {
+	for _, v := range conformanceVariants() {
+		v := v
+		t.Run(v.String(), func(t *testing.T) {
+			for _, err := range conform(newInterceptor(), v) {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+// variant is a combination of the optional interfaces of a driver.
+type variant struct {
+	conn ConnInterfaces
+	rows RowsInterfaces
+}
+
+func (v variant) String() string {
+	return fmt.Sprintf("conn=%08b/rows=%06b", v.conn, v.rows)
+}
+
+func conformanceVariants() []variant {
+	var vs []variant
+	for c := ConnInterfaces(0); c <= AllConnInterfaces; c++ {
+		vs = append(vs, variant{c, AllRowsInterfaces})
+	}
+	for r := RowsInterfaces(0); r < AllRowsInterfaces; r++ {
+		vs = append(vs, variant{AllConnInterfaces, r})
+	}
+	return vs
+}
+
+// customArg is an argument type only accepted by the driver.NamedValueChecker
+// of the connections.
+type customArg struct{ n int }
+
+type customArgMatcher struct{}
+
+func (customArgMatcher) Match(v driver.Value) bool {
+	_, ok := v.(customArg)
+	return ok
+}
+
+func (customArgMatcher) String() string { return "<customArg>" }
+
+type markerKey struct{}
+
+// spy checks the values an interceptor returns to sqlmw.
+type spy struct {
+	sqlmw.Interceptor
+
+	mu   sync.Mutex
+	errs []error
+}
+
+func (s *spy) errorf(format string, args ...interface{}) {
+	s.mu.Lock()
+	s.errs = append(s.errs, fmt.Errorf(format, args...))
+	s.mu.Unlock()
+}
+
+func (s *spy) checkContext(hook string, ctx context.Context) {
+	if ctx == nil {
+		s.errorf("%s returned a nil context", hook)
+	} else if ctx.Value(markerKey{}) == nil {
+		s.errorf("%s returned a context that is not derived from its context", hook)
+	}
+}
+
+// ConnIsValid forwards to the optional sqlmw.IsValidInterceptor of the
+// interceptor, which the embedded interface hides.
+func (s *spy) ConnIsValid(conn driver.Validator) bool {
+	if intr, ok := s.Interceptor.(sqlmw.IsValidInterceptor); ok {
+		return intr.ConnIsValid(conn)
+	}
+	return conn.IsValid()
+}
+
+// ConnResetSession forwards to the optional sqlmw.ResetSessionInterceptor of
+// the interceptor, which the embedded interface hides.
+func (s *spy) ConnResetSession(ctx context.Context, conn driver.SessionResetter) error {
+	if intr, ok := s.Interceptor.(sqlmw.ResetSessionInterceptor); ok {
+		return intr.ConnResetSession(ctx, conn)
+	}
+	return conn.ResetSession(ctx)
+}
+
+func (s *spy) ConnectorConnect(ctx context.Context, connect driver.Connector) (driver.Conn, error) {
+	conn, err := s.Interceptor.ConnectorConnect(ctx, connect)
+	if err == nil && conn == nil {
+		s.errorf("ConnectorConnect returned neither a connection nor an error")
+	}
+	return conn, err
+}
+
+func (s *spy) ConnBeginTx(ctx context.Context, conn driver.ConnBeginTx, txOpts driver.TxOptions) (context.Context, driver.Tx, error) {
+	ctx, tx, err := s.Interceptor.ConnBeginTx(ctx, conn, txOpts)
+	s.checkContext("ConnBeginTx", ctx)
+	if err == nil && tx == nil {
+		s.errorf("ConnBeginTx returned neither a transaction nor an error")
+	}
+	return ctx, tx, err
+}
+
+func (s *spy) ConnPrepareContext(ctx context.Context, conn driver.ConnPrepareContext, query string) (context.Context, driver.Stmt, error) {
+	ctx, stmt, err := s.Interceptor.ConnPrepareContext(ctx, conn, query)
+	s.checkContext("ConnPrepareContext", ctx)
+	if err == nil && stmt == nil {
+		s.errorf("ConnPrepareContext returned neither a statement nor an error")
+	}
+	return ctx, stmt, err
+}
+
+func (s *spy) ConnQueryContext(ctx context.Context, conn driver.QueryerContext, query string, args []driver.NamedValue) (context.Context, driver.Rows, error) {
+	ctx, rows, err := s.Interceptor.ConnQueryContext(ctx, conn, query, args)
+	s.checkContext("ConnQueryContext", ctx)
+	if err == nil && rows == nil {
+		s.errorf("ConnQueryContext returned neither rows nor an error")
+	}
+	return ctx, rows, err
+}
+
+func (s *spy) StmtQueryContext(ctx context.Context, stmt driver.StmtQueryContext, query string, args []driver.NamedValue) (context.Context, driver.Rows, error) {
+	ctx, rows, err := s.Interceptor.StmtQueryContext(ctx, stmt, query, args)
+	s.checkContext("StmtQueryContext", ctx)
+	if err == nil && rows == nil {
+		s.errorf("StmtQueryContext returned neither rows nor an error")
+	}
+	return ctx, rows, err
+}
+
+var (
+	selectQuery = Fingerprint("SELECT id, name FROM conformance WHERE id > ?")
+	updateQuery = Fingerprint("UPDATE conformance SET name = ? WHERE id = ?")
+	deleteQuery = Fingerprint("DELETE FROM conformance WHERE id = ?")
+	insertQuery = Fingerprint("INSERT INTO conformance (name) VALUES (?)")
+)
+
+func conformanceRows() *Rows {
+	return NewRows().
+		WithColumnTypes(
+			rowset.Column{Name: "id", DatabaseTypeName: "INT8", Nullable: false, HasNullable: true, ScanType: reflect.TypeOf(int64(0))},
+			rowset.Column{Name: "name", DatabaseTypeName: "VARCHAR", Length: 64, HasLength: true},
+		).
+		AddRow(int64(1), "alice").
+		AddRow(int64(2), "bob").
+		NextResultSet("count").
+		AddRow(int64(2))
+}
+
+// conform runs the statements of the suite through in, on a driver
+// implementing the optional interfaces of v, and returns the violations.
+func conform(in sqlmw.Interceptor, v variant) []error {
+	s := &spy{Interceptor: in}
+	m := New(Config{
+		DisableConn: AllConnInterfaces &^ v.conn,
+		DisableRows: AllRowsInterfaces &^ v.rows,
+		CheckNamedValue: func(nv *driver.NamedValue) error {
+			if _, ok := nv.Value.(customArg); ok {
+				return nil
+			}
+			return driver.ErrSkip
+		},
+	})
+	connector := sqlmw.Connector(m, s)
+	ctx := context.WithValue(context.Background(), markerKey{}, true)
+
+	errs := runDB(ctx, sql.OpenDB(connector), m, v)
+	errs = append(errs, runDriver(ctx, connector, m, v)...)
+	errs = append(errs, s.errs...)
+	if err := m.ExpectationsWereMet(); err != nil {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+// runDB runs the statements of the suite through database/sql.
+func runDB(ctx context.Context, db *sql.DB, m *Mock, v variant) (errs []error) {
+	defer func() {
+		if err := db.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("closing the database failed: %v", err))
+		}
+	}()
+	fail := func(format string, args ...interface{}) []error {
+		return append(errs, fmt.Errorf(format, args...))
+	}
+
+	m.ExpectQuery(selectQuery).WillReturnRows(conformanceRows())
+	rows, err := db.QueryContext(ctx, "SELECT id, name FROM conformance WHERE id > ?", 0)
+	if err != nil {
+		return fail("Query failed: %v", err)
+	}
+	n := 0
+	for rows.Next() {
+		n++
+	}
+	if n != 2 {
+		errs = append(errs, fmt.Errorf("got %d rows, want 2", n))
+	}
+	if v.rows&RowsNextResultSet != 0 {
+		if !rows.NextResultSet() {
+			errs = append(errs, fmt.Errorf("the second result set is missing"))
+		} else if !rows.Next() {
+			errs = append(errs, fmt.Errorf("the second result set is empty"))
+		}
+	}
+	if err := rows.Err(); err != nil {
+		errs = append(errs, fmt.Errorf("reading rows failed: %v", err))
+	}
+	if err := rows.Close(); err != nil {
+		errs = append(errs, fmt.Errorf("closing rows failed: %v", err))
+	}
+
+	m.ExpectExec(updateQuery).WillReturnResult(0, 1)
+	res, err := db.ExecContext(ctx, "UPDATE conformance SET name = ? WHERE id = ?", "carol", 1)
+	if err != nil {
+		return fail("Exec failed: %v", err)
+	}
+	if n, err := res.RowsAffected(); err != nil || n != 1 {
+		errs = append(errs, fmt.Errorf("got %d rows affected and error %v, want 1", n, err))
+	}
+
+	if v.conn&NamedValueChecker != 0 {
+		m.ExpectExec(updateQuery).WithArgs(AnyArg(), customArgMatcher{})
+		if _, err := db.ExecContext(ctx, "UPDATE conformance SET name = ? WHERE id = ?", "carol", customArg{1}); err != nil {
+			errs = append(errs, fmt.Errorf("Exec with an argument accepted by driver.NamedValueChecker failed: %v", err))
+		}
+	}
+
+	m.ExpectBegin()
+	m.ExpectExec(deleteQuery).WillReturnResult(0, 1)
+	m.ExpectCommit()
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fail("BeginTx failed: %v", err)
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM conformance WHERE id = ?", 2); err != nil {
+		return fail("Exec in a transaction failed: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fail("Commit failed: %v", err)
+	}
+
+	m.ExpectBegin()
+	m.ExpectQuery(selectQuery).WillReturnRows(conformanceRows())
+	m.ExpectRollback()
+	tx, err = db.BeginTx(ctx, nil)
+	if err != nil {
+		return fail("BeginTx failed: %v", err)
+	}
+	var id int64
+	var name string
+	if err := tx.QueryRowContext(ctx, "SELECT id, name FROM conformance WHERE id > ?", 0).Scan(&id, &name); err != nil || id != 1 || name != "alice" {
+		errs = append(errs, fmt.Errorf("got row %d, %q and error %v, want 1, alice", id, name, err))
+	}
+	if err := tx.Rollback(); err != nil {
+		return fail("Rollback failed: %v", err)
+	}
+
+	m.ExpectExec(insertQuery)
+	m.ExpectQuery(insertQuery)
+	stmt, err := db.PrepareContext(ctx, "INSERT INTO conformance (name) VALUES (?)")
+	if err != nil {
+		return fail("Prepare failed: %v", err)
+	}
+	if _, err := stmt.ExecContext(ctx, "dave"); err != nil {
+		errs = append(errs, fmt.Errorf("Exec of a prepared statement failed: %v", err))
+	}
+	rows, err = stmt.QueryContext(ctx, "erin")
+	if err != nil {
+		errs = append(errs, fmt.Errorf("Query of a prepared statement failed: %v", err))
+	} else if err := rows.Close(); err != nil {
+		errs = append(errs, fmt.Errorf("closing rows failed: %v", err))
+	}
+	if err := stmt.Close(); err != nil {
+		errs = append(errs, fmt.Errorf("closing a prepared statement failed: %v", err))
+	}
+	return errs
+}
+
+var rowsInterfaces = []reflect.Type{
+	reflect.TypeOf((*driver.RowsNextResultSet)(nil)).Elem(),
+	reflect.TypeOf((*driver.RowsColumnTypeDatabaseTypeName)(nil)).Elem(),
+	reflect.TypeOf((*driver.RowsColumnTypeLength)(nil)).Elem(),
+	reflect.TypeOf((*driver.RowsColumnTypeNullable)(nil)).Elem(),
+	reflect.TypeOf((*driver.RowsColumnTypePrecisionScale)(nil)).Elem(),
+	reflect.TypeOf((*driver.RowsColumnTypeScanType)(nil)).Elem(),
+}
+
+// runDriver checks the values sqlmw returns to database/sql.
+func runDriver(ctx context.Context, connector driver.Connector, m *Mock, v variant) (errs []error) {
+	conn, err := connector.Connect(ctx)
+	if err != nil {
+		return []error{fmt.Errorf("Connect failed: %v", err)}
+	}
+	defer func() {
+		if err := conn.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("closing the connection failed: %v", err))
+		}
+	}()
+
+	if v.conn&QueryerContext != 0 {
+		m.ExpectQuery(selectQuery).WillReturnRows(conformanceRows())
+		rows, err := conn.(driver.QueryerContext).QueryContext(ctx, "SELECT id, name FROM conformance WHERE id > ?", []driver.NamedValue{{Ordinal: 1, Value: int64(0)}})
+		if err != nil {
+			return append(errs, fmt.Errorf("Query failed: %v", err))
+		}
+		for i, intf := range rowsInterfaces {
+			want := v.rows&(1<<i) != 0
+			if got := reflect.TypeOf(rows).Implements(intf); got != want {
+				errs = append(errs, fmt.Errorf("got rows implementing %v: %v, want %v", intf, got, want))
+			}
+		}
+		if err := rows.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("closing rows failed: %v", err))
+		}
+	}
+
+	// driver.ErrBadConn must be returned as is by every call database/sql
+	// retries on another connection
+	badConn := func(call string, err error) {
+		if err != driver.ErrBadConn {
+			errs = append(errs, fmt.Errorf("%s: got error %v, want driver.ErrBadConn as is", call, err))
+		}
+	}
+	if v.conn&ExecerContext != 0 {
+		m.ExpectExec(updateQuery).WillReturnError(driver.ErrBadConn)
+		_, err := conn.(driver.ExecerContext).ExecContext(ctx, "UPDATE conformance SET name = ? WHERE id = ?", []driver.NamedValue{
+			{Ordinal: 1, Value: "frank"},
+			{Ordinal: 2, Value: int64(1)},
+		})
+		badConn("Exec", err)
+	}
+	if v.conn&QueryerContext != 0 {
+		m.ExpectQuery(selectQuery).WillReturnError(driver.ErrBadConn)
+		_, err := conn.(driver.QueryerContext).QueryContext(ctx, "SELECT id, name FROM conformance WHERE id > ?", []driver.NamedValue{{Ordinal: 1, Value: int64(0)}})
+		badConn("Query", err)
+	}
+	m.ExpectPrepare(updateQuery).WillReturnError(driver.ErrBadConn)
+	_, err = conn.(driver.ConnPrepareContext).PrepareContext(ctx, "UPDATE conformance SET name = ? WHERE id = ?")
+	badConn("Prepare", err)
+	m.ExpectBegin().WillReturnError(driver.ErrBadConn)
+	_, err = conn.(driver.ConnBeginTx).BeginTx(ctx, driver.TxOptions{})
+	badConn("BeginTx", err)
+	return errs
+}
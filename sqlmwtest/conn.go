@@ -0,0 +1,152 @@
+package sqlmwtest
+
+import (
+	"context"
+	"database/sql/driver"
+)
+
+// conn is a connection of a Mock. Its optional methods are implemented by
+// the conn* adapters, picked by connVariants.
+type conn struct {
+	m *Mock
+}
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	if err := c.m.prepare(query); err != nil {
+		return nil, err
+	}
+	return &stmt{c: c, query: query}, nil
+}
+
+func (c *conn) Close() error { return nil }
+
+func (c *conn) Begin() (driver.Tx, error) {
+	if _, err := c.m.match(kindBegin, "", nil); err != nil {
+		return nil, err
+	}
+	return &tx{c: c}, nil
+}
+
+func (c *conn) exec(query string, args []driver.NamedValue) (driver.Result, error) {
+	e, err := c.m.match(kindExec, query, args)
+	if err != nil {
+		return nil, err
+	}
+	if e.result == nil {
+		return driver.RowsAffected(0), nil
+	}
+	return e.result, nil
+}
+
+func (c *conn) query(query string, args []driver.NamedValue) (driver.Rows, error) {
+	e, err := c.m.match(kindQuery, query, args)
+	if err != nil {
+		return nil, err
+	}
+	return newRows(e.rows, c.m.cfg.DisableRows), nil
+}
+
+type connExecerContext struct{ *conn }
+
+func (c connExecerContext) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	return c.exec(query, args)
+}
+
+type connQueryerContext struct{ *conn }
+
+func (c connQueryerContext) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	return c.query(query, args)
+}
+
+type connConnPrepareContext struct{ *conn }
+
+func (c connConnPrepareContext) PrepareContext(_ context.Context, query string) (driver.Stmt, error) {
+	return c.Prepare(query)
+}
+
+type connConnBeginTx struct{ *conn }
+
+func (c connConnBeginTx) BeginTx(context.Context, driver.TxOptions) (driver.Tx, error) {
+	return c.Begin()
+}
+
+type connPinger struct{ *conn }
+
+func (c connPinger) Ping(context.Context) error { return nil }
+
+type connSessionResetter struct{ *conn }
+
+func (c connSessionResetter) ResetSession(context.Context) error { return nil }
+
+type connValidator struct{ *conn }
+
+func (c connValidator) IsValid() bool { return true }
+
+type connNamedValueChecker struct{ *conn }
+
+func (c connNamedValueChecker) CheckNamedValue(nv *driver.NamedValue) error {
+	if c.m.cfg.CheckNamedValue != nil {
+		return c.m.cfg.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+type stmt struct {
+	c     *conn
+	query string
+}
+
+var (
+	_ driver.StmtExecContext  = (*stmt)(nil)
+	_ driver.StmtQueryContext = (*stmt)(nil)
+)
+
+func (s *stmt) Close() error { return nil }
+
+func (s *stmt) NumInput() int { return -1 }
+
+func (s *stmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.c.exec(s.query, toNamed(args))
+}
+
+func (s *stmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.c.query(s.query, toNamed(args))
+}
+
+func (s *stmt) ExecContext(_ context.Context, args []driver.NamedValue) (driver.Result, error) {
+	return s.c.exec(s.query, args)
+}
+
+func (s *stmt) QueryContext(_ context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	return s.c.query(s.query, args)
+}
+
+type tx struct {
+	c *conn
+}
+
+func (t *tx) Commit() error {
+	_, err := t.c.m.match(kindCommit, "", nil)
+	return err
+}
+
+func (t *tx) Rollback() error {
+	_, err := t.c.m.match(kindRollback, "", nil)
+	return err
+}
+
+type result struct {
+	lastInsertID, rowsAffected int64
+}
+
+func (r result) LastInsertId() (int64, error) { return r.lastInsertID, nil }
+
+func (r result) RowsAffected() (int64, error) { return r.rowsAffected, nil }
+
+func toNamed(args []driver.Value) []driver.NamedValue {
+	nvs := make([]driver.NamedValue, len(args))
+	for i, v := range args {
+		nvs[i] = driver.NamedValue{Ordinal: i + 1, Value: v}
+	}
+	return nvs
+}
@@ -0,0 +1,37 @@
+package sqlmwtest
+
+//go:generate go run ../tools/sqlmwtest_variants_gen.go -o variants.go
+
+// ConnInterfaces is a set of the optional interfaces of a driver.Conn.
+type ConnInterfaces uint
+
+const (
+	ExecerContext ConnInterfaces = 1 << iota
+	QueryerContext
+	ConnPrepareContext
+	ConnBeginTx
+	Pinger
+	SessionResetter
+	Validator
+	NamedValueChecker
+
+	// AllConnInterfaces is the set of every optional interface of a
+	// driver.Conn.
+	AllConnInterfaces = 1<<iota - 1
+)
+
+// RowsInterfaces is a set of the optional interfaces of a driver.Rows.
+type RowsInterfaces uint
+
+const (
+	RowsNextResultSet RowsInterfaces = 1 << iota
+	RowsColumnTypeDatabaseTypeName
+	RowsColumnTypeLength
+	RowsColumnTypeNullable
+	RowsColumnTypePrecisionScale
+	RowsColumnTypeScanType
+
+	// AllRowsInterfaces is the set of every optional interface of a
+	// driver.Rows.
+	AllRowsInterfaces = 1<<iota - 1
+)
@@ -0,0 +1,137 @@
+package sqlmwtest
+
+import (
+	"database/sql/driver"
+	"reflect"
+
+	"github.com/ngrok/sqlmw/rowset"
+)
+
+// Rows are the rows returned by an expected query. Columns without type
+// metadata report it as unknown.
+type Rows struct {
+	set  rowset.Set
+	errs []map[int]error
+}
+
+// NewRows returns rows with the named columns.
+func NewRows(columns ...string) *Rows {
+	r := &Rows{}
+	return r.NextResultSet(columns...)
+}
+
+// NextResultSet starts a new result set with the named columns. The
+// following rows are added to it.
+func (r *Rows) NextResultSet(columns ...string) *Rows {
+	cols := make([]rowset.Column, len(columns))
+	for i, name := range columns {
+		cols[i] = rowset.Column{Name: name}
+	}
+	r.set.ResultSets = append(r.set.ResultSets, rowset.ResultSet{Columns: cols})
+	r.errs = append(r.errs, nil)
+	return r
+}
+
+func (r *Rows) current() *rowset.ResultSet {
+	return &r.set.ResultSets[len(r.set.ResultSets)-1]
+}
+
+// WithColumnTypes replaces the columns of the current result set with
+// columns described by their type metadata.
+func (r *Rows) WithColumnTypes(columns ...rowset.Column) *Rows {
+	r.current().Columns = columns
+	return r
+}
+
+// AddRow adds a row to the current result set.
+func (r *Rows) AddRow(values ...driver.Value) *Rows {
+	rs := r.current()
+	rs.Rows = append(rs.Rows, values)
+	return r
+}
+
+// RowError makes reading the row at index, counted from 0 within the
+// current result set, fail with err.
+func (r *Rows) RowError(index int, err error) *Rows {
+	errs := &r.errs[len(r.errs)-1]
+	if *errs == nil {
+		*errs = make(map[int]error)
+	}
+	(*errs)[index] = err
+	return r
+}
+
+// rows reads Rows. Its optional methods are implemented by the rows*
+// adapters, picked by rowsVariants.
+type rows struct {
+	r      *Rows
+	cursor *rowset.Rows
+	rs     int
+	row    int
+}
+
+func newRows(r *Rows, disabled RowsInterfaces) driver.Rows {
+	if r == nil {
+		r = NewRows()
+	}
+	x := &rows{r: r, cursor: r.set.Rows().(*rowset.Rows)}
+	return rowsVariants[AllRowsInterfaces&^disabled](x)
+}
+
+func (r *rows) Columns() []string { return r.cursor.Columns() }
+
+func (r *rows) Close() error { return r.cursor.Close() }
+
+func (r *rows) Next(dest []driver.Value) error {
+	if err := r.r.errs[r.rs][r.row]; err != nil {
+		return err
+	}
+	if err := r.cursor.Next(dest); err != nil {
+		return err
+	}
+	r.row++
+	return nil
+}
+
+type rowsNextResultSet struct{ *rows }
+
+func (r rowsNextResultSet) HasNextResultSet() bool { return r.cursor.HasNextResultSet() }
+
+func (r rowsNextResultSet) NextResultSet() error {
+	if err := r.cursor.NextResultSet(); err != nil {
+		return err
+	}
+	r.rs++
+	r.row = 0
+	return nil
+}
+
+type rowsColumnTypeDatabaseTypeName struct{ *rows }
+
+func (r rowsColumnTypeDatabaseTypeName) ColumnTypeDatabaseTypeName(index int) string {
+	return r.cursor.ColumnTypeDatabaseTypeName(index)
+}
+
+type rowsColumnTypeLength struct{ *rows }
+
+func (r rowsColumnTypeLength) ColumnTypeLength(index int) (int64, bool) {
+	return r.cursor.ColumnTypeLength(index)
+}
+
+type rowsColumnTypeNullable struct{ *rows }
+
+func (r rowsColumnTypeNullable) ColumnTypeNullable(index int) (bool, bool) {
+	return r.cursor.ColumnTypeNullable(index)
+}
+
+type rowsColumnTypePrecisionScale struct{ *rows }
+
+func (r rowsColumnTypePrecisionScale) ColumnTypePrecisionScale(index int) (int64, int64, bool) {
+	return r.cursor.ColumnTypePrecisionScale(index)
+}
+
+type rowsColumnTypeScanType struct{ *rows }
+
+func (r rowsColumnTypeScanType) ColumnTypeScanType(index int) reflect.Type {
+	return r.cursor.ColumnTypeScanType(index)
+}
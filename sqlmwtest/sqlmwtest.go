@@ -0,0 +1,348 @@
+// Package sqlmwtest provides a programmable database driver for the tests
+// of the applications and of the interceptors built on sqlmw.
+//
+// A Mock is a driver.Connector serving the statements it expects, in order:
+//
+//	m := sqlmwtest.New(sqlmwtest.Config{})
+//	m.ExpectBegin()
+//	m.ExpectQuery(sqlmwtest.Regexp(`^SELECT name FROM users`)).
+//		WithArgs(1).
+//		WillReturnRows(sqlmwtest.NewRows("name").AddRow("alice"))
+//	m.ExpectExec(sqlmwtest.Fingerprint("UPDATE users SET name = ? WHERE id = ?")).
+//		WillReturnResult(0, 1)
+//	m.ExpectCommit()
+//
+//	db := sql.OpenDB(sqlmw.Connector(m, interceptor))
+//	// ... run the code under test ...
+//	if err := m.ExpectationsWereMet(); err != nil {
+//		t.Error(err)
+//	}
+//
+// A statement that is not the next expected one fails, as well as
+// ExpectationsWereMet. Statements are prepared without expectations unless
+// the next expectation is an ExpectPrepare one.
+//
+// The connections and rows of a Mock implement every optional interface of
+// database/sql/driver, unless disabled by the Config, so that interceptors
+// can be tested against the drivers supporting any combination of them.
+package sqlmwtest
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ngrok/sqlmw/fingerprint"
+)
+
+type kind string
+
+const (
+	kindQuery    kind = "query"
+	kindExec     kind = "exec"
+	kindPrepare  kind = "prepare"
+	kindBegin    kind = "begin"
+	kindCommit   kind = "commit"
+	kindRollback kind = "rollback"
+)
+
+// QueryMatcher matches the query of the statements. A nil QueryMatcher
+// matches any query.
+type QueryMatcher interface {
+	MatchQuery(query string) bool
+	String() string
+}
+
+type queryMatcher struct {
+	match func(string) bool
+	desc  string
+}
+
+func (m queryMatcher) MatchQuery(query string) bool { return m.match(query) }
+
+func (m queryMatcher) String() string { return m.desc }
+
+// Regexp matches the queries matching the regular expression expr. It
+// panics if expr does not compile.
+func Regexp(expr string) QueryMatcher {
+	re := regexp.MustCompile(expr)
+	return queryMatcher{re.MatchString, "matching " + expr}
+}
+
+// Fingerprint matches the queries with the fingerprint of query, see
+// fingerprint.Normalize.
+func Fingerprint(query string) QueryMatcher {
+	fp := fingerprint.Normalize(query)
+	return queryMatcher{
+		func(q string) bool { return fingerprint.Normalize(q) == fp },
+		fmt.Sprintf("like %q", fp),
+	}
+}
+
+// Argument matches an argument of the statements.
+type Argument interface {
+	Match(driver.Value) bool
+}
+
+type anyArg struct{}
+
+func (anyArg) Match(driver.Value) bool { return true }
+
+func (anyArg) String() string { return "<any>" }
+
+// AnyArg matches any argument.
+func AnyArg() Argument {
+	return anyArg{}
+}
+
+// Expectation is a statement expected by a Mock.
+type Expectation struct {
+	kind  kind
+	query QueryMatcher
+	args  []interface{}
+	// anyArgs is set until WithArgs is called
+	anyArgs bool
+
+	rows   *Rows
+	result driver.Result
+	err    error
+	met    bool
+}
+
+// WithArgs sets the arguments of the statement. They are values, converted
+// like database/sql does, or an Argument. Any arguments are accepted
+// otherwise.
+func (e *Expectation) WithArgs(args ...interface{}) *Expectation {
+	e.args, e.anyArgs = args, false
+	return e
+}
+
+// WillReturnRows sets the rows returned by a query. It returns no rows
+// otherwise.
+func (e *Expectation) WillReturnRows(rows *Rows) *Expectation {
+	e.rows = rows
+	return e
+}
+
+// WillReturnResult sets the result of an exec. Its values are 0 otherwise.
+func (e *Expectation) WillReturnResult(lastInsertID, rowsAffected int64) *Expectation {
+	e.result = result{lastInsertID, rowsAffected}
+	return e
+}
+
+// WillReturnError makes the statement fail with err, such as
+// driver.ErrBadConn. database/sql retries the statements failing with
+// driver.ErrBadConn, which must then be expected again.
+func (e *Expectation) WillReturnError(err error) *Expectation {
+	e.err = err
+	return e
+}
+
+func (e *Expectation) String() string {
+	s := string(e.kind)
+	if e.query != nil {
+		s += " " + e.query.String()
+	}
+	if !e.anyArgs {
+		s += fmt.Sprintf(" with args %v", e.args)
+	}
+	return s
+}
+
+func (e *Expectation) matches(k kind, query string, args []driver.NamedValue) bool {
+	if e.kind != k || (e.query != nil && !e.query.MatchQuery(query)) {
+		return false
+	}
+	if e.anyArgs {
+		return true
+	}
+	if len(args) != len(e.args) {
+		return false
+	}
+	for i, want := range e.args {
+		if !argMatches(want, args[i].Value) {
+			return false
+		}
+	}
+	return true
+}
+
+func argMatches(want interface{}, v driver.Value) bool {
+	if a, ok := want.(Argument); ok {
+		return a.Match(v)
+	}
+	want, err := driver.DefaultParameterConverter.ConvertValue(want)
+	if err != nil {
+		return false
+	}
+	if t, ok := want.(time.Time); ok {
+		vt, ok := v.(time.Time)
+		return ok && t.Equal(vt)
+	}
+	return reflect.DeepEqual(want, v)
+}
+
+// Config configures a Mock.
+type Config struct {
+	// DisableConn are the optional interfaces the connections do not
+	// implement. They implement them all by default.
+	DisableConn ConnInterfaces
+
+	// DisableRows are the optional interfaces the rows do not implement.
+	// They implement them all by default.
+	DisableRows RowsInterfaces
+
+	// CheckNamedValue, if set, implements the driver.NamedValueChecker of
+	// the connections. They leave the arguments to the default conversion
+	// otherwise.
+	CheckNamedValue func(*driver.NamedValue) error
+}
+
+// Mock is a driver.Connector and a driver.Driver serving the statements it
+// expects. It is safe for concurrent use.
+type Mock struct {
+	cfg Config
+
+	mu           sync.Mutex
+	expectations []*Expectation
+	unexpected   []string
+}
+
+var (
+	_ driver.Connector = (*Mock)(nil)
+	_ driver.Driver    = (*Mock)(nil)
+)
+
+// New returns a Mock for the supplied configuration.
+func New(cfg Config) *Mock {
+	return &Mock{cfg: cfg}
+}
+
+func (m *Mock) Connect(context.Context) (driver.Conn, error) {
+	return connVariants[AllConnInterfaces&^m.cfg.DisableConn](&conn{m: m}), nil
+}
+
+func (m *Mock) Driver() driver.Driver {
+	return m
+}
+
+func (m *Mock) Open(string) (driver.Conn, error) {
+	return m.Connect(context.Background())
+}
+
+func (m *Mock) expect(k kind, query QueryMatcher) *Expectation {
+	e := &Expectation{kind: k, query: query, anyArgs: true}
+	m.mu.Lock()
+	m.expectations = append(m.expectations, e)
+	m.mu.Unlock()
+	return e
+}
+
+// ExpectQuery expects a query.
+func (m *Mock) ExpectQuery(query QueryMatcher) *Expectation {
+	return m.expect(kindQuery, query)
+}
+
+// ExpectExec expects an exec.
+func (m *Mock) ExpectExec(query QueryMatcher) *Expectation {
+	return m.expect(kindExec, query)
+}
+
+// ExpectPrepare expects a statement to be prepared. It is only needed to
+// make the preparation fail, as the statements are otherwise prepared
+// without expectations.
+func (m *Mock) ExpectPrepare(query QueryMatcher) *Expectation {
+	return m.expect(kindPrepare, query)
+}
+
+// ExpectBegin expects a transaction to begin.
+func (m *Mock) ExpectBegin() *Expectation {
+	return m.expect(kindBegin, nil)
+}
+
+// ExpectCommit expects a transaction to commit.
+func (m *Mock) ExpectCommit() *Expectation {
+	return m.expect(kindCommit, nil)
+}
+
+// ExpectRollback expects a transaction to roll back.
+func (m *Mock) ExpectRollback() *Expectation {
+	return m.expect(kindRollback, nil)
+}
+
+// ExpectationsWereMet returns an error listing the unexpected statements
+// and the expectations that were not met, if any.
+func (m *Mock) ExpectationsWereMet() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	problems := append([]string(nil), m.unexpected...)
+	for _, e := range m.expectations {
+		if !e.met {
+			problems = append(problems, "expected "+e.String()+", was not run")
+		}
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	return fmt.Errorf("sqlmwtest: %s", strings.Join(problems, "; "))
+}
+
+// next returns the next expectation that was not met, if any.
+func (m *Mock) next() *Expectation {
+	for _, e := range m.expectations {
+		if !e.met {
+			return e
+		}
+	}
+	return nil
+}
+
+// match returns the expectation a statement meets, or the error of the
+// statements that are not expected.
+func (m *Mock) match(k kind, query string, args []driver.NamedValue) (*Expectation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e := m.next()
+	if e != nil && e.matches(k, query, args) {
+		e.met = true
+		return e, e.err
+	}
+
+	call := string(k)
+	if query != "" {
+		call += fmt.Sprintf(" %q", query)
+	}
+	if len(args) > 0 {
+		values := make([]driver.Value, len(args))
+		for i, arg := range args {
+			values[i] = arg.Value
+		}
+		call += fmt.Sprintf(" with args %v", values)
+	}
+	msg := "unexpected " + call
+	if e != nil {
+		msg += ", expected " + e.String()
+	} else {
+		msg += ", all expectations were met"
+	}
+	m.unexpected = append(m.unexpected, msg)
+	return nil, fmt.Errorf("sqlmwtest: %s", msg)
+}
+
+// prepare returns the error of preparing query, when an ExpectPrepare
+// expectation is next.
+func (m *Mock) prepare(query string) error {
+	m.mu.Lock()
+	e := m.next()
+	m.mu.Unlock()
+	if e == nil || e.kind != kindPrepare {
+		return nil
+	}
+	_, err := m.match(kindPrepare, query, nil)
+	return err
+}
@@ -0,0 +1,238 @@
+package sqlmwtest
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ngrok/sqlmw"
+	"github.com/ngrok/sqlmw/rowset"
+)
+
+func openDB(t *testing.T, m *Mock) *sql.DB {
+	db := sql.OpenDB(sqlmw.Connector(m, sqlmw.NullInterceptor{}))
+	t.Cleanup(func() {
+		if err := db.Close(); err != nil {
+			t.Errorf("Failed to close db: %v", err)
+		}
+	})
+	return db
+}
+
+func TestMock(t *testing.T) {
+	m := New(Config{})
+	m.ExpectBegin()
+	m.ExpectQuery(Regexp(`^SELECT id, name FROM users`)).
+		WithArgs(AnyArg(), "alice").
+		WillReturnRows(NewRows().
+			WithColumnTypes(rowset.Column{Name: "id", DatabaseTypeName: "INT8"}, rowset.Column{Name: "name", Length: 64, HasLength: true}).
+			AddRow(int64(1), "alice").
+			AddRow(int64(2), "alice").
+			RowError(1, driver.ErrBadConn))
+	m.ExpectExec(Fingerprint("UPDATE users SET name = $1 WHERE id = $2")).WithArgs("bob", 1).WillReturnResult(0, 1)
+	m.ExpectCommit()
+	m.ExpectPrepare(Regexp("DELETE")).WillReturnError(errors.New("denied"))
+	db := openDB(t, m)
+	ctx := context.Background()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatalf("BeginTx failed: %v", err)
+	}
+	rows, err := tx.QueryContext(ctx, "SELECT id, name FROM users WHERE id > ? AND name = ?", 0, "alice")
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		t.Fatalf("ColumnTypes failed: %v", err)
+	}
+	if length, ok := types[1].Length(); types[0].DatabaseTypeName() != "INT8" || length != 64 || !ok {
+		t.Errorf("got column types %+v %+v", types[0], types[1])
+	}
+	var n int
+	for rows.Next() {
+		n++
+	}
+	if err := rows.Err(); n != 1 || err != driver.ErrBadConn {
+		t.Errorf("got %d rows and error %v, want 1 row and %v", n, err, driver.ErrBadConn)
+	}
+	rows.Close()
+
+	res, err := tx.ExecContext(ctx, "UPDATE users SET name = ? WHERE id = ?", "bob", 1)
+	if err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	if affected, _ := res.RowsAffected(); affected != 1 {
+		t.Errorf("got %d rows affected, want 1", affected)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+	if _, err := db.PrepareContext(ctx, "DELETE FROM users"); err == nil || err.Error() != "denied" {
+		t.Errorf("got prepare error %v, want denied", err)
+	}
+	if err := m.ExpectationsWereMet(); err != nil {
+		t.Errorf("ExpectationsWereMet failed: %v", err)
+	}
+}
+
+func TestUnexpected(t *testing.T) {
+	m := New(Config{})
+	m.ExpectBegin()
+	m.ExpectExec(Regexp("INSERT")).WithArgs(1)
+	m.ExpectCommit()
+	db := openDB(t, m)
+	ctx := context.Background()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatalf("BeginTx failed: %v", err)
+	}
+	if _, err := tx.ExecContext(ctx, "INSERT INTO t VALUES (?)", 2); err == nil {
+		t.Errorf("Exec with unexpected args succeeded")
+	}
+	if err := tx.Rollback(); err == nil {
+		t.Errorf("unexpected Rollback succeeded")
+	}
+
+	err = m.ExpectationsWereMet()
+	if err == nil {
+		t.Fatalf("ExpectationsWereMet succeeded")
+	}
+	for _, want := range []string{
+		`unexpected exec "INSERT INTO t VALUES (?)" with args [2], expected exec matching INSERT with args [1]`,
+		`unexpected rollback, expected exec matching INSERT with args [1]`,
+		`expected commit, was not run`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("got error %q, want it to contain %q", err, want)
+		}
+	}
+}
+
+func TestVariants(t *testing.T) {
+	connIntfs := []reflect.Type{
+		reflect.TypeOf((*driver.ExecerContext)(nil)).Elem(),
+		reflect.TypeOf((*driver.QueryerContext)(nil)).Elem(),
+		reflect.TypeOf((*driver.ConnPrepareContext)(nil)).Elem(),
+		reflect.TypeOf((*driver.ConnBeginTx)(nil)).Elem(),
+		reflect.TypeOf((*driver.Pinger)(nil)).Elem(),
+		reflect.TypeOf((*driver.SessionResetter)(nil)).Elem(),
+		reflect.TypeOf((*interface{ IsValid() bool })(nil)).Elem(),
+		reflect.TypeOf((*driver.NamedValueChecker)(nil)).Elem(),
+	}
+	rowsIntfs := []reflect.Type{
+		reflect.TypeOf((*driver.RowsNextResultSet)(nil)).Elem(),
+		reflect.TypeOf((*driver.RowsColumnTypeDatabaseTypeName)(nil)).Elem(),
+		reflect.TypeOf((*driver.RowsColumnTypeLength)(nil)).Elem(),
+		reflect.TypeOf((*driver.RowsColumnTypeNullable)(nil)).Elem(),
+		reflect.TypeOf((*driver.RowsColumnTypePrecisionScale)(nil)).Elem(),
+		reflect.TypeOf((*driver.RowsColumnTypeScanType)(nil)).Elem(),
+	}
+
+	for disabled := ConnInterfaces(0); disabled <= AllConnInterfaces; disabled++ {
+		m := New(Config{DisableConn: disabled})
+		c, err := m.Connect(context.Background())
+		if err != nil {
+			t.Fatalf("Connect failed: %v", err)
+		}
+		for i, intf := range connIntfs {
+			want := disabled&(1<<i) == 0
+			if got := reflect.TypeOf(c).Implements(intf); got != want {
+				t.Errorf("conn variant %d: got %v implementing %v, want %v", disabled, got, intf, want)
+			}
+		}
+	}
+
+	for disabled := RowsInterfaces(0); disabled <= AllRowsInterfaces; disabled++ {
+		m := New(Config{DisableRows: disabled})
+		m.ExpectQuery(nil).WillReturnRows(NewRows("a").AddRow(1).NextResultSet("b").AddRow(2))
+		c, _ := m.Connect(context.Background())
+		rows, err := c.(driver.QueryerContext).QueryContext(context.Background(), "SELECT", nil)
+		if err != nil {
+			t.Fatalf("Query failed: %v", err)
+		}
+		for i, intf := range rowsIntfs {
+			want := disabled&(1<<i) == 0
+			if got := reflect.TypeOf(rows).Implements(intf); got != want {
+				t.Errorf("rows variant %d: got %v implementing %v, want %v", disabled, got, intf, want)
+			}
+		}
+		if nrs, ok := rows.(driver.RowsNextResultSet); ok {
+			dest := make([]driver.Value, 1)
+			if err := rows.Next(dest); err != nil || rows.Next(dest) == nil {
+				t.Fatalf("got a first result set other than one row")
+			}
+			if !nrs.HasNextResultSet() || nrs.NextResultSet() != nil {
+				t.Fatalf("NextResultSet failed")
+			}
+			if err := rows.Next(dest); err != nil || dest[0] != 2 || !reflect.DeepEqual(rows.Columns(), []string{"b"}) {
+				t.Errorf("got second result set %v %v", rows.Columns(), dest)
+			}
+		}
+	}
+}
+
+func TestConformance(t *testing.T) {
+	RunConformance(t, func() sqlmw.Interceptor { return sqlmw.NullInterceptor{} })
+}
+
+// lossyRows wraps rows without implementing sqlmw.RowsUnwrapper.
+type lossyRows struct {
+	driver.Rows
+}
+
+// brokenInterceptor breaks the contract of sqlmw.Interceptor.
+type brokenInterceptor struct {
+	sqlmw.NullInterceptor
+}
+
+func (brokenInterceptor) ConnQueryContext(ctx context.Context, conn driver.QueryerContext, query string, args []driver.NamedValue) (context.Context, driver.Rows, error) {
+	rows, err := conn.QueryContext(ctx, query, args)
+	if err != nil {
+		return context.Background(), nil, err
+	}
+	return context.Background(), lossyRows{rows}, nil
+}
+
+func (brokenInterceptor) ConnExecContext(ctx context.Context, conn driver.ExecerContext, query string, args []driver.NamedValue) (driver.Result, error) {
+	res, err := conn.ExecContext(ctx, query, args)
+	if err != nil {
+		return nil, fmt.Errorf("exec: %w", err)
+	}
+	return res, nil
+}
+
+func (brokenInterceptor) ConnBeginTx(ctx context.Context, conn driver.ConnBeginTx, txOpts driver.TxOptions) (context.Context, driver.Tx, error) {
+	tx, err := conn.BeginTx(ctx, txOpts)
+	if err != nil {
+		return ctx, nil, fmt.Errorf("begin: %w", err)
+	}
+	return ctx, tx, nil
+}
+
+func TestConformanceViolations(t *testing.T) {
+	errs := conform(brokenInterceptor{}, variant{AllConnInterfaces, AllRowsInterfaces})
+	var got []string
+	for _, err := range errs {
+		got = append(got, err.Error())
+	}
+	msgs := strings.Join(got, "\n")
+	for _, want := range []string{
+		"ConnQueryContext returned a context that is not derived from its context",
+		"the second result set is missing",
+		"got rows implementing driver.RowsColumnTypeScanType: false, want true",
+		"Exec: got error exec: driver: bad connection, want driver.ErrBadConn as is",
+		"BeginTx: got error begin: driver: bad connection, want driver.ErrBadConn as is",
+	} {
+		if !strings.Contains(msgs, want) {
+			t.Errorf("got violations\n%s\nwant %q", msgs, want)
+		}
+	}
+}
@@ -0,0 +1,4676 @@
+// Code generated using tools/sqlmwtest_variants_gen.go DO NOT EDIT.
+// Date: Oct 18 17:10:31
+
+package sqlmwtest
+
+import "database/sql/driver"
+
+// connVariants are the implementations of driver.Conn, indexed by the set of
+// optional interfaces they implement.
+var connVariants = [256]func(*conn) driver.Conn{
+	func(x *conn) driver.Conn { return x },
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+		}{
+			x,
+			connExecerContext{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connQueryerContext
+		}{
+			x,
+			connQueryerContext{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connQueryerContext
+		}{
+			x,
+			connExecerContext{x},
+			connQueryerContext{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connConnPrepareContext
+		}{
+			x,
+			connConnPrepareContext{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connConnPrepareContext
+		}{
+			x,
+			connExecerContext{x},
+			connConnPrepareContext{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connQueryerContext
+			connConnPrepareContext
+		}{
+			x,
+			connQueryerContext{x},
+			connConnPrepareContext{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connQueryerContext
+			connConnPrepareContext
+		}{
+			x,
+			connExecerContext{x},
+			connQueryerContext{x},
+			connConnPrepareContext{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connConnBeginTx
+		}{
+			x,
+			connConnBeginTx{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connConnBeginTx
+		}{
+			x,
+			connExecerContext{x},
+			connConnBeginTx{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connQueryerContext
+			connConnBeginTx
+		}{
+			x,
+			connQueryerContext{x},
+			connConnBeginTx{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connQueryerContext
+			connConnBeginTx
+		}{
+			x,
+			connExecerContext{x},
+			connQueryerContext{x},
+			connConnBeginTx{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connConnPrepareContext
+			connConnBeginTx
+		}{
+			x,
+			connConnPrepareContext{x},
+			connConnBeginTx{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connConnPrepareContext
+			connConnBeginTx
+		}{
+			x,
+			connExecerContext{x},
+			connConnPrepareContext{x},
+			connConnBeginTx{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connQueryerContext
+			connConnPrepareContext
+			connConnBeginTx
+		}{
+			x,
+			connQueryerContext{x},
+			connConnPrepareContext{x},
+			connConnBeginTx{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connQueryerContext
+			connConnPrepareContext
+			connConnBeginTx
+		}{
+			x,
+			connExecerContext{x},
+			connQueryerContext{x},
+			connConnPrepareContext{x},
+			connConnBeginTx{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connPinger
+		}{
+			x,
+			connPinger{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connPinger
+		}{
+			x,
+			connExecerContext{x},
+			connPinger{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connQueryerContext
+			connPinger
+		}{
+			x,
+			connQueryerContext{x},
+			connPinger{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connQueryerContext
+			connPinger
+		}{
+			x,
+			connExecerContext{x},
+			connQueryerContext{x},
+			connPinger{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connConnPrepareContext
+			connPinger
+		}{
+			x,
+			connConnPrepareContext{x},
+			connPinger{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connConnPrepareContext
+			connPinger
+		}{
+			x,
+			connExecerContext{x},
+			connConnPrepareContext{x},
+			connPinger{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connQueryerContext
+			connConnPrepareContext
+			connPinger
+		}{
+			x,
+			connQueryerContext{x},
+			connConnPrepareContext{x},
+			connPinger{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connQueryerContext
+			connConnPrepareContext
+			connPinger
+		}{
+			x,
+			connExecerContext{x},
+			connQueryerContext{x},
+			connConnPrepareContext{x},
+			connPinger{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connConnBeginTx
+			connPinger
+		}{
+			x,
+			connConnBeginTx{x},
+			connPinger{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connConnBeginTx
+			connPinger
+		}{
+			x,
+			connExecerContext{x},
+			connConnBeginTx{x},
+			connPinger{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connQueryerContext
+			connConnBeginTx
+			connPinger
+		}{
+			x,
+			connQueryerContext{x},
+			connConnBeginTx{x},
+			connPinger{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connQueryerContext
+			connConnBeginTx
+			connPinger
+		}{
+			x,
+			connExecerContext{x},
+			connQueryerContext{x},
+			connConnBeginTx{x},
+			connPinger{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connConnPrepareContext
+			connConnBeginTx
+			connPinger
+		}{
+			x,
+			connConnPrepareContext{x},
+			connConnBeginTx{x},
+			connPinger{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connConnPrepareContext
+			connConnBeginTx
+			connPinger
+		}{
+			x,
+			connExecerContext{x},
+			connConnPrepareContext{x},
+			connConnBeginTx{x},
+			connPinger{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connQueryerContext
+			connConnPrepareContext
+			connConnBeginTx
+			connPinger
+		}{
+			x,
+			connQueryerContext{x},
+			connConnPrepareContext{x},
+			connConnBeginTx{x},
+			connPinger{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connQueryerContext
+			connConnPrepareContext
+			connConnBeginTx
+			connPinger
+		}{
+			x,
+			connExecerContext{x},
+			connQueryerContext{x},
+			connConnPrepareContext{x},
+			connConnBeginTx{x},
+			connPinger{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connSessionResetter
+		}{
+			x,
+			connSessionResetter{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connSessionResetter
+		}{
+			x,
+			connExecerContext{x},
+			connSessionResetter{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connQueryerContext
+			connSessionResetter
+		}{
+			x,
+			connQueryerContext{x},
+			connSessionResetter{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connQueryerContext
+			connSessionResetter
+		}{
+			x,
+			connExecerContext{x},
+			connQueryerContext{x},
+			connSessionResetter{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connConnPrepareContext
+			connSessionResetter
+		}{
+			x,
+			connConnPrepareContext{x},
+			connSessionResetter{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connConnPrepareContext
+			connSessionResetter
+		}{
+			x,
+			connExecerContext{x},
+			connConnPrepareContext{x},
+			connSessionResetter{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connQueryerContext
+			connConnPrepareContext
+			connSessionResetter
+		}{
+			x,
+			connQueryerContext{x},
+			connConnPrepareContext{x},
+			connSessionResetter{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connQueryerContext
+			connConnPrepareContext
+			connSessionResetter
+		}{
+			x,
+			connExecerContext{x},
+			connQueryerContext{x},
+			connConnPrepareContext{x},
+			connSessionResetter{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connConnBeginTx
+			connSessionResetter
+		}{
+			x,
+			connConnBeginTx{x},
+			connSessionResetter{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connConnBeginTx
+			connSessionResetter
+		}{
+			x,
+			connExecerContext{x},
+			connConnBeginTx{x},
+			connSessionResetter{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connQueryerContext
+			connConnBeginTx
+			connSessionResetter
+		}{
+			x,
+			connQueryerContext{x},
+			connConnBeginTx{x},
+			connSessionResetter{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connQueryerContext
+			connConnBeginTx
+			connSessionResetter
+		}{
+			x,
+			connExecerContext{x},
+			connQueryerContext{x},
+			connConnBeginTx{x},
+			connSessionResetter{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connConnPrepareContext
+			connConnBeginTx
+			connSessionResetter
+		}{
+			x,
+			connConnPrepareContext{x},
+			connConnBeginTx{x},
+			connSessionResetter{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connConnPrepareContext
+			connConnBeginTx
+			connSessionResetter
+		}{
+			x,
+			connExecerContext{x},
+			connConnPrepareContext{x},
+			connConnBeginTx{x},
+			connSessionResetter{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connQueryerContext
+			connConnPrepareContext
+			connConnBeginTx
+			connSessionResetter
+		}{
+			x,
+			connQueryerContext{x},
+			connConnPrepareContext{x},
+			connConnBeginTx{x},
+			connSessionResetter{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connQueryerContext
+			connConnPrepareContext
+			connConnBeginTx
+			connSessionResetter
+		}{
+			x,
+			connExecerContext{x},
+			connQueryerContext{x},
+			connConnPrepareContext{x},
+			connConnBeginTx{x},
+			connSessionResetter{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connPinger
+			connSessionResetter
+		}{
+			x,
+			connPinger{x},
+			connSessionResetter{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connPinger
+			connSessionResetter
+		}{
+			x,
+			connExecerContext{x},
+			connPinger{x},
+			connSessionResetter{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connQueryerContext
+			connPinger
+			connSessionResetter
+		}{
+			x,
+			connQueryerContext{x},
+			connPinger{x},
+			connSessionResetter{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connQueryerContext
+			connPinger
+			connSessionResetter
+		}{
+			x,
+			connExecerContext{x},
+			connQueryerContext{x},
+			connPinger{x},
+			connSessionResetter{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connConnPrepareContext
+			connPinger
+			connSessionResetter
+		}{
+			x,
+			connConnPrepareContext{x},
+			connPinger{x},
+			connSessionResetter{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connConnPrepareContext
+			connPinger
+			connSessionResetter
+		}{
+			x,
+			connExecerContext{x},
+			connConnPrepareContext{x},
+			connPinger{x},
+			connSessionResetter{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connQueryerContext
+			connConnPrepareContext
+			connPinger
+			connSessionResetter
+		}{
+			x,
+			connQueryerContext{x},
+			connConnPrepareContext{x},
+			connPinger{x},
+			connSessionResetter{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connQueryerContext
+			connConnPrepareContext
+			connPinger
+			connSessionResetter
+		}{
+			x,
+			connExecerContext{x},
+			connQueryerContext{x},
+			connConnPrepareContext{x},
+			connPinger{x},
+			connSessionResetter{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connConnBeginTx
+			connPinger
+			connSessionResetter
+		}{
+			x,
+			connConnBeginTx{x},
+			connPinger{x},
+			connSessionResetter{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connConnBeginTx
+			connPinger
+			connSessionResetter
+		}{
+			x,
+			connExecerContext{x},
+			connConnBeginTx{x},
+			connPinger{x},
+			connSessionResetter{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connQueryerContext
+			connConnBeginTx
+			connPinger
+			connSessionResetter
+		}{
+			x,
+			connQueryerContext{x},
+			connConnBeginTx{x},
+			connPinger{x},
+			connSessionResetter{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connQueryerContext
+			connConnBeginTx
+			connPinger
+			connSessionResetter
+		}{
+			x,
+			connExecerContext{x},
+			connQueryerContext{x},
+			connConnBeginTx{x},
+			connPinger{x},
+			connSessionResetter{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connConnPrepareContext
+			connConnBeginTx
+			connPinger
+			connSessionResetter
+		}{
+			x,
+			connConnPrepareContext{x},
+			connConnBeginTx{x},
+			connPinger{x},
+			connSessionResetter{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connConnPrepareContext
+			connConnBeginTx
+			connPinger
+			connSessionResetter
+		}{
+			x,
+			connExecerContext{x},
+			connConnPrepareContext{x},
+			connConnBeginTx{x},
+			connPinger{x},
+			connSessionResetter{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connQueryerContext
+			connConnPrepareContext
+			connConnBeginTx
+			connPinger
+			connSessionResetter
+		}{
+			x,
+			connQueryerContext{x},
+			connConnPrepareContext{x},
+			connConnBeginTx{x},
+			connPinger{x},
+			connSessionResetter{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connQueryerContext
+			connConnPrepareContext
+			connConnBeginTx
+			connPinger
+			connSessionResetter
+		}{
+			x,
+			connExecerContext{x},
+			connQueryerContext{x},
+			connConnPrepareContext{x},
+			connConnBeginTx{x},
+			connPinger{x},
+			connSessionResetter{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connValidator
+		}{
+			x,
+			connValidator{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connValidator
+		}{
+			x,
+			connExecerContext{x},
+			connValidator{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connQueryerContext
+			connValidator
+		}{
+			x,
+			connQueryerContext{x},
+			connValidator{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connQueryerContext
+			connValidator
+		}{
+			x,
+			connExecerContext{x},
+			connQueryerContext{x},
+			connValidator{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connConnPrepareContext
+			connValidator
+		}{
+			x,
+			connConnPrepareContext{x},
+			connValidator{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connConnPrepareContext
+			connValidator
+		}{
+			x,
+			connExecerContext{x},
+			connConnPrepareContext{x},
+			connValidator{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connQueryerContext
+			connConnPrepareContext
+			connValidator
+		}{
+			x,
+			connQueryerContext{x},
+			connConnPrepareContext{x},
+			connValidator{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connQueryerContext
+			connConnPrepareContext
+			connValidator
+		}{
+			x,
+			connExecerContext{x},
+			connQueryerContext{x},
+			connConnPrepareContext{x},
+			connValidator{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connConnBeginTx
+			connValidator
+		}{
+			x,
+			connConnBeginTx{x},
+			connValidator{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connConnBeginTx
+			connValidator
+		}{
+			x,
+			connExecerContext{x},
+			connConnBeginTx{x},
+			connValidator{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connQueryerContext
+			connConnBeginTx
+			connValidator
+		}{
+			x,
+			connQueryerContext{x},
+			connConnBeginTx{x},
+			connValidator{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connQueryerContext
+			connConnBeginTx
+			connValidator
+		}{
+			x,
+			connExecerContext{x},
+			connQueryerContext{x},
+			connConnBeginTx{x},
+			connValidator{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connConnPrepareContext
+			connConnBeginTx
+			connValidator
+		}{
+			x,
+			connConnPrepareContext{x},
+			connConnBeginTx{x},
+			connValidator{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connConnPrepareContext
+			connConnBeginTx
+			connValidator
+		}{
+			x,
+			connExecerContext{x},
+			connConnPrepareContext{x},
+			connConnBeginTx{x},
+			connValidator{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connQueryerContext
+			connConnPrepareContext
+			connConnBeginTx
+			connValidator
+		}{
+			x,
+			connQueryerContext{x},
+			connConnPrepareContext{x},
+			connConnBeginTx{x},
+			connValidator{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connQueryerContext
+			connConnPrepareContext
+			connConnBeginTx
+			connValidator
+		}{
+			x,
+			connExecerContext{x},
+			connQueryerContext{x},
+			connConnPrepareContext{x},
+			connConnBeginTx{x},
+			connValidator{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connPinger
+			connValidator
+		}{
+			x,
+			connPinger{x},
+			connValidator{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connPinger
+			connValidator
+		}{
+			x,
+			connExecerContext{x},
+			connPinger{x},
+			connValidator{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connQueryerContext
+			connPinger
+			connValidator
+		}{
+			x,
+			connQueryerContext{x},
+			connPinger{x},
+			connValidator{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connQueryerContext
+			connPinger
+			connValidator
+		}{
+			x,
+			connExecerContext{x},
+			connQueryerContext{x},
+			connPinger{x},
+			connValidator{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connConnPrepareContext
+			connPinger
+			connValidator
+		}{
+			x,
+			connConnPrepareContext{x},
+			connPinger{x},
+			connValidator{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connConnPrepareContext
+			connPinger
+			connValidator
+		}{
+			x,
+			connExecerContext{x},
+			connConnPrepareContext{x},
+			connPinger{x},
+			connValidator{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connQueryerContext
+			connConnPrepareContext
+			connPinger
+			connValidator
+		}{
+			x,
+			connQueryerContext{x},
+			connConnPrepareContext{x},
+			connPinger{x},
+			connValidator{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connQueryerContext
+			connConnPrepareContext
+			connPinger
+			connValidator
+		}{
+			x,
+			connExecerContext{x},
+			connQueryerContext{x},
+			connConnPrepareContext{x},
+			connPinger{x},
+			connValidator{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connConnBeginTx
+			connPinger
+			connValidator
+		}{
+			x,
+			connConnBeginTx{x},
+			connPinger{x},
+			connValidator{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connConnBeginTx
+			connPinger
+			connValidator
+		}{
+			x,
+			connExecerContext{x},
+			connConnBeginTx{x},
+			connPinger{x},
+			connValidator{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connQueryerContext
+			connConnBeginTx
+			connPinger
+			connValidator
+		}{
+			x,
+			connQueryerContext{x},
+			connConnBeginTx{x},
+			connPinger{x},
+			connValidator{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connQueryerContext
+			connConnBeginTx
+			connPinger
+			connValidator
+		}{
+			x,
+			connExecerContext{x},
+			connQueryerContext{x},
+			connConnBeginTx{x},
+			connPinger{x},
+			connValidator{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connConnPrepareContext
+			connConnBeginTx
+			connPinger
+			connValidator
+		}{
+			x,
+			connConnPrepareContext{x},
+			connConnBeginTx{x},
+			connPinger{x},
+			connValidator{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connConnPrepareContext
+			connConnBeginTx
+			connPinger
+			connValidator
+		}{
+			x,
+			connExecerContext{x},
+			connConnPrepareContext{x},
+			connConnBeginTx{x},
+			connPinger{x},
+			connValidator{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connQueryerContext
+			connConnPrepareContext
+			connConnBeginTx
+			connPinger
+			connValidator
+		}{
+			x,
+			connQueryerContext{x},
+			connConnPrepareContext{x},
+			connConnBeginTx{x},
+			connPinger{x},
+			connValidator{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connQueryerContext
+			connConnPrepareContext
+			connConnBeginTx
+			connPinger
+			connValidator
+		}{
+			x,
+			connExecerContext{x},
+			connQueryerContext{x},
+			connConnPrepareContext{x},
+			connConnBeginTx{x},
+			connPinger{x},
+			connValidator{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connSessionResetter
+			connValidator
+		}{
+			x,
+			connSessionResetter{x},
+			connValidator{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connSessionResetter
+			connValidator
+		}{
+			x,
+			connExecerContext{x},
+			connSessionResetter{x},
+			connValidator{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connQueryerContext
+			connSessionResetter
+			connValidator
+		}{
+			x,
+			connQueryerContext{x},
+			connSessionResetter{x},
+			connValidator{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connQueryerContext
+			connSessionResetter
+			connValidator
+		}{
+			x,
+			connExecerContext{x},
+			connQueryerContext{x},
+			connSessionResetter{x},
+			connValidator{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connConnPrepareContext
+			connSessionResetter
+			connValidator
+		}{
+			x,
+			connConnPrepareContext{x},
+			connSessionResetter{x},
+			connValidator{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connConnPrepareContext
+			connSessionResetter
+			connValidator
+		}{
+			x,
+			connExecerContext{x},
+			connConnPrepareContext{x},
+			connSessionResetter{x},
+			connValidator{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connQueryerContext
+			connConnPrepareContext
+			connSessionResetter
+			connValidator
+		}{
+			x,
+			connQueryerContext{x},
+			connConnPrepareContext{x},
+			connSessionResetter{x},
+			connValidator{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connQueryerContext
+			connConnPrepareContext
+			connSessionResetter
+			connValidator
+		}{
+			x,
+			connExecerContext{x},
+			connQueryerContext{x},
+			connConnPrepareContext{x},
+			connSessionResetter{x},
+			connValidator{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connConnBeginTx
+			connSessionResetter
+			connValidator
+		}{
+			x,
+			connConnBeginTx{x},
+			connSessionResetter{x},
+			connValidator{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connConnBeginTx
+			connSessionResetter
+			connValidator
+		}{
+			x,
+			connExecerContext{x},
+			connConnBeginTx{x},
+			connSessionResetter{x},
+			connValidator{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connQueryerContext
+			connConnBeginTx
+			connSessionResetter
+			connValidator
+		}{
+			x,
+			connQueryerContext{x},
+			connConnBeginTx{x},
+			connSessionResetter{x},
+			connValidator{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connQueryerContext
+			connConnBeginTx
+			connSessionResetter
+			connValidator
+		}{
+			x,
+			connExecerContext{x},
+			connQueryerContext{x},
+			connConnBeginTx{x},
+			connSessionResetter{x},
+			connValidator{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connConnPrepareContext
+			connConnBeginTx
+			connSessionResetter
+			connValidator
+		}{
+			x,
+			connConnPrepareContext{x},
+			connConnBeginTx{x},
+			connSessionResetter{x},
+			connValidator{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connConnPrepareContext
+			connConnBeginTx
+			connSessionResetter
+			connValidator
+		}{
+			x,
+			connExecerContext{x},
+			connConnPrepareContext{x},
+			connConnBeginTx{x},
+			connSessionResetter{x},
+			connValidator{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connQueryerContext
+			connConnPrepareContext
+			connConnBeginTx
+			connSessionResetter
+			connValidator
+		}{
+			x,
+			connQueryerContext{x},
+			connConnPrepareContext{x},
+			connConnBeginTx{x},
+			connSessionResetter{x},
+			connValidator{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connQueryerContext
+			connConnPrepareContext
+			connConnBeginTx
+			connSessionResetter
+			connValidator
+		}{
+			x,
+			connExecerContext{x},
+			connQueryerContext{x},
+			connConnPrepareContext{x},
+			connConnBeginTx{x},
+			connSessionResetter{x},
+			connValidator{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connPinger
+			connSessionResetter
+			connValidator
+		}{
+			x,
+			connPinger{x},
+			connSessionResetter{x},
+			connValidator{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connPinger
+			connSessionResetter
+			connValidator
+		}{
+			x,
+			connExecerContext{x},
+			connPinger{x},
+			connSessionResetter{x},
+			connValidator{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connQueryerContext
+			connPinger
+			connSessionResetter
+			connValidator
+		}{
+			x,
+			connQueryerContext{x},
+			connPinger{x},
+			connSessionResetter{x},
+			connValidator{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connQueryerContext
+			connPinger
+			connSessionResetter
+			connValidator
+		}{
+			x,
+			connExecerContext{x},
+			connQueryerContext{x},
+			connPinger{x},
+			connSessionResetter{x},
+			connValidator{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connConnPrepareContext
+			connPinger
+			connSessionResetter
+			connValidator
+		}{
+			x,
+			connConnPrepareContext{x},
+			connPinger{x},
+			connSessionResetter{x},
+			connValidator{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connConnPrepareContext
+			connPinger
+			connSessionResetter
+			connValidator
+		}{
+			x,
+			connExecerContext{x},
+			connConnPrepareContext{x},
+			connPinger{x},
+			connSessionResetter{x},
+			connValidator{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connQueryerContext
+			connConnPrepareContext
+			connPinger
+			connSessionResetter
+			connValidator
+		}{
+			x,
+			connQueryerContext{x},
+			connConnPrepareContext{x},
+			connPinger{x},
+			connSessionResetter{x},
+			connValidator{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connQueryerContext
+			connConnPrepareContext
+			connPinger
+			connSessionResetter
+			connValidator
+		}{
+			x,
+			connExecerContext{x},
+			connQueryerContext{x},
+			connConnPrepareContext{x},
+			connPinger{x},
+			connSessionResetter{x},
+			connValidator{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connConnBeginTx
+			connPinger
+			connSessionResetter
+			connValidator
+		}{
+			x,
+			connConnBeginTx{x},
+			connPinger{x},
+			connSessionResetter{x},
+			connValidator{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connConnBeginTx
+			connPinger
+			connSessionResetter
+			connValidator
+		}{
+			x,
+			connExecerContext{x},
+			connConnBeginTx{x},
+			connPinger{x},
+			connSessionResetter{x},
+			connValidator{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connQueryerContext
+			connConnBeginTx
+			connPinger
+			connSessionResetter
+			connValidator
+		}{
+			x,
+			connQueryerContext{x},
+			connConnBeginTx{x},
+			connPinger{x},
+			connSessionResetter{x},
+			connValidator{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connQueryerContext
+			connConnBeginTx
+			connPinger
+			connSessionResetter
+			connValidator
+		}{
+			x,
+			connExecerContext{x},
+			connQueryerContext{x},
+			connConnBeginTx{x},
+			connPinger{x},
+			connSessionResetter{x},
+			connValidator{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connConnPrepareContext
+			connConnBeginTx
+			connPinger
+			connSessionResetter
+			connValidator
+		}{
+			x,
+			connConnPrepareContext{x},
+			connConnBeginTx{x},
+			connPinger{x},
+			connSessionResetter{x},
+			connValidator{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connConnPrepareContext
+			connConnBeginTx
+			connPinger
+			connSessionResetter
+			connValidator
+		}{
+			x,
+			connExecerContext{x},
+			connConnPrepareContext{x},
+			connConnBeginTx{x},
+			connPinger{x},
+			connSessionResetter{x},
+			connValidator{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connQueryerContext
+			connConnPrepareContext
+			connConnBeginTx
+			connPinger
+			connSessionResetter
+			connValidator
+		}{
+			x,
+			connQueryerContext{x},
+			connConnPrepareContext{x},
+			connConnBeginTx{x},
+			connPinger{x},
+			connSessionResetter{x},
+			connValidator{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connQueryerContext
+			connConnPrepareContext
+			connConnBeginTx
+			connPinger
+			connSessionResetter
+			connValidator
+		}{
+			x,
+			connExecerContext{x},
+			connQueryerContext{x},
+			connConnPrepareContext{x},
+			connConnBeginTx{x},
+			connPinger{x},
+			connSessionResetter{x},
+			connValidator{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connNamedValueChecker
+		}{
+			x,
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connNamedValueChecker
+		}{
+			x,
+			connExecerContext{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connQueryerContext
+			connNamedValueChecker
+		}{
+			x,
+			connQueryerContext{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connQueryerContext
+			connNamedValueChecker
+		}{
+			x,
+			connExecerContext{x},
+			connQueryerContext{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connConnPrepareContext
+			connNamedValueChecker
+		}{
+			x,
+			connConnPrepareContext{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connConnPrepareContext
+			connNamedValueChecker
+		}{
+			x,
+			connExecerContext{x},
+			connConnPrepareContext{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connQueryerContext
+			connConnPrepareContext
+			connNamedValueChecker
+		}{
+			x,
+			connQueryerContext{x},
+			connConnPrepareContext{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connQueryerContext
+			connConnPrepareContext
+			connNamedValueChecker
+		}{
+			x,
+			connExecerContext{x},
+			connQueryerContext{x},
+			connConnPrepareContext{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connConnBeginTx
+			connNamedValueChecker
+		}{
+			x,
+			connConnBeginTx{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connConnBeginTx
+			connNamedValueChecker
+		}{
+			x,
+			connExecerContext{x},
+			connConnBeginTx{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connQueryerContext
+			connConnBeginTx
+			connNamedValueChecker
+		}{
+			x,
+			connQueryerContext{x},
+			connConnBeginTx{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connQueryerContext
+			connConnBeginTx
+			connNamedValueChecker
+		}{
+			x,
+			connExecerContext{x},
+			connQueryerContext{x},
+			connConnBeginTx{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connConnPrepareContext
+			connConnBeginTx
+			connNamedValueChecker
+		}{
+			x,
+			connConnPrepareContext{x},
+			connConnBeginTx{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connConnPrepareContext
+			connConnBeginTx
+			connNamedValueChecker
+		}{
+			x,
+			connExecerContext{x},
+			connConnPrepareContext{x},
+			connConnBeginTx{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connQueryerContext
+			connConnPrepareContext
+			connConnBeginTx
+			connNamedValueChecker
+		}{
+			x,
+			connQueryerContext{x},
+			connConnPrepareContext{x},
+			connConnBeginTx{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connQueryerContext
+			connConnPrepareContext
+			connConnBeginTx
+			connNamedValueChecker
+		}{
+			x,
+			connExecerContext{x},
+			connQueryerContext{x},
+			connConnPrepareContext{x},
+			connConnBeginTx{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connPinger
+			connNamedValueChecker
+		}{
+			x,
+			connPinger{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connPinger
+			connNamedValueChecker
+		}{
+			x,
+			connExecerContext{x},
+			connPinger{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connQueryerContext
+			connPinger
+			connNamedValueChecker
+		}{
+			x,
+			connQueryerContext{x},
+			connPinger{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connQueryerContext
+			connPinger
+			connNamedValueChecker
+		}{
+			x,
+			connExecerContext{x},
+			connQueryerContext{x},
+			connPinger{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connConnPrepareContext
+			connPinger
+			connNamedValueChecker
+		}{
+			x,
+			connConnPrepareContext{x},
+			connPinger{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connConnPrepareContext
+			connPinger
+			connNamedValueChecker
+		}{
+			x,
+			connExecerContext{x},
+			connConnPrepareContext{x},
+			connPinger{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connQueryerContext
+			connConnPrepareContext
+			connPinger
+			connNamedValueChecker
+		}{
+			x,
+			connQueryerContext{x},
+			connConnPrepareContext{x},
+			connPinger{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connQueryerContext
+			connConnPrepareContext
+			connPinger
+			connNamedValueChecker
+		}{
+			x,
+			connExecerContext{x},
+			connQueryerContext{x},
+			connConnPrepareContext{x},
+			connPinger{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connConnBeginTx
+			connPinger
+			connNamedValueChecker
+		}{
+			x,
+			connConnBeginTx{x},
+			connPinger{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connConnBeginTx
+			connPinger
+			connNamedValueChecker
+		}{
+			x,
+			connExecerContext{x},
+			connConnBeginTx{x},
+			connPinger{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connQueryerContext
+			connConnBeginTx
+			connPinger
+			connNamedValueChecker
+		}{
+			x,
+			connQueryerContext{x},
+			connConnBeginTx{x},
+			connPinger{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connQueryerContext
+			connConnBeginTx
+			connPinger
+			connNamedValueChecker
+		}{
+			x,
+			connExecerContext{x},
+			connQueryerContext{x},
+			connConnBeginTx{x},
+			connPinger{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connConnPrepareContext
+			connConnBeginTx
+			connPinger
+			connNamedValueChecker
+		}{
+			x,
+			connConnPrepareContext{x},
+			connConnBeginTx{x},
+			connPinger{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connConnPrepareContext
+			connConnBeginTx
+			connPinger
+			connNamedValueChecker
+		}{
+			x,
+			connExecerContext{x},
+			connConnPrepareContext{x},
+			connConnBeginTx{x},
+			connPinger{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connQueryerContext
+			connConnPrepareContext
+			connConnBeginTx
+			connPinger
+			connNamedValueChecker
+		}{
+			x,
+			connQueryerContext{x},
+			connConnPrepareContext{x},
+			connConnBeginTx{x},
+			connPinger{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connQueryerContext
+			connConnPrepareContext
+			connConnBeginTx
+			connPinger
+			connNamedValueChecker
+		}{
+			x,
+			connExecerContext{x},
+			connQueryerContext{x},
+			connConnPrepareContext{x},
+			connConnBeginTx{x},
+			connPinger{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connSessionResetter
+			connNamedValueChecker
+		}{
+			x,
+			connSessionResetter{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connSessionResetter
+			connNamedValueChecker
+		}{
+			x,
+			connExecerContext{x},
+			connSessionResetter{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connQueryerContext
+			connSessionResetter
+			connNamedValueChecker
+		}{
+			x,
+			connQueryerContext{x},
+			connSessionResetter{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connQueryerContext
+			connSessionResetter
+			connNamedValueChecker
+		}{
+			x,
+			connExecerContext{x},
+			connQueryerContext{x},
+			connSessionResetter{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connConnPrepareContext
+			connSessionResetter
+			connNamedValueChecker
+		}{
+			x,
+			connConnPrepareContext{x},
+			connSessionResetter{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connConnPrepareContext
+			connSessionResetter
+			connNamedValueChecker
+		}{
+			x,
+			connExecerContext{x},
+			connConnPrepareContext{x},
+			connSessionResetter{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connQueryerContext
+			connConnPrepareContext
+			connSessionResetter
+			connNamedValueChecker
+		}{
+			x,
+			connQueryerContext{x},
+			connConnPrepareContext{x},
+			connSessionResetter{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connQueryerContext
+			connConnPrepareContext
+			connSessionResetter
+			connNamedValueChecker
+		}{
+			x,
+			connExecerContext{x},
+			connQueryerContext{x},
+			connConnPrepareContext{x},
+			connSessionResetter{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connConnBeginTx
+			connSessionResetter
+			connNamedValueChecker
+		}{
+			x,
+			connConnBeginTx{x},
+			connSessionResetter{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connConnBeginTx
+			connSessionResetter
+			connNamedValueChecker
+		}{
+			x,
+			connExecerContext{x},
+			connConnBeginTx{x},
+			connSessionResetter{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connQueryerContext
+			connConnBeginTx
+			connSessionResetter
+			connNamedValueChecker
+		}{
+			x,
+			connQueryerContext{x},
+			connConnBeginTx{x},
+			connSessionResetter{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connQueryerContext
+			connConnBeginTx
+			connSessionResetter
+			connNamedValueChecker
+		}{
+			x,
+			connExecerContext{x},
+			connQueryerContext{x},
+			connConnBeginTx{x},
+			connSessionResetter{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connConnPrepareContext
+			connConnBeginTx
+			connSessionResetter
+			connNamedValueChecker
+		}{
+			x,
+			connConnPrepareContext{x},
+			connConnBeginTx{x},
+			connSessionResetter{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connConnPrepareContext
+			connConnBeginTx
+			connSessionResetter
+			connNamedValueChecker
+		}{
+			x,
+			connExecerContext{x},
+			connConnPrepareContext{x},
+			connConnBeginTx{x},
+			connSessionResetter{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connQueryerContext
+			connConnPrepareContext
+			connConnBeginTx
+			connSessionResetter
+			connNamedValueChecker
+		}{
+			x,
+			connQueryerContext{x},
+			connConnPrepareContext{x},
+			connConnBeginTx{x},
+			connSessionResetter{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connQueryerContext
+			connConnPrepareContext
+			connConnBeginTx
+			connSessionResetter
+			connNamedValueChecker
+		}{
+			x,
+			connExecerContext{x},
+			connQueryerContext{x},
+			connConnPrepareContext{x},
+			connConnBeginTx{x},
+			connSessionResetter{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connPinger
+			connSessionResetter
+			connNamedValueChecker
+		}{
+			x,
+			connPinger{x},
+			connSessionResetter{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connPinger
+			connSessionResetter
+			connNamedValueChecker
+		}{
+			x,
+			connExecerContext{x},
+			connPinger{x},
+			connSessionResetter{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connQueryerContext
+			connPinger
+			connSessionResetter
+			connNamedValueChecker
+		}{
+			x,
+			connQueryerContext{x},
+			connPinger{x},
+			connSessionResetter{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connQueryerContext
+			connPinger
+			connSessionResetter
+			connNamedValueChecker
+		}{
+			x,
+			connExecerContext{x},
+			connQueryerContext{x},
+			connPinger{x},
+			connSessionResetter{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connConnPrepareContext
+			connPinger
+			connSessionResetter
+			connNamedValueChecker
+		}{
+			x,
+			connConnPrepareContext{x},
+			connPinger{x},
+			connSessionResetter{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connConnPrepareContext
+			connPinger
+			connSessionResetter
+			connNamedValueChecker
+		}{
+			x,
+			connExecerContext{x},
+			connConnPrepareContext{x},
+			connPinger{x},
+			connSessionResetter{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connQueryerContext
+			connConnPrepareContext
+			connPinger
+			connSessionResetter
+			connNamedValueChecker
+		}{
+			x,
+			connQueryerContext{x},
+			connConnPrepareContext{x},
+			connPinger{x},
+			connSessionResetter{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connQueryerContext
+			connConnPrepareContext
+			connPinger
+			connSessionResetter
+			connNamedValueChecker
+		}{
+			x,
+			connExecerContext{x},
+			connQueryerContext{x},
+			connConnPrepareContext{x},
+			connPinger{x},
+			connSessionResetter{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connConnBeginTx
+			connPinger
+			connSessionResetter
+			connNamedValueChecker
+		}{
+			x,
+			connConnBeginTx{x},
+			connPinger{x},
+			connSessionResetter{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connConnBeginTx
+			connPinger
+			connSessionResetter
+			connNamedValueChecker
+		}{
+			x,
+			connExecerContext{x},
+			connConnBeginTx{x},
+			connPinger{x},
+			connSessionResetter{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connQueryerContext
+			connConnBeginTx
+			connPinger
+			connSessionResetter
+			connNamedValueChecker
+		}{
+			x,
+			connQueryerContext{x},
+			connConnBeginTx{x},
+			connPinger{x},
+			connSessionResetter{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connQueryerContext
+			connConnBeginTx
+			connPinger
+			connSessionResetter
+			connNamedValueChecker
+		}{
+			x,
+			connExecerContext{x},
+			connQueryerContext{x},
+			connConnBeginTx{x},
+			connPinger{x},
+			connSessionResetter{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connConnPrepareContext
+			connConnBeginTx
+			connPinger
+			connSessionResetter
+			connNamedValueChecker
+		}{
+			x,
+			connConnPrepareContext{x},
+			connConnBeginTx{x},
+			connPinger{x},
+			connSessionResetter{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connConnPrepareContext
+			connConnBeginTx
+			connPinger
+			connSessionResetter
+			connNamedValueChecker
+		}{
+			x,
+			connExecerContext{x},
+			connConnPrepareContext{x},
+			connConnBeginTx{x},
+			connPinger{x},
+			connSessionResetter{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connQueryerContext
+			connConnPrepareContext
+			connConnBeginTx
+			connPinger
+			connSessionResetter
+			connNamedValueChecker
+		}{
+			x,
+			connQueryerContext{x},
+			connConnPrepareContext{x},
+			connConnBeginTx{x},
+			connPinger{x},
+			connSessionResetter{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connQueryerContext
+			connConnPrepareContext
+			connConnBeginTx
+			connPinger
+			connSessionResetter
+			connNamedValueChecker
+		}{
+			x,
+			connExecerContext{x},
+			connQueryerContext{x},
+			connConnPrepareContext{x},
+			connConnBeginTx{x},
+			connPinger{x},
+			connSessionResetter{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connValidator
+			connNamedValueChecker
+		}{
+			x,
+			connValidator{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connValidator
+			connNamedValueChecker
+		}{
+			x,
+			connExecerContext{x},
+			connValidator{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connQueryerContext
+			connValidator
+			connNamedValueChecker
+		}{
+			x,
+			connQueryerContext{x},
+			connValidator{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connQueryerContext
+			connValidator
+			connNamedValueChecker
+		}{
+			x,
+			connExecerContext{x},
+			connQueryerContext{x},
+			connValidator{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connConnPrepareContext
+			connValidator
+			connNamedValueChecker
+		}{
+			x,
+			connConnPrepareContext{x},
+			connValidator{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connConnPrepareContext
+			connValidator
+			connNamedValueChecker
+		}{
+			x,
+			connExecerContext{x},
+			connConnPrepareContext{x},
+			connValidator{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connQueryerContext
+			connConnPrepareContext
+			connValidator
+			connNamedValueChecker
+		}{
+			x,
+			connQueryerContext{x},
+			connConnPrepareContext{x},
+			connValidator{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connQueryerContext
+			connConnPrepareContext
+			connValidator
+			connNamedValueChecker
+		}{
+			x,
+			connExecerContext{x},
+			connQueryerContext{x},
+			connConnPrepareContext{x},
+			connValidator{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connConnBeginTx
+			connValidator
+			connNamedValueChecker
+		}{
+			x,
+			connConnBeginTx{x},
+			connValidator{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connConnBeginTx
+			connValidator
+			connNamedValueChecker
+		}{
+			x,
+			connExecerContext{x},
+			connConnBeginTx{x},
+			connValidator{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connQueryerContext
+			connConnBeginTx
+			connValidator
+			connNamedValueChecker
+		}{
+			x,
+			connQueryerContext{x},
+			connConnBeginTx{x},
+			connValidator{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connQueryerContext
+			connConnBeginTx
+			connValidator
+			connNamedValueChecker
+		}{
+			x,
+			connExecerContext{x},
+			connQueryerContext{x},
+			connConnBeginTx{x},
+			connValidator{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connConnPrepareContext
+			connConnBeginTx
+			connValidator
+			connNamedValueChecker
+		}{
+			x,
+			connConnPrepareContext{x},
+			connConnBeginTx{x},
+			connValidator{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connConnPrepareContext
+			connConnBeginTx
+			connValidator
+			connNamedValueChecker
+		}{
+			x,
+			connExecerContext{x},
+			connConnPrepareContext{x},
+			connConnBeginTx{x},
+			connValidator{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connQueryerContext
+			connConnPrepareContext
+			connConnBeginTx
+			connValidator
+			connNamedValueChecker
+		}{
+			x,
+			connQueryerContext{x},
+			connConnPrepareContext{x},
+			connConnBeginTx{x},
+			connValidator{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connQueryerContext
+			connConnPrepareContext
+			connConnBeginTx
+			connValidator
+			connNamedValueChecker
+		}{
+			x,
+			connExecerContext{x},
+			connQueryerContext{x},
+			connConnPrepareContext{x},
+			connConnBeginTx{x},
+			connValidator{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connPinger
+			connValidator
+			connNamedValueChecker
+		}{
+			x,
+			connPinger{x},
+			connValidator{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connPinger
+			connValidator
+			connNamedValueChecker
+		}{
+			x,
+			connExecerContext{x},
+			connPinger{x},
+			connValidator{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connQueryerContext
+			connPinger
+			connValidator
+			connNamedValueChecker
+		}{
+			x,
+			connQueryerContext{x},
+			connPinger{x},
+			connValidator{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connQueryerContext
+			connPinger
+			connValidator
+			connNamedValueChecker
+		}{
+			x,
+			connExecerContext{x},
+			connQueryerContext{x},
+			connPinger{x},
+			connValidator{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connConnPrepareContext
+			connPinger
+			connValidator
+			connNamedValueChecker
+		}{
+			x,
+			connConnPrepareContext{x},
+			connPinger{x},
+			connValidator{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connConnPrepareContext
+			connPinger
+			connValidator
+			connNamedValueChecker
+		}{
+			x,
+			connExecerContext{x},
+			connConnPrepareContext{x},
+			connPinger{x},
+			connValidator{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connQueryerContext
+			connConnPrepareContext
+			connPinger
+			connValidator
+			connNamedValueChecker
+		}{
+			x,
+			connQueryerContext{x},
+			connConnPrepareContext{x},
+			connPinger{x},
+			connValidator{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connQueryerContext
+			connConnPrepareContext
+			connPinger
+			connValidator
+			connNamedValueChecker
+		}{
+			x,
+			connExecerContext{x},
+			connQueryerContext{x},
+			connConnPrepareContext{x},
+			connPinger{x},
+			connValidator{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connConnBeginTx
+			connPinger
+			connValidator
+			connNamedValueChecker
+		}{
+			x,
+			connConnBeginTx{x},
+			connPinger{x},
+			connValidator{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connConnBeginTx
+			connPinger
+			connValidator
+			connNamedValueChecker
+		}{
+			x,
+			connExecerContext{x},
+			connConnBeginTx{x},
+			connPinger{x},
+			connValidator{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connQueryerContext
+			connConnBeginTx
+			connPinger
+			connValidator
+			connNamedValueChecker
+		}{
+			x,
+			connQueryerContext{x},
+			connConnBeginTx{x},
+			connPinger{x},
+			connValidator{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connQueryerContext
+			connConnBeginTx
+			connPinger
+			connValidator
+			connNamedValueChecker
+		}{
+			x,
+			connExecerContext{x},
+			connQueryerContext{x},
+			connConnBeginTx{x},
+			connPinger{x},
+			connValidator{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connConnPrepareContext
+			connConnBeginTx
+			connPinger
+			connValidator
+			connNamedValueChecker
+		}{
+			x,
+			connConnPrepareContext{x},
+			connConnBeginTx{x},
+			connPinger{x},
+			connValidator{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connConnPrepareContext
+			connConnBeginTx
+			connPinger
+			connValidator
+			connNamedValueChecker
+		}{
+			x,
+			connExecerContext{x},
+			connConnPrepareContext{x},
+			connConnBeginTx{x},
+			connPinger{x},
+			connValidator{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connQueryerContext
+			connConnPrepareContext
+			connConnBeginTx
+			connPinger
+			connValidator
+			connNamedValueChecker
+		}{
+			x,
+			connQueryerContext{x},
+			connConnPrepareContext{x},
+			connConnBeginTx{x},
+			connPinger{x},
+			connValidator{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connQueryerContext
+			connConnPrepareContext
+			connConnBeginTx
+			connPinger
+			connValidator
+			connNamedValueChecker
+		}{
+			x,
+			connExecerContext{x},
+			connQueryerContext{x},
+			connConnPrepareContext{x},
+			connConnBeginTx{x},
+			connPinger{x},
+			connValidator{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connSessionResetter
+			connValidator
+			connNamedValueChecker
+		}{
+			x,
+			connSessionResetter{x},
+			connValidator{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connSessionResetter
+			connValidator
+			connNamedValueChecker
+		}{
+			x,
+			connExecerContext{x},
+			connSessionResetter{x},
+			connValidator{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connQueryerContext
+			connSessionResetter
+			connValidator
+			connNamedValueChecker
+		}{
+			x,
+			connQueryerContext{x},
+			connSessionResetter{x},
+			connValidator{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connQueryerContext
+			connSessionResetter
+			connValidator
+			connNamedValueChecker
+		}{
+			x,
+			connExecerContext{x},
+			connQueryerContext{x},
+			connSessionResetter{x},
+			connValidator{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connConnPrepareContext
+			connSessionResetter
+			connValidator
+			connNamedValueChecker
+		}{
+			x,
+			connConnPrepareContext{x},
+			connSessionResetter{x},
+			connValidator{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connConnPrepareContext
+			connSessionResetter
+			connValidator
+			connNamedValueChecker
+		}{
+			x,
+			connExecerContext{x},
+			connConnPrepareContext{x},
+			connSessionResetter{x},
+			connValidator{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connQueryerContext
+			connConnPrepareContext
+			connSessionResetter
+			connValidator
+			connNamedValueChecker
+		}{
+			x,
+			connQueryerContext{x},
+			connConnPrepareContext{x},
+			connSessionResetter{x},
+			connValidator{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connQueryerContext
+			connConnPrepareContext
+			connSessionResetter
+			connValidator
+			connNamedValueChecker
+		}{
+			x,
+			connExecerContext{x},
+			connQueryerContext{x},
+			connConnPrepareContext{x},
+			connSessionResetter{x},
+			connValidator{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connConnBeginTx
+			connSessionResetter
+			connValidator
+			connNamedValueChecker
+		}{
+			x,
+			connConnBeginTx{x},
+			connSessionResetter{x},
+			connValidator{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connConnBeginTx
+			connSessionResetter
+			connValidator
+			connNamedValueChecker
+		}{
+			x,
+			connExecerContext{x},
+			connConnBeginTx{x},
+			connSessionResetter{x},
+			connValidator{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connQueryerContext
+			connConnBeginTx
+			connSessionResetter
+			connValidator
+			connNamedValueChecker
+		}{
+			x,
+			connQueryerContext{x},
+			connConnBeginTx{x},
+			connSessionResetter{x},
+			connValidator{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connQueryerContext
+			connConnBeginTx
+			connSessionResetter
+			connValidator
+			connNamedValueChecker
+		}{
+			x,
+			connExecerContext{x},
+			connQueryerContext{x},
+			connConnBeginTx{x},
+			connSessionResetter{x},
+			connValidator{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connConnPrepareContext
+			connConnBeginTx
+			connSessionResetter
+			connValidator
+			connNamedValueChecker
+		}{
+			x,
+			connConnPrepareContext{x},
+			connConnBeginTx{x},
+			connSessionResetter{x},
+			connValidator{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connConnPrepareContext
+			connConnBeginTx
+			connSessionResetter
+			connValidator
+			connNamedValueChecker
+		}{
+			x,
+			connExecerContext{x},
+			connConnPrepareContext{x},
+			connConnBeginTx{x},
+			connSessionResetter{x},
+			connValidator{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connQueryerContext
+			connConnPrepareContext
+			connConnBeginTx
+			connSessionResetter
+			connValidator
+			connNamedValueChecker
+		}{
+			x,
+			connQueryerContext{x},
+			connConnPrepareContext{x},
+			connConnBeginTx{x},
+			connSessionResetter{x},
+			connValidator{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connQueryerContext
+			connConnPrepareContext
+			connConnBeginTx
+			connSessionResetter
+			connValidator
+			connNamedValueChecker
+		}{
+			x,
+			connExecerContext{x},
+			connQueryerContext{x},
+			connConnPrepareContext{x},
+			connConnBeginTx{x},
+			connSessionResetter{x},
+			connValidator{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connPinger
+			connSessionResetter
+			connValidator
+			connNamedValueChecker
+		}{
+			x,
+			connPinger{x},
+			connSessionResetter{x},
+			connValidator{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connPinger
+			connSessionResetter
+			connValidator
+			connNamedValueChecker
+		}{
+			x,
+			connExecerContext{x},
+			connPinger{x},
+			connSessionResetter{x},
+			connValidator{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connQueryerContext
+			connPinger
+			connSessionResetter
+			connValidator
+			connNamedValueChecker
+		}{
+			x,
+			connQueryerContext{x},
+			connPinger{x},
+			connSessionResetter{x},
+			connValidator{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connQueryerContext
+			connPinger
+			connSessionResetter
+			connValidator
+			connNamedValueChecker
+		}{
+			x,
+			connExecerContext{x},
+			connQueryerContext{x},
+			connPinger{x},
+			connSessionResetter{x},
+			connValidator{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connConnPrepareContext
+			connPinger
+			connSessionResetter
+			connValidator
+			connNamedValueChecker
+		}{
+			x,
+			connConnPrepareContext{x},
+			connPinger{x},
+			connSessionResetter{x},
+			connValidator{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connConnPrepareContext
+			connPinger
+			connSessionResetter
+			connValidator
+			connNamedValueChecker
+		}{
+			x,
+			connExecerContext{x},
+			connConnPrepareContext{x},
+			connPinger{x},
+			connSessionResetter{x},
+			connValidator{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connQueryerContext
+			connConnPrepareContext
+			connPinger
+			connSessionResetter
+			connValidator
+			connNamedValueChecker
+		}{
+			x,
+			connQueryerContext{x},
+			connConnPrepareContext{x},
+			connPinger{x},
+			connSessionResetter{x},
+			connValidator{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connQueryerContext
+			connConnPrepareContext
+			connPinger
+			connSessionResetter
+			connValidator
+			connNamedValueChecker
+		}{
+			x,
+			connExecerContext{x},
+			connQueryerContext{x},
+			connConnPrepareContext{x},
+			connPinger{x},
+			connSessionResetter{x},
+			connValidator{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connConnBeginTx
+			connPinger
+			connSessionResetter
+			connValidator
+			connNamedValueChecker
+		}{
+			x,
+			connConnBeginTx{x},
+			connPinger{x},
+			connSessionResetter{x},
+			connValidator{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connConnBeginTx
+			connPinger
+			connSessionResetter
+			connValidator
+			connNamedValueChecker
+		}{
+			x,
+			connExecerContext{x},
+			connConnBeginTx{x},
+			connPinger{x},
+			connSessionResetter{x},
+			connValidator{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connQueryerContext
+			connConnBeginTx
+			connPinger
+			connSessionResetter
+			connValidator
+			connNamedValueChecker
+		}{
+			x,
+			connQueryerContext{x},
+			connConnBeginTx{x},
+			connPinger{x},
+			connSessionResetter{x},
+			connValidator{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connQueryerContext
+			connConnBeginTx
+			connPinger
+			connSessionResetter
+			connValidator
+			connNamedValueChecker
+		}{
+			x,
+			connExecerContext{x},
+			connQueryerContext{x},
+			connConnBeginTx{x},
+			connPinger{x},
+			connSessionResetter{x},
+			connValidator{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connConnPrepareContext
+			connConnBeginTx
+			connPinger
+			connSessionResetter
+			connValidator
+			connNamedValueChecker
+		}{
+			x,
+			connConnPrepareContext{x},
+			connConnBeginTx{x},
+			connPinger{x},
+			connSessionResetter{x},
+			connValidator{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connConnPrepareContext
+			connConnBeginTx
+			connPinger
+			connSessionResetter
+			connValidator
+			connNamedValueChecker
+		}{
+			x,
+			connExecerContext{x},
+			connConnPrepareContext{x},
+			connConnBeginTx{x},
+			connPinger{x},
+			connSessionResetter{x},
+			connValidator{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connQueryerContext
+			connConnPrepareContext
+			connConnBeginTx
+			connPinger
+			connSessionResetter
+			connValidator
+			connNamedValueChecker
+		}{
+			x,
+			connQueryerContext{x},
+			connConnPrepareContext{x},
+			connConnBeginTx{x},
+			connPinger{x},
+			connSessionResetter{x},
+			connValidator{x},
+			connNamedValueChecker{x},
+		}
+	},
+	func(x *conn) driver.Conn {
+		return struct {
+			*conn
+			connExecerContext
+			connQueryerContext
+			connConnPrepareContext
+			connConnBeginTx
+			connPinger
+			connSessionResetter
+			connValidator
+			connNamedValueChecker
+		}{
+			x,
+			connExecerContext{x},
+			connQueryerContext{x},
+			connConnPrepareContext{x},
+			connConnBeginTx{x},
+			connPinger{x},
+			connSessionResetter{x},
+			connValidator{x},
+			connNamedValueChecker{x},
+		}
+	},
+}
+
+// rowsVariants are the implementations of driver.Rows, indexed by the set of
+// optional interfaces they implement.
+var rowsVariants = [64]func(*rows) driver.Rows{
+	func(x *rows) driver.Rows { return x },
+	func(x *rows) driver.Rows {
+		return struct {
+			*rows
+			rowsNextResultSet
+		}{
+			x,
+			rowsNextResultSet{x},
+		}
+	},
+	func(x *rows) driver.Rows {
+		return struct {
+			*rows
+			rowsColumnTypeDatabaseTypeName
+		}{
+			x,
+			rowsColumnTypeDatabaseTypeName{x},
+		}
+	},
+	func(x *rows) driver.Rows {
+		return struct {
+			*rows
+			rowsNextResultSet
+			rowsColumnTypeDatabaseTypeName
+		}{
+			x,
+			rowsNextResultSet{x},
+			rowsColumnTypeDatabaseTypeName{x},
+		}
+	},
+	func(x *rows) driver.Rows {
+		return struct {
+			*rows
+			rowsColumnTypeLength
+		}{
+			x,
+			rowsColumnTypeLength{x},
+		}
+	},
+	func(x *rows) driver.Rows {
+		return struct {
+			*rows
+			rowsNextResultSet
+			rowsColumnTypeLength
+		}{
+			x,
+			rowsNextResultSet{x},
+			rowsColumnTypeLength{x},
+		}
+	},
+	func(x *rows) driver.Rows {
+		return struct {
+			*rows
+			rowsColumnTypeDatabaseTypeName
+			rowsColumnTypeLength
+		}{
+			x,
+			rowsColumnTypeDatabaseTypeName{x},
+			rowsColumnTypeLength{x},
+		}
+	},
+	func(x *rows) driver.Rows {
+		return struct {
+			*rows
+			rowsNextResultSet
+			rowsColumnTypeDatabaseTypeName
+			rowsColumnTypeLength
+		}{
+			x,
+			rowsNextResultSet{x},
+			rowsColumnTypeDatabaseTypeName{x},
+			rowsColumnTypeLength{x},
+		}
+	},
+	func(x *rows) driver.Rows {
+		return struct {
+			*rows
+			rowsColumnTypeNullable
+		}{
+			x,
+			rowsColumnTypeNullable{x},
+		}
+	},
+	func(x *rows) driver.Rows {
+		return struct {
+			*rows
+			rowsNextResultSet
+			rowsColumnTypeNullable
+		}{
+			x,
+			rowsNextResultSet{x},
+			rowsColumnTypeNullable{x},
+		}
+	},
+	func(x *rows) driver.Rows {
+		return struct {
+			*rows
+			rowsColumnTypeDatabaseTypeName
+			rowsColumnTypeNullable
+		}{
+			x,
+			rowsColumnTypeDatabaseTypeName{x},
+			rowsColumnTypeNullable{x},
+		}
+	},
+	func(x *rows) driver.Rows {
+		return struct {
+			*rows
+			rowsNextResultSet
+			rowsColumnTypeDatabaseTypeName
+			rowsColumnTypeNullable
+		}{
+			x,
+			rowsNextResultSet{x},
+			rowsColumnTypeDatabaseTypeName{x},
+			rowsColumnTypeNullable{x},
+		}
+	},
+	func(x *rows) driver.Rows {
+		return struct {
+			*rows
+			rowsColumnTypeLength
+			rowsColumnTypeNullable
+		}{
+			x,
+			rowsColumnTypeLength{x},
+			rowsColumnTypeNullable{x},
+		}
+	},
+	func(x *rows) driver.Rows {
+		return struct {
+			*rows
+			rowsNextResultSet
+			rowsColumnTypeLength
+			rowsColumnTypeNullable
+		}{
+			x,
+			rowsNextResultSet{x},
+			rowsColumnTypeLength{x},
+			rowsColumnTypeNullable{x},
+		}
+	},
+	func(x *rows) driver.Rows {
+		return struct {
+			*rows
+			rowsColumnTypeDatabaseTypeName
+			rowsColumnTypeLength
+			rowsColumnTypeNullable
+		}{
+			x,
+			rowsColumnTypeDatabaseTypeName{x},
+			rowsColumnTypeLength{x},
+			rowsColumnTypeNullable{x},
+		}
+	},
+	func(x *rows) driver.Rows {
+		return struct {
+			*rows
+			rowsNextResultSet
+			rowsColumnTypeDatabaseTypeName
+			rowsColumnTypeLength
+			rowsColumnTypeNullable
+		}{
+			x,
+			rowsNextResultSet{x},
+			rowsColumnTypeDatabaseTypeName{x},
+			rowsColumnTypeLength{x},
+			rowsColumnTypeNullable{x},
+		}
+	},
+	func(x *rows) driver.Rows {
+		return struct {
+			*rows
+			rowsColumnTypePrecisionScale
+		}{
+			x,
+			rowsColumnTypePrecisionScale{x},
+		}
+	},
+	func(x *rows) driver.Rows {
+		return struct {
+			*rows
+			rowsNextResultSet
+			rowsColumnTypePrecisionScale
+		}{
+			x,
+			rowsNextResultSet{x},
+			rowsColumnTypePrecisionScale{x},
+		}
+	},
+	func(x *rows) driver.Rows {
+		return struct {
+			*rows
+			rowsColumnTypeDatabaseTypeName
+			rowsColumnTypePrecisionScale
+		}{
+			x,
+			rowsColumnTypeDatabaseTypeName{x},
+			rowsColumnTypePrecisionScale{x},
+		}
+	},
+	func(x *rows) driver.Rows {
+		return struct {
+			*rows
+			rowsNextResultSet
+			rowsColumnTypeDatabaseTypeName
+			rowsColumnTypePrecisionScale
+		}{
+			x,
+			rowsNextResultSet{x},
+			rowsColumnTypeDatabaseTypeName{x},
+			rowsColumnTypePrecisionScale{x},
+		}
+	},
+	func(x *rows) driver.Rows {
+		return struct {
+			*rows
+			rowsColumnTypeLength
+			rowsColumnTypePrecisionScale
+		}{
+			x,
+			rowsColumnTypeLength{x},
+			rowsColumnTypePrecisionScale{x},
+		}
+	},
+	func(x *rows) driver.Rows {
+		return struct {
+			*rows
+			rowsNextResultSet
+			rowsColumnTypeLength
+			rowsColumnTypePrecisionScale
+		}{
+			x,
+			rowsNextResultSet{x},
+			rowsColumnTypeLength{x},
+			rowsColumnTypePrecisionScale{x},
+		}
+	},
+	func(x *rows) driver.Rows {
+		return struct {
+			*rows
+			rowsColumnTypeDatabaseTypeName
+			rowsColumnTypeLength
+			rowsColumnTypePrecisionScale
+		}{
+			x,
+			rowsColumnTypeDatabaseTypeName{x},
+			rowsColumnTypeLength{x},
+			rowsColumnTypePrecisionScale{x},
+		}
+	},
+	func(x *rows) driver.Rows {
+		return struct {
+			*rows
+			rowsNextResultSet
+			rowsColumnTypeDatabaseTypeName
+			rowsColumnTypeLength
+			rowsColumnTypePrecisionScale
+		}{
+			x,
+			rowsNextResultSet{x},
+			rowsColumnTypeDatabaseTypeName{x},
+			rowsColumnTypeLength{x},
+			rowsColumnTypePrecisionScale{x},
+		}
+	},
+	func(x *rows) driver.Rows {
+		return struct {
+			*rows
+			rowsColumnTypeNullable
+			rowsColumnTypePrecisionScale
+		}{
+			x,
+			rowsColumnTypeNullable{x},
+			rowsColumnTypePrecisionScale{x},
+		}
+	},
+	func(x *rows) driver.Rows {
+		return struct {
+			*rows
+			rowsNextResultSet
+			rowsColumnTypeNullable
+			rowsColumnTypePrecisionScale
+		}{
+			x,
+			rowsNextResultSet{x},
+			rowsColumnTypeNullable{x},
+			rowsColumnTypePrecisionScale{x},
+		}
+	},
+	func(x *rows) driver.Rows {
+		return struct {
+			*rows
+			rowsColumnTypeDatabaseTypeName
+			rowsColumnTypeNullable
+			rowsColumnTypePrecisionScale
+		}{
+			x,
+			rowsColumnTypeDatabaseTypeName{x},
+			rowsColumnTypeNullable{x},
+			rowsColumnTypePrecisionScale{x},
+		}
+	},
+	func(x *rows) driver.Rows {
+		return struct {
+			*rows
+			rowsNextResultSet
+			rowsColumnTypeDatabaseTypeName
+			rowsColumnTypeNullable
+			rowsColumnTypePrecisionScale
+		}{
+			x,
+			rowsNextResultSet{x},
+			rowsColumnTypeDatabaseTypeName{x},
+			rowsColumnTypeNullable{x},
+			rowsColumnTypePrecisionScale{x},
+		}
+	},
+	func(x *rows) driver.Rows {
+		return struct {
+			*rows
+			rowsColumnTypeLength
+			rowsColumnTypeNullable
+			rowsColumnTypePrecisionScale
+		}{
+			x,
+			rowsColumnTypeLength{x},
+			rowsColumnTypeNullable{x},
+			rowsColumnTypePrecisionScale{x},
+		}
+	},
+	func(x *rows) driver.Rows {
+		return struct {
+			*rows
+			rowsNextResultSet
+			rowsColumnTypeLength
+			rowsColumnTypeNullable
+			rowsColumnTypePrecisionScale
+		}{
+			x,
+			rowsNextResultSet{x},
+			rowsColumnTypeLength{x},
+			rowsColumnTypeNullable{x},
+			rowsColumnTypePrecisionScale{x},
+		}
+	},
+	func(x *rows) driver.Rows {
+		return struct {
+			*rows
+			rowsColumnTypeDatabaseTypeName
+			rowsColumnTypeLength
+			rowsColumnTypeNullable
+			rowsColumnTypePrecisionScale
+		}{
+			x,
+			rowsColumnTypeDatabaseTypeName{x},
+			rowsColumnTypeLength{x},
+			rowsColumnTypeNullable{x},
+			rowsColumnTypePrecisionScale{x},
+		}
+	},
+	func(x *rows) driver.Rows {
+		return struct {
+			*rows
+			rowsNextResultSet
+			rowsColumnTypeDatabaseTypeName
+			rowsColumnTypeLength
+			rowsColumnTypeNullable
+			rowsColumnTypePrecisionScale
+		}{
+			x,
+			rowsNextResultSet{x},
+			rowsColumnTypeDatabaseTypeName{x},
+			rowsColumnTypeLength{x},
+			rowsColumnTypeNullable{x},
+			rowsColumnTypePrecisionScale{x},
+		}
+	},
+	func(x *rows) driver.Rows {
+		return struct {
+			*rows
+			rowsColumnTypeScanType
+		}{
+			x,
+			rowsColumnTypeScanType{x},
+		}
+	},
+	func(x *rows) driver.Rows {
+		return struct {
+			*rows
+			rowsNextResultSet
+			rowsColumnTypeScanType
+		}{
+			x,
+			rowsNextResultSet{x},
+			rowsColumnTypeScanType{x},
+		}
+	},
+	func(x *rows) driver.Rows {
+		return struct {
+			*rows
+			rowsColumnTypeDatabaseTypeName
+			rowsColumnTypeScanType
+		}{
+			x,
+			rowsColumnTypeDatabaseTypeName{x},
+			rowsColumnTypeScanType{x},
+		}
+	},
+	func(x *rows) driver.Rows {
+		return struct {
+			*rows
+			rowsNextResultSet
+			rowsColumnTypeDatabaseTypeName
+			rowsColumnTypeScanType
+		}{
+			x,
+			rowsNextResultSet{x},
+			rowsColumnTypeDatabaseTypeName{x},
+			rowsColumnTypeScanType{x},
+		}
+	},
+	func(x *rows) driver.Rows {
+		return struct {
+			*rows
+			rowsColumnTypeLength
+			rowsColumnTypeScanType
+		}{
+			x,
+			rowsColumnTypeLength{x},
+			rowsColumnTypeScanType{x},
+		}
+	},
+	func(x *rows) driver.Rows {
+		return struct {
+			*rows
+			rowsNextResultSet
+			rowsColumnTypeLength
+			rowsColumnTypeScanType
+		}{
+			x,
+			rowsNextResultSet{x},
+			rowsColumnTypeLength{x},
+			rowsColumnTypeScanType{x},
+		}
+	},
+	func(x *rows) driver.Rows {
+		return struct {
+			*rows
+			rowsColumnTypeDatabaseTypeName
+			rowsColumnTypeLength
+			rowsColumnTypeScanType
+		}{
+			x,
+			rowsColumnTypeDatabaseTypeName{x},
+			rowsColumnTypeLength{x},
+			rowsColumnTypeScanType{x},
+		}
+	},
+	func(x *rows) driver.Rows {
+		return struct {
+			*rows
+			rowsNextResultSet
+			rowsColumnTypeDatabaseTypeName
+			rowsColumnTypeLength
+			rowsColumnTypeScanType
+		}{
+			x,
+			rowsNextResultSet{x},
+			rowsColumnTypeDatabaseTypeName{x},
+			rowsColumnTypeLength{x},
+			rowsColumnTypeScanType{x},
+		}
+	},
+	func(x *rows) driver.Rows {
+		return struct {
+			*rows
+			rowsColumnTypeNullable
+			rowsColumnTypeScanType
+		}{
+			x,
+			rowsColumnTypeNullable{x},
+			rowsColumnTypeScanType{x},
+		}
+	},
+	func(x *rows) driver.Rows {
+		return struct {
+			*rows
+			rowsNextResultSet
+			rowsColumnTypeNullable
+			rowsColumnTypeScanType
+		}{
+			x,
+			rowsNextResultSet{x},
+			rowsColumnTypeNullable{x},
+			rowsColumnTypeScanType{x},
+		}
+	},
+	func(x *rows) driver.Rows {
+		return struct {
+			*rows
+			rowsColumnTypeDatabaseTypeName
+			rowsColumnTypeNullable
+			rowsColumnTypeScanType
+		}{
+			x,
+			rowsColumnTypeDatabaseTypeName{x},
+			rowsColumnTypeNullable{x},
+			rowsColumnTypeScanType{x},
+		}
+	},
+	func(x *rows) driver.Rows {
+		return struct {
+			*rows
+			rowsNextResultSet
+			rowsColumnTypeDatabaseTypeName
+			rowsColumnTypeNullable
+			rowsColumnTypeScanType
+		}{
+			x,
+			rowsNextResultSet{x},
+			rowsColumnTypeDatabaseTypeName{x},
+			rowsColumnTypeNullable{x},
+			rowsColumnTypeScanType{x},
+		}
+	},
+	func(x *rows) driver.Rows {
+		return struct {
+			*rows
+			rowsColumnTypeLength
+			rowsColumnTypeNullable
+			rowsColumnTypeScanType
+		}{
+			x,
+			rowsColumnTypeLength{x},
+			rowsColumnTypeNullable{x},
+			rowsColumnTypeScanType{x},
+		}
+	},
+	func(x *rows) driver.Rows {
+		return struct {
+			*rows
+			rowsNextResultSet
+			rowsColumnTypeLength
+			rowsColumnTypeNullable
+			rowsColumnTypeScanType
+		}{
+			x,
+			rowsNextResultSet{x},
+			rowsColumnTypeLength{x},
+			rowsColumnTypeNullable{x},
+			rowsColumnTypeScanType{x},
+		}
+	},
+	func(x *rows) driver.Rows {
+		return struct {
+			*rows
+			rowsColumnTypeDatabaseTypeName
+			rowsColumnTypeLength
+			rowsColumnTypeNullable
+			rowsColumnTypeScanType
+		}{
+			x,
+			rowsColumnTypeDatabaseTypeName{x},
+			rowsColumnTypeLength{x},
+			rowsColumnTypeNullable{x},
+			rowsColumnTypeScanType{x},
+		}
+	},
+	func(x *rows) driver.Rows {
+		return struct {
+			*rows
+			rowsNextResultSet
+			rowsColumnTypeDatabaseTypeName
+			rowsColumnTypeLength
+			rowsColumnTypeNullable
+			rowsColumnTypeScanType
+		}{
+			x,
+			rowsNextResultSet{x},
+			rowsColumnTypeDatabaseTypeName{x},
+			rowsColumnTypeLength{x},
+			rowsColumnTypeNullable{x},
+			rowsColumnTypeScanType{x},
+		}
+	},
+	func(x *rows) driver.Rows {
+		return struct {
+			*rows
+			rowsColumnTypePrecisionScale
+			rowsColumnTypeScanType
+		}{
+			x,
+			rowsColumnTypePrecisionScale{x},
+			rowsColumnTypeScanType{x},
+		}
+	},
+	func(x *rows) driver.Rows {
+		return struct {
+			*rows
+			rowsNextResultSet
+			rowsColumnTypePrecisionScale
+			rowsColumnTypeScanType
+		}{
+			x,
+			rowsNextResultSet{x},
+			rowsColumnTypePrecisionScale{x},
+			rowsColumnTypeScanType{x},
+		}
+	},
+	func(x *rows) driver.Rows {
+		return struct {
+			*rows
+			rowsColumnTypeDatabaseTypeName
+			rowsColumnTypePrecisionScale
+			rowsColumnTypeScanType
+		}{
+			x,
+			rowsColumnTypeDatabaseTypeName{x},
+			rowsColumnTypePrecisionScale{x},
+			rowsColumnTypeScanType{x},
+		}
+	},
+	func(x *rows) driver.Rows {
+		return struct {
+			*rows
+			rowsNextResultSet
+			rowsColumnTypeDatabaseTypeName
+			rowsColumnTypePrecisionScale
+			rowsColumnTypeScanType
+		}{
+			x,
+			rowsNextResultSet{x},
+			rowsColumnTypeDatabaseTypeName{x},
+			rowsColumnTypePrecisionScale{x},
+			rowsColumnTypeScanType{x},
+		}
+	},
+	func(x *rows) driver.Rows {
+		return struct {
+			*rows
+			rowsColumnTypeLength
+			rowsColumnTypePrecisionScale
+			rowsColumnTypeScanType
+		}{
+			x,
+			rowsColumnTypeLength{x},
+			rowsColumnTypePrecisionScale{x},
+			rowsColumnTypeScanType{x},
+		}
+	},
+	func(x *rows) driver.Rows {
+		return struct {
+			*rows
+			rowsNextResultSet
+			rowsColumnTypeLength
+			rowsColumnTypePrecisionScale
+			rowsColumnTypeScanType
+		}{
+			x,
+			rowsNextResultSet{x},
+			rowsColumnTypeLength{x},
+			rowsColumnTypePrecisionScale{x},
+			rowsColumnTypeScanType{x},
+		}
+	},
+	func(x *rows) driver.Rows {
+		return struct {
+			*rows
+			rowsColumnTypeDatabaseTypeName
+			rowsColumnTypeLength
+			rowsColumnTypePrecisionScale
+			rowsColumnTypeScanType
+		}{
+			x,
+			rowsColumnTypeDatabaseTypeName{x},
+			rowsColumnTypeLength{x},
+			rowsColumnTypePrecisionScale{x},
+			rowsColumnTypeScanType{x},
+		}
+	},
+	func(x *rows) driver.Rows {
+		return struct {
+			*rows
+			rowsNextResultSet
+			rowsColumnTypeDatabaseTypeName
+			rowsColumnTypeLength
+			rowsColumnTypePrecisionScale
+			rowsColumnTypeScanType
+		}{
+			x,
+			rowsNextResultSet{x},
+			rowsColumnTypeDatabaseTypeName{x},
+			rowsColumnTypeLength{x},
+			rowsColumnTypePrecisionScale{x},
+			rowsColumnTypeScanType{x},
+		}
+	},
+	func(x *rows) driver.Rows {
+		return struct {
+			*rows
+			rowsColumnTypeNullable
+			rowsColumnTypePrecisionScale
+			rowsColumnTypeScanType
+		}{
+			x,
+			rowsColumnTypeNullable{x},
+			rowsColumnTypePrecisionScale{x},
+			rowsColumnTypeScanType{x},
+		}
+	},
+	func(x *rows) driver.Rows {
+		return struct {
+			*rows
+			rowsNextResultSet
+			rowsColumnTypeNullable
+			rowsColumnTypePrecisionScale
+			rowsColumnTypeScanType
+		}{
+			x,
+			rowsNextResultSet{x},
+			rowsColumnTypeNullable{x},
+			rowsColumnTypePrecisionScale{x},
+			rowsColumnTypeScanType{x},
+		}
+	},
+	func(x *rows) driver.Rows {
+		return struct {
+			*rows
+			rowsColumnTypeDatabaseTypeName
+			rowsColumnTypeNullable
+			rowsColumnTypePrecisionScale
+			rowsColumnTypeScanType
+		}{
+			x,
+			rowsColumnTypeDatabaseTypeName{x},
+			rowsColumnTypeNullable{x},
+			rowsColumnTypePrecisionScale{x},
+			rowsColumnTypeScanType{x},
+		}
+	},
+	func(x *rows) driver.Rows {
+		return struct {
+			*rows
+			rowsNextResultSet
+			rowsColumnTypeDatabaseTypeName
+			rowsColumnTypeNullable
+			rowsColumnTypePrecisionScale
+			rowsColumnTypeScanType
+		}{
+			x,
+			rowsNextResultSet{x},
+			rowsColumnTypeDatabaseTypeName{x},
+			rowsColumnTypeNullable{x},
+			rowsColumnTypePrecisionScale{x},
+			rowsColumnTypeScanType{x},
+		}
+	},
+	func(x *rows) driver.Rows {
+		return struct {
+			*rows
+			rowsColumnTypeLength
+			rowsColumnTypeNullable
+			rowsColumnTypePrecisionScale
+			rowsColumnTypeScanType
+		}{
+			x,
+			rowsColumnTypeLength{x},
+			rowsColumnTypeNullable{x},
+			rowsColumnTypePrecisionScale{x},
+			rowsColumnTypeScanType{x},
+		}
+	},
+	func(x *rows) driver.Rows {
+		return struct {
+			*rows
+			rowsNextResultSet
+			rowsColumnTypeLength
+			rowsColumnTypeNullable
+			rowsColumnTypePrecisionScale
+			rowsColumnTypeScanType
+		}{
+			x,
+			rowsNextResultSet{x},
+			rowsColumnTypeLength{x},
+			rowsColumnTypeNullable{x},
+			rowsColumnTypePrecisionScale{x},
+			rowsColumnTypeScanType{x},
+		}
+	},
+	func(x *rows) driver.Rows {
+		return struct {
+			*rows
+			rowsColumnTypeDatabaseTypeName
+			rowsColumnTypeLength
+			rowsColumnTypeNullable
+			rowsColumnTypePrecisionScale
+			rowsColumnTypeScanType
+		}{
+			x,
+			rowsColumnTypeDatabaseTypeName{x},
+			rowsColumnTypeLength{x},
+			rowsColumnTypeNullable{x},
+			rowsColumnTypePrecisionScale{x},
+			rowsColumnTypeScanType{x},
+		}
+	},
+	func(x *rows) driver.Rows {
+		return struct {
+			*rows
+			rowsNextResultSet
+			rowsColumnTypeDatabaseTypeName
+			rowsColumnTypeLength
+			rowsColumnTypeNullable
+			rowsColumnTypePrecisionScale
+			rowsColumnTypeScanType
+		}{
+			x,
+			rowsNextResultSet{x},
+			rowsColumnTypeDatabaseTypeName{x},
+			rowsColumnTypeLength{x},
+			rowsColumnTypeNullable{x},
+			rowsColumnTypePrecisionScale{x},
+			rowsColumnTypeScanType{x},
+		}
+	},
+}
@@ -5,6 +5,29 @@ import (
 	"database/sql/driver"
 )
 
+// StmtUnwrapper must be used by any middleware that returns its own
+// driver.Stmt from ConnPrepareContext. Unwrap should return the original
+// driver.Stmt the middleware received.
+//
+// sqlmw needs to retrieve the original driver.Stmt in order to find the
+// optional methods it supports, like driver.NamedValueChecker and
+// driver.ColumnConverter. The custom driver.Stmt only needs to implement
+// driver.Stmt, driver.StmtExecContext and driver.StmtQueryContext.
+type StmtUnwrapper interface {
+	Unwrap() driver.Stmt
+}
+
+// unwrapStmt returns the driver.Stmt of the database driver.
+func unwrapStmt(stmt driver.Stmt) driver.Stmt {
+	for {
+		u, ok := stmt.(StmtUnwrapper)
+		if !ok {
+			return stmt
+		}
+		stmt = u.Unwrap()
+	}
+}
+
 type wrappedStmt struct {
 	intr   Interceptor
 	ctx    context.Context
@@ -47,6 +70,7 @@ func (s wrappedStmt) Query(args []driver.Value) (rows driver.Rows, err error) {
 
 func (s wrappedStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (res driver.Result, err error) {
 	wrappedParent := wrappedParentStmt{Stmt: s.parent}
+	ctx = s.conn.withConn(ctx)
 	res, err = s.intr.StmtExecContext(ctx, wrappedParent, s.query, args)
 	if err != nil {
 		return nil, err
@@ -56,7 +80,7 @@ func (s wrappedStmt) ExecContext(ctx context.Context, args []driver.NamedValue)
 
 func (s wrappedStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (rows driver.Rows, err error) {
 	wrappedParent := wrappedParentStmt{Stmt: s.parent}
-	ctx, rows, err = s.intr.StmtQueryContext(ctx, wrappedParent, s.query, args)
+	ctx, rows, err = s.intr.StmtQueryContext(s.conn.withConn(ctx), wrappedParent, s.query, args)
 	if err != nil {
 		return nil, err
 	}
@@ -64,7 +88,7 @@ func (s wrappedStmt) QueryContext(ctx context.Context, args []driver.NamedValue)
 }
 
 func (s wrappedStmt) ColumnConverter(idx int) driver.ValueConverter {
-	if converter, ok := s.parent.(driver.ColumnConverter); ok {
+	if converter, ok := unwrapStmt(s.parent).(driver.ColumnConverter); ok {
 		return converter.ColumnConverter(idx)
 	}
 
@@ -5,7 +5,7 @@ import "database/sql/driver"
 var _ driver.NamedValueChecker = wrappedStmt{}
 
 func (s wrappedStmt) CheckNamedValue(v *driver.NamedValue) error {
-	if checker, ok := s.parent.(driver.NamedValueChecker); ok {
+	if checker, ok := unwrapStmt(s.parent).(driver.NamedValueChecker); ok {
 		return checker.CheckNamedValue(v)
 	}
 
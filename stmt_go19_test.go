@@ -1,6 +1,7 @@
 package sqlmw
 
 import (
+	"context"
 	"database/sql"
 	"database/sql/driver"
 	"reflect"
@@ -169,3 +170,65 @@ func TestWrappedStmt_CheckNamedValue(t *testing.T) {
 		})
 	}
 }
+
+// customStmt is a driver.Stmt returned by a middleware.
+type customStmt struct {
+	driver.Stmt
+}
+
+func (s customStmt) Unwrap() driver.Stmt {
+	return s.Stmt
+}
+
+func (s customStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	return s.Stmt.(driver.StmtQueryContext).QueryContext(ctx, args)
+}
+
+func (s customStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	return s.Stmt.(driver.StmtExecContext).ExecContext(ctx, args)
+}
+
+type customStmtInterceptor struct {
+	NullInterceptor
+}
+
+func (customStmtInterceptor) ConnPrepareContext(ctx context.Context, conn driver.ConnPrepareContext, query string) (context.Context, driver.Stmt, error) {
+	stmt, err := conn.PrepareContext(ctx, query)
+	if err != nil {
+		return ctx, nil, err
+	}
+	return ctx, customStmt{stmt}, nil
+}
+
+func TestStmtUnwrapper(t *testing.T) {
+	fd := &fakeDriver{
+		conn: &fakeConnWithoutCheckNamedValue{
+			fakeConn: fakeConn{
+				stmt: &fakeStmtWithCheckNamedValue{},
+			},
+		},
+	}
+	driverName := driverName(t)
+	sql.Register(driverName, Driver(fd, customStmtInterceptor{}))
+	db, err := sql.Open(driverName, "dummy")
+	if err != nil {
+		t.Fatalf("Failed to open: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := db.Close(); err != nil {
+			t.Errorf("Failed to close db: %v", err)
+		}
+	})
+
+	stmt, err := db.Prepare("SELECT foo FROM bar Where 1 = ?")
+	if err != nil {
+		t.Fatalf("Failed to prepare: %v", err)
+	}
+	if _, err := stmt.Query(1); err != nil {
+		t.Fatalf("Failed to query: %v", err)
+	}
+
+	if !fd.conn.(*fakeConnWithoutCheckNamedValue).stmt.(*fakeStmtWithCheckNamedValue).called {
+		t.Error("CheckNamedValue of the driver statement was not called")
+	}
+}
@@ -0,0 +1,853 @@
+// Package tenant provides an sqlmw.Interceptor isolating the rows of the
+// tenants of a shared database. The tenant of a statement is the one of its
+// context, see WithTenant, and the interceptor fails closed: a statement on a
+// tenant table run without a tenant is rejected with ErrNoTenant.
+//
+// In the Enforce mode, a statement on a tenant table must compare the tenant
+// column to the tenant of its context, with a literal or an argument:
+//
+//	SELECT * FROM invoices WHERE tenant_id = $1 AND id = $2
+//
+// and an INSERT must list the tenant column and give it the tenant in every
+// row. Every tenant table a query block reads or writes must be constrained
+// by such a comparison, as a top-level AND conjunct of the WHERE clause of
+// the block or of the ON clause of its join, qualified with the alias of
+// the table or unqualified:
+//
+//	SELECT * FROM invoices i JOIN users u ON u.id = i.user_id AND u.tenant_id = $1
+//	WHERE i.tenant_id = $1
+//
+// Other statements are rejected with ErrUnconstrained. The check is a
+// shallow one, meant to catch a forgotten or weakened predicate rather than
+// to stand against a hostile statement.
+//
+// In the SetSession mode, the tenant is instead set as a setting of the
+// session, for the row level security policies of PostgreSQL to use:
+//
+//	CREATE POLICY isolation ON invoices
+//		USING (tenant_id = current_setting('app.tenant_id')::uuid);
+//
+// The setting is updated before a statement or a transaction whenever the
+// tenant differs from the one of the previous statement of the connection,
+// and reset when the context has no tenant.
+//
+// Statements on the tables of every tenant, like migrations, are run with a
+// context returned by Unscoped.
+package tenant
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ngrok/sqlmw"
+	"github.com/ngrok/sqlmw/sqlinfo"
+)
+
+const (
+	defaultColumn       = "tenant_id"
+	defaultSetting      = "app.tenant_id"
+	defaultSessionQuery = "SELECT set_config($1, $2, false)"
+)
+
+var (
+	// ErrNoTenant is returned for the statements on a tenant table run
+	// without a tenant.
+	ErrNoTenant = errors.New("tenant: no tenant in context")
+
+	// ErrUnconstrained is returned in the Enforce mode for the statements on
+	// a tenant table that are not constrained to the tenant of their
+	// context.
+	ErrUnconstrained = errors.New("tenant: statement is not constrained to the tenant")
+)
+
+// Mode is how an Interceptor isolates tenants.
+type Mode int
+
+const (
+	// Enforce rejects the statements that are not constrained to their
+	// tenant.
+	Enforce Mode = iota
+	// SetSession sets the tenant as a setting of the session, for row level
+	// security policies.
+	SetSession
+)
+
+// Config configures an Interceptor.
+type Config struct {
+	// Mode is how tenants are isolated. Defaults to Enforce.
+	Mode Mode
+
+	// Tables are the tenant tables, named as sqlinfo.Parse reports them:
+	// without their schema, in lower case unless quoted.
+	Tables []string
+
+	// Column is the tenant column of the tables. Defaults to "tenant_id".
+	Column string
+
+	// Setting is the session setting holding the tenant in the SetSession
+	// mode. Defaults to "app.tenant_id".
+	Setting string
+
+	// SessionQuery sets the setting in the SetSession mode, with the setting
+	// and the tenant as arguments. Defaults to
+	// "SELECT set_config($1, $2, false)".
+	SessionQuery string
+
+	// Dialect is used to describe statements that are not described by an
+	// sqlinfo.Interceptor. Defaults to sqlinfo.Generic.
+	Dialect sqlinfo.Dialect
+}
+
+type (
+	tenantKey   struct{}
+	unscopedKey struct{}
+)
+
+// WithTenant returns a context running statements for tenant.
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// FromContext returns the tenant of ctx, if any.
+func FromContext(ctx context.Context) (string, bool) {
+	tenant, ok := ctx.Value(tenantKey{}).(string)
+	return tenant, ok
+}
+
+// Unscoped returns a context whose statements are not checked. In the
+// SetSession mode, they run without a tenant setting.
+func Unscoped(ctx context.Context) context.Context {
+	return context.WithValue(ctx, unscopedKey{}, true)
+}
+
+// IsUnscoped reports whether ctx was returned by Unscoped.
+func IsUnscoped(ctx context.Context) bool {
+	unscoped, _ := ctx.Value(unscopedKey{}).(bool)
+	return unscoped
+}
+
+// Interceptor isolates tenants.
+type Interceptor struct {
+	sqlmw.NullInterceptor
+
+	cfg    Config
+	tables map[string]bool
+}
+
+// New returns an Interceptor for the supplied configuration.
+func New(cfg Config) *Interceptor {
+	if cfg.Column == "" {
+		cfg.Column = defaultColumn
+	}
+	if cfg.Setting == "" {
+		cfg.Setting = defaultSetting
+	}
+	if cfg.SessionQuery == "" {
+		cfg.SessionQuery = defaultSessionQuery
+	}
+	in := &Interceptor{cfg: cfg, tables: make(map[string]bool)}
+	for _, t := range cfg.Tables {
+		in.tables[t] = true
+	}
+	return in
+}
+
+func (in *Interceptor) ConnBeginTx(ctx context.Context, conn driver.ConnBeginTx, opts driver.TxOptions) (context.Context, driver.Tx, error) {
+	if in.cfg.Mode == SetSession {
+		// set before BEGIN, a setting changed within the transaction would
+		// be lost on rollback
+		if err := in.setSession(ctx); err != nil {
+			return ctx, nil, err
+		}
+	}
+	tx, err := conn.BeginTx(ctx, opts)
+	return ctx, tx, err
+}
+
+func (in *Interceptor) TxRollback(ctx context.Context, tx driver.Tx) error {
+	if in.cfg.Mode == SetSession {
+		if c, ok := sqlmw.ConnFromContext(ctx); ok {
+			c.SetValue(sessionKey{}, session{})
+		}
+	}
+	return tx.Rollback()
+}
+
+func (in *Interceptor) ConnExecContext(ctx context.Context, conn driver.ExecerContext, query string, args []driver.NamedValue) (driver.Result, error) {
+	if err := in.check(ctx, query, args); err != nil {
+		return nil, err
+	}
+	return conn.ExecContext(ctx, query, args)
+}
+
+func (in *Interceptor) ConnQueryContext(ctx context.Context, conn driver.QueryerContext, query string, args []driver.NamedValue) (context.Context, driver.Rows, error) {
+	if err := in.check(ctx, query, args); err != nil {
+		return ctx, nil, err
+	}
+	rows, err := conn.QueryContext(ctx, query, args)
+	return ctx, rows, err
+}
+
+func (in *Interceptor) StmtExecContext(ctx context.Context, stmt driver.StmtExecContext, query string, args []driver.NamedValue) (driver.Result, error) {
+	if err := in.check(ctx, query, args); err != nil {
+		return nil, err
+	}
+	return stmt.ExecContext(ctx, args)
+}
+
+func (in *Interceptor) StmtQueryContext(ctx context.Context, stmt driver.StmtQueryContext, query string, args []driver.NamedValue) (context.Context, driver.Rows, error) {
+	if err := in.check(ctx, query, args); err != nil {
+		return ctx, nil, err
+	}
+	rows, err := stmt.QueryContext(ctx, args)
+	return ctx, rows, err
+}
+
+// check returns the error to fail a statement with, after setting the
+// session of its connection in the SetSession mode.
+func (in *Interceptor) check(ctx context.Context, query string, args []driver.NamedValue) error {
+	if in.cfg.Mode == SetSession {
+		if err := in.setSession(ctx); err != nil {
+			return err
+		}
+	}
+	if IsUnscoped(ctx) {
+		return nil
+	}
+
+	st, ok := sqlinfo.FromContext(ctx)
+	if !ok {
+		st = sqlinfo.Parse(in.cfg.Dialect, query)
+	}
+	scoped := false
+	for _, t := range st.Tables {
+		if in.tables[t] {
+			scoped = true
+			break
+		}
+	}
+	if !scoped {
+		return nil
+	}
+
+	tenant, ok := FromContext(ctx)
+	if !ok {
+		return ErrNoTenant
+	}
+	if in.cfg.Mode == SetSession {
+		return nil
+	}
+	return in.constrained(st, query, args, tenant)
+}
+
+type sessionKey struct{}
+
+// session is the setting of a connection. The zero value is an unknown
+// setting, the one of a connection whose transaction was rolled back.
+type session struct {
+	tenant string
+	known  bool
+}
+
+// setSession sets the tenant of ctx on its connection, unless it is already
+// set.
+func (in *Interceptor) setSession(ctx context.Context) error {
+	c, ok := sqlmw.ConnFromContext(ctx)
+	if !ok {
+		return errors.New("tenant: no connection in context")
+	}
+	tenant, ok := FromContext(ctx)
+	if !ok || IsUnscoped(ctx) {
+		tenant = ""
+	}
+
+	// a new connection has no setting
+	current := session{known: true}
+	if v, ok := c.Value(sessionKey{}).(session); ok {
+		current = v
+	}
+	if current.known && current.tenant == tenant {
+		return nil
+	}
+
+	_, err := c.ExecContext(ctx, in.cfg.SessionQuery, []driver.NamedValue{
+		{Ordinal: 1, Value: in.cfg.Setting},
+		{Ordinal: 2, Value: tenant},
+	})
+	if err != nil {
+		c.SetValue(sessionKey{}, session{})
+		if err == driver.ErrSkip {
+			// database/sql compares it as is
+			return err
+		}
+		return fmt.Errorf("tenant: setting %s: %w", in.cfg.Setting, err)
+	}
+	c.SetValue(sessionKey{}, session{tenant: tenant, known: true})
+	return nil
+}
+
+// constrained checks that every tenant table query reads or writes is
+// constrained to tenant, and that query never compares the tenant column to
+// another tenant.
+func (in *Interceptor) constrained(st *sqlinfo.Statement, query string, args []driver.NamedValue, tenant string) error {
+	var toks []sqlinfo.Token
+	end := false
+	l := sqlinfo.NewLexer(in.cfg.Dialect, query)
+	for {
+		tok, ok := l.Next()
+		if !ok {
+			break
+		}
+		if tok.Kind == sqlinfo.Comment {
+			continue
+		}
+		if isPunct(tok, ";") {
+			// a single statement is checked
+			end = true
+			continue
+		}
+		if end {
+			return ErrUnconstrained
+		}
+		toks = append(toks, tok)
+	}
+	values := resolve(toks, args)
+
+	for i := range toks {
+		if v := in.comparedValue(toks, i); v >= 0 && (values[v] == nil || *values[v] != tenant) {
+			return ErrUnconstrained
+		}
+	}
+
+	c := &checker{in: in, toks: toks, values: values, tenant: tenant}
+	c.query(0, len(toks))
+	if c.err != nil {
+		return c.err
+	}
+	if st.Verb == "INSERT" {
+		return in.insertConstrained(toks, values, tenant, c.found)
+	}
+	if c.refs == 0 {
+		// the tenant tables are used in a way that is not checked
+		return ErrUnconstrained
+	}
+	return nil
+}
+
+// comparedValue returns the index of the value the tenant column at toks[i]
+// is compared or assigned to, as in "column = value" or, with a qualified
+// column, "value = t.column", or -1.
+func (in *Interceptor) comparedValue(toks []sqlinfo.Token, i int) int {
+	if !in.isColumn(toks, i) {
+		return -1
+	}
+	if i+2 < len(toks) && isPunct(toks[i+1], "=") && isValue(toks[i+2]) {
+		return i + 2
+	}
+	j := i
+	for j >= 2 && isPunct(toks[j-1], ".") && isName(toks[j-2]) {
+		j -= 2
+	}
+	if j >= 2 && isPunct(toks[j-1], "=") && isValue(toks[j-2]) {
+		return j - 2
+	}
+	return -1
+}
+
+// insertConstrained checks that an INSERT lists the tenant column and gives
+// it tenant in every row of its VALUES, or selects its rows from the tenant
+// when it has none.
+func (in *Interceptor) insertConstrained(toks []sqlinfo.Token, values []*string, tenant string, found bool) error {
+	column, depth := -1, 0
+	for i := 0; i < len(toks); i++ {
+		tok := toks[i]
+		switch {
+		case isPunct(tok, "("):
+			depth++
+			if depth == 1 && column < 0 {
+				column = in.columnIndex(toks[i+1:])
+				if column < 0 {
+					return ErrUnconstrained
+				}
+			}
+		case isPunct(tok, ")"):
+			depth--
+		case depth == 0 && (tok.Is("values") || tok.Is("value")):
+			if column < 0 {
+				return ErrUnconstrained
+			}
+			return valuesConstrained(toks[i+1:], values[i+1:], column, tenant)
+		case depth == 0 && (tok.Is("select") || tok.Is("with")):
+			if column < 0 || !found {
+				return ErrUnconstrained
+			}
+			return nil
+		}
+	}
+	return ErrUnconstrained
+}
+
+// columnIndex returns the index of the tenant column in the column list
+// starting at toks, or -1.
+func (in *Interceptor) columnIndex(toks []sqlinfo.Token) int {
+	n := 0
+	for i, tok := range toks {
+		switch {
+		case isPunct(tok, ")"):
+			return -1
+		case isPunct(tok, ","):
+			n++
+		case in.isColumn(toks, i):
+			return n
+		}
+	}
+	return -1
+}
+
+// valuesConstrained checks the rows of a VALUES clause starting at toks.
+func valuesConstrained(toks []sqlinfo.Token, values []*string, column int, tenant string) error {
+	rows, depth, n, start, ok := 0, 0, 0, 0, false
+	for i, tok := range toks {
+		if depth == 0 {
+			switch {
+			case isPunct(tok, "("):
+				depth, n, start, ok = 1, 0, i+1, false
+			case isPunct(tok, ","):
+			case rows == 0:
+				return ErrUnconstrained
+			default:
+				// ON CONFLICT, RETURNING...
+				return nil
+			}
+			continue
+		}
+		switch {
+		case isPunct(tok, "("):
+			depth++
+		case isPunct(tok, ")") && depth > 1:
+			depth--
+		case depth == 1 && (isPunct(tok, ",") || isPunct(tok, ")")):
+			if n == column {
+				// the value must be a single literal or argument
+				ok = i == start+1 && values[start] != nil && *values[start] == tenant
+			}
+			n, start = n+1, i+1
+			if isPunct(tok, ")") {
+				if !ok {
+					return ErrUnconstrained
+				}
+				depth = 0
+				rows++
+			}
+		}
+	}
+	if rows == 0 {
+		return ErrUnconstrained
+	}
+	return nil
+}
+
+// isColumn reports whether toks[i] names the tenant column, as opposed to a
+// table or a function named alike.
+func (in *Interceptor) isColumn(toks []sqlinfo.Token, i int) bool {
+	if !isName(toks[i]) || toks[i].Ident() != in.cfg.Column {
+		return false
+	}
+	return i+1 == len(toks) || !(isPunct(toks[i+1], ".") || isPunct(toks[i+1], "("))
+}
+
+// resolve returns the values of the literal and argument tokens, nil for the
+// other tokens and the missing arguments.
+func resolve(toks []sqlinfo.Token, args []driver.NamedValue) []*string {
+	values := make([]*string, len(toks))
+	positional := 0
+	for i, tok := range toks {
+		var v string
+		switch tok.Kind {
+		case sqlinfo.Number:
+			v = tok.Text
+		case sqlinfo.String:
+			s, ok := unquote(tok.Text)
+			if !ok {
+				continue
+			}
+			v = s
+		case sqlinfo.Placeholder:
+			ordinal, name := tok.Placeholder()
+			if ordinal == 0 && name == "" {
+				positional++
+				ordinal = positional
+			}
+			arg, ok := lookup(args, ordinal, name)
+			if !ok {
+				continue
+			}
+			v = arg
+		default:
+			continue
+		}
+		values[i] = &v
+	}
+	return values
+}
+
+func lookup(args []driver.NamedValue, ordinal int, name string) (string, bool) {
+	for _, arg := range args {
+		if (name != "" && arg.Name == name) || (name == "" && arg.Ordinal == ordinal) {
+			switch v := arg.Value.(type) {
+			case nil:
+				return "", false
+			case []byte:
+				return string(v), true
+			default:
+				return fmt.Sprint(v), true
+			}
+		}
+	}
+	return "", false
+}
+
+// unquote returns the value of a plain single quoted string literal.
+func unquote(s string) (string, bool) {
+	if len(s) < 2 || s[0] != '\'' || s[len(s)-1] != '\'' || strings.IndexByte(s, '\\') >= 0 {
+		return "", false
+	}
+	return strings.Replace(s[1:len(s)-1], "''", "'", -1), true
+}
+
+// checker checks the query blocks of a statement: every tenant table a
+// block reads or writes must be constrained by a comparison of its tenant
+// column to the tenant that is a top-level AND conjunct of the WHERE clause
+// of the block, or of the ON clause of its join.
+type checker struct {
+	in     *Interceptor
+	toks   []sqlinfo.Token
+	values []*string
+	tenant string
+
+	refs  int  // the tenant tables referenced
+	found bool // whether a tenant conjunct was found
+	err   error
+}
+
+// reference is a table referenced by a query block.
+type reference struct {
+	// name is the alias of the table, or else its name.
+	name   string
+	tenant bool
+}
+
+// condition is a WHERE clause, or the ON clause of the reference ref.
+type condition struct {
+	from, to int
+	ref      int
+}
+
+// query checks the query toks[from:to], whose blocks are separated by set
+// operators.
+func (c *checker) query(from, to int) {
+	start := from
+	for i := from; i < to; i++ {
+		tok := c.toks[i]
+		switch {
+		case isPunct(tok, "("):
+			i = c.closing(i, to)
+		case tok.Is("union") || tok.Is("intersect") || tok.Is("except"):
+			c.block(start, i)
+			start = i + 1
+		}
+	}
+	c.block(start, to)
+}
+
+// group checks the queries nested in the expression toks[from:to].
+func (c *checker) group(from, to int) {
+	for i := from; i < to; i++ {
+		if isPunct(c.toks[i], "(") {
+			end := c.closing(i, to)
+			c.nested(i+1, end)
+			i = end
+		}
+	}
+}
+
+// nested checks the parenthesized toks[from:to], a query or an expression.
+func (c *checker) nested(from, to int) {
+	if from < to && (c.toks[from].Is("select") || c.toks[from].Is("with")) {
+		c.query(from, to)
+		return
+	}
+	c.group(from, to)
+}
+
+// closing returns the index of the parenthesis closing the one at i, or to.
+func (c *checker) closing(i, to int) int {
+	depth := 0
+	for ; i < to; i++ {
+		switch {
+		case isPunct(c.toks[i], "("):
+			depth++
+		case isPunct(c.toks[i], ")"):
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return to
+}
+
+// block checks the query block toks[from:to].
+func (c *checker) block(from, to int) {
+	var (
+		refs   []reference
+		conds  []condition
+		clause string
+		expect bool // a table reference is expected
+		start  = -1 // of the current condition
+		owner  int  // of the current condition
+	)
+	closeCondition := func(i int) {
+		if start >= 0 {
+			conds = append(conds, condition{start, i, owner})
+			start = -1
+		}
+	}
+	for i := from; i < to; i++ {
+		tok := c.toks[i]
+		if isPunct(tok, "(") {
+			end := c.closing(i, to)
+			c.nested(i+1, end)
+			expect = false
+			i = end
+			continue
+		}
+		if isPunct(tok, ",") {
+			if clause == "on" {
+				closeCondition(i)
+				clause = "from"
+			}
+			expect = clause == "from" || clause == "update"
+			continue
+		}
+		if tok.Kind == sqlinfo.Word {
+			switch strings.ToLower(tok.Text) {
+			case "from", "join", "straight_join":
+				closeCondition(i)
+				clause, expect = "from", true
+				continue
+			case "update":
+				closeCondition(i)
+				clause, expect = "update", true
+				continue
+			case "using":
+				closeCondition(i)
+				if i+1 < to && isPunct(c.toks[i+1], "(") {
+					// the columns of a join
+					clause, expect = "using", false
+				} else {
+					clause, expect = "from", true
+				}
+				continue
+			case "on":
+				closeCondition(i)
+				if clause == "from" && len(refs) > 0 {
+					clause, start, owner = "on", i+1, len(refs)-1
+				} else {
+					// ON CONFLICT, ON DUPLICATE KEY UPDATE
+					clause = "other"
+				}
+				expect = false
+				continue
+			case "where":
+				closeCondition(i)
+				clause, start, owner, expect = "where", i+1, -1, false
+				continue
+			case "left", "right", "inner", "outer", "full", "cross", "natural":
+				closeCondition(i)
+				clause, expect = "from", false
+				continue
+			case "select", "set", "values", "value", "into", "insert", "delete",
+				"group", "order", "limit", "offset", "having", "window",
+				"returning", "for", "lock", "fetch":
+				closeCondition(i)
+				clause, expect = "other", false
+				continue
+			}
+		}
+		if expect && isName(tok) {
+			var ref reference
+			ref, i = c.reference(i, to)
+			refs = append(refs, ref)
+			expect = false
+		}
+	}
+	closeCondition(to)
+
+	var where []int
+	for _, cond := range conds {
+		if cond.ref < 0 {
+			where = append(where, c.tenantConjuncts(cond.from, cond.to)...)
+		}
+	}
+	for r, ref := range refs {
+		if !ref.tenant {
+			continue
+		}
+		c.refs++
+		constrained := c.constrains(where, ref)
+		for _, cond := range conds {
+			if cond.ref == r && c.constrains(c.tenantConjuncts(cond.from, cond.to), ref) {
+				constrained = true
+			}
+		}
+		if !constrained {
+			c.err = ErrUnconstrained
+		}
+	}
+}
+
+// reference reads the table reference starting at toks[i] and returns it
+// with the index of its last token.
+func (c *checker) reference(i, to int) (reference, int) {
+	for i+2 < to && isPunct(c.toks[i+1], ".") && isName(c.toks[i+2]) {
+		i += 2
+	}
+	if i+1 < to && isPunct(c.toks[i+1], "(") {
+		// a function
+		return reference{}, i
+	}
+	table := c.toks[i].Ident()
+	ref := reference{name: table, tenant: c.in.tables[table]}
+	j := i + 1
+	if j < to && c.toks[j].Is("as") {
+		j++
+	}
+	if j < to && isName(c.toks[j]) && !isKeyword(c.toks[j]) {
+		ref.name, i = c.toks[j].Ident(), j
+	}
+	return ref, i
+}
+
+// tenantConjuncts returns the indexes of the qualifiers of the tenant
+// conjuncts of the condition toks[from:to], -1 for the unqualified ones, or
+// none when the condition is a disjunction.
+func (c *checker) tenantConjuncts(from, to int) []int {
+	var qualifiers []int
+	depth, between, start := 0, false, from
+	for i := from; i <= to; i++ {
+		if i < to {
+			tok := c.toks[i]
+			switch {
+			case isPunct(tok, "("):
+				depth++
+				continue
+			case isPunct(tok, ")"):
+				depth--
+				continue
+			case depth > 0:
+				continue
+			case tok.Is("or"):
+				return nil
+			case tok.Is("between"):
+				between = true
+				continue
+			case !tok.Is("and"):
+				continue
+			case between:
+				between = false
+				continue
+			}
+		}
+		if q, ok := c.tenantConjunct(start, i); ok {
+			qualifiers = append(qualifiers, q)
+		}
+		start = i + 1
+	}
+	return qualifiers
+}
+
+// tenantConjunct reports whether toks[from:to] compares the tenant column
+// to the tenant, and returns the index of the qualifier of the column, or
+// -1 when it is unqualified.
+func (c *checker) tenantConjunct(from, to int) (int, bool) {
+	if to-from >= 2 && isPunct(c.toks[from], "(") && c.closing(from, to) == to-1 {
+		qualifiers := c.tenantConjuncts(from+1, to-1)
+		if len(qualifiers) == 0 {
+			return 0, false
+		}
+		return qualifiers[0], true
+	}
+	for i := from; i < to; i++ {
+		v := c.in.comparedValue(c.toks[from:to], i-from)
+		if v < 0 {
+			continue
+		}
+		// the comparison must be the whole conjunct
+		column := i
+		first, last := from+v, column
+		if from+v > column {
+			first, last = column, from+v
+		}
+		qualifier := -1
+		if column >= 2 && isPunct(c.toks[column-1], ".") {
+			qualifier = column - 2
+			if first == column {
+				first = column - 2
+				for first >= 2 && isPunct(c.toks[first-1], ".") {
+					first -= 2
+				}
+			}
+		}
+		if first != from || last != to-1 {
+			return 0, false
+		}
+		c.found = true
+		return qualifier, true
+	}
+	return 0, false
+}
+
+// constrains reports whether one of the tenant conjuncts whose qualifiers
+// are at the given indexes applies to ref.
+func (c *checker) constrains(qualifiers []int, ref reference) bool {
+	for _, q := range qualifiers {
+		if q < 0 || c.toks[q].Ident() == ref.name {
+			return true
+		}
+	}
+	return false
+}
+
+// isKeyword reports whether tok ends a table reference rather than being
+// its alias.
+func isKeyword(tok sqlinfo.Token) bool {
+	if tok.Kind != sqlinfo.Word {
+		return false
+	}
+	switch strings.ToLower(tok.Text) {
+	case "where", "on", "using", "join", "straight_join", "left", "right",
+		"inner", "outer", "full", "cross", "natural", "set", "group",
+		"order", "limit", "offset", "having", "window", "returning", "union",
+		"intersect", "except", "for", "lock", "fetch", "values", "value",
+		"select", "from", "into", "use", "force", "ignore", "partition",
+		"tablesample":
+		return true
+	}
+	return false
+}
+
+func isPunct(tok sqlinfo.Token, text string) bool {
+	return tok.Kind == sqlinfo.Punct && tok.Text == text
+}
+
+func isName(tok sqlinfo.Token) bool {
+	return tok.Kind == sqlinfo.Word || tok.Kind == sqlinfo.QuotedIdent
+}
+
+func isValue(tok sqlinfo.Token) bool {
+	return tok.Kind == sqlinfo.Number || tok.Kind == sqlinfo.String || tok.Kind == sqlinfo.Placeholder
+}
@@ -0,0 +1,171 @@
+package tenant
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"reflect"
+	"testing"
+
+	"github.com/ngrok/sqlmw"
+	"github.com/ngrok/sqlmw/internal/fakedb"
+	"github.com/ngrok/sqlmw/sqlinfo"
+	"github.com/ngrok/sqlmw/sqlmwtest"
+)
+
+func TestEnforce(t *testing.T) {
+	in := New(Config{Tables: []string{"invoices", "users"}, Dialect: sqlinfo.PostgreSQL})
+	con := &fakedb.Connector{}
+	db := sql.OpenDB(sqlmw.Connector(con, in))
+	t.Cleanup(func() {
+		if err := db.Close(); err != nil {
+			t.Errorf("Failed to close db: %v", err)
+		}
+	})
+	ctx := WithTenant(context.Background(), "42")
+
+	tests := []struct {
+		ctx      context.Context
+		query    string
+		args     []interface{}
+		expected error
+	}{
+		{ctx, "SELECT * FROM invoices WHERE tenant_id = $1 AND id = $2", []interface{}{42, 7}, nil},
+		{ctx, "SELECT * FROM invoices i WHERE $1 = i.tenant_id", []interface{}{"42"}, nil},
+		{ctx, "SELECT * FROM invoices WHERE tenant_id = '42'", nil, nil},
+		{ctx, "SELECT * FROM invoices WHERE tenant_id = $1", []interface{}{43}, ErrUnconstrained},
+		{ctx, "SELECT * FROM invoices WHERE id = $1", []interface{}{7}, ErrUnconstrained},
+		{ctx, "SELECT * FROM invoices i JOIN users u ON u.tenant_id = i.tenant_id", nil, ErrUnconstrained},
+		{ctx, "SELECT * FROM invoices WHERE tenant_id = 42; DELETE FROM users", nil, ErrUnconstrained},
+		{ctx, "UPDATE users SET tenant_id = $1 WHERE tenant_id = $2", []interface{}{43, 42}, ErrUnconstrained},
+		{ctx, "UPDATE invoices SET tenant_id = $1", []interface{}{42}, ErrUnconstrained},
+		{ctx, "UPDATE invoices SET tenant_id = $1 WHERE tenant_id = $1", []interface{}{42}, nil},
+		{ctx, "SELECT tenant_id = 42 FROM invoices", nil, ErrUnconstrained},
+		{ctx, "SELECT * FROM invoices WHERE tenant_id = $1 OR 1 = 1", []interface{}{42}, ErrUnconstrained},
+		{ctx, "SELECT * FROM invoices WHERE tenant_id = $1 OR status = 'open'", []interface{}{42}, ErrUnconstrained},
+		{ctx, "SELECT * FROM invoices WHERE (tenant_id = $1 AND id = 2) AND status = 'open'", []interface{}{42}, nil},
+		{ctx, "SELECT * FROM invoices WHERE id BETWEEN 1 AND 9 AND tenant_id = 42", nil, nil},
+		{ctx, "SELECT * FROM orders o JOIN users u ON u.id = o.user_id WHERE o.tenant_id = $1", []interface{}{42}, ErrUnconstrained},
+		{ctx, "SELECT * FROM invoices i JOIN users u ON u.id = i.user_id WHERE i.tenant_id = $1", []interface{}{42}, ErrUnconstrained},
+		{ctx, "SELECT * FROM invoices i JOIN users u ON u.id = i.user_id AND u.tenant_id = $1 WHERE i.tenant_id = $1", []interface{}{42}, nil},
+		{ctx, "SELECT * FROM invoices i, users u WHERE u.tenant_id = 42 AND i.tenant_id = 42", nil, nil},
+		{ctx, "SELECT * FROM invoices WHERE tenant_id = 42 AND id IN (SELECT invoice_id FROM users)", nil, ErrUnconstrained},
+		{ctx, "SELECT * FROM invoices WHERE tenant_id = 42 UNION SELECT * FROM invoices", nil, ErrUnconstrained},
+		{ctx, "TRUNCATE invoices", nil, ErrUnconstrained},
+		{ctx, "INSERT INTO invoices (id, tenant_id) VALUES ($1, $2), (3, 42) RETURNING id", []interface{}{1, 42}, nil},
+		{ctx, "INSERT INTO invoices (id, tenant_id) VALUES ($1, $2), (3, 43)", []interface{}{1, 42}, ErrUnconstrained},
+		{ctx, "INSERT INTO invoices (id, tenant_id) VALUES ($1, lower($2))", []interface{}{1, 42}, ErrUnconstrained},
+		{ctx, "INSERT INTO invoices VALUES ($1, $2)", []interface{}{1, 42}, ErrUnconstrained},
+		{ctx, "INSERT INTO invoices (id, tenant_id) SELECT id, tenant_id FROM users WHERE tenant_id = 42", nil, nil},
+		{ctx, "SELECT * FROM teams", nil, nil},
+		{context.Background(), "SELECT * FROM teams", nil, nil},
+		{context.Background(), "SELECT * FROM invoices WHERE tenant_id = 42", nil, ErrNoTenant},
+		{Unscoped(context.Background()), "DELETE FROM invoices", nil, nil},
+	}
+	for _, test := range tests {
+		if _, err := db.ExecContext(test.ctx, test.query, test.args...); err != test.expected {
+			t.Errorf("Exec(%q) = %v, expected %v", test.query, err, test.expected)
+		}
+	}
+
+	stmt, err := db.PrepareContext(ctx, "DELETE FROM users WHERE id = $1 AND tenant_id = $2")
+	if err != nil {
+		t.Fatalf("Prepare failed: %v", err)
+	}
+	defer stmt.Close()
+	if _, err := stmt.ExecContext(ctx, 1, "42"); err != nil {
+		t.Errorf("Exec failed: %v", err)
+	}
+	if _, err := stmt.ExecContext(ctx, 1, "43"); err != ErrUnconstrained {
+		t.Errorf("expected %v, got %v", ErrUnconstrained, err)
+	}
+}
+
+func TestSetSession(t *testing.T) {
+	in := New(Config{Mode: SetSession, Tables: []string{"invoices"}})
+	con := &fakedb.Connector{}
+	db := sql.OpenDB(sqlmw.Connector(con, in))
+	t.Cleanup(func() {
+		if err := db.Close(); err != nil {
+			t.Errorf("Failed to close db: %v", err)
+		}
+	})
+	db.SetMaxOpenConns(1)
+	ctx := context.Background()
+	a, b := WithTenant(ctx, "a"), WithTenant(ctx, "b")
+
+	for _, ctx := range []context.Context{a, a, b} {
+		if _, err := db.ExecContext(ctx, "DELETE FROM invoices"); err != nil {
+			t.Fatalf("Exec failed: %v", err)
+		}
+	}
+	if _, err := db.ExecContext(ctx, "DELETE FROM invoices"); err != ErrNoTenant {
+		t.Errorf("expected %v, got %v", ErrNoTenant, err)
+	}
+
+	tx, err := db.BeginTx(a, nil)
+	if err != nil {
+		t.Fatalf("BeginTx failed: %v", err)
+	}
+	if _, err := tx.ExecContext(a, "DELETE FROM invoices"); err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	if err := tx.Rollback(); err != nil {
+		t.Fatalf("Rollback failed: %v", err)
+	}
+	if _, err := db.ExecContext(a, "DELETE FROM invoices"); err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+
+	var got []string
+	for _, call := range con.Calls() {
+		if call.Query == defaultSessionQuery {
+			got = append(got, "set "+call.Args[1].Value.(string))
+		} else {
+			got = append(got, call.Query)
+		}
+	}
+	expected := []string{
+		"set a", "DELETE FROM invoices", "DELETE FROM invoices",
+		"set b", "DELETE FROM invoices",
+		"set ",
+		"set a", "BEGIN", "DELETE FROM invoices", "ROLLBACK",
+		"set a", "DELETE FROM invoices",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("unexpected statements:\n got: %q\nwant: %q", got, expected)
+	}
+	if arg := con.Calls()[0].Args[0]; arg != (driver.NamedValue{Ordinal: 1, Value: "app.tenant_id"}) {
+		t.Errorf("unexpected setting argument %+v", arg)
+	}
+}
+
+func TestSetSessionSkip(t *testing.T) {
+	in := New(Config{Mode: SetSession, Tables: []string{"invoices"}})
+	skipped := false
+	con := &fakedb.Connector{Handler: func(_ context.Context, query string, _ []driver.NamedValue) (*fakedb.Response, error) {
+		if query == defaultSessionQuery && !skipped {
+			skipped = true
+			return nil, driver.ErrSkip
+		}
+		return &fakedb.Response{}, nil
+	}}
+	db := sql.OpenDB(sqlmw.Connector(con, in))
+	t.Cleanup(func() {
+		if err := db.Close(); err != nil {
+			t.Errorf("Failed to close db: %v", err)
+		}
+	})
+
+	// database/sql runs the statement again as a prepared one
+	if _, err := db.ExecContext(WithTenant(context.Background(), "a"), "DELETE FROM invoices"); err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	if !skipped {
+		t.Error("expected the setting to be skipped once")
+	}
+}
+
+func TestConformance(t *testing.T) {
+	sqlmwtest.RunConformance(t, func() sqlmw.Interceptor { return New(Config{}) })
+}
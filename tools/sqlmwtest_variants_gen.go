@@ -0,0 +1,113 @@
+// +build ignore
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+)
+
+// variants describes the optional interfaces of a driver type, generating
+// one implementation per subset of them.
+type variants struct {
+	base   string // the type implementing the mandatory methods
+	iface  string // the interface of the variants
+	prefix string // the prefix of the adapters implementing the optional interfaces
+	intfs  []string
+}
+
+func main() {
+	var err error
+	fn := flag.String("o", "", "output file")
+	flag.Parse()
+
+	out := os.Stdout
+	if *fn != "" {
+		out, err = os.Create(*fn)
+		if err != nil {
+			log.Fatalf("could not create file %q, %v", *fn, err)
+		}
+	}
+
+	genComment(out)
+	fmt.Fprintln(out, "package sqlmwtest")
+	fmt.Fprintln(out, "")
+	fmt.Fprintln(out, "import \"database/sql/driver\"")
+
+	genVariants(out, variants{
+		base:   "conn",
+		iface:  "driver.Conn",
+		prefix: "conn",
+		intfs: []string{
+			"ExecerContext",
+			"QueryerContext",
+			"ConnPrepareContext",
+			"ConnBeginTx",
+			"Pinger",
+			"SessionResetter",
+			"Validator",
+			"NamedValueChecker",
+		},
+	})
+	genVariants(out, variants{
+		base:   "rows",
+		iface:  "driver.Rows",
+		prefix: "rows",
+		intfs: []string{
+			"NextResultSet",
+			"ColumnTypeDatabaseTypeName",
+			"ColumnTypeLength",
+			"ColumnTypeNullable",
+			"ColumnTypePrecisionScale",
+			"ColumnTypeScanType",
+		},
+	})
+
+	err = out.Close()
+	if err != nil {
+		log.Fatalf("could close file, %v", err)
+	}
+}
+
+func genComment(w io.Writer) {
+	str := time.Now().Format(time.Stamp)
+	fmt.Fprintln(w, "// Code generated using tools/sqlmwtest_variants_gen.go DO NOT EDIT.")
+	fmt.Fprintf(w, "// Date: %s\n", str)
+	fmt.Fprintln(w, "")
+}
+
+func forEachBit(n int, intfs []string, f func(intf string)) {
+	for i := 0; i < len(intfs); i++ {
+		b := 1 << i
+		if b&n == b {
+			f(intfs[i])
+		}
+	}
+}
+
+func genVariants(w io.Writer, v variants) {
+	tlen := 1 << len(v.intfs)
+	fmt.Fprintf(w, "\n// %sVariants are the implementations of %s, indexed by the set of\n", v.base, v.iface)
+	fmt.Fprintf(w, "// optional interfaces they implement.\n")
+	fmt.Fprintf(w, "var %sVariants = [%d]func(*%s) %s{\n", v.base, tlen, v.base, v.iface)
+	defer fmt.Fprintln(w, "}")
+
+	fmt.Fprintf(w, "\tfunc(x *%s) %s { return x },\n", v.base, v.iface)
+	for i := 1; i < tlen; i++ {
+		fmt.Fprintf(w, "\tfunc(x *%s) %s {\n", v.base, v.iface)
+		fmt.Fprintf(w, "\t\treturn struct {\n\t\t\t*%s\n", v.base)
+		forEachBit(i, v.intfs, func(intf string) {
+			fmt.Fprintf(w, "\t\t\t%s%s\n", v.prefix, intf)
+		})
+		fmt.Fprintln(w, "\t\t}{\n\t\t\tx,")
+		forEachBit(i, v.intfs, func(intf string) {
+			fmt.Fprintf(w, "\t\t\t%s%s{x},\n", v.prefix, intf)
+		})
+		fmt.Fprintln(w, "\t\t}")
+		fmt.Fprintln(w, "\t},")
+	}
+}
@@ -9,6 +9,7 @@ type wrappedTx struct {
 	intr   Interceptor
 	ctx    context.Context
 	parent driver.Tx
+	conn   wrappedConn
 }
 
 // Compile time validation that our types implement the expected interfaces
@@ -17,9 +18,25 @@ var (
 )
 
 func (t wrappedTx) Commit() (err error) {
+	defer t.conn.setInTx(false)
 	return t.intr.TxCommit(t.ctx, t.parent)
 }
 
 func (t wrappedTx) Rollback() (err error) {
+	defer t.conn.setInTx(false)
 	return t.intr.TxRollback(t.ctx, t.parent)
 }
+
+type txKey struct{}
+
+// InTx reports whether an intercepted call is made on a connection with an
+// open transaction. ctx must be the context received by one of the Conn, Stmt
+// or Rows methods of an Interceptor.
+//
+// Statements run inside a transaction are not otherwise distinguishable from
+// the others, which matters to interceptors that reorder, cache or batch
+// statements.
+func InTx(ctx context.Context) bool {
+	inTx, _ := ctx.Value(txKey{}).(bool)
+	return inTx
+}
@@ -0,0 +1,77 @@
+package sqlmw
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"testing"
+)
+
+type inTxInterceptor struct {
+	NullInterceptor
+	inTx []bool
+}
+
+func (i *inTxInterceptor) ConnExecContext(ctx context.Context, conn driver.ExecerContext, query string, args []driver.NamedValue) (driver.Result, error) {
+	i.inTx = append(i.inTx, InTx(ctx))
+	return conn.ExecContext(ctx, query, args)
+}
+
+func TestInTx(t *testing.T) {
+	driverName := driverName(t)
+
+	con := &fakeConn{tx: fakeTx{}}
+	ti := &inTxInterceptor{}
+
+	sql.Register(
+		driverName,
+		Driver(&fakeDriver{conn: con}, ti),
+	)
+
+	db, err := sql.Open(driverName, "")
+	if err != nil {
+		t.Fatalf("Failed to open: %v", err)
+	}
+
+	t.Cleanup(func() {
+		if err := db.Close(); err != nil {
+			t.Errorf("Failed to close db: %v", err)
+		}
+	})
+
+	ctx := context.Background()
+	if _, err := db.ExecContext(ctx, ""); err != nil {
+		t.Fatalf("Exec failed: %s", err)
+	}
+
+	for _, commit := range []bool{true, false} {
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			t.Fatalf("BeginTx failed: %s", err)
+		}
+		if _, err := tx.ExecContext(ctx, ""); err != nil {
+			t.Fatalf("Exec failed: %s", err)
+		}
+		if commit {
+			err = tx.Commit()
+		} else {
+			err = tx.Rollback()
+		}
+		if err != nil {
+			t.Fatalf("ending tx failed: %s", err)
+		}
+		if _, err := db.ExecContext(ctx, ""); err != nil {
+			t.Fatalf("Exec failed: %s", err)
+		}
+	}
+
+	expected := []bool{false, true, false, true, false}
+	if len(ti.inTx) != len(expected) {
+		t.Fatalf("expected %d calls, got %d", len(expected), len(ti.inTx))
+	}
+	for i := range expected {
+		if ti.inTx[i] != expected[i] {
+			t.Errorf("call %d: InTx = %v, expected %v", i, ti.inTx[i], expected[i])
+		}
+	}
+}